@@ -5,11 +5,17 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/claude-code-template/prompt-manager/internal/api"
 	"github.com/claude-code-template/prompt-manager/internal/api/handlers"
 	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+	"github.com/claude-code-template/prompt-manager/internal/metrics"
+	"github.com/claude-code-template/prompt-manager/internal/models"
+	"github.com/claude-code-template/prompt-manager/internal/validation"
 )
 
 const (
@@ -22,8 +28,61 @@ func main() {
 		port = DefaultPort
 	}
 
+	// Request and database metrics, served at /metrics
+	registry := metrics.NewRegistry()
+
+	// Shared by the database, API server and hook handlers
+	logger := logging.Default()
+
 	// Initialize database
 	config := database.DefaultConfig()
+	config.Metrics = registry
+	config.Logger = logger
+	if limit := os.Getenv("MAX_CONVERSATIONS_PER_SESSION"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			log.Fatalf("Invalid MAX_CONVERSATIONS_PER_SESSION: %q", limit)
+		}
+		config.MaxConversationsPerSession = n
+	}
+	if quota := os.Getenv("MAX_CHARACTERS_PER_SESSION"); quota != "" {
+		n, err := strconv.Atoi(quota)
+		if err != nil || n < 0 {
+			log.Fatalf("Invalid MAX_CHARACTERS_PER_SESSION: %q", quota)
+		}
+		config.MaxCharactersPerSession = n
+	}
+	if threshold := os.Getenv("INCREMENTAL_VACUUM_THRESHOLD"); threshold != "" {
+		n, err := strconv.Atoi(threshold)
+		if err != nil || n < 0 {
+			log.Fatalf("Invalid INCREMENTAL_VACUUM_THRESHOLD: %q", threshold)
+		}
+		config.IncrementalVacuumThreshold = n
+	}
+	if window := os.Getenv("CONVERSATION_REUSE_WINDOW"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid CONVERSATION_REUSE_WINDOW: %q", window)
+		}
+		config.ConversationReuseWindow = d
+	}
+	if timeout := os.Getenv("MIGRATION_LOCK_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid MIGRATION_LOCK_TIMEOUT: %q", timeout)
+		}
+		config.MigrationLockTimeout = d
+	}
+	config.LinkByTranscriptPath = os.Getenv("LINK_BY_TRANSCRIPT_PATH") == "true"
+	config.DeleteEmptySessions = os.Getenv("DELETE_EMPTY_SESSIONS") == "true"
+	if method := os.Getenv("RATING_AGGREGATION"); method != "" {
+		aggregation, err := models.ParseRatingAggregation(method)
+		if err != nil {
+			log.Fatalf("Invalid RATING_AGGREGATION: %v", err)
+		}
+		config.RatingAggregation = aggregation
+	}
+
 	db, err := database.New(config)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
@@ -35,40 +94,179 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Converting an existing database rewrites the whole file, so it only
+	// happens when explicitly requested
+	if os.Getenv("CONVERT_INCREMENTAL_VACUUM") == "true" {
+		if err := db.EnableIncrementalVacuum(); err != nil {
+			log.Fatalf("Failed to enable incremental vacuum: %v", err)
+		}
+	}
+
 	// Initialize API server
-	server := api.NewServer(db)
+	serverConfig := api.DefaultConfig()
+	serverConfig.Logger = logger
+	serverConfig.CollapseCommentWhitespace = os.Getenv("COLLAPSE_COMMENT_WHITESPACE") == "true"
+	serverConfig.CamelCaseJSON = os.Getenv("CAMEL_CASE_JSON") == "true"
+	serverConfig.RatingWebhookURL = os.Getenv("RATING_WEBHOOK_URL")
+	serverConfig.StrictSessionIDs = os.Getenv("STRICT_SESSION_IDS") == "true"
+	serverConfig.StrictPagination = os.Getenv("STRICT_PAGINATION") == "true"
+	if limit := os.Getenv("MAX_WORKING_DIRECTORY_LENGTH"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			log.Fatalf("Invalid MAX_WORKING_DIRECTORY_LENGTH: %q", limit)
+		}
+		serverConfig.MaxWorkingDirectoryLength = n
+	}
+	if limit := os.Getenv("MAX_TRANSCRIPT_PATH_LENGTH"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			log.Fatalf("Invalid MAX_TRANSCRIPT_PATH_LENGTH: %q", limit)
+		}
+		serverConfig.MaxTranscriptPathLength = n
+	}
+	if size := os.Getenv("MAX_PAGE_SIZE"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < validation.MinPageSize || n > validation.HardMaxPageSize {
+			log.Fatalf("Invalid MAX_PAGE_SIZE: %q (must be between %d and %d)", size, validation.MinPageSize, validation.HardMaxPageSize)
+		}
+		serverConfig.MaxPageSize = n
+	}
+	if limit := os.Getenv("MAX_IN_FLIGHT_REQUESTS"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			log.Fatalf("Invalid MAX_IN_FLIGHT_REQUESTS: %q", limit)
+		}
+		serverConfig.MaxInFlightRequests = n
+	}
+	if window := os.Getenv("EVENT_BATCH_WINDOW"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid EVENT_BATCH_WINDOW: %q", window)
+		}
+		serverConfig.EventBatchWindow = d
+	}
+	server := api.NewServerWithConfig(db, serverConfig)
 
-	// Initialize message handlers
-	promptHandler := handlers.NewPromptHandler(db)
-	responseHandler := handlers.NewResponseHandler(db)
-	sessionHandler := handlers.NewSessionHandler(db)
+	hookConfig := handlers.DefaultConfig()
+	hookConfig.Logger = logger
+	hookConfig.StrictDecoding = os.Getenv("STRICT_HOOK_DECODING") == "true"
 
 	// Setup routes
+	router := newRouter(db, server, hookConfig, registry)
+
+	fmt.Printf("Starting Prompt Manager server on port %s\n", port)
+	fmt.Printf("Database: %s\n", config.DatabasePath)
+	log.Fatal(http.ListenAndServe(":"+port, router))
+}
+
+// newRouter wires the API server and hook handlers onto their routes
+func newRouter(db *database.DB, server *api.Server, hookConfig handlers.Config, registry *metrics.Registry) *mux.Router {
+	// Initialize message handlers
+	hookConfig.Events = server.Events()
+	promptHandler := handlers.NewPromptHandlerWithConfig(db, hookConfig)
+	responseHandler := handlers.NewResponseHandlerWithConfig(db, hookConfig)
+	sessionHandler := handlers.NewSessionHandlerWithConfig(db, hookConfig)
+	limitBody := api.MaxBodyBytes(hookConfig.MaxRequestBytes, hookConfig.Logger)
+	limitRate := api.RateLimitMiddleware(hookConfig.RateLimit, hookConfig.RateLimitBurst, hookConfig.Logger)
+
 	router := mux.NewRouter()
+	router.MethodNotAllowedHandler = api.MethodNotAllowedHandler(router, server.Logger())
+	router.Use(api.MetricsMiddleware(registry))
+	// Probes and scrapes must get through a busy server. The event stream
+	// stays open for as long as the client listens, so it must neither hold
+	// a concurrency slot nor be buffered for compression or rewriting.
+	router.Use(server.ConcurrencyLimitMiddleware("/health", "/livez", "/readyz", "/metrics", "/events"))
+	router.Use(api.GzipMiddleware(api.DefaultGzipMinSize, "/events"))
+	router.Use(server.JSONCaseMiddleware("/events"))
 	
-	// Health check endpoint
-	router.HandleFunc("/health", server.HealthHandler).Methods("GET")
+	// Health check endpoints: /livez only checks the process is up, while
+	// /health and /readyz also check the database
+	router.HandleFunc("/health", server.HealthHandler).Methods("GET", "HEAD")
+	router.HandleFunc("/readyz", server.HealthHandler).Methods("GET", "HEAD")
+	router.HandleFunc("/livez", server.LivenessHandler).Methods("GET", "HEAD")
+	
+	// Prometheus metrics, with database counts sampled on each scrape
+	registry.RegisterGauges(metrics.DBStatsGauges(db.Stats))
+	router.Handle("/metrics", registry).Methods("GET")
 	
 	// Message endpoints for hook processing
-	router.HandleFunc("/messages/prompt", promptHandler.HandlePromptSubmit).Methods("POST")
-	router.HandleFunc("/messages/response", responseHandler.HandleResponseSubmit).Methods("POST")
-	router.HandleFunc("/messages/session", sessionHandler.HandleSessionEvent).Methods("POST")
+	router.Handle("/messages/prompt", limitRate(limitBody(http.HandlerFunc(promptHandler.HandlePromptSubmit)))).Methods("POST")
+	router.Handle("/messages/response", limitRate(limitBody(http.HandlerFunc(responseHandler.HandleResponseSubmit)))).Methods("POST")
+	router.Handle("/messages/session", limitRate(limitBody(http.HandlerFunc(sessionHandler.HandleSessionEvent)))).Methods("POST")
+	
+	// Live updates for new conversations and messages
+	router.HandleFunc("/events", server.EventsHandler).Methods("GET")
 	
 	// Conversation endpoints (at root level for activity monitor compatibility)
 	router.HandleFunc("/conversations", server.ListConversationsHandler).Methods("GET")
 	router.HandleFunc("/conversations", server.CreateConversationHandler).Methods("POST")
+	router.HandleFunc("/conversations/bulk-delete", server.BulkDeleteConversationsHandler).Methods("POST")
+	router.HandleFunc("/conversations/purge", server.PurgeDeletedConversationsHandler).Methods("POST")
+	router.HandleFunc("/conversations/awaiting-response", server.ListAwaitingResponseHandler).Methods("GET")
 	router.HandleFunc("/conversations/{id}", server.GetConversationHandler).Methods("GET")
 	router.HandleFunc("/conversations/{id}", server.UpdateConversationHandler).Methods("PUT")
 	router.HandleFunc("/conversations/{id}", server.DeleteConversationHandler).Methods("DELETE")
+	router.HandleFunc("/conversations/{id}/restore", server.RestoreConversationHandler).Methods("POST")
+	router.HandleFunc("/conversations/{id}/linked", server.GetLinkedConversationsHandler).Methods("GET")
+	router.HandleFunc("/conversations/{id}/export", server.ExportConversationHandler).Methods("GET")
+	router.HandleFunc("/conversations/{id}/completeness", server.GetConversationCompletenessHandler).Methods("GET")
+	router.HandleFunc("/conversations/{id}/timeline", server.GetConversationTimelineHandler).Methods("GET")
+	router.HandleFunc("/conversations/{id}/stats", server.GetConversationContentStatsHandler).Methods("GET")
+	router.HandleFunc("/conversations/{id}/tags", server.AddConversationTagHandler).Methods("POST")
+	router.HandleFunc("/conversations/{id}/tags/{tag_id}", server.RemoveConversationTagHandler).Methods("DELETE")
 	
 	// Rating endpoints
 	router.HandleFunc("/conversations/{id}/ratings", server.CreateConversationRatingHandler).Methods("POST")
 	router.HandleFunc("/conversations/{id}/ratings", server.GetConversationRatingsHandler).Methods("GET")
 	router.HandleFunc("/ratings/{id}", server.UpdateRatingHandler).Methods("PUT")
+	router.HandleFunc("/ratings/{id}", server.PatchRatingHandler).Methods("PATCH")
 	router.HandleFunc("/ratings/{id}", server.DeleteRatingHandler).Methods("DELETE")
 	router.HandleFunc("/ratings/stats", server.GetRatingStatsHandler).Methods("GET")
 	
-	fmt.Printf("Starting Prompt Manager server on port %s\n", port)
-	fmt.Printf("Database: %s\n", config.DatabasePath)
-	log.Fatal(http.ListenAndServe(":"+port, router))
-}
\ No newline at end of file
+	// Tag endpoints
+	router.HandleFunc("/tags", server.ListTagsHandler).Methods("GET")
+	router.HandleFunc("/tags", server.CreateTagHandler).Methods("POST")
+	router.HandleFunc("/tags/merge", server.MergeTagsHandler).Methods("POST")
+	router.HandleFunc("/tags/{id}", server.UpdateTagHandler).Methods("PUT")
+	router.HandleFunc("/tags/{id}", server.DeleteTagHandler).Methods("DELETE")
+	
+	// Tag rule endpoints
+	router.HandleFunc("/tag-rules", server.ListTagRulesHandler).Methods("GET")
+	router.HandleFunc("/tag-rules", server.CreateTagRuleHandler).Methods("POST")
+	router.HandleFunc("/tag-rules/preview", server.PreviewTagRuleHandler).Methods("POST")
+	router.HandleFunc("/tag-rules/{id}", server.GetTagRuleHandler).Methods("GET")
+	router.HandleFunc("/tag-rules/{id}", server.UpdateTagRuleHandler).Methods("PUT")
+	router.HandleFunc("/tag-rules/{id}", server.DeleteTagRuleHandler).Methods("DELETE")
+	
+	// Stats endpoints
+	router.HandleFunc("/stats/overview", server.GetOverviewStatsHandler).Methods("GET")
+	router.HandleFunc("/stats/report", server.GetStatsReportHandler).Methods("GET")
+	router.HandleFunc("/stats/rating-coverage", server.GetRatingCoverageHandler).Methods("GET")
+	router.HandleFunc("/stats/rating-by-length", server.GetRatingByLengthHandler).Methods("GET")
+	router.HandleFunc("/stats/by-directory", server.GetDirectoryStatsHandler).Methods("GET")
+	router.HandleFunc("/stats/model-latency", server.GetModelLatencyStatsHandler).Methods("GET")
+	router.HandleFunc("/stats/tools", server.GetToolCallStatsHandler).Methods("GET")
+	router.HandleFunc("/stats/tool-durations", server.GetToolDurationStatsHandler).Methods("GET")
+	router.HandleFunc("/stats/most-tools", server.GetMostToolsHandler).Methods("GET")
+	router.HandleFunc("/stats/message-sizes", server.GetMessageSizeStatsHandler).Methods("GET")
+	router.HandleFunc("/stats/activity-range", server.GetActivityRangeHandler).Methods("GET")
+	router.HandleFunc("/stats/heatmap", server.GetActivityHeatmapHandler).Methods("GET")
+	router.HandleFunc("/stats/weekday", server.GetWeekdayStatsHandler).Methods("GET")
+	router.HandleFunc("/stats/creation-rate", server.GetCreationRateHandler).Methods("GET")
+	router.HandleFunc("/stats/requests", server.GetRequestStatsHandler).Methods("GET")
+	
+	// Session endpoints
+	router.HandleFunc("/sessions", server.ListSessionsHandler).Methods("GET")
+	router.HandleFunc("/sessions/stats", server.GetSessionStatsHandler).Methods("POST")
+	router.HandleFunc("/sessions/{session_id}", server.GetSessionHandler).Methods("GET")
+	router.HandleFunc("/sessions/{session_id}/graph", server.GetSessionGraphHandler).Methods("GET")
+	router.HandleFunc("/sessions/{session_id}/status", server.UpdateSessionStatusHandler).Methods("PUT")
+	
+	// Message endpoints
+	router.HandleFunc("/messages", server.ListMessagesHandler).Methods("GET")
+	router.HandleFunc("/messages/{id}/neighbors", server.GetMessageNeighborsHandler).Methods("GET")
+	router.HandleFunc("/messages/{id}", server.GetMessageHandler).Methods("GET")
+	router.HandleFunc("/messages/{id}", server.DeleteMessageHandler).Methods("DELETE")
+
+	return router
+}
@@ -1,11 +1,22 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/claude-code-template/prompt-manager/internal/api"
+	"github.com/claude-code-template/prompt-manager/internal/api/handlers"
 	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/metrics"
+	"github.com/claude-code-template/prompt-manager/internal/models"
 )
 
 func TestMainIntegration(t *testing.T) {
@@ -56,4 +67,224 @@ func TestMainIntegration(t *testing.T) {
 	if stats == nil {
 		t.Error("Expected non-nil stats")
 	}
-}
\ No newline at end of file
+}
+
+// testRouterConfig is what newTestRouter builds the router from. Options see
+// the migrated database and may adjust the server and hook configurations.
+type testRouterConfig struct {
+	db     *database.DB
+	server api.Config
+	hooks  handlers.Config
+}
+
+// newTestRouter returns the application router backed by a freshly migrated
+// database in a temporary directory, closed when the test ends
+func newTestRouter(t *testing.T, options ...func(*testRouterConfig)) http.Handler {
+	t.Helper()
+
+	config := &database.Config{
+		DatabasePath:  filepath.Join(t.TempDir(), "test.db"),
+		MigrationsDir: "../database/migrations",
+	}
+
+	db, err := database.New(config)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.RunMigrations(config.MigrationsDir); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	routerConfig := testRouterConfig{db: db, server: api.DefaultConfig(), hooks: handlers.DefaultConfig()}
+	for _, option := range options {
+		option(&routerConfig)
+	}
+
+	return newRouter(db, api.NewServerWithConfig(db, routerConfig.server), routerConfig.hooks, metrics.NewRegistry())
+}
+
+func TestHealthHeadRequest(t *testing.T) {
+	router := newTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodHead, "/health", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	if rr.Body.Len() != 0 {
+		t.Errorf("Expected empty body for HEAD request, got %q", rr.Body.String())
+	}
+}
+
+func TestLivenessAndReadinessRoutes(t *testing.T) {
+	var db *database.DB
+	router := newTestRouter(t, func(c *testRouterConfig) { db = c.db })
+
+	// Liveness must not depend on the database
+	db.Close()
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{"/livez", http.StatusOK},
+		{"/readyz", http.StatusServiceUnavailable},
+		{"/health", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.expectedStatus, rr.Code)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	router := newTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPatch, "/conversations/1", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected status %d, got %d", http.StatusMethodNotAllowed, rr.Code)
+	}
+
+	if allow := rr.Header().Get("Allow"); allow != "GET, PUT, DELETE" {
+		t.Errorf("Expected Allow header %q, got %q", "GET, PUT, DELETE", allow)
+	}
+
+	var response api.APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Success || response.Error == nil || *response.Error != "Method not allowed" {
+		t.Errorf("Expected a method not allowed error, got %s", rr.Body.String())
+	}
+}
+
+func TestStrictHookDecoding(t *testing.T) {
+	// Payload with a misspelled session_id field alongside the real one
+	payload := `{"event": "UserPromptSubmit", "session_id": "strict-session", "sesion_id": "typo", "data": {"prompt": "Test prompt"}}`
+
+	tests := []struct {
+		name           string
+		strict         bool
+		expectedStatus int
+	}{
+		{"lenient mode ignores unknown field", false, http.StatusCreated},
+		{"strict mode rejects unknown field", true, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(t, func(c *testRouterConfig) { c.hooks.StrictDecoding = tt.strict })
+
+			req := httptest.NewRequest(http.MethodPost, "/messages/prompt", strings.NewReader(payload))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.strict && !strings.Contains(rr.Body.String(), `sesion_id`) {
+				t.Errorf("Expected the error to name the unknown field, got %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	// camelCase rewriting is on, and the default client asks for gzip, to
+	// check the stream is buffered by neither
+	ts := httptest.NewServer(newTestRouter(t, func(c *testRouterConfig) { c.server.CamelCaseJSON = true }))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	defer stream.Body.Close()
+
+	if got := stream.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", got)
+	}
+
+	payload := `{"event": "UserPromptSubmit", "session_id": "events-session", "data": {"prompt": "Hello"}}`
+	resp, err := http.Post(ts.URL+"/messages/prompt", "application/json", strings.NewReader(payload))
+	if err != nil {
+		t.Fatalf("Failed to submit prompt: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	// A new session yields a conversation event followed by a message event
+	reader := bufio.NewReader(stream.Body)
+	var events []models.Event
+	for len(events) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event stream: %v", err)
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		var event models.Event
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("Failed to decode event %q: %v", data, err)
+		}
+		events = append(events, event)
+	}
+
+	if events[0].Type != models.EventConversationCreated || events[0].SessionID != "events-session" {
+		t.Errorf("Expected a conversation.created event for events-session, got %+v", events[0])
+	}
+	if events[1].Type != models.EventMessageCreated || events[1].MessageID == nil || events[1].MessageType != "prompt" {
+		t.Errorf("Expected a message.created event for the prompt, got %+v", events[1])
+	}
+	if events[1].ConversationID != events[0].ConversationID {
+		t.Errorf("Expected both events for conversation %d, got %d", events[0].ConversationID, events[1].ConversationID)
+	}
+}
+
+func TestMetricsRoute(t *testing.T) {
+	router := newTestRouter(t)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	body := rr.Body.String()
+	for _, line := range []string{
+		`prompt_manager_http_requests_total{handler="/health",status="200"} 1`,
+		"prompt_manager_conversations 0",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected %q in metrics output:\n%s", line, body)
+		}
+	}
+}
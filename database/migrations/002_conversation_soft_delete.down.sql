@@ -0,0 +1,6 @@
+-- Rollback migration for conversation soft delete
+-- Version: 002
+
+DROP INDEX IF EXISTS idx_conversations_deleted_at;
+
+ALTER TABLE conversations DROP COLUMN deleted_at;
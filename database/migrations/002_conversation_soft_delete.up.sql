@@ -0,0 +1,7 @@
+-- Conversation soft delete
+-- Version: 002
+-- Description: Track soft-deleted conversations so they can be excluded from live data
+
+ALTER TABLE conversations ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_conversations_deleted_at ON conversations(deleted_at);
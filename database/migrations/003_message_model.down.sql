@@ -0,0 +1,6 @@
+-- Rollback migration for message model attribution
+-- Version: 003
+
+DROP INDEX IF EXISTS idx_messages_model;
+
+ALTER TABLE messages DROP COLUMN model;
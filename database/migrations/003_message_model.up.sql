@@ -0,0 +1,7 @@
+-- Message model attribution
+-- Version: 003
+-- Description: Record which model produced each response so latency and quality can be compared per model
+
+ALTER TABLE messages ADD COLUMN model TEXT;
+
+CREATE INDEX idx_messages_model ON messages(model);
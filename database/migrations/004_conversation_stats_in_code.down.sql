@@ -0,0 +1,13 @@
+-- Rollback migration for conversation stats maintained in code
+-- Version: 004
+
+CREATE TRIGGER update_conversation_stats
+    AFTER INSERT ON messages
+    FOR EACH ROW
+BEGIN
+    UPDATE conversations 
+    SET prompt_count = prompt_count + 1,
+        total_characters = total_characters + NEW.character_count,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = NEW.conversation_id;
+END;
@@ -0,0 +1,30 @@
+-- Conversation stats maintained in code
+-- Version: 004
+-- Description: Drop the stats trigger, which counted responses as prompts.
+-- Message inserts now update prompt_count and total_characters in the same transaction.
+
+DROP TRIGGER IF EXISTS update_conversation_stats;
+
+-- The timestamp trigger would stamp every corrected row with the migration
+-- time, so it is dropped for the backfill and recreated afterwards
+DROP TRIGGER IF EXISTS update_conversation_timestamp;
+
+-- Correct existing counts so prompt_count only reflects prompts
+UPDATE conversations
+SET prompt_count = (
+    SELECT COUNT(*) FROM messages
+    WHERE messages.conversation_id = conversations.id AND messages.message_type = 'prompt'
+)
+WHERE prompt_count <> (
+    SELECT COUNT(*) FROM messages
+    WHERE messages.conversation_id = conversations.id AND messages.message_type = 'prompt'
+);
+
+CREATE TRIGGER update_conversation_timestamp
+    AFTER UPDATE ON conversations
+    FOR EACH ROW
+BEGIN
+    UPDATE conversations 
+    SET updated_at = CURRENT_TIMESTAMP
+    WHERE id = NEW.id;
+END;
@@ -0,0 +1,6 @@
+-- Rollback migration for the composite message index
+-- Version: 005
+
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_id ON messages(conversation_id);
+
+DROP INDEX IF EXISTS idx_messages_conversation_timestamp;
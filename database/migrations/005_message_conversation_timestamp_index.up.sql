@@ -0,0 +1,10 @@
+-- Composite index for per-conversation message reads
+-- Version: 005
+-- Description: Messages are always read per conversation in timestamp order, so index
+-- (conversation_id, timestamp) to serve both the filter and the sort. It supersedes
+-- the single-column conversation_id index. The session_id and rating lookups are
+-- already covered by indexes from 001.
+
+CREATE INDEX idx_messages_conversation_timestamp ON messages(conversation_id, timestamp);
+
+DROP INDEX IF EXISTS idx_messages_conversation_id;
@@ -0,0 +1,6 @@
+-- Rollback migration for automatic tagging rules
+-- Version: 006
+
+DROP INDEX IF EXISTS idx_tag_rules_tag_id;
+
+DROP TABLE IF EXISTS tag_rules;
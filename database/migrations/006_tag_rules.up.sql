@@ -0,0 +1,15 @@
+-- Automatic tagging rules
+-- Version: 006
+-- Description: Map a keyword or regular expression to a tag that is attached to a
+-- conversation whenever one of its messages matches
+
+CREATE TABLE tag_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    pattern TEXT NOT NULL,
+    is_regex BOOLEAN NOT NULL DEFAULT 0,
+    tag_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_tag_rules_tag_id ON tag_rules(tag_id);
@@ -0,0 +1,4 @@
+-- Rollback migration for session duration
+-- Version: 007
+
+ALTER TABLE sessions DROP COLUMN duration_ms;
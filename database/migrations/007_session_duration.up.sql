@@ -0,0 +1,6 @@
+-- Session duration
+-- Version: 007
+-- Description: Record how long a session ran, as reported by the SessionEnd hook or
+-- measured from the session start time
+
+ALTER TABLE sessions ADD COLUMN duration_ms INTEGER;
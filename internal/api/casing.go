@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// JSONCaseHeader lets a client ask for camelCase keys in JSON responses by
+// sending "X-JSON-Case: camel"
+const JSONCaseHeader = "X-JSON-Case"
+
+// opaqueJSONFields hold free-form, user-supplied objects whose keys are
+// passed through unchanged when rewriting keys to camelCase
+var opaqueJSONFields = map[string]bool{
+	"arguments": true,
+	"metadata":  true,
+}
+
+// JSONCaseMiddleware rewrites the object keys of JSON responses from
+// snake_case to camelCase when Config.CamelCaseJSON is set or the request
+// carries "X-JSON-Case: camel". Other responses pass through untouched.
+// Rewriting buffers the whole response, so streaming endpoints must be
+// listed in exemptPaths.
+func (s *Server) JSONCaseMiddleware(exemptPaths ...string) func(http.Handler) http.Handler {
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", JSONCaseHeader)
+
+			if !s.config.CamelCaseJSON && !strings.EqualFold(r.Header.Get(JSONCaseHeader), "camel") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			buffered := &bufferedResponse{header: w.Header(), status: http.StatusOK}
+			next.ServeHTTP(buffered, r)
+
+			body := buffered.body.Bytes()
+			if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+				if rewritten, err := camelCaseJSON(body); err == nil {
+					body = rewritten
+					w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+				}
+			}
+
+			w.WriteHeader(buffered.status)
+			w.Write(body)
+		})
+	}
+}
+
+// bufferedResponse captures a handler's status and body so the body can be
+// rewritten before it is sent. Headers are written straight through.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) { b.status = status }
+
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+// camelCaseJSON re-encodes a JSON document with its object keys in camelCase
+func camelCaseJSON(body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := json.NewEncoder(&out).Encode(camelCaseKeys(value)); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// camelCaseKeys rewrites the keys of every object in a decoded JSON value,
+// leaving the contents of opaque fields as they are
+func camelCaseKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		rewritten := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			if opaqueJSONFields[key] {
+				rewritten[snakeToCamel(key)] = child
+				continue
+			}
+			rewritten[snakeToCamel(key)] = camelCaseKeys(child)
+		}
+		return rewritten
+	case []interface{}:
+		for i, child := range v {
+			v[i] = camelCaseKeys(child)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// snakeToCamel converts a snake_case key such as "prompt_count" to
+// "promptCount"
+func snakeToCamel(key string) string {
+	parts := strings.Split(key, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
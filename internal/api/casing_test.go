@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestJSONCaseMiddleware(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("camel-session", stringPtr("Camel"), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	toolCalls := `[{"name":"Read","arguments":{"file_path":"main.go"}}]`
+	if _, err := server.db.CreateMessage(conv.ID, "prompt", "hello", &toolCalls, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.Use(server.JSONCaseMiddleware())
+	router.HandleFunc("/conversations/{id}", server.GetConversationHandler)
+
+	get := func(t *testing.T, header string) map[string]interface{} {
+		req, err := http.NewRequest("GET", fmt.Sprintf("/conversations/%d", conv.ID), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header != "" {
+			req.Header.Set(JSONCaseHeader, header)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return response.Data
+	}
+
+	t.Run("snake_case by default", func(t *testing.T) {
+		data := get(t, "")
+		if _, ok := data["session_id"]; !ok {
+			t.Errorf("Expected session_id key, got %v", data)
+		}
+		if _, ok := data["sessionId"]; ok {
+			t.Errorf("Expected no sessionId key, got %v", data)
+		}
+	})
+
+	t.Run("camelCase on request", func(t *testing.T) {
+		data := get(t, "camel")
+		if data["sessionId"] != "camel-session" {
+			t.Errorf("Expected sessionId key, got %v", data)
+		}
+		if data["promptCount"] != float64(1) {
+			t.Errorf("Expected promptCount 1, got %v", data["promptCount"])
+		}
+
+		messages, ok := data["messages"].([]interface{})
+		if !ok || len(messages) != 1 {
+			t.Fatalf("Expected 1 message, got %v", data["messages"])
+		}
+		message := messages[0].(map[string]interface{})
+		if message["messageType"] != "prompt" {
+			t.Errorf("Expected nested messageType key, got %v", message)
+		}
+
+		// Tool call arguments are user data and keep their keys
+		calls := message["toolCalls"].([]interface{})
+		arguments := calls[0].(map[string]interface{})["arguments"].(map[string]interface{})
+		if arguments["file_path"] != "main.go" {
+			t.Errorf("Expected tool call arguments to be unchanged, got %v", arguments)
+		}
+	})
+
+	t.Run("camelCase from config", func(t *testing.T) {
+		server.config.CamelCaseJSON = true
+		defer func() { server.config.CamelCaseJSON = false }()
+
+		data := get(t, "")
+		if _, ok := data["promptCount"]; !ok {
+			t.Errorf("Expected promptCount key, got %v", data)
+		}
+	})
+}
+
+func TestSnakeToCamel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"id", "id"},
+		{"session_id", "sessionId"},
+		{"avg_response_time_ms", "avgResponseTimeMs"},
+		{"already_camelCase", "alreadyCamelCase"},
+		{"trailing_", "trailing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := snakeToCamel(tt.input); got != tt.expected {
+				t.Errorf("snakeToCamel(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// concurrencyRetryAfter is the Retry-After value, in seconds, sent when the
+// server is saturated; requests are short, so a slot frees up quickly
+const concurrencyRetryAfter = "1"
+
+// concurrencyLimiter caps how many requests are served at once using a
+// semaphore. With a single SQLite writer, excess requests would otherwise
+// queue on the connection and pile up.
+type concurrencyLimiter struct {
+	slots    chan struct{}
+	inFlight atomic.Int64
+}
+
+// newConcurrencyLimiter creates a limiter allowing max requests at once.
+// A non-positive max only counts in-flight requests without limiting them.
+func newConcurrencyLimiter(max int) *concurrencyLimiter {
+	l := &concurrencyLimiter{}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+// acquire takes a slot, returning false when none is free
+func (l *concurrencyLimiter) acquire() bool {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	l.inFlight.Add(1)
+	return true
+}
+
+// release returns a slot taken by acquire
+func (l *concurrencyLimiter) release() {
+	l.inFlight.Add(-1)
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// max returns the configured limit, or zero when unlimited
+func (l *concurrencyLimiter) max() int {
+	return cap(l.slots)
+}
+
+// ConcurrencyLimitMiddleware returns middleware that rejects requests with
+// 503 and a Retry-After header while MaxInFlightRequests are already being
+// served. Requests to the exempt paths, such as health checks, are neither
+// limited nor counted.
+func (s *Server) ConcurrencyLimitMiddleware(exemptPaths ...string) func(http.Handler) http.Handler {
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !s.limiter.acquire() {
+				w.Header().Set("Retry-After", concurrencyRetryAfter)
+				s.errorResponse(w, "Server is busy, please retry", http.StatusServiceUnavailable)
+				return
+			}
+			defer s.limiter.release()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestStats reports the server's current request load
+type requestStats struct {
+	InFlight    int `json:"in_flight"`
+	MaxInFlight int `json:"max_in_flight"`
+}
+
+// GetRequestStatsHandler returns how many requests are currently being
+// served and the configured limit, where zero means unlimited
+func (s *Server) GetRequestStatsHandler(w http.ResponseWriter, r *http.Request) {
+	s.successResponse(w, requestStats{
+		InFlight:    int(s.limiter.inFlight.Load()),
+		MaxInFlight: s.limiter.max(),
+	}, nil)
+}
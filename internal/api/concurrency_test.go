@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	server := setupTestServer(t)
+	server.limiter = newConcurrencyLimiter(2)
+
+	release := make(chan struct{})
+	blocking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := server.ConcurrencyLimitMiddleware("/health")(blocking)
+
+	// Saturate the limit with requests that block until released
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/conversations", nil))
+		}()
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.limiter.inFlight.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for requests to become in flight")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The overflow request is rejected
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/conversations", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d when saturated, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+	if retryAfter := rr.Header().Get("Retry-After"); retryAfter == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	// Stats report the saturated load
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetRequestStatsHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/stats/requests", nil))
+	var response struct {
+		Data requestStats `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Data.InFlight != 2 || response.Data.MaxInFlight != 2 {
+		t.Errorf("Expected 2 of 2 requests in flight, got %+v", response.Data)
+	}
+
+	// Health checks are exempt
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected health check to bypass the limit, got %d", rr.Code)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if inFlight := server.limiter.inFlight.Load(); inFlight != 0 {
+		t.Errorf("Expected no requests in flight after release, got %d", inFlight)
+	}
+
+	// Released slots can be reused
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/conversations", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d after release, got %d", http.StatusOK, rr.Code)
+	}
+}
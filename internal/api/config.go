@@ -0,0 +1,118 @@
+package api
+
+import (
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+	"github.com/claude-code-template/prompt-manager/internal/validation"
+)
+
+// DefaultMaxInFlightRequests is the default cap on concurrently served requests
+const DefaultMaxInFlightRequests = 64
+
+// Config holds options controlling the API server
+type Config struct {
+	// CollapseCommentWhitespace collapses runs of whitespace inside rating
+	// comments to a single space before storage, so comment search is not
+	// thrown off by inconsistent spacing. Comments are always trimmed.
+	CollapseCommentWhitespace bool
+	// MaxWorkingDirectoryLength caps the length of a conversation's working
+	// directory; zero falls back to validation.MaxPathLength
+	MaxWorkingDirectoryLength int
+	// MaxTranscriptPathLength caps the length of a conversation's transcript
+	// path, which may sit deep in a temp directory; zero falls back to
+	// validation.MaxPathLength
+	MaxTranscriptPathLength int
+	// MaxInFlightRequests caps how many requests are served at once; excess
+	// requests get 503. Zero disables the limit.
+	MaxInFlightRequests int
+	// MaxPageSize caps per_page on paginated endpoints. Zero falls back to
+	// validation.MaxPageSize; values above validation.HardMaxPageSize are
+	// clamped to it.
+	MaxPageSize int
+	// CamelCaseJSON rewrites JSON response keys to camelCase for every
+	// request. Clients can also opt in per request with JSONCaseHeader.
+	CamelCaseJSON bool
+	// RatingWebhookURL receives a POST of each new conversation rating as
+	// JSON, sent in the background with retries. Empty disables the webhook.
+	RatingWebhookURL string
+	// StrictSessionIDs limits session IDs to letters, numbers, underscores
+	// and hyphens, rejecting the dots and colons accepted by default
+	StrictSessionIDs bool
+	// StrictPagination answers 404 for a conversation list page past the
+	// last one instead of an empty list. Page 1 is always allowed.
+	StrictPagination bool
+	// EventBatchWindow collects the events published within the window and
+	// sends them to /events subscribers as a single array event, easing the
+	// load on clients during bursts. Zero sends each event as it happens.
+	EventBatchWindow time.Duration
+	// Logger receives failures the server cannot report to a client, such
+	// as responses that fail to encode and undeliverable webhooks. Nil uses
+	// the standard logger.
+	Logger logging.Logger
+}
+
+// DefaultConfig returns the default server configuration, which only trims
+// rating comments and caps in-flight requests at DefaultMaxInFlightRequests
+func DefaultConfig() Config {
+	return Config{
+		CollapseCommentWhitespace: false,
+		MaxWorkingDirectoryLength: validation.MaxPathLength,
+		MaxTranscriptPathLength:   validation.MaxPathLength,
+		MaxInFlightRequests:       DefaultMaxInFlightRequests,
+		MaxPageSize:               validation.MaxPageSize,
+	}
+}
+
+// logger returns the configured logger, falling back to the standard logger
+func (c Config) logger() logging.Logger {
+	return loggerOrDefault(c.Logger)
+}
+
+// loggerOrDefault returns logger, or the standard logger when it is nil
+func loggerOrDefault(logger logging.Logger) logging.Logger {
+	if logger != nil {
+		return logger
+	}
+	return logging.Default()
+}
+
+// maxWorkingDirectoryLength returns the working directory limit, falling back
+// to the default when unset
+func (c Config) maxWorkingDirectoryLength() int {
+	if c.MaxWorkingDirectoryLength > 0 {
+		return c.MaxWorkingDirectoryLength
+	}
+	return validation.MaxPathLength
+}
+
+// maxTranscriptPathLength returns the transcript path limit, falling back to
+// the default when unset
+func (c Config) maxTranscriptPathLength() int {
+	if c.MaxTranscriptPathLength > 0 {
+		return c.MaxTranscriptPathLength
+	}
+	return validation.MaxPathLength
+}
+
+// sessionIDSeparators returns the punctuation accepted in session IDs besides
+// underscores and hyphens
+func (c Config) sessionIDSeparators() string {
+	if c.StrictSessionIDs {
+		return ""
+	}
+	return validation.SessionIDSeparators
+}
+
+// maxPageSize returns the per_page limit, falling back to the default when
+// unset and never exceeding the hard ceiling
+func (c Config) maxPageSize() int {
+	switch {
+	case c.MaxPageSize <= 0:
+		return validation.MaxPageSize
+	case c.MaxPageSize > validation.HardMaxPageSize:
+		return validation.HardMaxPageSize
+	default:
+		return c.MaxPageSize
+	}
+}
@@ -19,6 +19,8 @@ func ConvertConversation(dbConv *database.Conversation) models.Conversation {
 		TotalCharacters:  dbConv.TotalCharacters,
 		WorkingDirectory: dbConv.WorkingDirectory,
 		TranscriptPath:   dbConv.TranscriptPath,
+		DeletedAt:        dbConv.DeletedAt,
+		Tags:             ConvertTags(dbConv.Tags),
 	}
 }
 
@@ -55,10 +57,28 @@ func ConvertMessage(dbMsg *database.Message) (models.Message, error) {
 		CharacterCount: dbMsg.CharacterCount,
 		Timestamp:      dbMsg.Timestamp,
 		ToolCalls:      toolCalls,
+		ToolCallCount:  len(toolCalls),
 		ExecutionTime:  dbMsg.ExecutionTime,
+		Model:          dbMsg.Model,
 	}, nil
 }
 
+// ConvertSession converts aggregated database session metrics to an API session model
+func ConvertSession(dbSession *database.Session) models.Session {
+	lastActivity := dbSession.LastActivity
+	return models.Session{
+		SessionID:         dbSession.SessionID,
+		StartTime:         dbSession.StartTime,
+		ConversationCount: dbSession.ConversationCount,
+		TotalPromptCount:  dbSession.TotalPromptCount,
+		AvgResponseTime:   dbSession.AvgResponseTime,
+		LastActivity:      &lastActivity,
+		Status:            dbSession.Status,
+		EndTime:           dbSession.EndTime,
+		DurationMs:        dbSession.DurationMs,
+	}
+}
+
 // ConvertRating converts a database rating to an API rating model
 func ConvertRating(dbRating *database.Rating) models.Rating {
 	return models.Rating{
@@ -72,13 +92,16 @@ func ConvertRating(dbRating *database.Rating) models.Rating {
 	}
 }
 
-// ConvertConversationsToSummaries converts multiple database conversations to API conversation summaries
+// ConvertConversationsToSummaries converts multiple database conversations to API conversation summaries.
+// The rating score comes from the listing, which applies the configured rating aggregation.
 func ConvertConversationsToSummaries(dbConversations []database.Conversation) []models.ConversationSummary {
 	summaries := make([]models.ConversationSummary, len(dbConversations))
 	for i := range dbConversations {
 		// Convert to API model first to use the ToSummary method
 		apiConv := ConvertConversation(&dbConversations[i])
 		summaries[i] = apiConv.ToSummary()
+		summaries[i].AvgResponseTimeMs = dbConversations[i].AvgResponseTimeMs
+		summaries[i].AvgRating = dbConversations[i].AvgRating
 	}
 	return summaries
 }
@@ -92,3 +115,23 @@ func ConvertRatings(dbRatings []database.Rating) []models.Rating {
 	return apiRatings
 }
 
+// ConvertTag converts a database tag to an API tag model
+func ConvertTag(dbTag *database.Tag) models.Tag {
+	return models.Tag{
+		ID:          dbTag.ID,
+		Name:        dbTag.Name,
+		Description: dbTag.Description,
+		Color:       dbTag.Color,
+		CreatedAt:   dbTag.CreatedAt,
+		UsageCount:  dbTag.UsageCount,
+	}
+}
+
+// ConvertTags converts multiple database tags to API tag models
+func ConvertTags(dbTags []database.Tag) []models.Tag {
+	apiTags := make([]models.Tag, len(dbTags))
+	for i := range dbTags {
+		apiTags[i] = ConvertTag(&dbTags[i])
+	}
+	return apiTags
+}
@@ -91,6 +91,30 @@ func TestConvertMessage(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "successful conversion with multiple tool calls",
+			dbMsg: &database.Message{
+				ID:             4,
+				ConversationID: 1,
+				MessageType:    "prompt",
+				Content:        "Test message content",
+				CharacterCount: 20,
+				Timestamp:      time.Now(),
+				ToolCalls:      stringPtr(`[{"name": "test_tool", "arguments": {"key": "value"}}, {"name": "bash", "arguments": {}}]`),
+			},
+			expectError: false,
+			validateMsg: func(t *testing.T, msg models.Message) {
+				if len(msg.ToolCalls) != 2 {
+					t.Errorf("Expected 2 tool calls, got %d", len(msg.ToolCalls))
+				}
+				if msg.ToolCallCount != 2 {
+					t.Errorf("Expected tool call count 2, got %d", msg.ToolCallCount)
+				}
+				if len(msg.ToolCalls) > 1 && msg.ToolCalls[1].Name != "bash" {
+					t.Errorf("Expected second tool call name 'bash', got %s", msg.ToolCalls[1].Name)
+				}
+			},
+		},
 		{
 			name: "successful conversion with nil tool calls",
 			dbMsg: &database.Message{
@@ -111,6 +135,9 @@ func TestConvertMessage(t *testing.T) {
 				if len(msg.ToolCalls) != 0 {
 					t.Errorf("Expected 0 tool calls, got %d", len(msg.ToolCalls))
 				}
+				if msg.ToolCallCount != 0 {
+					t.Errorf("Expected tool call count 0, got %d", msg.ToolCallCount)
+				}
 			},
 		},
 		{
@@ -358,6 +385,7 @@ func TestConvertConversationsToSummaries(t *testing.T) {
 	now := time.Now()
 	title1 := "First Conversation"
 	title2 := "Second Conversation"
+	score := 4.5
 
 	dbConversations := []database.Conversation{
 		{
@@ -381,6 +409,7 @@ func TestConvertConversationsToSummaries(t *testing.T) {
 			TotalCharacters:  200,
 			WorkingDirectory: nil,
 			TranscriptPath:   nil,
+			AvgRating:        &score,
 		},
 	}
 
@@ -404,6 +433,9 @@ func TestConvertConversationsToSummaries(t *testing.T) {
 		if summary.TotalCharacters != expected.TotalCharacters {
 			t.Errorf("Summary %d: Expected TotalCharacters %d, got %d", i, expected.TotalCharacters, summary.TotalCharacters)
 		}
+		if summary.AvgRating != expected.AvgRating {
+			t.Errorf("Summary %d: Expected AvgRating %v, got %v", i, expected.AvgRating, summary.AvgRating)
+		}
 	}
 }
 
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/claude-code-template/prompt-manager/internal/database"
+)
+
+// conversationETag computes a weak ETag for a conversation response. It
+// covers the conversation's counts and last update, the returned messages in
+// order, its tags and its ratings, so adding a message or rating changes it.
+func conversationETag(conv *database.ConversationWithMessages, ratings database.RatingsVersion) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d|%d|%d|%d|", conv.ID, conv.UpdatedAt.UnixNano(), conv.PromptCount, conv.TotalCharacters)
+	for _, msg := range conv.Messages {
+		fmt.Fprintf(hash, "m%d,", msg.ID)
+	}
+	for _, tag := range conv.Tags {
+		color := ""
+		if tag.Color != nil {
+			color = *tag.Color
+		}
+		fmt.Fprintf(hash, "t%d:%q:%q,", tag.ID, tag.Name, color)
+	}
+	fmt.Fprintf(hash, "|%d|%d|%d|%s", ratings.Count, ratings.Sum, ratings.MaxID, ratings.LastUpdated)
+
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using the
+// weak comparison If-None-Match calls for
+func etagMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestGetConversationETag(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("etag-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := server.db.CreateMessage(conv.ID, "prompt", "hello", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/conversations/{id}", server.GetConversationHandler)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", fmt.Sprintf("/conversations/%d", conv.ID), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := get("")
+	if first.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", first.Code, http.StatusOK)
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag header")
+	}
+
+	cached := get(etag)
+	if cached.Code != http.StatusNotModified {
+		t.Fatalf("Expected status %d for a matching ETag, got %d", http.StatusNotModified, cached.Code)
+	}
+	if cached.Body.Len() != 0 {
+		t.Errorf("Expected an empty 304 body, got %q", cached.Body.String())
+	}
+	if cached.Header().Get("ETag") != etag {
+		t.Errorf("Expected the 304 to repeat ETag %s, got %s", etag, cached.Header().Get("ETag"))
+	}
+
+	// A new rating changes the representation
+	if _, err := server.db.CreateConversationRating(conv.ID, 4, nil); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+	rated := get(etag)
+	if rated.Code != http.StatusOK {
+		t.Fatalf("Expected status %d after a new rating, got %d", http.StatusOK, rated.Code)
+	}
+	if rated.Header().Get("ETag") == etag {
+		t.Error("Expected the ETag to change after a new rating")
+	}
+
+	// So does a new message
+	ratedETag := rated.Header().Get("ETag")
+	if _, err := server.db.CreateMessage(conv.ID, "response", "hi", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if answered := get(ratedETag); answered.Code != http.StatusOK || answered.Header().Get("ETag") == ratedETag {
+		t.Errorf("Expected a new ETag after a new message, got status %d and ETag %s", answered.Code, answered.Header().Get("ETag"))
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `W/"abc"`
+
+	tests := []struct {
+		header   string
+		expected bool
+	}{
+		{`W/"abc"`, true},
+		{`"abc"`, true},
+		{`"xyz", W/"abc"`, true},
+		{`*`, true},
+		{`"xyz"`, false},
+		{``, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := etagMatches(tt.header, etag); got != tt.expected {
+				t.Errorf("etagMatches(%q, %q) = %v, expected %v", tt.header, etag, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+	"github.com/claude-code-template/prompt-manager/internal/models"
+)
+
+// Event stream settings. Each subscriber has a small buffer; events arriving
+// while it is full are dropped for that subscriber rather than stalling the
+// request that published them.
+const (
+	eventSubscriberBuffer = 16
+	eventKeepAlive        = 30 * time.Second
+)
+
+// EventBroker fans out conversation and message events to live subscribers
+// such as the /events stream. Subscribers receive events in batches: a
+// broker with a batch window collects the events published during it and
+// delivers them together, otherwise every batch holds a single event.
+type EventBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan []models.Event]struct{}
+	logger      logging.Logger
+	batchWindow time.Duration
+	pending     []models.Event
+	flushTimer  *time.Timer
+}
+
+// NewEventBroker creates a broker with no subscribers that delivers each
+// event as soon as it is published
+func NewEventBroker() *EventBroker {
+	return newEventBroker(logging.Default(), 0)
+}
+
+// newEventBroker creates a broker that reports dropped events to logger and
+// batches events over batchWindow. A zero window delivers events one by one.
+func newEventBroker(logger logging.Logger, batchWindow time.Duration) *EventBroker {
+	return &EventBroker{
+		subscribers: make(map[chan []models.Event]struct{}),
+		logger:      logger,
+		batchWindow: batchWindow,
+	}
+}
+
+// Batching reports whether the broker collects events into batches
+func (b *EventBroker) Batching() bool {
+	return b.batchWindow > 0
+}
+
+// Subscribe registers a new subscriber. The returned function unsubscribes
+// and closes the channel; it is safe to call more than once.
+func (b *EventBroker) Subscribe() (<-chan []models.Event, func()) {
+	ch := make(chan []models.Event, eventSubscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish delivers an event to every subscriber without blocking. With a
+// batch window the event is held until the window that it opened or joined
+// closes.
+func (b *EventBroker) Publish(event models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.batchWindow <= 0 {
+		b.deliver([]models.Event{event})
+		return
+	}
+
+	b.pending = append(b.pending, event)
+	if b.flushTimer == nil {
+		b.flushTimer = time.AfterFunc(b.batchWindow, b.flush)
+	}
+}
+
+// flush delivers the events collected during the current batch window
+func (b *EventBroker) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch := b.pending
+	b.pending = nil
+	b.flushTimer = nil
+
+	if len(batch) > 0 {
+		b.deliver(batch)
+	}
+}
+
+// deliver sends a batch to every subscriber, dropping it for those whose
+// buffer is full. The caller must hold b.mu.
+func (b *EventBroker) deliver(batch []models.Event) {
+	for ch := range b.subscribers {
+		select {
+		case ch <- batch:
+		default:
+			b.logger.Printf("Event subscriber is falling behind, dropping %d event(s) starting with %s", len(batch), batch[0].Type)
+		}
+	}
+}
+
+// Events returns the server's event broker, which hook handlers publish to
+func (s *Server) Events() *EventBroker {
+	return s.events
+}
+
+// EventsHandler streams conversation and message events to the client as
+// server-sent events until the client disconnects. When the broker batches
+// events, each batch is sent as one "batch" event whose data is an array.
+func (s *Server) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.errorResponse(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			// Comment lines keep idle proxies from closing the stream
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case batch := <-events:
+			eventType, payload := models.EventBatch, interface{}(batch)
+			if !s.events.Batching() {
+				eventType, payload = batch[0].Type, batch[0]
+			}
+			data, err := json.Marshal(payload)
+			if err != nil {
+				s.config.logger().Errorf("Failed to encode %s event: %v", eventType, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+	"github.com/claude-code-template/prompt-manager/internal/models"
+)
+
+func TestEventBroker(t *testing.T) {
+	broker := NewEventBroker()
+
+	first, unsubscribeFirst := broker.Subscribe()
+	second, unsubscribeSecond := broker.Subscribe()
+	defer unsubscribeSecond()
+
+	broker.Publish(models.Event{Type: models.EventConversationCreated, ConversationID: 1})
+
+	for _, ch := range []<-chan []models.Event{first, second} {
+		select {
+		case batch := <-ch:
+			if len(batch) != 1 || batch[0].ConversationID != 1 {
+				t.Errorf("Expected a single event for conversation 1, got %+v", batch)
+			}
+		default:
+			t.Fatal("Expected every subscriber to receive the event")
+		}
+	}
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	if _, open := <-first; open {
+		t.Error("Expected the channel to be closed after unsubscribing")
+	}
+
+	// A subscriber that stops reading must not block publishers
+	for i := 0; i < eventSubscriberBuffer*2; i++ {
+		broker.Publish(models.Event{Type: models.EventMessageCreated, ConversationID: 1})
+	}
+	if got := len(second); got != eventSubscriberBuffer {
+		t.Errorf("Expected %d buffered events, got %d", eventSubscriberBuffer, got)
+	}
+}
+
+func TestEventBrokerBatching(t *testing.T) {
+	broker := newEventBroker(logging.Nop{}, 50*time.Millisecond)
+	if !broker.Batching() {
+		t.Fatal("Expected a broker with a batch window to batch events")
+	}
+
+	events, unsubscribe := broker.Subscribe()
+	defer unsubscribe()
+
+	for i := 1; i <= 3; i++ {
+		broker.Publish(models.Event{Type: models.EventMessageCreated, ConversationID: i})
+	}
+
+	select {
+	case batch := <-events:
+		t.Fatalf("Expected events to be held until the window closes, got %+v", batch)
+	default:
+	}
+
+	select {
+	case batch := <-events:
+		if len(batch) != 3 {
+			t.Fatalf("Expected one batch of 3 events, got %+v", batch)
+		}
+		for i, event := range batch {
+			if event.ConversationID != i+1 {
+				t.Errorf("Expected event %d for conversation %d, got %+v", i, i+1, event)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the batch to be delivered once the window closed")
+	}
+
+	// The next event opens a new window
+	broker.Publish(models.Event{Type: models.EventMessageCreated, ConversationID: 4})
+	select {
+	case batch := <-events:
+		if len(batch) != 1 || batch[0].ConversationID != 4 {
+			t.Errorf("Expected a batch holding only conversation 4, got %+v", batch)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the second batch to be delivered")
+	}
+}
+
+func TestEventsHandlerBatching(t *testing.T) {
+	config := DefaultConfig()
+	config.EventBatchWindow = 50 * time.Millisecond
+	server := NewServerWithConfig(setupTestServer(t).db, config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/events", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		server.EventsHandler(rr, req)
+		close(done)
+	}()
+
+	waitForSubscribers(t, server.events, 1)
+	for i := 1; i <= 3; i++ {
+		server.events.Publish(models.Event{Type: models.EventMessageCreated, ConversationID: i})
+	}
+
+	// Wait out the batch window before disconnecting
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Handler did not return after the client disconnected")
+	}
+
+	body := rr.Body.String()
+	if strings.Contains(body, "event: message.created") {
+		t.Errorf("Expected no individual events with batching enabled, got %q", body)
+	}
+	if count := strings.Count(body, "event: batch\ndata: "); count != 1 {
+		t.Fatalf("Expected exactly one batch event, got %d in %q", count, body)
+	}
+
+	data := body[strings.Index(body, "data: ")+len("data: "):]
+	data = data[:strings.Index(data, "\n")]
+	var batch []models.Event
+	if err := json.Unmarshal([]byte(data), &batch); err != nil {
+		t.Fatalf("Failed to decode batch %q: %v", data, err)
+	}
+	if len(batch) != 3 {
+		t.Errorf("Expected 3 events in the batch, got %d", len(batch))
+	}
+}
+
+func TestEventsHandlerDisconnect(t *testing.T) {
+	server := setupTestServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/events", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		server.EventsHandler(rr, req)
+		close(done)
+	}()
+
+	waitForSubscribers(t, server.events, 1)
+	server.events.Publish(models.Event{Type: models.EventMessageCreated, ConversationID: 7})
+
+	// Give the handler a moment to write the event before disconnecting
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Handler did not return after the client disconnected")
+	}
+
+	waitForSubscribers(t, server.events, 0)
+
+	if got := rr.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", got)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "event: message.created\ndata: ") {
+		t.Errorf("Expected a message.created event in the stream, got %q", body)
+	}
+}
+
+// waitForSubscribers waits until the broker has exactly n subscribers
+func waitForSubscribers(t *testing.T, broker *EventBroker, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		broker.mu.Lock()
+		count := len(broker.subscribers)
+		broker.mu.Unlock()
+
+		if count == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d subscribers, got %d", n, count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
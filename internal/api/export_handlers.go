@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/export"
+	"github.com/claude-code-template/prompt-manager/internal/validation"
+	"github.com/gorilla/mux"
+)
+
+// Export handlers
+
+// ExportConversationHandler downloads a conversation as JSON (the default)
+// or as CSV with one row per message
+func (s *Server) ExportConversationHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "conversation_id")
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		s.errorResponse(w, fmt.Sprintf("Unsupported export format: %s", format), http.StatusBadRequest)
+		return
+	}
+
+	conv, err := s.db.GetConversationWithMessages(id)
+	if err != nil {
+		if errors.Is(err, database.ErrConversationNotFound) {
+			s.errorResponse(w, "Conversation not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to get conversation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	apiConv, err := ConvertConversationWithMessages(conv)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to convert conversation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var body []byte
+	var contentType string
+	switch format {
+	case "csv":
+		body, err = export.CSVMessages(apiConv.Messages)
+		contentType = "text/csv; charset=utf-8"
+	default:
+		body, err = json.MarshalIndent(apiConv, "", "  ")
+		contentType = "application/json"
+	}
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to export conversation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="conversation-%d.%s"`, id, format))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		s.config.logger().Errorf("Failed to write conversation %d export: %v", id, err)
+	}
+}
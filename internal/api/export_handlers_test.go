@@ -0,0 +1,88 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/claude-code-template/prompt-manager/internal/models"
+	"github.com/gorilla/mux"
+)
+
+func TestExportConversation(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("export-session", stringPtr("Export me"), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	content := "First line, with comma\nSecond line"
+	if _, err := server.db.CreateMessage(conv.ID, "prompt", content, nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/conversations/{id}/export", server.ExportConversationHandler)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedType   string
+	}{
+		{"default json", "", http.StatusOK, "application/json"},
+		{"csv", "?format=csv", http.StatusOK, "text/csv; charset=utf-8"},
+		{"unsupported format", "?format=xml", http.StatusBadRequest, "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", fmt.Sprintf("/conversations/%d/export%s", conv.ID, tt.query), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+
+			if contentType := rr.Header().Get("Content-Type"); contentType != tt.expectedType {
+				t.Errorf("Expected Content-Type %s, got %s", tt.expectedType, contentType)
+			}
+		})
+	}
+
+	// JSON export contains the nested messages
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/conversations/%d/export", conv.ID), nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var exported models.Conversation
+	if err := json.Unmarshal(rr.Body.Bytes(), &exported); err != nil {
+		t.Fatalf("Failed to unmarshal JSON export: %v", err)
+	}
+	if len(exported.Messages) != 1 || exported.Messages[0].Content != content {
+		t.Errorf("Expected exported message content to round-trip, got %v", exported.Messages)
+	}
+
+	// CSV export round-trips multi-line content
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/conversations/%d/export?format=csv", conv.ID), nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	records, err := csv.NewReader(bytes.NewReader(rr.Body.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV export: %v", err)
+	}
+	if len(records) != 2 || records[1][5] != content {
+		t.Errorf("Expected CSV content to round-trip, got %v", records)
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultGzipMinSize is the smallest response body, in bytes, worth
+// compressing. Below it the gzip framing outweighs the savings.
+const DefaultGzipMinSize = 1024
+
+// GzipMiddleware compresses responses of at least minSize bytes for clients
+// that send "Accept-Encoding: gzip". The body is held back until it reaches
+// minSize, so small responses are sent unchanged once the handler returns.
+// A flush before then also sends the response uncompressed. Streaming
+// endpoints should still be listed in exemptPaths so they bypass the
+// buffering entirely.
+func GzipMiddleware(minSize int, exemptPaths ...string) func(http.Handler) http.Handler {
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipResponseWriter{ResponseWriter: w, minSize: minSize, status: http.StatusOK}
+			defer gw.Close()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip, honouring
+// an explicit "q=0" refusal
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+
+		q, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !found {
+			return true
+		}
+		weight, err := strconv.ParseFloat(q, 64)
+		return err != nil || weight > 0
+	}
+	return false
+}
+
+// gzipResponseWriter buffers a response until it is large enough to
+// compress. The status code is held back too, since Content-Encoding must be
+// decided before the header is written.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize     int
+	status      int
+	wroteHeader bool
+	buf         bytes.Buffer
+	decided     bool
+	gz          *gzip.Writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.wroteHeader {
+		return
+	}
+	g.status = status
+	g.wroteHeader = true
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	g.wroteHeader = true
+
+	if g.decided {
+		if g.gz != nil {
+			return g.gz.Write(p)
+		}
+		return g.ResponseWriter.Write(p)
+	}
+
+	g.buf.Write(p)
+	if g.buf.Len() < g.minSize {
+		return len(p), nil
+	}
+
+	if err := g.start(true); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// start sends the held-back header, compressed or not, followed by the
+// buffered body
+func (g *gzipResponseWriter) start(compress bool) error {
+	g.decided = true
+
+	header := g.ResponseWriter.Header()
+	if compress && header.Get("Content-Encoding") == "" {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+
+	g.ResponseWriter.WriteHeader(g.status)
+
+	if g.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if g.gz != nil {
+		_, err = g.gz.Write(g.buf.Bytes())
+	} else {
+		_, err = g.ResponseWriter.Write(g.buf.Bytes())
+	}
+	g.buf.Reset()
+	return err
+}
+
+// Flush sends whatever has been written so far. A response still below the
+// threshold is sent uncompressed.
+func (g *gzipResponseWriter) Flush() {
+	if !g.decided {
+		g.start(false)
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if flusher, ok := g.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close finishes the response once the handler returns: small responses are
+// sent as they are and compressed ones get their gzip trailer
+func (g *gzipResponseWriter) Close() error {
+	if !g.decided {
+		return g.start(false)
+	}
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	return nil
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzipMiddleware(t *testing.T) {
+	large := strings.Repeat("compressible ", 200)
+	server := NewServer(nil)
+	handler := GzipMiddleware(DefaultGzipMinSize, "/stream")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large", "/stream":
+			server.successResponse(w, large, nil)
+		case "/created":
+			server.writeJSON(w, http.StatusCreated, APIResponse{Success: true, Data: large})
+		default:
+			server.successResponse(w, "ok", nil)
+		}
+	}))
+
+	get := func(t *testing.T, path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	decode := func(t *testing.T, body io.Reader) APIResponse {
+		var response APIResponse
+		if err := json.NewDecoder(body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("large body is compressed", func(t *testing.T) {
+		rr := get(t, "/created", "gzip, deflate")
+
+		if rr.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d", http.StatusCreated, rr.Code)
+		}
+		if encoding := rr.Header().Get("Content-Encoding"); encoding != "gzip" {
+			t.Fatalf("Expected Content-Encoding gzip, got %q", encoding)
+		}
+		if rr.Body.Len() >= len(large) {
+			t.Errorf("Expected the compressed body to be smaller than %d bytes, got %d", len(large), rr.Body.Len())
+		}
+
+		reader, err := gzip.NewReader(rr.Body)
+		if err != nil {
+			t.Fatalf("Failed to open gzip body: %v", err)
+		}
+		if response := decode(t, reader); response.Data != large {
+			t.Error("Expected the decompressed body to match the original")
+		}
+	})
+
+	t.Run("small body is not compressed", func(t *testing.T) {
+		rr := get(t, "/small", "gzip")
+
+		if encoding := rr.Header().Get("Content-Encoding"); encoding != "" {
+			t.Errorf("Expected no Content-Encoding, got %q", encoding)
+		}
+		if response := decode(t, rr.Body); response.Data != "ok" {
+			t.Errorf("Expected data ok, got %v", response.Data)
+		}
+		if vary := rr.Header().Get("Vary"); vary != "Accept-Encoding" {
+			t.Errorf("Expected Vary: Accept-Encoding, got %q", vary)
+		}
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+	}{
+		{"client without gzip", "/large", ""},
+		{"client refusing gzip", "/large", "gzip;q=0, identity"},
+		{"exempt path", "/stream", "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := get(t, tt.path, tt.acceptEncoding)
+
+			if encoding := rr.Header().Get("Content-Encoding"); encoding != "" {
+				t.Errorf("Expected no Content-Encoding, got %q", encoding)
+			}
+			if response := decode(t, rr.Body); response.Data != large {
+				t.Error("Expected the body to be sent as is")
+			}
+		})
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, GZIP", true},
+		{"gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"br, identity", false},
+	}
+
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestGzipMiddlewareFlush(t *testing.T) {
+	handler := GzipMiddleware(DefaultGzipMinSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: ping\n\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte(strings.Repeat("data: more\n\n", 200)))
+	}))
+
+	req := httptest.NewRequest("GET", "/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if !rr.Flushed {
+		t.Error("Expected the flush to reach the underlying writer")
+	}
+	if encoding := rr.Header().Get("Content-Encoding"); encoding != "" {
+		t.Errorf("Expected a response flushed below the threshold to stay uncompressed, got %q", encoding)
+	}
+	if !strings.HasPrefix(rr.Body.String(), "event: ping\n\n") {
+		t.Errorf("Unexpected body prefix %q", rr.Body.String()[:20])
+	}
+}
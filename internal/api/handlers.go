@@ -5,77 +5,192 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/httpjson"
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+	"github.com/claude-code-template/prompt-manager/internal/models"
 	"github.com/claude-code-template/prompt-manager/internal/validation"
 	"github.com/gorilla/mux"
 )
 
 // Server holds the database connection and provides HTTP handlers
 type Server struct {
-	db *database.DB
+	db       *database.DB
+	config   Config
+	limiter  *concurrencyLimiter
+	notifier *ratingNotifier
+	events   *EventBroker
 }
 
 // NewServer creates a new API server
 func NewServer(db *database.DB) *Server {
-	return &Server{db: db}
+	return NewServerWithConfig(db, DefaultConfig())
+}
+
+// NewServerWithConfig creates a new API server with the given configuration
+func NewServerWithConfig(db *database.DB, config Config) *Server {
+	return &Server{
+		db:       db,
+		config:   config,
+		limiter:  newConcurrencyLimiter(config.MaxInFlightRequests),
+		notifier: newRatingNotifier(config.RatingWebhookURL, config.logger()),
+		events:   newEventBroker(config.logger(), config.EventBatchWindow),
+	}
+}
+
+// Logger returns the server's logger, for middleware wired alongside it
+func (s *Server) Logger() logging.Logger {
+	return s.config.logger()
 }
 
 // APIResponse represents a standard API response
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   *string     `json:"error,omitempty"`
-	Meta    *Meta       `json:"meta,omitempty"`
+	Success bool         `json:"success"`
+	Data    interface{}  `json:"data,omitempty"`
+	Error   *string      `json:"error,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+	Meta    *Meta        `json:"meta,omitempty"`
+}
+
+// FieldError names the request field that failed validation, so clients can
+// show the message next to the right input
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // Meta provides pagination and additional response metadata
 type Meta struct {
 	Page       int `json:"page,omitempty"`
 	PerPage    int `json:"per_page,omitempty"`
-	Total      int `json:"total,omitempty"`
-	TotalPages int `json:"total_pages,omitempty"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+// Response helpers. Handlers respond through the Server methods, which log
+// encode and write failures through the configured logger. Middleware has no
+// Server and calls httpjson.Write with the logger it was constructed with.
+
+// writeJSON writes payload as JSON, logging failures through the server's logger
+func (s *Server) writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	httpjson.Write(s.config.logger(), w, status, payload)
 }
 
-// Error response helpers
-func errorResponse(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+func (s *Server) errorResponse(w http.ResponseWriter, message string, statusCode int) {
+	s.writeJSON(w, statusCode, errorBody(message))
+}
 
-	response := APIResponse{
+func errorBody(message string) APIResponse {
+	return APIResponse{
 		Success: false,
 		Error:   &message,
 	}
+}
 
-	json.NewEncoder(w).Encode(response)
+// validationErrorResponse sends a 400 for a failed validation. The flat error
+// message is kept for older clients, and each failure from a
+// validation.ValidationError or ValidationErrors is also reported by field.
+func (s *Server) validationErrorResponse(w http.ResponseWriter, err error) {
+	s.writeJSON(w, http.StatusBadRequest, validationErrorBody(err))
 }
 
-func successResponse(w http.ResponseWriter, data interface{}, meta *Meta) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+func validationErrorBody(err error) APIResponse {
+	response := errorBody(err.Error())
 
-	response := APIResponse{
+	var validationErrs validation.ValidationErrors
+	var validationErr *validation.ValidationError
+	if errors.As(err, &validationErrs) {
+		for _, fieldErr := range validationErrs {
+			response.Errors = append(response.Errors, FieldError{Field: fieldErr.Field, Message: fieldErr.Message})
+		}
+	} else if errors.As(err, &validationErr) {
+		response.Errors = []FieldError{{Field: validationErr.Field, Message: validationErr.Message}}
+	}
+
+	return response
+}
+
+// parsePagination reads the page and per_page query parameters, capping
+// per_page at the configured maximum. It writes an error response and returns
+// false when either is invalid.
+func (s *Server) parsePagination(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	page, perPage, err := validation.ParseAndValidatePageWithMax(
+		r.URL.Query().Get("page"),
+		r.URL.Query().Get("per_page"),
+		s.config.maxPageSize(),
+	)
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return 0, 0, false
+		}
+		s.errorResponse(w, "Invalid pagination parameters", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return page, perPage, true
+}
+
+// newPagination builds the response metadata for one page of total items
+func newPagination(page, perPage, total int) *Meta {
+	return &Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
+	}
+}
+
+func (s *Server) successResponse(w http.ResponseWriter, data interface{}, meta *Meta) {
+	s.writeJSON(w, http.StatusOK, successBody(data, meta))
+}
+
+func successBody(data interface{}, meta *Meta) APIResponse {
+	return APIResponse{
 		Success: true,
 		Data:    data,
 		Meta:    meta,
 	}
+}
+
+// sanitizeComment cleans a rating comment for storage, collapsing internal
+// whitespace when configured
+func (s *Server) sanitizeComment(comment *string) *string {
+	if comment == nil {
+		return nil
+	}
 
-	json.NewEncoder(w).Encode(response)
+	sanitized := validation.SanitizeString(*comment, validation.MaxCommentLength)
+	if s.config.CollapseCommentWhitespace {
+		sanitized = validation.CollapseWhitespace(sanitized)
+	}
+	return &sanitized
 }
 
 // Health check handler
 func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	// Check database health
 	if err := s.db.Health(); err != nil {
-		errorResponse(w, fmt.Sprintf("Database unhealthy: %v", err), http.StatusServiceUnavailable)
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Database unhealthy: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	// HEAD probes only need the status code, so skip building the body
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
 		return
 	}
 
 	// Get database stats
 	stats, err := s.db.Stats()
 	if err != nil {
-		errorResponse(w, fmt.Sprintf("Failed to get stats: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to get stats: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -86,145 +201,424 @@ func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
 		"database":  stats,
 	}
 
-	successResponse(w, healthData, nil)
+	s.successResponse(w, healthData, nil)
+}
+
+// LivenessHandler reports that the process is up without touching the
+// database, so a database outage does not get the server restarted
+func (s *Server) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	s.successResponse(w, map[string]interface{}{
+		"status":    "alive",
+		"service":   "prompt-manager",
+		"timestamp": time.Now().UTC(),
+	}, nil)
 }
 
 // Conversation handlers
 
 // ListConversationsHandler returns a paginated list of conversations
 func (s *Server) ListConversationsHandler(w http.ResponseWriter, r *http.Request) {
-	// Parse and validate pagination parameters
-	page, perPage, err := validation.ParseAndValidatePage(
-		r.URL.Query().Get("page"),
-		r.URL.Query().Get("per_page"),
+	page, perPage, ok := s.parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	offset := (page - 1) * perPage
+
+	from, to, err := validation.ParseAndValidateTimeRange(
+		r.URL.Query().Get("from"),
+		r.URL.Query().Get("to"),
 	)
 	if err != nil {
-		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// since is a relative alternative to from, e.g. since=24h
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		if from != nil {
+			s.errorResponse(w, "since cannot be combined with from", http.StatusBadRequest)
+			return
+		}
+		from, err = validation.ParseAndValidateSince(sinceStr, time.Now())
+		if err != nil {
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid pagination parameters", http.StatusBadRequest)
+	}
+
+	tagIDs, ok := s.parseTagFilter(w, r)
+	if !ok {
 		return
 	}
 
-	offset := (page - 1) * perPage
+	tagMode := database.TagMatchAll
+	if modeStr := r.URL.Query().Get("tag_mode"); modeStr != "" {
+		tagMode, err = database.ParseTagMatchMode(modeStr)
+		if err != nil {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// include_deleted lets admins see soft-deleted conversations
+	includeDeleted := false
+	if includeStr := r.URL.Query().Get("include_deleted"); includeStr != "" {
+		includeDeleted, err = strconv.ParseBool(includeStr)
+		if err != nil {
+			s.errorResponse(w, "include_deleted must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var sort database.SortOption
+	if sortStr := r.URL.Query().Get("sort"); sortStr != "" {
+		sort, err = database.ParseSortOption(sortStr)
+		if err != nil {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
-	conversations, err := s.db.ListConversations(perPage, offset)
+	// tool keeps conversations where at least one message called that tool
+	toolName := r.URL.Query().Get("tool")
+	if r.URL.Query().Has("tool") {
+		if err := validation.ValidateToolName(toolName); err != nil {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// working_directory keeps conversations recorded in that directory, and
+	// prefix=true widens it to every directory below
+	workingDirectory := r.URL.Query().Get("working_directory")
+	if r.URL.Query().Has("working_directory") {
+		maxLength := s.config.maxWorkingDirectoryLength()
+		if err := validation.ValidatePathField(&workingDirectory, "working_directory", maxLength); err != nil {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		workingDirectory = validation.SanitizeString(workingDirectory, maxLength)
+	}
+
+	prefix := false
+	if prefixStr := r.URL.Query().Get("prefix"); prefixStr != "" {
+		prefix, err = strconv.ParseBool(prefixStr)
+		if err != nil {
+			s.errorResponse(w, "prefix must be true or false", http.StatusBadRequest)
+			return
+		}
+		if prefix && workingDirectory == "" {
+			s.errorResponse(w, "prefix requires working_directory", http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter := &database.ConversationFilter{
+		CreatedAfter:           from,
+		CreatedBefore:          to,
+		TagIDs:                 tagIDs,
+		TagMode:                tagMode,
+		Sort:                   sort,
+		IncludeDeleted:         includeDeleted,
+		ToolName:               toolName,
+		WorkingDirectory:       workingDirectory,
+		WorkingDirectoryPrefix: prefix,
+	}
+
+	// Get total count for pagination first, so a page past the end can skip
+	// the list query
+	totalCount, err := s.db.GetConversationCount(filter)
 	if err != nil {
-		errorResponse(w, fmt.Sprintf("Failed to list conversations: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to get conversation count: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	meta := newPagination(page, perPage, totalCount)
+
+	// Page 1 of an empty result is an ordinary empty list, but later pages
+	// past the end are reported as not found when StrictPagination is set
+	if page > 1 && page > meta.TotalPages {
+		if s.config.StrictPagination {
+			s.errorResponse(w, fmt.Sprintf("Page %d is past the last page (%d)", page, meta.TotalPages), http.StatusNotFound)
+			return
+		}
+		s.successResponse(w, []models.ConversationSummary{}, meta)
 		return
 	}
 
-	// Get total count for pagination
-	totalCount, err := s.db.GetConversationCount()
+	conversations, err := s.db.ListConversations(filter, perPage, offset)
 	if err != nil {
-		errorResponse(w, fmt.Sprintf("Failed to get conversation count: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to list conversations: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Convert to summaries for list view
 	summaries := ConvertConversationsToSummaries(conversations)
 
-	totalPages := (totalCount + perPage - 1) / perPage // Calculate total pages with ceiling division
-	meta := &Meta{
-		Page:       page,
-		PerPage:    perPage,
-		Total:      totalCount,
-		TotalPages: totalPages,
+	s.successResponse(w, summaries, meta)
+}
+
+// ListAwaitingResponseHandler returns a paginated list of conversations whose
+// latest message is a prompt, meaning the assistant has not replied yet or the
+// response hook failed
+func (s *Server) ListAwaitingResponseHandler(w http.ResponseWriter, r *http.Request) {
+	page, perPage, ok := s.parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	filter := &database.ConversationFilter{AwaitingResponse: true}
+
+	conversations, err := s.db.ListConversations(filter, perPage, (page-1)*perPage)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to list conversations: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	totalCount, err := s.db.GetConversationCount(filter)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get conversation count: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	successResponse(w, summaries, meta)
+	meta := newPagination(page, perPage, totalCount)
+
+	s.successResponse(w, ConvertConversationsToSummaries(conversations), meta)
 }
 
-// GetConversationHandler returns a specific conversation with messages
+// GetConversationHandler returns a specific conversation with messages,
+// optionally only those of one message_type and optionally newest first.
+// Responses carry an ETag and a matching If-None-Match yields 304.
 func (s *Server) GetConversationHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr, exists := vars["id"]
 	if !exists {
-		errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
+		s.errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
 		return
 	}
 
 	id, err := validation.ParseAndValidateID(idStr, "conversation_id")
 	if err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	// message_type narrows the messages returned; counts still cover the whole conversation
+	messageType := r.URL.Query().Get("message_type")
+	if messageType != "" && messageType != "prompt" && messageType != "response" {
+		s.errorResponse(w, "message_type must be prompt or response", http.StatusBadRequest)
 		return
 	}
 
-	conv, err := s.db.GetConversationWithMessages(id)
+	// order=desc lists the newest messages first
+	order := database.MessageOrderAsc
+	if orderStr := r.URL.Query().Get("order"); orderStr != "" {
+		order, err = database.ParseMessageOrder(orderStr)
+		if err != nil {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	conv, err := s.db.GetConversationWithMessagesByType(id, messageType, order)
 	if err != nil {
 		if errors.Is(err, database.ErrConversationNotFound) {
-			errorResponse(w, "Conversation not found", http.StatusNotFound)
+			s.errorResponse(w, "Conversation not found", http.StatusNotFound)
 			return
 		}
-		errorResponse(w, fmt.Sprintf("Failed to get conversation: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to get conversation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	ratings, err := s.db.GetConversationRatingsVersion(id)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get conversation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Let clients revalidate a cached copy with If-None-Match
+	etag := conversationETag(conv, ratings)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	// Convert database models to API models
 	apiConv, err := ConvertConversationWithMessages(conv)
 	if err != nil {
-		errorResponse(w, fmt.Sprintf("Failed to convert conversation: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to convert conversation: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	successResponse(w, apiConv, nil)
+	s.successResponse(w, apiConv, nil)
 }
 
-// CreateConversationHandler creates a new conversation
-func (s *Server) CreateConversationHandler(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		SessionID        string  `json:"session_id"`
-		Title            *string `json:"title"`
-		WorkingDirectory *string `json:"working_directory"`
-		TranscriptPath   *string `json:"transcript_path"`
+// GetLinkedConversationsHandler returns conversations sharing the transcript
+// path of the given conversation
+func (s *Server) GetLinkedConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+	id, err := validation.ParseAndValidateID(idStr, "conversation_id")
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
 		return
 	}
 
-	// Validate session ID
-	if err := validation.ValidateSessionID(req.SessionID); err != nil {
-		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+	conversations, err := s.db.ListLinkedConversations(id)
+	if err != nil {
+		if errors.Is(err, database.ErrConversationNotFound) {
+			s.errorResponse(w, "Conversation not found", http.StatusNotFound)
 			return
 		}
-		errorResponse(w, "Invalid session ID", http.StatusBadRequest)
+		s.errorResponse(w, fmt.Sprintf("Failed to list linked conversations: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// Validate title
-	if err := validation.ValidateTitle(req.Title); err != nil {
+	summaries := ConvertConversationsToSummaries(conversations)
+
+	s.successResponse(w, summaries, nil)
+}
+
+// GetConversationCompletenessHandler returns how many of a conversation's
+// prompts received a response
+func (s *Server) GetConversationCompletenessHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "conversation_id")
+	if err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid title", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
 		return
 	}
 
-	// Validate paths
-	if err := validation.ValidatePath(req.WorkingDirectory); err != nil {
+	completeness, err := s.db.GetConversationCompleteness(id)
+	if err != nil {
+		if errors.Is(err, database.ErrConversationNotFound) {
+			s.errorResponse(w, "Conversation not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to get conversation completeness: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, completeness, nil)
+}
+
+// GetConversationTimelineHandler returns the ordered type, timestamp and size
+// of a conversation's messages without their content
+func (s *Server) GetConversationTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "conversation_id")
+	if err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	timeline, err := s.db.GetConversationTimeline(id)
+	if err != nil {
+		if errors.Is(err, database.ErrConversationNotFound) {
+			s.errorResponse(w, "Conversation not found", http.StatusNotFound)
 			return
 		}
-		errorResponse(w, "Invalid working directory path", http.StatusBadRequest)
+		s.errorResponse(w, fmt.Sprintf("Failed to get conversation timeline: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	if err := validation.ValidatePath(req.TranscriptPath); err != nil {
+	s.successResponse(w, timeline, nil)
+}
+
+// GetConversationContentStatsHandler returns a conversation's character,
+// message and word counts split by message type
+func (s *Server) GetConversationContentStatsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "conversation_id")
+	if err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := s.db.GetConversationContentStats(id)
+	if err != nil {
+		if errors.Is(err, database.ErrConversationNotFound) {
+			s.errorResponse(w, "Conversation not found", http.StatusNotFound)
 			return
 		}
-		errorResponse(w, "Invalid transcript path", http.StatusBadRequest)
+		s.errorResponse(w, fmt.Sprintf("Failed to get conversation stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, stats, nil)
+}
+
+// CreateConversationHandler creates a new conversation
+func (s *Server) CreateConversationHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SessionID        string  `json:"session_id"`
+		Title            *string `json:"title"`
+		WorkingDirectory *string `json:"working_directory"`
+		TranscriptPath   *string `json:"transcript_path"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate every field so all failures are reported together
+	v := &validation.Validator{}
+	v.Check(validation.ValidateSessionIDWithSeparators(req.SessionID, s.config.sessionIDSeparators()))
+	v.Check(validation.ValidateTitle(req.Title))
+	s.checkPaths(v, req.WorkingDirectory, req.TranscriptPath)
+	if err := v.Err(); err != nil {
+		s.validationErrorResponse(w, err)
 		return
 	}
 
@@ -234,91 +628,145 @@ func (s *Server) CreateConversationHandler(w http.ResponseWriter, r *http.Reques
 		req.Title = &sanitized
 	}
 	if req.WorkingDirectory != nil {
-		sanitized := validation.SanitizeString(*req.WorkingDirectory, validation.MaxPathLength)
+		sanitized := validation.SanitizeString(*req.WorkingDirectory, s.config.maxWorkingDirectoryLength())
 		req.WorkingDirectory = &sanitized
 	}
 	if req.TranscriptPath != nil {
-		sanitized := validation.SanitizeString(*req.TranscriptPath, validation.MaxPathLength)
+		sanitized := validation.SanitizeString(*req.TranscriptPath, s.config.maxTranscriptPathLength())
 		req.TranscriptPath = &sanitized
 	}
 
 	conv, err := s.db.CreateConversation(req.SessionID, req.Title, req.WorkingDirectory, req.TranscriptPath)
 	if err != nil {
-		errorResponse(w, fmt.Sprintf("Failed to create conversation: %v", err), http.StatusInternalServerError)
+		if errors.Is(err, database.ErrSessionConversationLimit) {
+			s.errorResponse(w, "Session conversation limit reached", http.StatusTooManyRequests)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to create conversation: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	s.events.Publish(models.Event{
+		Type:           models.EventConversationCreated,
+		ConversationID: conv.ID,
+		SessionID:      conv.SessionID,
+		Timestamp:      conv.CreatedAt,
+	})
+
 	apiConv := ConvertConversation(conv)
 
-	w.WriteHeader(http.StatusCreated)
-	successResponse(w, apiConv, nil)
+	s.writeJSON(w, http.StatusCreated, successBody(apiConv, nil))
+}
+
+// validatePaths checks a conversation's working directory and transcript path
+// against their configured length limits. It writes an error response and
+// returns false when either is invalid.
+func (s *Server) validatePaths(w http.ResponseWriter, workingDir, transcriptPath *string) bool {
+	v := &validation.Validator{}
+	s.checkPaths(v, workingDir, transcriptPath)
+	if err := v.Err(); err != nil {
+		s.validationErrorResponse(w, err)
+		return false
+	}
+
+	return true
 }
 
-// UpdateConversationHandler updates a conversation's title
+// checkPaths records any working directory or transcript path failures on v
+func (s *Server) checkPaths(v *validation.Validator, workingDir, transcriptPath *string) {
+	v.Check(validation.ValidatePathField(workingDir, "working_directory", s.config.maxWorkingDirectoryLength()))
+	v.Check(validation.ValidatePathField(transcriptPath, "transcript_path", s.config.maxTranscriptPathLength()))
+}
+
+// UpdateConversationHandler updates a conversation's title, working directory
+// and transcript path. Fields omitted from the request are left unchanged.
 func (s *Server) UpdateConversationHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr, exists := vars["id"]
 	if !exists {
-		errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
+		s.errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
 		return
 	}
 
 	id, err := validation.ParseAndValidateID(idStr, "conversation_id")
 	if err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
 		return
 	}
 
 	var req struct {
-		Title string `json:"title"`
+		Title            *string `json:"title"`
+		WorkingDirectory *string `json:"working_directory"`
+		TranscriptPath   *string `json:"transcript_path"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Title == nil && req.WorkingDirectory == nil && req.TranscriptPath == nil {
+		s.errorResponse(w, "At least one of title, working_directory or transcript_path is required", http.StatusBadRequest)
 		return
 	}
 
 	// Validate title
-	if err := validation.ValidateTitle(&req.Title); err != nil {
+	if err := validation.ValidateTitle(req.Title); err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid title", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid title", http.StatusBadRequest)
 		return
 	}
 
-	if req.Title == "" {
-		errorResponse(w, "title is required", http.StatusBadRequest)
+	if req.Title != nil && *req.Title == "" {
+		s.errorResponse(w, "title is required", http.StatusBadRequest)
 		return
 	}
 
-	// Sanitize title
-	req.Title = validation.SanitizeString(req.Title, validation.MaxTitleLength)
+	// Validate paths
+	if !s.validatePaths(w, req.WorkingDirectory, req.TranscriptPath) {
+		return
+	}
 
-	if err := s.db.UpdateConversationTitle(id, req.Title); err != nil {
+	// Sanitize strings
+	if req.Title != nil {
+		sanitized := validation.SanitizeString(*req.Title, validation.MaxTitleLength)
+		req.Title = &sanitized
+	}
+	if req.WorkingDirectory != nil {
+		sanitized := validation.SanitizeString(*req.WorkingDirectory, s.config.maxWorkingDirectoryLength())
+		req.WorkingDirectory = &sanitized
+	}
+	if req.TranscriptPath != nil {
+		sanitized := validation.SanitizeString(*req.TranscriptPath, s.config.maxTranscriptPathLength())
+		req.TranscriptPath = &sanitized
+	}
+
+	if err := s.db.UpdateConversationMetadata(id, req.Title, req.WorkingDirectory, req.TranscriptPath); err != nil {
 		if errors.Is(err, database.ErrConversationNotFound) {
-			errorResponse(w, "Conversation not found", http.StatusNotFound)
+			s.errorResponse(w, "Conversation not found", http.StatusNotFound)
 			return
 		}
-		errorResponse(w, fmt.Sprintf("Failed to update conversation: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to update conversation: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Return updated conversation
 	conv, err := s.db.GetConversation(id)
 	if err != nil {
-		errorResponse(w, fmt.Sprintf("Failed to get updated conversation: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to get updated conversation: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	apiConv := ConvertConversation(conv)
 
-	successResponse(w, apiConv, nil)
+	s.successResponse(w, apiConv, nil)
 }
 
 // DeleteConversationHandler deletes a conversation
@@ -326,32 +774,144 @@ func (s *Server) DeleteConversationHandler(w http.ResponseWriter, r *http.Reques
 	vars := mux.Vars(r)
 	idStr, exists := vars["id"]
 	if !exists {
-		errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
+		s.errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
 		return
 	}
 
 	id, err := validation.ParseAndValidateID(idStr, "conversation_id")
 	if err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
 		return
 	}
 
 	if err := s.db.DeleteConversation(id); err != nil {
 		if errors.Is(err, database.ErrConversationNotFound) {
-			errorResponse(w, "Conversation not found", http.StatusNotFound)
+			s.errorResponse(w, "Conversation not found", http.StatusNotFound)
 			return
 		}
-		errorResponse(w, fmt.Sprintf("Failed to delete conversation: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to delete conversation: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// RestoreConversationHandler clears the soft deletion of a conversation
+func (s *Server) RestoreConversationHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "conversation_id")
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.RestoreConversation(id); err != nil {
+		if errors.Is(err, database.ErrConversationNotFound) {
+			s.errorResponse(w, "Conversation not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to restore conversation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	conv, err := s.db.GetConversation(id)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get conversation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, ConvertConversation(conv), nil)
+}
+
+// maxBulkDeleteSize caps how many conversations one bulk delete request may remove
+const maxBulkDeleteSize = 500
+
+// BulkDeleteConversationsHandler deletes several conversations at once
+func (s *Server) BulkDeleteConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs []int `json:"ids"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.IDs) == 0 {
+		s.errorResponse(w, "ids must contain at least one conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.IDs) > maxBulkDeleteSize {
+		s.errorResponse(w, fmt.Sprintf("Cannot delete more than %d conversations at once", maxBulkDeleteSize), http.StatusBadRequest)
+		return
+	}
+
+	// Validate IDs, ignoring duplicates
+	seen := make(map[int]bool, len(req.IDs))
+	ids := make([]int, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		if err := validation.ValidateID(id, "conversation_id"); err != nil {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	deleted, err := s.db.DeleteConversations(ids)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to delete conversations: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	result := map[string]int{
+		"deleted":   deleted,
+		"not_found": len(ids) - deleted,
+	}
+
+	s.successResponse(w, result, nil)
+}
+
+// PurgeDeletedConversationsHandler permanently removes soft-deleted
+// conversations. The optional older_than duration keeps conversations deleted
+// more recently than that; by default every deleted conversation is purged.
+func (s *Server) PurgeDeletedConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	var olderThan time.Duration
+	if value := r.URL.Query().Get("older_than"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			s.errorResponse(w, "older_than must be a non-negative duration such as 720h", http.StatusBadRequest)
+			return
+		}
+		olderThan = d
+	}
+
+	purged, err := s.db.PurgeDeletedConversations(time.Now().Add(-olderThan))
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to purge conversations: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, map[string]int{"purged": purged}, nil)
+}
+
 // Rating handlers
 
 // CreateConversationRatingHandler creates a rating for a conversation
@@ -359,17 +919,17 @@ func (s *Server) CreateConversationRatingHandler(w http.ResponseWriter, r *http.
 	vars := mux.Vars(r)
 	idStr, exists := vars["id"]
 	if !exists {
-		errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
+		s.errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
 		return
 	}
 
 	id, err := validation.ParseAndValidateID(idStr, "conversation_id")
 	if err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
 		return
 	}
 
@@ -379,46 +939,43 @@ func (s *Server) CreateConversationRatingHandler(w http.ResponseWriter, r *http.
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
 		return
 	}
 
 	// Validate rating
 	if err := validation.ValidateRating(req.Rating); err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid rating", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid rating", http.StatusBadRequest)
 		return
 	}
 
 	// Validate comment
 	if err := validation.ValidateComment(req.Comment); err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid comment", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid comment", http.StatusBadRequest)
 		return
 	}
 
 	// Sanitize comment
-	if req.Comment != nil {
-		sanitized := validation.SanitizeString(*req.Comment, validation.MaxCommentLength)
-		req.Comment = &sanitized
-	}
+	req.Comment = s.sanitizeComment(req.Comment)
 
 	rating, err := s.db.CreateConversationRating(id, req.Rating, req.Comment)
 	if err != nil {
-		errorResponse(w, fmt.Sprintf("Failed to create rating: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to create rating: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	apiRating := ConvertRating(rating)
+	s.notifyRating(apiRating)
 
-	w.WriteHeader(http.StatusCreated)
-	successResponse(w, apiRating, nil)
+	s.writeJSON(w, http.StatusCreated, successBody(apiRating, nil))
 }
 
 // GetConversationRatingsHandler returns all ratings for a conversation
@@ -426,29 +983,29 @@ func (s *Server) GetConversationRatingsHandler(w http.ResponseWriter, r *http.Re
 	vars := mux.Vars(r)
 	idStr, exists := vars["id"]
 	if !exists {
-		errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
+		s.errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
 		return
 	}
 
 	id, err := validation.ParseAndValidateID(idStr, "conversation_id")
 	if err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
 		return
 	}
 
 	ratings, err := s.db.GetConversationRatings(id)
 	if err != nil {
-		errorResponse(w, fmt.Sprintf("Failed to get ratings: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to get ratings: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	apiRatings := ConvertRatings(ratings)
 
-	successResponse(w, apiRatings, nil)
+	s.successResponse(w, apiRatings, nil)
 }
 
 // UpdateRatingHandler updates a rating
@@ -456,17 +1013,17 @@ func (s *Server) UpdateRatingHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr, exists := vars["id"]
 	if !exists {
-		errorResponse(w, "Rating ID is required", http.StatusBadRequest)
+		s.errorResponse(w, "Rating ID is required", http.StatusBadRequest)
 		return
 	}
 
 	id, err := validation.ParseAndValidateID(idStr, "rating_id")
 	if err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid rating ID", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid rating ID", http.StatusBadRequest)
 		return
 	}
 
@@ -476,55 +1033,131 @@ func (s *Server) UpdateRatingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
 		return
 	}
 
 	// Validate rating
 	if err := validation.ValidateRating(req.Rating); err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid rating", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid rating", http.StatusBadRequest)
 		return
 	}
 
 	// Validate comment
 	if err := validation.ValidateComment(req.Comment); err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid comment", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid comment", http.StatusBadRequest)
 		return
 	}
 
 	// Sanitize comment
-	if req.Comment != nil {
-		sanitized := validation.SanitizeString(*req.Comment, validation.MaxCommentLength)
-		req.Comment = &sanitized
-	}
+	req.Comment = s.sanitizeComment(req.Comment)
 
 	if err := s.db.UpdateRating(id, req.Rating, req.Comment); err != nil {
 		if errors.Is(err, database.ErrRatingNotFound) {
-			errorResponse(w, "Rating not found", http.StatusNotFound)
+			s.errorResponse(w, "Rating not found", http.StatusNotFound)
 			return
 		}
-		errorResponse(w, fmt.Sprintf("Failed to update rating: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to update rating: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Return updated rating
 	rating, err := s.db.GetRating(id)
 	if err != nil {
-		errorResponse(w, fmt.Sprintf("Failed to get updated rating: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to get updated rating: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	apiRating := ConvertRating(rating)
 
-	successResponse(w, apiRating, nil)
+	s.successResponse(w, apiRating, nil)
+}
+
+// PatchRatingHandler updates only the rating fields present in the request
+// body, leaving the others unchanged
+func (s *Server) PatchRatingHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Rating ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "rating_id")
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid rating ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Rating  *int    `json:"rating"`
+		Comment *string `json:"comment"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Rating == nil && req.Comment == nil {
+		s.errorResponse(w, "At least one of rating or comment is required", http.StatusBadRequest)
+		return
+	}
+
+	// Validate rating
+	if req.Rating != nil {
+		if err := validation.ValidateRating(*req.Rating); err != nil {
+			if validation.IsValidationError(err) {
+				s.validationErrorResponse(w, err)
+				return
+			}
+			s.errorResponse(w, "Invalid rating", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Validate comment
+	if err := validation.ValidateComment(req.Comment); err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid comment", http.StatusBadRequest)
+		return
+	}
+
+	// Sanitize comment
+	req.Comment = s.sanitizeComment(req.Comment)
+
+	if err := s.db.PatchRating(id, req.Rating, req.Comment); err != nil {
+		if errors.Is(err, database.ErrRatingNotFound) {
+			s.errorResponse(w, "Rating not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to update rating: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return updated rating
+	rating, err := s.db.GetRating(id)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get updated rating: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, ConvertRating(rating), nil)
 }
 
 // DeleteRatingHandler deletes a rating
@@ -532,26 +1165,26 @@ func (s *Server) DeleteRatingHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr, exists := vars["id"]
 	if !exists {
-		errorResponse(w, "Rating ID is required", http.StatusBadRequest)
+		s.errorResponse(w, "Rating ID is required", http.StatusBadRequest)
 		return
 	}
 
 	id, err := validation.ParseAndValidateID(idStr, "rating_id")
 	if err != nil {
 		if validation.IsValidationError(err) {
-			errorResponse(w, err.Error(), http.StatusBadRequest)
+			s.validationErrorResponse(w, err)
 			return
 		}
-		errorResponse(w, "Invalid rating ID", http.StatusBadRequest)
+		s.errorResponse(w, "Invalid rating ID", http.StatusBadRequest)
 		return
 	}
 
 	if err := s.db.DeleteRating(id); err != nil {
 		if errors.Is(err, database.ErrRatingNotFound) {
-			errorResponse(w, "Rating not found", http.StatusNotFound)
+			s.errorResponse(w, "Rating not found", http.StatusNotFound)
 			return
 		}
-		errorResponse(w, fmt.Sprintf("Failed to delete rating: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to delete rating: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -562,10 +1195,10 @@ func (s *Server) DeleteRatingHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) GetRatingStatsHandler(w http.ResponseWriter, r *http.Request) {
 	stats, err := s.db.GetRatingStats()
 	if err != nil {
-		errorResponse(w, fmt.Sprintf("Failed to get rating stats: %v", err), http.StatusInternalServerError)
+		s.errorResponse(w, fmt.Sprintf("Failed to get rating stats: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	successResponse(w, stats, nil)
+	s.successResponse(w, stats, nil)
 }
 
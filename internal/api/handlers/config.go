@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+	"github.com/claude-code-template/prompt-manager/internal/models"
+)
+
+// ContentField names a hook data field that can carry response text
+type ContentField string
+
+const (
+	ContentFieldResponse ContentField = "response"
+	ContentFieldContent  ContentField = "content"
+)
+
+// DefaultMaxRequestBytes is the default cap on hook request bodies
+const DefaultMaxRequestBytes int64 = 2 << 20
+
+// Default per-client rate limit for hook requests, generous enough for
+// bursts of tool activity while stopping a runaway script
+const (
+	DefaultRateLimit      = 20.0
+	DefaultRateLimitBurst = 50
+)
+
+// DefaultMaxTimestampSkew is how far in the future a hook timestamp may be by
+// default, allowing for clock drift between the hook host and the server
+const DefaultMaxTimestampSkew = 5 * time.Minute
+
+// Config holds options controlling how hook payloads are ingested
+type Config struct {
+	// StrictDecoding rejects hook payloads containing fields that HookData
+	// does not define, so typos such as "sessionId" surface as a clear error
+	// instead of a confusing "session_id is required"
+	StrictDecoding bool
+
+	// PreferredContentField picks which field supplies the response text when
+	// a payload carries both "response" and "content". The zero value
+	// prefers "response".
+	PreferredContentField ContentField
+
+	// Logger receives ingestion warnings, such as payloads whose response
+	// and content fields disagree, and failures writing responses. Nil uses
+	// the standard logger.
+	Logger logging.Logger
+
+	// MaxToolCallDepth caps how deeply submitted tool calls and their
+	// arguments may nest. Zero uses models.DefaultMaxToolCallDepth.
+	MaxToolCallDepth int
+
+	// MaxRequestBytes caps the size of hook request bodies. It is enforced
+	// by wrapping the hook routes with api.MaxBodyBytes.
+	MaxRequestBytes int64
+
+	// RateLimit and RateLimitBurst bound how many hook requests per second
+	// a single client IP may make, enforced by wrapping the hook routes
+	// with api.RateLimitMiddleware. Zero disables the limit.
+	RateLimit      float64
+	RateLimitBurst int
+
+	// MaxTimestampSkew bounds how far in the future a hook's timestamp may
+	// be before the payload is rejected. Zero uses DefaultMaxTimestampSkew.
+	MaxTimestampSkew time.Duration
+
+	// Events is notified after each conversation and message the hooks
+	// create, feeding live update streams. Nil disables notifications.
+	Events EventPublisher
+}
+
+// EventPublisher receives notifications of newly created records.
+// Publish must not block the hook request.
+type EventPublisher interface {
+	Publish(event models.Event)
+}
+
+// DefaultConfig returns the default ingestion configuration, which accepts
+// unknown fields for compatibility with older hook scripts
+func DefaultConfig() Config {
+	return Config{
+		StrictDecoding:        false,
+		PreferredContentField: ContentFieldResponse,
+		MaxToolCallDepth:      models.DefaultMaxToolCallDepth,
+		MaxRequestBytes:       DefaultMaxRequestBytes,
+		RateLimit:             DefaultRateLimit,
+		RateLimitBurst:        DefaultRateLimitBurst,
+		MaxTimestampSkew:      DefaultMaxTimestampSkew,
+	}
+}
+
+// logger returns the configured logger, falling back to the standard logger
+func (c Config) logger() logging.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return logging.Default()
+}
+
+// publish sends an event to the configured publisher, if any
+func (c Config) publish(event models.Event) {
+	if c.Events != nil {
+		c.Events.Publish(event)
+	}
+}
+
+// maxToolCallDepth returns the configured tool call depth limit, falling back
+// to the default
+func (c Config) maxToolCallDepth() int {
+	if c.MaxToolCallDepth > 0 {
+		return c.MaxToolCallDepth
+	}
+	return models.DefaultMaxToolCallDepth
+}
+
+// maxTimestampSkew returns the allowed future skew for hook timestamps,
+// falling back to the default
+func (c Config) maxTimestampSkew() time.Duration {
+	if c.MaxTimestampSkew > 0 {
+		return c.MaxTimestampSkew
+	}
+	return DefaultMaxTimestampSkew
+}
@@ -1,69 +1,107 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/httpjson"
 )
 
 // PromptHandler handles user prompt submissions
 type PromptHandler struct {
-	db *database.DB
+	db     *database.DB
+	config Config
 }
 
 // NewPromptHandler creates a new prompt handler
 func NewPromptHandler(db *database.DB) *PromptHandler {
-	return &PromptHandler{db: db}
+	return NewPromptHandlerWithConfig(db, DefaultConfig())
+}
+
+// NewPromptHandlerWithConfig creates a new prompt handler with the given ingestion configuration
+func NewPromptHandlerWithConfig(db *database.DB, config Config) *PromptHandler {
+	return &PromptHandler{db: db, config: config}
 }
 
 // HandlePromptSubmit processes user prompt submissions
 func (ph *PromptHandler) HandlePromptSubmit(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
 	if r.Method != http.MethodPost {
-		ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		ph.config.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var hookData HookData
-	if err := json.NewDecoder(r.Body).Decode(&hookData); err != nil {
-		ErrorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+	hookData, ok := decodeHookData(w, r, ph.config)
+	if !ok {
 		return
 	}
 
 	if hookData.SessionID == "" {
-		ErrorResponse(w, "session_id is required", http.StatusBadRequest)
+		ph.config.ErrorResponse(w, "session_id is required", http.StatusBadRequest)
 		return
 	}
 
 	// Extract prompt content from hook data
 	promptData, ok := hookData.Data["prompt"]
 	if !ok {
-		ErrorResponse(w, "no prompt data in request", http.StatusBadRequest)
+		ph.config.ErrorResponse(w, "no prompt data in request", http.StatusBadRequest)
 		return
 	}
 
 	prompt, ok := promptData.(string)
 	if !ok {
-		ErrorResponse(w, "prompt data must be a string", http.StatusBadRequest)
+		ph.config.ErrorResponse(w, "prompt data must be a string", http.StatusBadRequest)
+		return
+	}
+
+	prompt, err := sanitizeContent(prompt)
+	if err != nil {
+		ph.config.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	timestamp, err := hookTimestamp(hookData, ph.config)
+	if err != nil {
+		ph.config.ErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Get or create conversation
-	conversationID, err := GetOrCreateConversation(ph.db, hookData.SessionID, hookData.Data)
+	conversationID, created, err := getOrCreateConversation(ph.db, hookData.SessionID, hookData.Data)
 	if err != nil {
-		ErrorResponse(w, fmt.Sprintf("Failed to get or create conversation: %v", err), http.StatusInternalServerError)
+		if errors.Is(err, database.ErrSessionConversationLimit) {
+			ph.config.ErrorResponse(w, "Session conversation limit reached", http.StatusTooManyRequests)
+			return
+		}
+		ph.config.ErrorResponse(w, fmt.Sprintf("Failed to get or create conversation: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if created {
+		publishConversationCreated(ph.config, conversationID, hookData.SessionID)
+	}
 
 	// Create message record
-	message, err := ph.db.CreateMessage(conversationID, "prompt", prompt, nil, nil)
+	message, err := ph.db.CreateMessageFromInput(conversationID, database.MessageInput{
+		MessageType: "prompt",
+		Content:     prompt,
+		Timestamp:   timestamp,
+	})
 	if err != nil {
-		ErrorResponse(w, fmt.Sprintf("Failed to create message: %v", err), http.StatusInternalServerError)
+		if errors.Is(err, database.ErrSessionQuotaExceeded) {
+			ph.config.ErrorResponse(w, "Session storage quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+		ph.config.ErrorResponse(w, fmt.Sprintf("Failed to create message: %v", err), http.StatusInternalServerError)
 		return
 	}
+	publishMessageCreated(ph.config, message, hookData.SessionID)
+
+	// Name an untitled conversation after its first prompt; a failure here
+	// should not lose the prompt that was just stored
+	if err := ph.db.AutoTitleConversation(conversationID, prompt); err != nil {
+		ph.config.logger().Printf("Failed to auto-title conversation %d: %v", conversationID, err)
+	}
 
 	response := APIResponse{
 		Success: true,
@@ -76,6 +114,5 @@ func (ph *PromptHandler) HandlePromptSubmit(w http.ResponseWriter, r *http.Reque
 		},
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	httpjson.Write(ph.config.logger(), w, http.StatusCreated, response)
 }
@@ -5,8 +5,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/validation"
 )
 
 
@@ -271,4 +276,282 @@ func TestPromptHandler_ConversationReuse(t *testing.T) {
 	if conversationID1 != conversationID2 {
 		t.Errorf("Expected same conversation ID for same session, got %v and %v", conversationID1, conversationID2)
 	}
-}
\ No newline at end of file
+}
+
+func TestPromptHandler_ConversationTitle(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	handler := NewPromptHandler(db)
+
+	submit := func(t *testing.T, sessionID string, data map[string]interface{}) int {
+		payload, _ := json.Marshal(HookData{Event: "UserPromptSubmit", SessionID: sessionID, Data: data})
+		req := httptest.NewRequest(http.MethodPost, "/messages/prompt", bytes.NewBuffer(payload))
+		w := httptest.NewRecorder()
+		handler.HandlePromptSubmit(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Request failed with status %d: %s", w.Code, w.Body.String())
+		}
+
+		var response APIResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		return int(response.Data.(map[string]interface{})["conversation_id"].(float64))
+	}
+
+	title := func(t *testing.T, id int) string {
+		conv, err := db.GetConversation(id)
+		if err != nil {
+			t.Fatalf("Failed to get conversation: %v", err)
+		}
+		if conv.Title == nil {
+			return ""
+		}
+		return *conv.Title
+	}
+
+	t.Run("derived from the first prompt", func(t *testing.T) {
+		id := submit(t, "title-derived", map[string]interface{}{"prompt": "Refactor the parser\nKeep the API"})
+		submit(t, "title-derived", map[string]interface{}{"prompt": "Now add tests"})
+
+		if got := title(t, id); got != "Refactor the parser" {
+			t.Errorf("Expected title from the first prompt, got %q", got)
+		}
+	})
+
+	t.Run("taken from hook data", func(t *testing.T) {
+		id := submit(t, "title-given", map[string]interface{}{"prompt": "Hello", "title": "  Release prep  "})
+
+		if got := title(t, id); got != "Release prep" {
+			t.Errorf("Expected title from hook data, got %q", got)
+		}
+	})
+}
+
+func TestPromptHandler_ContentValidation(t *testing.T) {
+	tests := []struct {
+		name            string
+		prompt          string
+		expectedStatus  int
+		expectedContent string
+	}{
+		{"empty prompt", "", http.StatusBadRequest, ""},
+		{"only control characters", "\x00\x07 ", http.StatusBadRequest, ""},
+		{"prompt over the limit", strings.Repeat("a", validation.MaxContentLength+1), http.StatusBadRequest, ""},
+		{"control characters stripped", "  Fix\x00 the\x1b bug\n ", http.StatusCreated, "Fix the bug"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+
+			handler := NewPromptHandler(db)
+
+			payload, _ := json.Marshal(HookData{
+				Event:     "UserPromptSubmit",
+				SessionID: "content-session",
+				Data:      map[string]interface{}{"prompt": tt.prompt},
+			})
+			req := httptest.NewRequest(http.MethodPost, "/messages/prompt", bytes.NewBuffer(payload))
+			w := httptest.NewRecorder()
+			handler.HandlePromptSubmit(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusCreated {
+				return
+			}
+
+			var response APIResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			conversationID := int(response.Data.(map[string]interface{})["conversation_id"].(float64))
+
+			messages, err := db.GetMessagesByConversation(conversationID)
+			if err != nil {
+				t.Fatalf("Failed to get messages: %v", err)
+			}
+			if len(messages) != 1 || messages[0].Content != tt.expectedContent {
+				t.Errorf("Expected stored content %q, got %+v", tt.expectedContent, messages)
+			}
+		})
+	}
+}
+
+func TestPromptHandler_StrictDecoding(t *testing.T) {
+	// Payload with a misspelled session_id field
+	payload := `{"event": "UserPromptSubmit", "sessionId": "test-session-123", "data": {"prompt": "Test prompt"}}`
+	
+	tests := []struct {
+		name           string
+		config         Config
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "lenient mode ignores unknown field",
+			config:         DefaultConfig(),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "session_id is required",
+		},
+		{
+			name:           "strict mode names unknown field",
+			config:         Config{StrictDecoding: true},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  `Unknown field in request body: "sessionId"`,
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+			
+			handler := NewPromptHandlerWithConfig(db, tt.config)
+			
+			req := httptest.NewRequest(http.MethodPost, "/messages/prompt", bytes.NewBufferString(payload))
+			req.Header.Set("Content-Type", "application/json")
+			
+			w := httptest.NewRecorder()
+			handler.HandlePromptSubmit(w, req)
+			
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			
+			var response APIResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			
+			if response.Error == nil || *response.Error != tt.expectedError {
+				t.Errorf("Expected error '%s', got %v", tt.expectedError, response.Error)
+			}
+		})
+	}
+}
+func TestPromptHandler_BodyTooLarge(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	handler := NewPromptHandler(db)
+
+	payload := `{"event": "UserPromptSubmit", "session_id": "test-session-123", "data": {"prompt": "` + strings.Repeat("x", 1024) + `"}}`
+	req := httptest.NewRequest(http.MethodPost, "/messages/prompt", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	req.Body = http.MaxBytesReader(w, req.Body, 256)
+	handler.HandlePromptSubmit(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+
+	var response APIResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Error == nil || *response.Error != "Request body exceeds 256 bytes" {
+		t.Errorf("Expected body size error, got %v", response.Error)
+	}
+}
+
+func TestPromptHandler_SessionQuota(t *testing.T) {
+	config := &database.Config{
+		DatabasePath:            filepath.Join(t.TempDir(), "test.db"),
+		MigrationsDir:           "../../../database/migrations",
+		MaxCharactersPerSession: 20,
+	}
+
+	db, err := database.New(config)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.RunMigrations(config.MigrationsDir); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	handler := NewPromptHandler(db)
+
+	submit := func(sessionID, prompt string) int {
+		payload, _ := json.Marshal(HookData{
+			Event:     "UserPromptSubmit",
+			SessionID: sessionID,
+			Data:      map[string]interface{}{"prompt": prompt},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/messages/prompt", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		handler.HandlePromptSubmit(w, req)
+		return w.Code
+	}
+
+	if code := submit("quota-session", "first prompt"); code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, code)
+	}
+
+	if code := submit("quota-session", "second prompt"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d once the quota is exceeded, got %d", http.StatusTooManyRequests, code)
+	}
+
+	if code := submit("other-session", "second prompt"); code != http.StatusCreated {
+		t.Errorf("Expected other session to be unaffected, got status %d", code)
+	}
+}
+
+func TestPromptHandler_HookTimestamp(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	handler := NewPromptHandler(db)
+	sessionID := "test-session-timestamps"
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	submit := func(prompt string, timestamp string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(HookData{
+			Event:     "UserPromptSubmit",
+			Timestamp: timestamp,
+			SessionID: sessionID,
+			Data:      map[string]interface{}{"prompt": prompt},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/messages/prompt", bytes.NewBuffer(payload))
+		w := httptest.NewRecorder()
+		handler.HandlePromptSubmit(w, req)
+		return w
+	}
+
+	// The later prompt's hook is delivered first
+	if w := submit("second", base.Add(time.Minute).Format(time.RFC3339)); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	if w := submit("first", base.Format(time.RFC3339)); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	conv, err := db.GetConversationBySessionID(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	messages, err := db.GetMessagesByConversation(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get messages: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Content != "first" || messages[1].Content != "second" {
+		t.Fatalf("Expected messages ordered by hook timestamp, got %+v", messages)
+	}
+	if !messages[0].Timestamp.Equal(base) {
+		t.Errorf("Expected stored timestamp %v, got %v", base, messages[0].Timestamp)
+	}
+
+	// Timestamps too far in the future are rejected
+	w := submit("from the future", time.Now().Add(time.Hour).Format(time.RFC3339))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a future timestamp, got %d", http.StatusBadRequest, w.Code)
+	}
+}
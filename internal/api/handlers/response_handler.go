@@ -2,39 +2,47 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/httpjson"
+	"github.com/claude-code-template/prompt-manager/internal/models"
+	"github.com/claude-code-template/prompt-manager/internal/validation"
 )
 
 // ResponseHandler handles assistant response submissions
 type ResponseHandler struct {
-	db *database.DB
+	db     *database.DB
+	config Config
 }
 
 // NewResponseHandler creates a new response handler
 func NewResponseHandler(db *database.DB) *ResponseHandler {
-	return &ResponseHandler{db: db}
+	return NewResponseHandlerWithConfig(db, DefaultConfig())
+}
+
+// NewResponseHandlerWithConfig creates a new response handler with the given ingestion configuration
+func NewResponseHandlerWithConfig(db *database.DB, config Config) *ResponseHandler {
+	return &ResponseHandler{db: db, config: config}
 }
 
 // HandleResponseSubmit processes assistant response submissions
 func (rh *ResponseHandler) HandleResponseSubmit(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
 	if r.Method != http.MethodPost {
-		ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		rh.config.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var hookData HookData
-	if err := json.NewDecoder(r.Body).Decode(&hookData); err != nil {
-		ErrorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+	hookData, ok := decodeHookData(w, r, rh.config)
+	if !ok {
 		return
 	}
 
 	if hookData.SessionID == "" {
-		ErrorResponse(w, "session_id is required", http.StatusBadRequest)
+		rh.config.ErrorResponse(w, "session_id is required", http.StatusBadRequest)
 		return
 	}
 
@@ -44,25 +52,34 @@ func (rh *ResponseHandler) HandleResponseSubmit(w http.ResponseWriter, r *http.R
 	var executionTime *int
 
 	// Try to extract response content from various possible fields
-	if content, ok := hookData.Data["response"]; ok {
-		if str, ok := content.(string); ok {
-			responseContent = str
-		}
-	} else if content, ok := hookData.Data["content"]; ok {
-		if str, ok := content.(string); ok {
-			responseContent = str
-		}
+	responseContent = rh.extractResponseContent(hookData)
+
+	// Whitespace-only responses carry nothing worth storing
+	if strings.TrimSpace(responseContent) == "" {
+		rh.config.ErrorResponse(w, "no response content in request", http.StatusBadRequest)
+		return
 	}
 
-	if responseContent == "" {
-		ErrorResponse(w, "no response content in request", http.StatusBadRequest)
+	responseContent, err := sanitizeContent(responseContent)
+	if err != nil {
+		rh.config.ErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Extract tool calls if present
 	if toolCalls, ok := hookData.Data["tool_calls"]; ok {
+		// Pathologically nested arguments are rejected before they are stored
+		if err := models.ValidateToolCallDepth(toolCalls, rh.config.maxToolCallDepth()); err != nil {
+			rh.config.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if toolCallsData, err := json.Marshal(toolCalls); err == nil {
 			toolCallsStr := string(toolCallsData)
+			// Oversized tool calls are rejected rather than bloating the database
+			if err := validation.ValidateToolCalls(toolCallsStr); err != nil {
+				rh.config.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 			toolCallsJSON = &toolCallsStr
 		}
 	}
@@ -75,19 +92,47 @@ func (rh *ResponseHandler) HandleResponseSubmit(w http.ResponseWriter, r *http.R
 		}
 	}
 
+	// Extract the model that produced the response if present
+	model := ExtractStringFromData(hookData.Data, "model")
+
+	timestamp, err := hookTimestamp(hookData, rh.config)
+	if err != nil {
+		rh.config.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Get or create conversation
-	conversationID, err := GetOrCreateConversation(rh.db, hookData.SessionID, hookData.Data)
+	conversationID, created, err := getOrCreateConversation(rh.db, hookData.SessionID, hookData.Data)
 	if err != nil {
-		ErrorResponse(w, fmt.Sprintf("Failed to get or create conversation: %v", err), http.StatusInternalServerError)
+		if errors.Is(err, database.ErrSessionConversationLimit) {
+			rh.config.ErrorResponse(w, "Session conversation limit reached", http.StatusTooManyRequests)
+			return
+		}
+		rh.config.ErrorResponse(w, fmt.Sprintf("Failed to get or create conversation: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if created {
+		publishConversationCreated(rh.config, conversationID, hookData.SessionID)
+	}
 
 	// Create message record
-	message, err := rh.db.CreateMessage(conversationID, "response", responseContent, toolCallsJSON, executionTime)
+	message, err := rh.db.CreateMessageFromInput(conversationID, database.MessageInput{
+		MessageType:   "response",
+		Content:       responseContent,
+		ToolCalls:     toolCallsJSON,
+		ExecutionTime: executionTime,
+		Model:         model,
+		Timestamp:     timestamp,
+	})
 	if err != nil {
-		ErrorResponse(w, fmt.Sprintf("Failed to create message: %v", err), http.StatusInternalServerError)
+		if errors.Is(err, database.ErrSessionQuotaExceeded) {
+			rh.config.ErrorResponse(w, "Session storage quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+		rh.config.ErrorResponse(w, fmt.Sprintf("Failed to create message: %v", err), http.StatusInternalServerError)
 		return
 	}
+	publishMessageCreated(rh.config, message, hookData.SessionID)
 
 	response := APIResponse{
 		Success: true,
@@ -99,9 +144,42 @@ func (rh *ResponseHandler) HandleResponseSubmit(w http.ResponseWriter, r *http.R
 			"timestamp":       message.Timestamp,
 			"has_tool_calls":  toolCallsJSON != nil,
 			"execution_time":  executionTime,
+			"model":           model,
 		},
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	httpjson.Write(rh.config.logger(), w, http.StatusCreated, response)
+}
+
+// extractResponseContent returns the response text from the "response" or
+// "content" field, preferring the configured field when both are present.
+// Payloads whose two fields disagree are logged, since that points to a
+// confused hook.
+func (rh *ResponseHandler) extractResponseContent(hookData HookData) string {
+	primary, secondary := ContentFieldResponse, ContentFieldContent
+	if rh.config.PreferredContentField == ContentFieldContent {
+		primary, secondary = secondary, primary
+	}
+
+	primaryValue, hasPrimary := hookData.Data[string(primary)]
+	secondaryValue, hasSecondary := hookData.Data[string(secondary)]
+
+	if hasPrimary && hasSecondary {
+		primaryStr, _ := primaryValue.(string)
+		secondaryStr, _ := secondaryValue.(string)
+		if primaryStr != secondaryStr {
+			rh.config.logger().Printf(
+				"warning: session %s sent differing %q and %q fields; using %q",
+				hookData.SessionID, primary, secondary, primary,
+			)
+		}
+	}
+
+	value := primaryValue
+	if !hasPrimary {
+		value = secondaryValue
+	}
+
+	str, _ := value.(string)
+	return str
 }
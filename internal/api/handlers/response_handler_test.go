@@ -3,10 +3,15 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+	"github.com/claude-code-template/prompt-manager/internal/validation"
 )
 
 func TestNewResponseHandler(t *testing.T) {
@@ -70,6 +75,28 @@ func TestResponseHandler_HandleResponseSubmit(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "response with model attribution",
+			method: http.MethodPost,
+			payload: HookData{
+				Event:     "Stop",
+				Timestamp: time.Now().Format(time.RFC3339),
+				SessionID: "test-session-model",
+				Filename:  "activity-monitor",
+				Data: map[string]interface{}{
+					"response":       "Attributed response",
+					"model":          "test-model",
+					"execution_time": 800,
+				},
+			},
+			expectedStatus: http.StatusCreated,
+			expectSuccess:  true,
+			validateData: func(t *testing.T, data map[string]interface{}) {
+				if data["model"] != "test-model" {
+					t.Errorf("Expected model 'test-model', got %v", data["model"])
+				}
+			},
+		},
 		{
 			name:           "method not allowed",
 			method:         http.MethodGet,
@@ -119,6 +146,22 @@ func TestResponseHandler_HandleResponseSubmit(t *testing.T) {
 			expectedError:  "no response content in request",
 			expectSuccess:  false,
 		},
+		{
+			name:   "whitespace-only response",
+			method: http.MethodPost,
+			payload: HookData{
+				Event:     "PostToolUse",
+				Timestamp: time.Now().Format(time.RFC3339),
+				SessionID: "test-session-123",
+				Filename:  "activity-monitor",
+				Data: map[string]interface{}{
+					"response": "  \n\t \n",
+				},
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "no response content in request",
+			expectSuccess:  false,
+		},
 		{
 			name:   "invalid response data type",
 			method: http.MethodPost,
@@ -296,4 +339,236 @@ func TestResponseHandler_ConversationCreation(t *testing.T) {
 	if data["session_id"] != hookData.SessionID {
 		t.Errorf("Expected session_id %s, got %v", hookData.SessionID, data["session_id"])
 	}
-}
\ No newline at end of file
+}
+
+func TestResponseHandler_ContentPrecedence(t *testing.T) {
+	tests := []struct {
+		name            string
+		preferred       ContentField
+		data            map[string]interface{}
+		expectedContent string
+		expectWarning   bool
+	}{
+		{
+			name:            "response preferred by default",
+			data:            map[string]interface{}{"response": "from response", "content": "from content"},
+			expectedContent: "from response",
+			expectWarning:   true,
+		},
+		{
+			name:            "content preferred when configured",
+			preferred:       ContentFieldContent,
+			data:            map[string]interface{}{"response": "from response", "content": "from content"},
+			expectedContent: "from content",
+			expectWarning:   true,
+		},
+		{
+			name:            "falls back to the other field",
+			preferred:       ContentFieldContent,
+			data:            map[string]interface{}{"response": "only response"},
+			expectedContent: "only response",
+			expectWarning:   false,
+		},
+		{
+			name:            "matching fields do not warn",
+			data:            map[string]interface{}{"response": "same", "content": "same"},
+			expectedContent: "same",
+			expectWarning:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+
+			var logs bytes.Buffer
+			config := DefaultConfig()
+			if tt.preferred != "" {
+				config.PreferredContentField = tt.preferred
+			}
+			config.Logger = logging.Standard{Logger: log.New(&logs, "", 0)}
+
+			handler := NewResponseHandlerWithConfig(db, config)
+
+			payload, _ := json.Marshal(HookData{
+				Event:     "Stop",
+				SessionID: "precedence-session",
+				Data:      tt.data,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/messages/response", bytes.NewBuffer(payload))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			handler.HandleResponseSubmit(w, req)
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+			}
+
+			var response APIResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			data := response.Data.(map[string]interface{})
+			msg, err := db.GetMessage(int(data["message_id"].(float64)))
+			if err != nil {
+				t.Fatalf("Failed to get stored message: %v", err)
+			}
+
+			if msg.Content != tt.expectedContent {
+				t.Errorf("Expected stored content %q, got %q", tt.expectedContent, msg.Content)
+			}
+
+			warned := strings.Contains(logs.String(), "warning")
+			if warned != tt.expectWarning {
+				t.Errorf("Expected warning = %v, got log %q", tt.expectWarning, logs.String())
+			}
+		})
+	}
+}
+
+func TestResponseHandler_ToolCallDepth(t *testing.T) {
+	nested := func(depth int) []interface{} {
+		var arguments interface{} = "leaf"
+		for i := 0; i < depth; i++ {
+			arguments = map[string]interface{}{"nested": arguments}
+		}
+		return []interface{}{map[string]interface{}{"name": "tool", "arguments": arguments}}
+	}
+
+	tests := []struct {
+		name           string
+		maxDepth       int
+		toolCalls      []interface{}
+		expectedStatus int
+	}{
+		{"within default limit", 0, nested(10), http.StatusCreated},
+		{"beyond default limit", 0, nested(100), http.StatusBadRequest},
+		{"within configured limit", 5, nested(3), http.StatusCreated},
+		{"beyond configured limit", 5, nested(4), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+
+			config := DefaultConfig()
+			config.MaxToolCallDepth = tt.maxDepth
+			handler := NewResponseHandlerWithConfig(db, config)
+
+			payload, _ := json.Marshal(HookData{
+				Event:     "Stop",
+				SessionID: "depth-session",
+				Data: map[string]interface{}{
+					"response":   "done",
+					"tool_calls": tt.toolCalls,
+				},
+			})
+			req := httptest.NewRequest(http.MethodPost, "/messages/response", bytes.NewBuffer(payload))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			handler.HandleResponseSubmit(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestResponseHandler_ToolCallLength(t *testing.T) {
+	// Pad a single argument so the stored JSON lands on an exact size
+	toolCallsOfSize := func(size int) []interface{} {
+		call := func(padding string) []interface{} {
+			return []interface{}{map[string]interface{}{"name": "tool", "arguments": padding}}
+		}
+		empty, _ := json.Marshal(call(""))
+		return call(strings.Repeat("a", size-len(empty)))
+	}
+
+	tests := []struct {
+		name           string
+		size           int
+		expectedStatus int
+	}{
+		{"at the limit", validation.MaxToolCallLength, http.StatusCreated},
+		{"just over the limit", validation.MaxToolCallLength + 1, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+
+			handler := NewResponseHandler(db)
+
+			payload, _ := json.Marshal(HookData{
+				Event:     "Stop",
+				SessionID: "tool-length-session",
+				Data: map[string]interface{}{
+					"response":   "done",
+					"tool_calls": toolCallsOfSize(tt.size),
+				},
+			})
+			req := httptest.NewRequest(http.MethodPost, "/messages/response", bytes.NewBuffer(payload))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			handler.HandleResponseSubmit(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+
+			stats, err := db.Stats()
+			if err != nil {
+				t.Fatalf("Failed to get stats: %v", err)
+			}
+			expectedMessages := 0
+			if tt.expectedStatus == http.StatusCreated {
+				expectedMessages = 1
+			}
+			if stats["messages"] != expectedMessages {
+				t.Errorf("Expected %d stored messages, got %v", expectedMessages, stats["messages"])
+			}
+		})
+	}
+}
+
+func TestResponseHandler_ContentValidation(t *testing.T) {
+	tests := []struct {
+		name           string
+		response       string
+		expectedStatus int
+	}{
+		{"empty response", "", http.StatusBadRequest},
+		{"response over the limit", strings.Repeat("a", validation.MaxContentLength+1), http.StatusBadRequest},
+		{"response at the limit", strings.Repeat("a", validation.MaxContentLength), http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+
+			handler := NewResponseHandler(db)
+
+			payload, _ := json.Marshal(HookData{
+				Event:     "Stop",
+				SessionID: "content-session",
+				Data:      map[string]interface{}{"response": tt.response},
+			})
+			req := httptest.NewRequest(http.MethodPost, "/messages/response", bytes.NewBuffer(payload))
+			w := httptest.NewRecorder()
+			handler.HandleResponseSubmit(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -1,40 +1,46 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/httpjson"
+	"github.com/claude-code-template/prompt-manager/internal/models"
 )
 
 // SessionHandler handles session events (start/stop)
 type SessionHandler struct {
-	db *database.DB
+	db     *database.DB
+	config Config
 }
 
 // NewSessionHandler creates a new session handler
 func NewSessionHandler(db *database.DB) *SessionHandler {
-	return &SessionHandler{db: db}
+	return NewSessionHandlerWithConfig(db, DefaultConfig())
+}
+
+// NewSessionHandlerWithConfig creates a new session handler with the given ingestion configuration
+func NewSessionHandlerWithConfig(db *database.DB, config Config) *SessionHandler {
+	return &SessionHandler{db: db, config: config}
 }
 
 // HandleSessionEvent processes session start/stop events
 func (sh *SessionHandler) HandleSessionEvent(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
 	if r.Method != http.MethodPost {
-		ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		sh.config.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var hookData HookData
-	if err := json.NewDecoder(r.Body).Decode(&hookData); err != nil {
-		ErrorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+	hookData, ok := decodeHookData(w, r, sh.config)
+	if !ok {
 		return
 	}
 
 	if hookData.SessionID == "" {
-		ErrorResponse(w, "session_id is required", http.StatusBadRequest)
+		sh.config.ErrorResponse(w, "session_id is required", http.StatusBadRequest)
 		return
 	}
 
@@ -46,7 +52,7 @@ func (sh *SessionHandler) HandleSessionEvent(w http.ResponseWriter, r *http.Requ
 		sh.handleSessionEnd(w, &hookData)
 		return
 	default:
-		ErrorResponse(w, fmt.Sprintf("Unknown session event: %s", hookData.Event), http.StatusBadRequest)
+		sh.config.ErrorResponse(w, fmt.Sprintf("Unknown session event: %s", hookData.Event), http.StatusBadRequest)
 		return
 	}
 }
@@ -54,9 +60,21 @@ func (sh *SessionHandler) HandleSessionEvent(w http.ResponseWriter, r *http.Requ
 // handleSessionStart processes session start events
 func (sh *SessionHandler) handleSessionStart(w http.ResponseWriter, hookData *HookData) {
 	// Get or create conversation
-	conversationID, err := GetOrCreateConversation(sh.db, hookData.SessionID, hookData.Data)
+	conversationID, created, err := getOrCreateConversation(sh.db, hookData.SessionID, hookData.Data)
 	if err != nil {
-		ErrorResponse(w, fmt.Sprintf("Failed to get or create conversation: %v", err), http.StatusInternalServerError)
+		if errors.Is(err, database.ErrSessionConversationLimit) {
+			sh.config.ErrorResponse(w, "Session conversation limit reached", http.StatusTooManyRequests)
+			return
+		}
+		sh.config.ErrorResponse(w, fmt.Sprintf("Failed to get or create conversation: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if created {
+		publishConversationCreated(sh.config, conversationID, hookData.SessionID)
+	}
+
+	if err := recordSessionStatus(sh.db, sh.config, hookData.SessionID, hookData.Data, models.SessionStatusActive); err != nil {
+		sh.config.ErrorResponse(w, fmt.Sprintf("Failed to update session status: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -69,32 +87,66 @@ func (sh *SessionHandler) handleSessionStart(w http.ResponseWriter, hookData *Ho
 		},
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	httpjson.Write(sh.config.logger(), w, http.StatusOK, response)
 }
 
 // handleSessionEnd processes session end/stop events
 func (sh *SessionHandler) handleSessionEnd(w http.ResponseWriter, hookData *HookData) {
+	timestamp, err := hookTimestamp(*hookData, sh.config)
+	if err != nil {
+		sh.config.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	endTime := time.Now()
+	if timestamp != nil {
+		endTime = *timestamp
+	}
+
 	// Try to find existing conversation for this session using efficient lookup
 	var conversationID *int
 	if conv, err := sh.db.GetConversationBySessionID(hookData.SessionID); err == nil {
 		conversationID = &conv.ID
 	} else if err.Error() != "conversation not found" {
 		// Only return error for actual database errors, not "not found"
-		ErrorResponse(w, fmt.Sprintf("Failed to lookup conversation: %v", err), http.StatusInternalServerError)
+		sh.config.ErrorResponse(w, fmt.Sprintf("Failed to lookup conversation: %v", err), http.StatusInternalServerError)
 		return
 	}
 	// If conversation not found, conversationID remains nil which is fine for session end
 
+	if err := recordSessionStatus(sh.db, sh.config, hookData.SessionID, hookData.Data, models.SessionStatusCompleted); err != nil {
+		sh.config.ErrorResponse(w, fmt.Sprintf("Failed to update session status: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	end, err := sh.db.RecordSessionEnd(hookData.SessionID, endTime, extractDuration(hookData.Data))
+	if err != nil {
+		sh.config.ErrorResponse(w, fmt.Sprintf("Failed to record session end: %v", err), http.StatusInternalServerError)
+		return
+	}
+
 	response := APIResponse{
 		Success: true,
 		Data: map[string]interface{}{
 			"event":           "session_end",
 			"conversation_id": conversationID,
 			"session_id":      hookData.SessionID,
+			"end_time":        end.EndTime,
+			"duration_ms":     end.DurationMs,
 		},
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	httpjson.Write(sh.config.logger(), w, http.StatusOK, response)
+}
+
+// extractDuration returns the session duration in milliseconds reported by
+// the hook, or nil when it is missing or negative so the duration is
+// measured from the session start instead
+func extractDuration(data map[string]interface{}) *int64 {
+	value, ok := data["duration"].(float64)
+	if !ok || value < 0 {
+		return nil
+	}
+
+	duration := int64(value)
+	return &duration
 }
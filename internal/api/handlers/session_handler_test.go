@@ -7,6 +7,8 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/models"
 )
 
 func TestNewSessionHandler(t *testing.T) {
@@ -278,4 +280,119 @@ func TestSessionHandler_ConversationCreation(t *testing.T) {
 	if data["session_id"] != hookData.SessionID {
 		t.Errorf("Expected session_id %s, got %v", hookData.SessionID, data["session_id"])
 	}
-}
\ No newline at end of file
+}
+func TestSessionHandler_StatusTransitions(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	handler := NewSessionHandler(db)
+	sessionID := "status-transition-session"
+
+	send := func(event string) {
+		t.Helper()
+		hookData := HookData{
+			Event:     event,
+			Timestamp: time.Now().Format(time.RFC3339),
+			SessionID: sessionID,
+			Data:      map[string]interface{}{"cwd": "/status/test"},
+		}
+		payload, _ := json.Marshal(hookData)
+		req := httptest.NewRequest(http.MethodPost, "/messages/session", bytes.NewBuffer(payload))
+		w := httptest.NewRecorder()
+		handler.HandleSessionEvent(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", event, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+
+	expectStatus := func(expected models.SessionStatus) {
+		t.Helper()
+		status, err := db.GetSessionStatus(sessionID)
+		if err != nil {
+			t.Fatalf("GetSessionStatus() error = %v", err)
+		}
+		if status != expected {
+			t.Errorf("Expected session status %q, got %q", expected, status)
+		}
+	}
+
+	send("SessionStart")
+	expectStatus(models.SessionStatusActive)
+
+	send("Stop")
+	expectStatus(models.SessionStatusCompleted)
+
+	send("SessionStart")
+	expectStatus(models.SessionStatusActive)
+
+	// Archived sessions stay archived, and the hook still succeeds
+	if err := db.SetSessionStatus(sessionID, models.SessionStatusArchived); err != nil {
+		t.Fatalf("SetSessionStatus() error = %v", err)
+	}
+	send("SessionStart")
+	expectStatus(models.SessionStatusArchived)
+}
+
+func TestSessionHandler_RecordsDuration(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	handler := NewSessionHandler(db)
+	sessionID := "duration-session"
+
+	send := func(event string, data map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		hookData := HookData{
+			Event:     event,
+			Timestamp: time.Now().Format(time.RFC3339),
+			SessionID: sessionID,
+			Data:      data,
+		}
+		payload, _ := json.Marshal(hookData)
+		req := httptest.NewRequest(http.MethodPost, "/messages/session", bytes.NewBuffer(payload))
+		w := httptest.NewRecorder()
+		handler.HandleSessionEvent(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", event, http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var response APIResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response.Data.(map[string]interface{})
+	}
+
+	send("SessionStart", map[string]interface{}{})
+
+	// Back-date the start so the measured duration is about a minute
+	start := time.Now().UTC().Add(-time.Minute).Format("2006-01-02 15:04:05")
+	if _, err := db.Conn().Exec("UPDATE sessions SET start_time = ? WHERE session_id = ?", start, sessionID); err != nil {
+		t.Fatalf("Failed to back-date session: %v", err)
+	}
+
+	data := send("SessionEnd", map[string]interface{}{})
+	duration, ok := data["duration_ms"].(float64)
+	if !ok || duration < 59000 || duration > 62000 {
+		t.Errorf("Expected a measured duration of about 60000ms, got %v", data["duration_ms"])
+	}
+	if data["end_time"] == nil {
+		t.Error("Expected end_time to be set")
+	}
+
+	data = send("SessionEnd", map[string]interface{}{"duration": 4500})
+	if data["duration_ms"] != float64(4500) {
+		t.Errorf("Expected the reported duration of 4500ms, got %v", data["duration_ms"])
+	}
+
+	session, err := db.GetSessionMetrics(sessionID)
+	if err != nil {
+		t.Fatalf("GetSessionMetrics() error = %v", err)
+	}
+	if session.DurationMs == nil || *session.DurationMs != 4500 {
+		t.Errorf("Expected the stored duration to be 4500ms, got %v", session.DurationMs)
+	}
+	if session.EndTime == nil {
+		t.Error("Expected the stored end time to be set")
+	}
+}
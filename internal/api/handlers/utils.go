@@ -2,39 +2,86 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/httpjson"
+	"github.com/claude-code-template/prompt-manager/internal/models"
+	"github.com/claude-code-template/prompt-manager/internal/validation"
 )
 
 // GetOrCreateConversation finds an existing conversation by session ID or creates a new one.
-// It uses a direct database query to efficiently find conversations by session ID.
-// If no match is found, it creates a new conversation with optional context data.
+// The lookup and insert happen atomically in the database, so concurrent hook calls
+// for a brand-new session share a single conversation.
+// A newly created conversation records the optional context data, including
+// an initial title from the "title" field.
 func GetOrCreateConversation(db *database.DB, sessionID string, data map[string]interface{}) (int, error) {
-	// Try to find existing conversation for this session using efficient lookup
-	conv, err := db.GetConversationBySessionID(sessionID)
-	if err == nil {
-		// Found existing conversation
-		return conv.ID, nil
-	}
+	conversationID, _, err := getOrCreateConversation(db, sessionID, data)
+	return conversationID, err
+}
 
-	// Check if error is "not found" - if so, create new conversation
-	// For other errors, return them
-	if err.Error() != "conversation not found" {
-		return 0, fmt.Errorf("failed to lookup conversation by session ID: %w", err)
+// getOrCreateConversation is GetOrCreateConversation that also reports
+// whether the conversation was newly created
+func getOrCreateConversation(db *database.DB, sessionID string, data map[string]interface{}) (int, bool, error) {
+	var title *string
+	if raw := ExtractStringFromData(data, "title"); raw != nil {
+		if sanitized := validation.SanitizeString(*raw, validation.MaxTitleLength); sanitized != "" {
+			title = &sanitized
+		}
 	}
-
-	// Create new conversation
 	workingDir := ExtractStringFromData(data, "cwd")
 	transcriptPath := ExtractStringFromData(data, "transcript_path")
 
-	newConv, err := db.CreateConversation(sessionID, nil, workingDir, transcriptPath)
+	conv, created, err := db.GetOrCreateConversationBySessionID(sessionID, title, workingDir, transcriptPath)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create conversation: %w", err)
+		return 0, false, fmt.Errorf("failed to get or create conversation: %w", err)
+	}
+
+	return conv.ID, created, nil
+}
+
+// recordSessionStatus records the session if needed and moves it to status.
+// Transitions the session does not allow, such as reactivating an archived
+// session, are logged and skipped so a hook never fails because of them.
+func recordSessionStatus(db *database.DB, config Config, sessionID string, data map[string]interface{}, status models.SessionStatus) error {
+	if err := db.UpsertSession(sessionID, ExtractStringFromData(data, "cwd")); err != nil {
+		return err
+	}
+
+	err := db.SetSessionStatus(sessionID, status)
+	if errors.Is(err, database.ErrInvalidSessionTransition) {
+		config.logger().Printf("Leaving session %s unchanged: %v", sessionID, err)
+		return nil
 	}
+	return err
+}
 
-	return newConv.ID, nil
+// publishConversationCreated notifies the configured publisher of a
+// conversation the hooks just created
+func publishConversationCreated(config Config, conversationID int, sessionID string) {
+	config.publish(models.Event{
+		Type:           models.EventConversationCreated,
+		ConversationID: conversationID,
+		SessionID:      sessionID,
+		Timestamp:      time.Now().UTC(),
+	})
+}
+
+// publishMessageCreated notifies the configured publisher of a stored message
+func publishMessageCreated(config Config, message *database.Message, sessionID string) {
+	messageID := message.ID
+	config.publish(models.Event{
+		Type:           models.EventMessageCreated,
+		ConversationID: message.ConversationID,
+		MessageID:      &messageID,
+		MessageType:    message.MessageType,
+		SessionID:      sessionID,
+		Timestamp:      message.Timestamp,
+	})
 }
 
 // ExtractStringFromData safely extracts a string value from map data.
@@ -49,16 +96,78 @@ func ExtractStringFromData(data map[string]interface{}, key string) *string {
 	return nil
 }
 
+// sanitizeContent validates prompt or response text and strips control
+// characters and surrounding whitespace before it is stored. Content that is
+// empty once sanitized is rejected too.
+func sanitizeContent(content string) (string, error) {
+	if err := validation.ValidateContent(content); err != nil {
+		return "", err
+	}
+
+	sanitized := validation.SanitizeString(content, validation.MaxContentLength)
+	return sanitized, validation.ValidateContent(sanitized)
+}
+
+// hookTimestamp parses the RFC3339 timestamp sent with a hook so messages are
+// stored in the order they happened even when hooks arrive out of order.
+// It returns nil, meaning server time should be used, when the timestamp is
+// empty or unparseable, and an error when it lies further in the future than
+// the configured skew allows.
+func hookTimestamp(hookData HookData, config Config) (*time.Time, error) {
+	if hookData.Timestamp == "" {
+		return nil, nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, hookData.Timestamp)
+	if err != nil {
+		return nil, nil
+	}
+
+	if skew := config.maxTimestampSkew(); timestamp.After(time.Now().Add(skew)) {
+		return nil, fmt.Errorf("timestamp is more than %s in the future", skew)
+	}
+
+	return &timestamp, nil
+}
+
 // ErrorResponse sends a standardized error response in JSON format.
 // It sets the appropriate content type, status code, and response structure
-// consistent across all handlers.
-func ErrorResponse(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
+// consistent across all handlers. A failed write is logged to the
+// configured logger.
+func (c Config) ErrorResponse(w http.ResponseWriter, message string, statusCode int) {
 	response := APIResponse{
 		Success: false,
 		Error:   &message,
 	}
-	json.NewEncoder(w).Encode(response)
+	httpjson.Write(c.logger(), w, statusCode, response)
+}
+
+// decodeHookData decodes a hook payload from the request body. In strict mode
+// unknown fields are rejected with an error naming the offending field.
+// Bodies cut off by http.MaxBytesReader are rejected with 413.
+// It writes an error response and returns false when decoding fails.
+func decodeHookData(w http.ResponseWriter, r *http.Request, config Config) (HookData, bool) {
+	var hookData HookData
+
+	decoder := json.NewDecoder(r.Body)
+	if config.StrictDecoding {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(&hookData); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			config.ErrorResponse(w, fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+			return hookData, false
+		}
+		// encoding/json has no typed error for unknown fields, only this message
+		if field, found := strings.CutPrefix(err.Error(), "json: unknown field "); found {
+			config.ErrorResponse(w, fmt.Sprintf("Unknown field in request body: %s", field), http.StatusBadRequest)
+			return hookData, false
+		}
+		config.ErrorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return hookData, false
+	}
+
+	return hookData, true
 }
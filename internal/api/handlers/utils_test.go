@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/logging"
 )
 
 func TestGetOrCreateConversation(t *testing.T) {
@@ -75,7 +82,7 @@ func TestGetOrCreateConversation(t *testing.T) {
 			}
 
 			// Get initial conversation count
-			initialConvs, err := db.ListConversations(100, 0)
+			initialConvs, err := db.ListConversations(nil, 100, 0)
 			if err != nil {
 				t.Fatalf("Failed to list initial conversations: %v", err)
 			}
@@ -100,7 +107,7 @@ func TestGetOrCreateConversation(t *testing.T) {
 			}
 
 			// Check if new conversation was created or existing one returned
-			finalConvs, err := db.ListConversations(100, 0)
+			finalConvs, err := db.ListConversations(nil, 100, 0)
 			if err != nil {
 				t.Fatalf("Failed to list final conversations: %v", err)
 			}
@@ -134,6 +141,117 @@ func TestGetOrCreateConversation(t *testing.T) {
 	}
 }
 
+func TestGetOrCreateConversationConcurrent(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	const numGoroutines = 20
+	const sessionID = "concurrent-session"
+
+	type result struct {
+		id  int
+		err error
+	}
+	results := make(chan result, numGoroutines)
+
+	start := make(chan struct{})
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			<-start
+			id, err := GetOrCreateConversation(db, sessionID, nil)
+			results <- result{id, err}
+		}()
+	}
+	close(start)
+
+	ids := make(map[int]bool)
+	for i := 0; i < numGoroutines; i++ {
+		res := <-results
+		if res.err != nil {
+			t.Fatalf("GetOrCreateConversation() error = %v", res.err)
+		}
+		ids[res.id] = true
+	}
+
+	if len(ids) != 1 {
+		t.Errorf("Expected every call to return the same conversation, got IDs %v", ids)
+	}
+
+	conversations, err := db.ListConversationsBySession(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to list session conversations: %v", err)
+	}
+	if len(conversations) != 1 {
+		t.Errorf("Expected exactly 1 conversation for the session, got %d", len(conversations))
+	}
+}
+
+func TestSessionConversationLimit(t *testing.T) {
+	config := &database.Config{
+		DatabasePath:               filepath.Join(t.TempDir(), "test.db"),
+		MigrationsDir:              "../../../database/migrations",
+		MaxConversationsPerSession: 1,
+		ConversationReuseWindow:    time.Hour,
+	}
+
+	db, err := database.New(config)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.RunMigrations(config.MigrationsDir); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// An idle conversation is outside the reuse window, so each hook tries
+	// to start a new one, which the limit refuses. Inserted directly since
+	// updates refresh updated_at via a trigger.
+	const sessionID = "limited-session"
+	lastActivity := time.Now().Add(-24 * time.Hour).UTC().Format("2006-01-02 15:04:05")
+	if _, err := db.Conn().Exec(
+		"INSERT INTO conversations (session_id, created_at, updated_at) VALUES (?, ?, ?)",
+		sessionID, lastActivity, lastActivity,
+	); err != nil {
+		t.Fatalf("Failed to insert conversation: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		event   string
+		path    string
+		handler http.HandlerFunc
+		data    map[string]interface{}
+	}{
+		{"prompt", "UserPromptSubmit", "/messages/prompt", NewPromptHandler(db).HandlePromptSubmit, map[string]interface{}{"prompt": "Hello"}},
+		{"response", "Stop", "/messages/response", NewResponseHandler(db).HandleResponseSubmit, map[string]interface{}{"response": "Hi"}},
+		{"session", "SessionStart", "/messages/session", NewSessionHandler(db).HandleSessionEvent, map[string]interface{}{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, _ := json.Marshal(HookData{Event: tt.event, SessionID: sessionID, Data: tt.data})
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewBuffer(payload))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+
+			if w.Code != http.StatusTooManyRequests {
+				t.Errorf("Expected status %d, got %d: %s", http.StatusTooManyRequests, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	conversations, err := db.ListConversationsBySession(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to list session conversations: %v", err)
+	}
+	if len(conversations) != 1 {
+		t.Errorf("Expected the session to keep 1 conversation, got %d", len(conversations))
+	}
+}
+
 func TestExtractStringFromData(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -223,6 +341,43 @@ func TestExtractStringFromData(t *testing.T) {
 	}
 }
 
+func TestHookTimestamp(t *testing.T) {
+	past := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	soon := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+	config := DefaultConfig()
+
+	tests := []struct {
+		name      string
+		timestamp string
+		expected  *time.Time
+		expectErr bool
+	}{
+		{"empty falls back to server time", "", nil, false},
+		{"unparseable falls back to server time", "yesterday", nil, false},
+		{"past timestamp is used", past.Format(time.RFC3339), &past, false},
+		{"within skew is used", soon.Format(time.RFC3339), &soon, false},
+		{"beyond skew is rejected", time.Now().Add(time.Hour).Format(time.RFC3339), nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamp, err := hookTimestamp(HookData{Timestamp: tt.timestamp}, config)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("hookTimestamp() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if tt.expected == nil {
+				if timestamp != nil {
+					t.Errorf("Expected nil timestamp, got %v", timestamp)
+				}
+				return
+			}
+			if timestamp == nil || !timestamp.Equal(*tt.expected) {
+				t.Errorf("Expected timestamp %v, got %v", tt.expected, timestamp)
+			}
+		})
+	}
+}
+
 func TestErrorResponse(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -276,7 +431,7 @@ func TestErrorResponse(t *testing.T) {
 			w := httptest.NewRecorder()
 
 			// Call ErrorResponse
-			ErrorResponse(w, tt.message, tt.statusCode)
+			DefaultConfig().ErrorResponse(w, tt.message, tt.statusCode)
 
 			// Check status code
 			if w.Code != tt.expectedStatus {
@@ -317,7 +472,26 @@ func TestErrorResponse(t *testing.T) {
 	}
 }
 
+// brokenPipeWriter is a ResponseWriter whose body writes always fail
+type brokenPipeWriter struct {
+	httptest.ResponseRecorder
+}
+
+func (b *brokenPipeWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestErrorResponseUsesConfiguredLogger(t *testing.T) {
+	var logs bytes.Buffer
+	config := DefaultConfig()
+	config.Logger = logging.Standard{Logger: log.New(&logs, "", 0)}
+
+	config.ErrorResponse(&brokenPipeWriter{ResponseRecorder: *httptest.NewRecorder()}, "Invalid request", http.StatusBadRequest)
+
+	if !strings.Contains(logs.String(), "ERROR: Failed to write JSON response: broken pipe") {
+		t.Errorf("Expected the write failure to be logged through the configured logger, got %q", logs.String())
+	}
+}
+
 // Helper function to create string pointers for tests
 func stringPtr(s string) *string {
 	return &s
-}
\ No newline at end of file
+}
@@ -3,13 +3,21 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/models"
+	"github.com/claude-code-template/prompt-manager/internal/validation"
 )
 
 func setupTestServer(t *testing.T) *Server {
@@ -88,6 +96,37 @@ func TestHealthHandler(t *testing.T) {
 	}
 }
 
+func TestLivenessHandlerWithClosedDatabase(t *testing.T) {
+	server := setupTestServer(t)
+	server.db.Close()
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		req, err := http.NewRequest(method, "/livez", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.LivenessHandler).ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("%s /livez returned wrong status code: got %v want %v", method, status, http.StatusOK)
+		}
+	}
+
+	req, err := http.NewRequest("GET", "/health", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.HealthHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("readiness check returned wrong status code: got %v want %v", status, http.StatusServiceUnavailable)
+	}
+}
+
 func TestCreateConversation(t *testing.T) {
 	server := setupTestServer(t)
 
@@ -111,6 +150,9 @@ func TestCreateConversation(t *testing.T) {
 	if status := rr.Code; status != http.StatusCreated {
 		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
 	}
+	if contentType := rr.Result().Header.Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", contentType)
+	}
 
 	var response APIResponse
 	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
@@ -174,6 +216,164 @@ func TestCreateConversationInvalidRequest(t *testing.T) {
 	}
 }
 
+func TestCreateConversationFieldErrors(t *testing.T) {
+	server := setupTestServer(t)
+
+	req, err := http.NewRequest("POST", "/conversations", strings.NewReader(`{"session_id": "bad session!"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.CreateConversationHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Errors) != 1 || response.Errors[0].Field != "session_id" {
+		t.Fatalf("Expected one session_id field error, got %+v", response.Errors)
+	}
+	if response.Errors[0].Message != `can only contain letters, numbers, underscores, hyphens, and ".:"` {
+		t.Errorf("Expected the bare validation message, got %q", response.Errors[0].Message)
+	}
+
+	// The flat message is kept for older clients
+	if response.Error == nil || !strings.Contains(*response.Error, "session_id") {
+		t.Errorf("Expected the flat error to name session_id, got %v", response.Error)
+	}
+}
+
+func TestCreateConversationSessionIDSeparators(t *testing.T) {
+	tests := []struct {
+		name           string
+		sessionID      string
+		strict         bool
+		expectedStatus int
+	}{
+		{"UUID", "3f2b8c1e-9a4d-4e7b-b6a2-1c0d9e8f7a65", false, http.StatusCreated},
+		{"dotted", "claude.session.42", false, http.StatusCreated},
+		{"colon separated", "project:42", false, http.StatusCreated},
+		{"dotted in strict mode", "claude.session.42", true, http.StatusBadRequest},
+		{"plain in strict mode", "session_42", true, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := setupTestServer(t)
+			server.config.StrictSessionIDs = tt.strict
+
+			body, _ := json.Marshal(map[string]interface{}{"session_id": tt.sessionID})
+			req, err := http.NewRequest("POST", "/conversations", bytes.NewBuffer(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(server.CreateConversationHandler).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", status, tt.expectedStatus, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCreateConversationMultipleFieldErrors(t *testing.T) {
+	server := setupTestServer(t)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"session_id":        "multi-error-session",
+		"title":             strings.Repeat("t", validation.MaxTitleLength+1),
+		"working_directory": "/home/user/project\x00",
+	})
+	req, err := http.NewRequest("POST", "/conversations", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.CreateConversationHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Errors) != 2 {
+		t.Fatalf("Expected 2 field errors, got %+v", response.Errors)
+	}
+	if response.Errors[0].Field != "title" || response.Errors[1].Field != "working_directory" {
+		t.Errorf("Expected title and working_directory errors, got %+v", response.Errors)
+	}
+	if response.Error == nil || !strings.Contains(*response.Error, "title") || !strings.Contains(*response.Error, "working_directory") {
+		t.Errorf("Expected the flat error to mention both fields, got %v", response.Error)
+	}
+}
+
+func TestCreateConversationPathLimits(t *testing.T) {
+	server := setupTestServer(t)
+	server.config.MaxWorkingDirectoryLength = 100
+	server.config.MaxTranscriptPathLength = 2000
+
+	longPath := func(n int) string {
+		return "/" + strings.Repeat("a", n-1)
+	}
+
+	tests := []struct {
+		name           string
+		workingDir     string
+		transcriptPath string
+		expectedStatus int
+	}{
+		{"transcript path at higher limit", "/work", longPath(2000), http.StatusCreated},
+		{"transcript path over limit", "/work", longPath(2001), http.StatusBadRequest},
+		{"working directory at limit", longPath(100), "/t.jsonl", http.StatusCreated},
+		{"working directory over limit", longPath(101), "/t.jsonl", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{
+				"session_id":        "path-session",
+				"working_directory": tt.workingDir,
+				"transcript_path":   tt.transcriptPath,
+			})
+			req, err := http.NewRequest("POST", "/conversations", bytes.NewBuffer(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(server.CreateConversationHandler).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+
+			if tt.expectedStatus == http.StatusCreated {
+				var response struct {
+					Data struct {
+						TranscriptPath string `json:"transcript_path"`
+					} `json:"data"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if response.Data.TranscriptPath != tt.transcriptPath {
+					t.Errorf("Expected transcript path of length %d, got length %d", len(tt.transcriptPath), len(response.Data.TranscriptPath))
+				}
+			}
+		})
+	}
+}
+
 func TestGetConversation(t *testing.T) {
 	server := setupTestServer(t)
 
@@ -235,6 +435,270 @@ func TestGetConversation(t *testing.T) {
 	}
 }
 
+func TestGetConversationMessageTypeFilter(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("filter-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	for _, m := range []struct{ messageType, content string }{
+		{"prompt", "first question"},
+		{"response", "first answer"},
+		{"prompt", "second question"},
+	} {
+		if _, err := server.db.CreateMessage(conv.ID, m.messageType, m.content, nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/conversations/{id}", server.GetConversationHandler)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", fmt.Sprintf("/conversations/%d%s", conv.ID, query), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("?message_type=prompt")
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response struct {
+		Data struct {
+			PromptCount     int `json:"prompt_count"`
+			TotalCharacters int `json:"total_characters"`
+			Messages        []struct {
+				MessageType string `json:"message_type"`
+			} `json:"messages"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Data.Messages) != 2 {
+		t.Fatalf("Expected 2 prompts, got %d messages", len(response.Data.Messages))
+	}
+	for _, msg := range response.Data.Messages {
+		if msg.MessageType != "prompt" {
+			t.Errorf("Expected only prompts, got %s", msg.MessageType)
+		}
+	}
+
+	// Counts still describe the whole conversation
+	expectedCharacters := len("first question") + len("first answer") + len("second question")
+	if response.Data.PromptCount != 2 || response.Data.TotalCharacters != expectedCharacters {
+		t.Errorf("Expected full conversation counts (2, %d), got (%d, %d)",
+			expectedCharacters, response.Data.PromptCount, response.Data.TotalCharacters)
+	}
+
+	if status := get("?message_type=tool").Code; status != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown message_type, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestGetConversationMessageOrder(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("order-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	for _, content := range []string{"first", "second", "third"} {
+		if _, err := server.db.CreateMessage(conv.ID, "prompt", content, nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/conversations/{id}", server.GetConversationHandler)
+
+	tests := []struct {
+		query          string
+		expectedStatus int
+		expected       []string
+	}{
+		{"", http.StatusOK, []string{"first", "second", "third"}},
+		{"?order=asc", http.StatusOK, []string{"first", "second", "third"}},
+		{"?order=desc", http.StatusOK, []string{"third", "second", "first"}},
+		{"?order=newest", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req, err := http.NewRequest("GET", fmt.Sprintf("/conversations/%d%s", conv.ID, tt.query), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+			if tt.expected == nil {
+				return
+			}
+
+			var response struct {
+				Data struct {
+					Messages []struct {
+						Content string `json:"content"`
+					} `json:"messages"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			if len(response.Data.Messages) != len(tt.expected) {
+				t.Fatalf("Expected %d messages, got %d", len(tt.expected), len(response.Data.Messages))
+			}
+			for i, msg := range response.Data.Messages {
+				if msg.Content != tt.expected[i] {
+					t.Errorf("Position %d: expected %q, got %q", i, tt.expected[i], msg.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestGetConversationTimeline(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("timeline-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	prompt, err := server.db.CreateMessage(conv.ID, "prompt", "What is the answer?", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	response, err := server.db.CreateMessage(conv.ID, "response", "Forty-two", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("/api/v1/conversations/%d/timeline", conv.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/conversations/{id}/timeline", server.GetConversationTimelineHandler)
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	if strings.Contains(rr.Body.String(), "Forty-two") || strings.Contains(rr.Body.String(), `"content"`) {
+		t.Errorf("Expected timeline to exclude message content, got %s", rr.Body.String())
+	}
+
+	var body struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(body.Data) != 2 {
+		t.Fatalf("Expected 2 timeline entries, got %d", len(body.Data))
+	}
+	expected := []struct {
+		id        int
+		msgType   string
+		charCount int
+	}{
+		{prompt.ID, "prompt", len("What is the answer?")},
+		{response.ID, "response", len("Forty-two")},
+	}
+	for i, want := range expected {
+		entry := body.Data[i]
+		if int(entry["message_id"].(float64)) != want.id || entry["type"] != want.msgType || int(entry["char_count"].(float64)) != want.charCount {
+			t.Errorf("Entry %d: expected %+v, got %v", i, want, entry)
+		}
+	}
+
+	req, err = http.NewRequest("GET", "/api/v1/conversations/999/timeline", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing conversation, got %d", http.StatusNotFound, status)
+	}
+}
+
+func TestGetConversationContentStats(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("stats-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := server.db.CreateMessage(conv.ID, "prompt", "Bonjour, ça va ?", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := server.db.CreateMessage(conv.ID, "response", "Très bien", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/conversations/{id}/stats", server.GetConversationContentStatsHandler)
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("/conversations/%d/stats", conv.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response struct {
+		Data database.ConversationContentStats `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	expected := database.ConversationContentStats{
+		ConversationID:     conv.ID,
+		PromptCharacters:   len("Bonjour, ça va ?"),
+		ResponseCharacters: len("Très bien"),
+		PromptCount:        1,
+		ResponseCount:      1,
+		WordCount:          6,
+	}
+	if response.Data != expected {
+		t.Errorf("Expected %+v, got %+v", expected, response.Data)
+	}
+
+	req, err = http.NewRequest("GET", "/conversations/999/stats", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing conversation, got %d", http.StatusNotFound, status)
+	}
+}
+
 func TestGetConversationNotFound(t *testing.T) {
 	server := setupTestServer(t)
 
@@ -349,6 +813,9 @@ func TestCreateConversationRating(t *testing.T) {
 	if status := rr.Code; status != http.StatusCreated {
 		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
 	}
+	if contentType := rr.Result().Header.Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", contentType)
+	}
 
 	var response APIResponse
 	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
@@ -376,6 +843,118 @@ func TestCreateConversationRating(t *testing.T) {
 	_ = conv // Suppress unused variable warning
 }
 
+func TestCreateRatingCommentNormalization(t *testing.T) {
+	tests := []struct {
+		name     string
+		collapse bool
+		expected string
+	}{
+		{"trimmed by default", false, "Too   slow,  but\tcorrect"},
+		{"collapsed when enabled", true, "Too slow, but correct"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := setupTestServer(t)
+			server.config.CollapseCommentWhitespace = tt.collapse
+
+			conv, err := server.db.CreateConversation("comment-session", nil, nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to create conversation: %v", err)
+			}
+
+			body, _ := json.Marshal(map[string]interface{}{
+				"rating":  3,
+				"comment": "  Too   slow,  but\tcorrect  ",
+			})
+			req, err := http.NewRequest("POST", fmt.Sprintf("/conversations/%d/ratings", conv.ID), bytes.NewBuffer(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/conversations/{id}/ratings", server.CreateConversationRatingHandler)
+			router.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusCreated {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+			}
+
+			ratings, err := server.db.GetConversationRatings(conv.ID)
+			if err != nil {
+				t.Fatalf("Failed to get ratings: %v", err)
+			}
+			if len(ratings) != 1 || ratings[0].Comment == nil || *ratings[0].Comment != tt.expected {
+				t.Errorf("Expected stored comment %q, got %+v", tt.expected, ratings)
+			}
+		})
+	}
+}
+
+func TestPatchRating(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("patch-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	rating, err := server.db.CreateConversationRating(conv.ID, 4, stringPtr("Original comment"))
+	if err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/ratings/{id}", server.PatchRatingHandler)
+
+	tests := []struct {
+		name            string
+		body            string
+		expectedStatus  int
+		expectedRating  int
+		expectedComment string
+	}{
+		{"comment only keeps score", `{"comment": "Revised comment"}`, http.StatusOK, 4, "Revised comment"},
+		{"score only keeps comment", `{"rating": 2}`, http.StatusOK, 2, "Revised comment"},
+		{"empty body rejected", `{}`, http.StatusBadRequest, 2, "Revised comment"},
+		{"out of range rejected", `{"rating": 0}`, http.StatusBadRequest, 2, "Revised comment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("PATCH", fmt.Sprintf("/ratings/%d", rating.ID), strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+
+			stored, err := server.db.GetRating(rating.ID)
+			if err != nil {
+				t.Fatalf("Failed to get rating: %v", err)
+			}
+			if stored.Rating != tt.expectedRating || stored.Comment == nil || *stored.Comment != tt.expectedComment {
+				t.Errorf("Expected rating %d with comment %q, got %d with %v", tt.expectedRating, tt.expectedComment, stored.Rating, stored.Comment)
+			}
+		})
+	}
+
+	req, err := http.NewRequest("PATCH", "/ratings/999", strings.NewReader(`{"rating": 3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing rating, got %d", http.StatusNotFound, status)
+	}
+}
+
 func TestCreateRatingInvalidRange(t *testing.T) {
 	server := setupTestServer(t)
 
@@ -482,3 +1061,823 @@ func TestGetRatingStats(t *testing.T) {
 	}
 }
 
+func TestListConversationsInvalidTimeRange(t *testing.T) {
+	server := setupTestServer(t)
+
+	req, err := http.NewRequest("GET", "/api/v1/conversations?from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.ListConversationsHandler)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestListConversationsInvalidSort(t *testing.T) {
+	server := setupTestServer(t)
+
+	req, err := http.NewRequest("GET", "/api/v1/conversations?sort=title:asc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.ListConversationsHandler)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if response.Error == nil || !strings.Contains(*response.Error, "prompt_count") {
+		t.Errorf("Expected error listing allowed sort fields, got %v", response.Error)
+	}
+}
+
+func TestListConversationsConfiguredMaxPageSize(t *testing.T) {
+	server := setupTestServer(t)
+
+	tests := []struct {
+		name           string
+		maxPageSize    int
+		expectedStatus int
+	}{
+		{"default max rejects larger page", 0, http.StatusBadRequest},
+		{"raised max accepts larger page", 500, http.StatusOK},
+		{"max above hard ceiling is clamped", 5000, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.config.MaxPageSize = tt.maxPageSize
+
+			req, err := http.NewRequest("GET", "/api/v1/conversations?per_page=300", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(server.ListConversationsHandler)
+
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+		})
+	}
+
+	// Even a raised max cannot exceed the hard ceiling
+	server.config.MaxPageSize = 5000
+
+	req, err := http.NewRequest("GET", "/api/v1/conversations?per_page=1001", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.ListConversationsHandler)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestListConversationsPageBoundary(t *testing.T) {
+	list := func(t *testing.T, server *Server, query string) (int, map[string]interface{}) {
+		req, err := http.NewRequest("GET", "/conversations?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.ListConversationsHandler).ServeHTTP(rr, req)
+
+		var response map[string]interface{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return rr.Code, response
+	}
+
+	t.Run("empty database", func(t *testing.T) {
+		server := setupTestServer(t)
+		server.config.StrictPagination = true
+
+		status, response := list(t, server, "page=1")
+		if status != http.StatusOK {
+			t.Fatalf("Expected page 1 of an empty list to succeed, got %d", status)
+		}
+		meta := response["meta"].(map[string]interface{})
+		if meta["total"] != float64(0) || meta["total_pages"] != float64(0) {
+			t.Errorf("Expected total and total_pages of 0 to be reported, got %v", meta)
+		}
+	})
+
+	server := setupTestServer(t)
+	for i := 0; i < 3; i++ {
+		if _, err := server.db.CreateConversation(fmt.Sprintf("page-session-%d", i), nil, nil, nil); err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		page           int
+		strict         bool
+		expectedStatus int
+		expectedCount  int
+	}{
+		{"last page", 2, false, http.StatusOK, 1},
+		{"past the last page", 3, false, http.StatusOK, 0},
+		{"last page in strict mode", 2, true, http.StatusOK, 1},
+		{"past the last page in strict mode", 3, true, http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.config.StrictPagination = tt.strict
+
+			status, response := list(t, server, fmt.Sprintf("page=%d&per_page=2", tt.page))
+			if status != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %v", tt.expectedStatus, status, response)
+			}
+			if status != http.StatusOK {
+				return
+			}
+
+			data := response["data"].([]interface{})
+			if len(data) != tt.expectedCount {
+				t.Errorf("Expected %d conversations, got %d", tt.expectedCount, len(data))
+			}
+			meta := response["meta"].(map[string]interface{})
+			if meta["total"] != float64(3) || meta["total_pages"] != float64(2) || meta["page"] != float64(tt.page) {
+				t.Errorf("Expected accurate pagination metadata, got %v", meta)
+			}
+		})
+	}
+}
+
+func TestListConversationsByTool(t *testing.T) {
+	server := setupTestServer(t)
+
+	bashConv, err := server.db.CreateConversation("bash-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	readConv, err := server.db.CreateConversation("read-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	bashCalls := `[{"name":"Bash","arguments":{"command":"ls"}}]`
+	readCalls := `[{"name":"Read","arguments":{"path":"main.go"}}]`
+	if _, err := server.db.CreateMessage(bashConv.ID, "response", "answer", &bashCalls, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := server.db.CreateMessage(readConv.ID, "response", "answer", &readCalls, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "/api/v1/conversations?tool=Bash", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.ListConversationsHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response struct {
+		Data []struct {
+			ID int `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Data) != 1 || response.Data[0].ID != bashConv.ID {
+		t.Errorf("Expected only conversation %d, got %+v", bashConv.ID, response.Data)
+	}
+
+	for _, query := range []string{"tool=", "tool=" + strings.Repeat("a", 101)} {
+		req, err := http.NewRequest("GET", "/api/v1/conversations?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.ListConversationsHandler).ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", query, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestBulkDeleteConversations(t *testing.T) {
+	server := setupTestServer(t)
+
+	var ids []int
+	for i := 0; i < 2; i++ {
+		conv, err := server.db.CreateConversation("bulk-session", nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		ids = append(ids, conv.ID)
+	}
+
+	tooMany := make([]int, maxBulkDeleteSize+1)
+	for i := range tooMany {
+		tooMany[i] = i + 1
+	}
+
+	tests := []struct {
+		name             string
+		ids              []int
+		expectedStatus   int
+		expectedDeleted  float64
+		expectedNotFound float64
+	}{
+		{"empty list", []int{}, http.StatusBadRequest, 0, 0},
+		{"invalid ID", []int{ids[0], -1}, http.StatusBadRequest, 0, 0},
+		{"batch too large", tooMany, http.StatusBadRequest, 0, 0},
+		{"deletes existing and reports missing", []int{ids[0], ids[1], ids[1], 999}, http.StatusOK, 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string][]int{"ids": tt.ids})
+			req, err := http.NewRequest("POST", "/conversations/bulk-delete", bytes.NewBuffer(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(server.BulkDeleteConversationsHandler).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response APIResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			data := response.Data.(map[string]interface{})
+			if data["deleted"] != tt.expectedDeleted || data["not_found"] != tt.expectedNotFound {
+				t.Errorf("Expected deleted=%v not_found=%v, got %v", tt.expectedDeleted, tt.expectedNotFound, data)
+			}
+		})
+	}
+}
+
+func TestPurgeDeletedConversations(t *testing.T) {
+	server := setupTestServer(t)
+
+	live, err := server.db.CreateConversation("purge-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	deleted, err := server.db.CreateConversation("purge-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if err := server.db.DeleteConversation(deleted.ID); err != nil {
+		t.Fatalf("Failed to delete conversation: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedPurged float64
+	}{
+		{"invalid duration", "?older_than=soon", http.StatusBadRequest, 0},
+		{"negative duration", "?older_than=-1h", http.StatusBadRequest, 0},
+		{"keeps recent deletions", "?older_than=24h", http.StatusOK, 0},
+		{"purges every deletion", "", http.StatusOK, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/conversations/purge"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(server.PurgeDeletedConversationsHandler).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response APIResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if data := response.Data.(map[string]interface{}); data["purged"] != tt.expectedPurged {
+				t.Errorf("Expected purged=%v, got %v", tt.expectedPurged, data)
+			}
+		})
+	}
+
+	if _, err := server.db.GetConversationIncludingDeleted(deleted.ID); !errors.Is(err, database.ErrConversationNotFound) {
+		t.Errorf("Expected the deleted conversation to be purged, got %v", err)
+	}
+	if _, err := server.db.GetConversation(live.ID); err != nil {
+		t.Errorf("Expected the live conversation to be kept, got %v", err)
+	}
+}
+
+func TestSoftDeleteAndRestoreConversation(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("soft-delete-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/conversations", server.ListConversationsHandler).Methods("GET")
+	router.HandleFunc("/conversations/{id}", server.DeleteConversationHandler).Methods("DELETE")
+	router.HandleFunc("/conversations/{id}/restore", server.RestoreConversationHandler).Methods("POST")
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	listTotal := func(path string) int {
+		rr := serve("GET", path)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("list returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var response APIResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return response.Meta.Total
+	}
+
+	convPath := fmt.Sprintf("/conversations/%d", conv.ID)
+
+	if rr := serve("DELETE", convPath); rr.Code != http.StatusNoContent {
+		t.Fatalf("delete returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+
+	if total := listTotal("/conversations"); total != 0 {
+		t.Errorf("Expected soft-deleted conversation to be hidden, got total %d", total)
+	}
+	if total := listTotal("/conversations?include_deleted=true"); total != 1 {
+		t.Errorf("Expected soft-deleted conversation with include_deleted, got total %d", total)
+	}
+	if rr := serve("GET", "/conversations?include_deleted=maybe"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid include_deleted, got %v", rr.Code)
+	}
+
+	if rr := serve("POST", convPath+"/restore"); rr.Code != http.StatusOK {
+		t.Fatalf("restore returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if total := listTotal("/conversations"); total != 1 {
+		t.Errorf("Expected restored conversation to be listed, got total %d", total)
+	}
+
+	if rr := serve("POST", "/conversations/999/restore"); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 restoring missing conversation, got %v", rr.Code)
+	}
+}
+
+func TestListConversationsAvgResponseTime(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("timed-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	for _, ms := range []int{100, 300} {
+		ms := ms
+		if _, err := server.db.CreateMessage(conv.ID, "response", "answer", nil, &ms); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+	if _, err := server.db.CreateConversation("untimed-session", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "/conversations", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.ListConversationsHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	for _, item := range response.Data.([]interface{}) {
+		summary := item.(map[string]interface{})
+		avg, present := summary["avg_response_time_ms"]
+		if !present {
+			t.Fatalf("Expected avg_response_time_ms in summary, got %v", summary)
+		}
+
+		switch summary["session_id"] {
+		case "timed-session":
+			if avg != float64(200) {
+				t.Errorf("Expected average response time 200, got %v", avg)
+			}
+		case "untimed-session":
+			if avg != nil {
+				t.Errorf("Expected null average response time, got %v", avg)
+			}
+		}
+	}
+}
+
+func TestListAwaitingResponse(t *testing.T) {
+	server := setupTestServer(t)
+
+	waiting, err := server.db.CreateConversation("waiting-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	answered, err := server.db.CreateConversation("answered-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	if _, err := server.db.CreateMessage(waiting.ID, "prompt", "still waiting", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := server.db.CreateMessage(answered.ID, "prompt", "question", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := server.db.CreateMessage(answered.ID, "response", "answer", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "/conversations/awaiting-response", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.ListAwaitingResponseHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	conversations, ok := response.Data.([]interface{})
+	if !ok {
+		t.Fatal("Expected response.Data to be an array")
+	}
+	if len(conversations) != 1 {
+		t.Fatalf("Expected 1 conversation, got %d", len(conversations))
+	}
+	if id := conversations[0].(map[string]interface{})["id"]; id != float64(waiting.ID) {
+		t.Errorf("Expected conversation %d, got %v", waiting.ID, id)
+	}
+
+	if response.Meta == nil || response.Meta.Total != 1 {
+		t.Errorf("Expected meta total 1, got %+v", response.Meta)
+	}
+}
+
+func TestUpdateConversationMetadata(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("update-session", stringPtr("Original"), stringPtr("/home/user/project"), stringPtr("/tmp/transcript.jsonl"))
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/conversations/{id}", server.UpdateConversationHandler).Methods("PUT")
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", fmt.Sprintf("/conversations/%d", conv.ID), strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// A title-only update leaves the paths untouched
+	if rr := put(`{"title": "Renamed"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	updated, err := server.db.GetConversation(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	if updated.Title == nil || *updated.Title != "Renamed" {
+		t.Errorf("Expected title Renamed, got %v", updated.Title)
+	}
+	if updated.WorkingDirectory == nil || *updated.WorkingDirectory != "/home/user/project" {
+		t.Errorf("Expected working directory to be unchanged, got %v", updated.WorkingDirectory)
+	}
+	if updated.TranscriptPath == nil || *updated.TranscriptPath != "/tmp/transcript.jsonl" {
+		t.Errorf("Expected transcript path to be unchanged, got %v", updated.TranscriptPath)
+	}
+
+	// Paths can be updated without a title
+	if rr := put(`{"working_directory": "/home/user/other", "transcript_path": "/tmp/other.jsonl"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	updated, err = server.db.GetConversation(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	if updated.Title == nil || *updated.Title != "Renamed" {
+		t.Errorf("Expected title to be unchanged, got %v", updated.Title)
+	}
+	if updated.WorkingDirectory == nil || *updated.WorkingDirectory != "/home/user/other" {
+		t.Errorf("Expected updated working directory, got %v", updated.WorkingDirectory)
+	}
+	if updated.TranscriptPath == nil || *updated.TranscriptPath != "/tmp/other.jsonl" {
+		t.Errorf("Expected updated transcript path, got %v", updated.TranscriptPath)
+	}
+
+	// Invalid paths and empty updates are rejected
+	if rr := put(`{"working_directory": "/tmp/bad\u0000path"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid path, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if rr := put(`{}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an empty update, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	// A soft-deleted conversation is not found rather than a server error
+	if err := server.db.DeleteConversation(conv.ID); err != nil {
+		t.Fatalf("Failed to delete conversation: %v", err)
+	}
+	if rr := put(`{"title": "Deleted"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a deleted conversation, got %d: %s", http.StatusNotFound, rr.Code, rr.Body.String())
+	}
+}
+
+func TestListConversationsByWorkingDirectory(t *testing.T) {
+	server := setupTestServer(t)
+
+	dirs := map[string]string{
+		"wd-root":    "/srv/app",
+		"wd-sub":     "/srv/app/web",
+		"wd-sibling": "/srv/application",
+	}
+	for sessionID, dir := range dirs {
+		dir := dir
+		if _, err := server.db.CreateConversation(sessionID, nil, &dir, nil); err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedTotal  int
+	}{
+		{"exact match", "working_directory=/srv/app", http.StatusOK, 1},
+		{"trailing slash", "working_directory=/srv/app/", http.StatusOK, 1},
+		{"subtree", "working_directory=/srv/app&prefix=true", http.StatusOK, 2},
+		{"no match", "working_directory=/srv/other", http.StatusOK, 0},
+		{"invalid path", "working_directory=%00", http.StatusBadRequest, 0},
+		{"invalid prefix", "working_directory=/srv/app&prefix=maybe", http.StatusBadRequest, 0},
+		{"prefix without directory", "prefix=true", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/conversations?"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(server.ListConversationsHandler).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			meta := response["meta"].(map[string]interface{})
+			if meta["total"] != float64(tt.expectedTotal) {
+				t.Errorf("Expected total %d, got %v", tt.expectedTotal, meta["total"])
+			}
+			if data := response["data"].([]interface{}); len(data) != tt.expectedTotal {
+				t.Errorf("Expected %d conversations, got %d", tt.expectedTotal, len(data))
+			}
+		})
+	}
+}
+
+func TestListConversationsSince(t *testing.T) {
+	server := setupTestServer(t)
+
+	// Back-date conversations to either side of a one hour window
+	ages := map[string]time.Duration{
+		"since-inside":  59 * time.Minute,
+		"since-outside": 61 * time.Minute,
+	}
+	for sessionID, age := range ages {
+		conv, err := server.db.CreateConversation(sessionID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		createdAt := time.Now().UTC().Add(-age).Format("2006-01-02 15:04:05")
+		if _, err := server.db.Conn().Exec("UPDATE conversations SET created_at = ? WHERE id = ?", createdAt, conv.ID); err != nil {
+			t.Fatalf("Failed to back-date conversation: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedStatus   int
+		expectedSessions []string
+	}{
+		{"one hour", "since=1h", http.StatusOK, []string{"since-inside"}},
+		{"two hours", "since=2h", http.StatusOK, []string{"since-inside", "since-outside"}},
+		{"with to", "since=2h&to=" + time.Now().Add(-time.Hour).UTC().Format(time.RFC3339), http.StatusOK, []string{"since-outside"}},
+		{"with from", "since=1h&from=2024-01-01T00:00:00Z", http.StatusBadRequest, nil},
+		{"negative", "since=-1h", http.StatusBadRequest, nil},
+		{"too large", "since=100000h", http.StatusBadRequest, nil},
+		{"invalid", "since=yesterday", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/conversations?"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(server.ListConversationsHandler).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data []models.ConversationSummary `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			var sessions []string
+			for _, conv := range response.Data {
+				sessions = append(sessions, conv.SessionID)
+			}
+			sort.Strings(sessions)
+			if strings.Join(sessions, ",") != strings.Join(tt.expectedSessions, ",") {
+				t.Errorf("Expected sessions %v, got %v", tt.expectedSessions, sessions)
+			}
+		})
+	}
+}
+
+// failingWriter is a ResponseWriter whose body writes always fail, like a
+// client that has gone away
+type failingWriter struct {
+	header http.Header
+	status int
+}
+
+func (f *failingWriter) Header() http.Header       { return f.header }
+func (f *failingWriter) WriteHeader(status int)    { f.status = status }
+func (f *failingWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+// recordingLogger captures log messages so tests can assert on them
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.record(fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLogger) Errorf(format string, v ...interface{}) {
+	l.record("ERROR: " + fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLogger) record(message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, message)
+}
+
+// contains reports whether any message contains substr
+func (l *recordingLogger) contains(substr string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, message := range l.messages {
+		if strings.Contains(message, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWriteJSON(t *testing.T) {
+	logger := &recordingLogger{}
+	config := DefaultConfig()
+	config.Logger = logger
+	server := NewServerWithConfig(setupTestServer(t).db, config)
+
+	t.Run("encodes payload", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		server.writeJSON(rr, http.StatusAccepted, APIResponse{Success: true, Data: "ok"})
+
+		if rr.Code != http.StatusAccepted {
+			t.Errorf("Expected status %d, got %d", http.StatusAccepted, rr.Code)
+		}
+		if contentType := rr.Result().Header.Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("Expected Content-Type application/json, got %s", contentType)
+		}
+		if body := rr.Body.String(); body != `{"success":true,"data":"ok"}`+"\n" {
+			t.Errorf("Unexpected body %q", body)
+		}
+	})
+
+	t.Run("unencodable payload", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		server.successResponse(rr, map[string]interface{}{"bad": make(chan int)}, nil)
+
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+		}
+		var response APIResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Expected the fallback body to be valid JSON: %v", err)
+		}
+		if response.Success || response.Error == nil {
+			t.Errorf("Expected an error response, got %+v", response)
+		}
+		if !logger.contains("ERROR: Failed to encode JSON response") {
+			t.Errorf("Expected the encode error to be logged, got %q", logger.messages)
+		}
+	})
+
+	t.Run("write failure", func(t *testing.T) {
+		w := &failingWriter{header: http.Header{}}
+		server.errorResponse(w, "Not found", http.StatusNotFound)
+
+		if w.status != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.status)
+		}
+		if !logger.contains("ERROR: Failed to write JSON response: broken pipe") {
+			t.Errorf("Expected the write error to be logged, got %q", logger.messages)
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/models"
+	"github.com/claude-code-template/prompt-manager/internal/validation"
+	"github.com/gorilla/mux"
+)
+
+// Message handlers
+
+// ListMessagesHandler returns a paginated feed of messages across all
+// conversations, filterable by type, session_id, from/to and has_tool_calls
+func (s *Server) ListMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, perPage, ok := s.parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, err := validation.ParseAndValidateTimeRange(query.Get("from"), query.Get("to"))
+	if err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := database.MessageFilter{
+		SessionID: query.Get("session_id"),
+		From:      from,
+		To:        to,
+		Limit:     perPage,
+		Offset:    (page - 1) * perPage,
+	}
+
+	if msgType := query.Get("type"); msgType != "" {
+		if msgType != "prompt" && msgType != "response" {
+			s.errorResponse(w, "type must be prompt or response", http.StatusBadRequest)
+			return
+		}
+		filter.MessageType = msgType
+	}
+
+	if hasToolCallsStr := query.Get("has_tool_calls"); hasToolCallsStr != "" {
+		hasToolCalls, err := strconv.ParseBool(hasToolCallsStr)
+		if err != nil {
+			s.errorResponse(w, "has_tool_calls must be true or false", http.StatusBadRequest)
+			return
+		}
+		filter.HasToolCalls = &hasToolCalls
+	}
+
+	messages, err := s.db.ListMessages(filter)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to list messages: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	totalCount, err := s.db.GetMessageCount(filter)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get message count: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	apiMessages := make([]models.Message, 0, len(messages))
+	for i := range messages {
+		apiMsg, err := ConvertMessage(&messages[i])
+		if err != nil {
+			s.errorResponse(w, fmt.Sprintf("Failed to convert message: %v", err), http.StatusInternalServerError)
+			return
+		}
+		apiMessages = append(apiMessages, apiMsg)
+	}
+
+	meta := newPagination(page, perPage, totalCount)
+
+	s.successResponse(w, apiMessages, meta)
+}
+
+// GetMessageHandler returns a single message with its tool calls parsed and
+// its ratings attached
+func (s *Server) GetMessageHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Message ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "message_id")
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	msg, err := s.db.GetMessage(id)
+	if err != nil {
+		if errors.Is(err, database.ErrMessageNotFound) {
+			s.errorResponse(w, "Message not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to get message: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	apiMsg, err := ConvertMessage(msg)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to convert message: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	ratings, err := s.db.GetMessageRatings(id)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get message ratings: %v", err), http.StatusInternalServerError)
+		return
+	}
+	apiMsg.Ratings = ConvertRatings(ratings)
+
+	s.successResponse(w, apiMsg, nil)
+}
+
+// GetMessageNeighborsHandler returns the previous and next message IDs for a message
+func (s *Server) GetMessageNeighborsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Message ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "message_id")
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	neighbors, err := s.db.GetMessageNeighbors(id)
+	if err != nil {
+		if errors.Is(err, database.ErrMessageNotFound) {
+			s.errorResponse(w, "Message not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to get message neighbors: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, neighbors, nil)
+}
+
+// DeleteMessageHandler deletes a single message and updates its
+// conversation's counts
+func (s *Server) DeleteMessageHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Message ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "message_id")
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.DeleteMessage(id); err != nil {
+		if errors.Is(err, database.ErrMessageNotFound) {
+			s.errorResponse(w, "Message not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to delete message: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
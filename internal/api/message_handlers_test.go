@@ -0,0 +1,293 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/claude-code-template/prompt-manager/internal/models"
+	"github.com/gorilla/mux"
+)
+
+func TestGetMessageNeighbors(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("test-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	var ids []int
+	for i, content := range []string{"first", "second", "third"} {
+		msgType := "prompt"
+		if i%2 == 1 {
+			msgType = "response"
+		}
+		msg, err := server.db.CreateMessage(conv.ID, msgType, content, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		ids = append(ids, msg.ID)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/messages/{id}/neighbors", server.GetMessageNeighborsHandler)
+
+	tests := []struct {
+		name         string
+		messageID    int
+		expectedPrev interface{}
+		expectedNext interface{}
+	}{
+		{"first message", ids[0], nil, float64(ids[1])},
+		{"middle message", ids[1], float64(ids[0]), float64(ids[2])},
+		{"last message", ids[2], float64(ids[1]), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", fmt.Sprintf("/messages/%d/neighbors", tt.messageID), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+			}
+
+			var response APIResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			data, ok := response.Data.(map[string]interface{})
+			if !ok {
+				t.Fatal("Expected response.Data to be a map")
+			}
+
+			if data["previous_id"] != tt.expectedPrev {
+				t.Errorf("Expected previous_id=%v, got %v", tt.expectedPrev, data["previous_id"])
+			}
+
+			if data["next_id"] != tt.expectedNext {
+				t.Errorf("Expected next_id=%v, got %v", tt.expectedNext, data["next_id"])
+			}
+		})
+	}
+}
+
+func TestGetMessageNeighborsNotFound(t *testing.T) {
+	server := setupTestServer(t)
+
+	req, err := http.NewRequest("GET", "/messages/999/neighbors", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/messages/{id}/neighbors", server.GetMessageNeighborsHandler)
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestListMessages(t *testing.T) {
+	server := setupTestServer(t)
+
+	for _, sessionID := range []string{"feed-session-a", "feed-session-b"} {
+		conv, err := server.db.CreateConversation(sessionID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		if _, err := server.db.CreateMessage(conv.ID, "prompt", "question", nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		toolCalls := `[{"name":"bash"}]`
+		if _, err := server.db.CreateMessage(conv.ID, "response", "answer", &toolCalls, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedCount int
+		expectedTotal int
+	}{
+		{"all messages", "", http.StatusOK, 4, 4},
+		{"by type", "?type=prompt", http.StatusOK, 2, 2},
+		{"by session and type", "?session_id=feed-session-a&type=response", http.StatusOK, 1, 1},
+		{"with tool calls", "?has_tool_calls=true", http.StatusOK, 2, 2},
+		{"paginated", "?per_page=3&page=2", http.StatusOK, 1, 4},
+		{"invalid type", "?type=system", http.StatusBadRequest, 0, 0},
+		{"invalid has_tool_calls", "?has_tool_calls=maybe", http.StatusBadRequest, 0, 0},
+		{"invalid time range", "?from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z", http.StatusBadRequest, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/messages"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			server.ListMessagesHandler(rr, req)
+
+			if status := rr.Code; status != tt.expectedCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.expectedCode)
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var response APIResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			data, ok := response.Data.([]interface{})
+			if !ok {
+				t.Fatal("Expected response.Data to be an array")
+			}
+			if len(data) != tt.expectedCount {
+				t.Errorf("Expected %d messages, got %d", tt.expectedCount, len(data))
+			}
+
+			if response.Meta == nil || response.Meta.Total != tt.expectedTotal {
+				t.Errorf("Expected meta total %d, got %+v", tt.expectedTotal, response.Meta)
+			}
+		})
+	}
+}
+
+func TestDeleteMessage(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("delete-message-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	msg, err := server.db.CreateMessage(conv.ID, "prompt", "typo'd prompt", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/messages/{id}", server.DeleteMessageHandler).Methods("DELETE")
+
+	req, err := http.NewRequest("DELETE", fmt.Sprintf("/messages/%d", msg.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
+	}
+
+	updated, err := server.db.GetConversation(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	if updated.PromptCount != 0 || updated.TotalCharacters != 0 {
+		t.Errorf("Expected zeroed counts, got %d prompts and %d characters", updated.PromptCount, updated.TotalCharacters)
+	}
+
+	// Deleting it again reports not found
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+
+	// So does a message whose conversation was soft-deleted
+	kept, err := server.db.CreateMessage(conv.ID, "prompt", "kept", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if err := server.db.DeleteConversation(conv.ID); err != nil {
+		t.Fatalf("Failed to delete conversation: %v", err)
+	}
+	req, err = http.NewRequest("DELETE", fmt.Sprintf("/messages/%d", kept.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code for a deleted conversation: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestGetMessage(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("get-message-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	toolCalls := `[{"name": "Read", "arguments": {"file_path": "/tmp/a.go"}}]`
+	msg, err := server.db.CreateMessage(conv.ID, "response", "done", &toolCalls, nil)
+	if err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := server.db.CreateMessageRating(msg.ID, 4, stringPtr("helpful")); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+	malformed := `[{"name": "Read"`
+	broken, err := server.db.CreateMessage(conv.ID, "response", "broken", &malformed, nil)
+	if err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/messages/{id}", server.GetMessageHandler).Methods("GET")
+
+	get := func(id int) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", fmt.Sprintf("/messages/%d", id), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get(msg.ID)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response struct {
+		Data models.Message `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Data.ToolCalls) != 1 || response.Data.ToolCalls[0].Name != "Read" {
+		t.Errorf("Expected parsed Read tool call, got %+v", response.Data.ToolCalls)
+	}
+	if len(response.Data.Ratings) != 1 || response.Data.Ratings[0].Rating != 4 {
+		t.Errorf("Expected the message's rating, got %+v", response.Data.Ratings)
+	}
+
+	if status := get(broken.ID).Code; status != http.StatusInternalServerError {
+		t.Errorf("Expected status %d for malformed tool calls, got %d", http.StatusInternalServerError, status)
+	}
+
+	if status := get(99999).Code; status != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing message, got %d", http.StatusNotFound, status)
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/metrics"
+	"github.com/gorilla/mux"
+)
+
+// unmatchedRoute labels requests that did not match a registered route
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware returns middleware that reports each request's route,
+// status code and latency to recorder. Requests are labelled by route
+// template, such as "/conversations/{id}", so IDs do not create new series.
+// A nil recorder disables recording.
+func MetricsMiddleware(recorder metrics.Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if recorder == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorded := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorded, r)
+			recorder.ObserveRequest(routeLabel(r), recorded.status, time.Since(start))
+		})
+	}
+}
+
+// routeLabel returns the template of the route that served the request
+func routeLabel(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return unmatchedRoute
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return unmatchedRoute
+	}
+	return template
+}
+
+// statusRecorder remembers the status code written through it. It forwards
+// flushes so streaming handlers keep working.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(p)
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// fakeRecorder captures observations in memory
+type fakeRecorder struct {
+	mu       sync.Mutex
+	requests []observedRequest
+}
+
+type observedRequest struct {
+	handler string
+	status  int
+}
+
+func (f *fakeRecorder) ObserveRequest(handler string, status int, duration time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, observedRequest{handler: handler, status: status})
+}
+
+func (f *fakeRecorder) ObserveDBOperation(operation string, duration time.Duration) {}
+
+func TestMetricsMiddleware(t *testing.T) {
+	server := setupTestServer(t)
+	recorder := &fakeRecorder{}
+
+	router := mux.NewRouter()
+	router.Use(MetricsMiddleware(recorder))
+	router.HandleFunc("/conversations/{id}", server.GetConversationHandler)
+	router.HandleFunc("/health", server.HealthHandler)
+
+	for _, path := range []string{"/conversations/999", "/health"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	expected := []observedRequest{
+		{handler: "/conversations/{id}", status: http.StatusNotFound},
+		{handler: "/health", status: http.StatusOK},
+	}
+	if len(recorder.requests) != len(expected) {
+		t.Fatalf("Expected %d observations, got %+v", len(expected), recorder.requests)
+	}
+	for i, want := range expected {
+		if recorder.requests[i] != want {
+			t.Errorf("Observation %d: expected %+v, got %+v", i, want, recorder.requests[i])
+		}
+	}
+}
+
+func TestStatusRecorderFlush(t *testing.T) {
+	rr := httptest.NewRecorder()
+	var w http.ResponseWriter = &statusRecorder{ResponseWriter: rr, status: http.StatusOK}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		t.Fatal("Expected statusRecorder to support flushing for event streams")
+	}
+	flusher.Flush()
+	if !rr.Flushed {
+		t.Error("Expected the flush to reach the underlying writer")
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/claude-code-template/prompt-manager/internal/httpjson"
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+	"github.com/gorilla/mux"
+)
+
+// MaxBodyBytes returns middleware that caps request bodies at limit bytes.
+// Requests that declare a larger Content-Length are rejected with 413 up
+// front; other bodies are wrapped with http.MaxBytesReader so reads fail
+// once the limit is exceeded. A non-positive limit disables the cap. Failed
+// responses are logged to logger, or the standard logger when it is nil.
+func MaxBodyBytes(limit int64, logger logging.Logger) func(http.Handler) http.Handler {
+	logger = loggerOrDefault(logger)
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				httpjson.Write(logger, w, http.StatusRequestEntityTooLarge, errorBody(fmt.Sprintf("Request body exceeds %d bytes", limit)))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MethodNotAllowedHandler returns a handler for router.MethodNotAllowedHandler
+// that responds with the standard JSON error and an Allow header listing the
+// methods registered for the requested path. Failed responses are logged to
+// logger, or the standard logger when it is nil.
+func MethodNotAllowedHandler(router *mux.Router, logger logging.Logger) http.Handler {
+	logger = loggerOrDefault(logger)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(allowedMethods(router, r), ", "))
+		httpjson.Write(logger, w, http.StatusMethodNotAllowed, errorBody("Method not allowed"))
+	})
+}
+
+// allowedMethods returns the methods of every route matching the request's
+// path, in registration order
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	var allowed []string
+	seen := make(map[string]bool)
+
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // Route accepts any method
+		}
+
+		for _, method := range methods {
+			if seen[method] {
+				continue
+			}
+
+			req := r.Clone(r.Context())
+			req.Method = method
+			var match mux.RouteMatch
+			if route.Match(req, &match) {
+				seen[method] = true
+				allowed = append(allowed, method)
+			}
+		}
+		return nil
+	})
+
+	return allowed
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+	"github.com/gorilla/mux"
+)
+
+func TestMaxBodyBytes(t *testing.T) {
+	// The wrapped handler reports whether it could read the whole body
+	server := NewServer(nil)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				server.errorResponse(w, "too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			server.errorResponse(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		server.successResponse(w, nil, nil)
+	})
+
+	tests := []struct {
+		name           string
+		limit          int64
+		body           string
+		unknownLength  bool
+		expectedStatus int
+	}{
+		{"within limit", 10, "small", false, http.StatusOK},
+		{"exactly at limit", 5, "small", false, http.StatusOK},
+		{"declared length over limit", 4, "small", false, http.StatusRequestEntityTooLarge},
+		{"streamed body over limit", 4, "small", true, http.StatusRequestEntityTooLarge},
+		{"non-positive limit disables cap", 0, "small", false, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/messages/prompt", strings.NewReader(tt.body))
+			if tt.unknownLength {
+				req.ContentLength = -1
+			}
+
+			rr := httptest.NewRecorder()
+			MaxBodyBytes(tt.limit, logging.Nop{})(next).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			if tt.expectedStatus == http.StatusRequestEntityTooLarge {
+				var response APIResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if response.Success || response.Error == nil {
+					t.Errorf("Expected a JSON error response, got %s", rr.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowedHandler(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	router := mux.NewRouter()
+	router.MethodNotAllowedHandler = MethodNotAllowedHandler(router, logging.Nop{})
+	router.HandleFunc("/items", ok).Methods("GET", "POST")
+	router.HandleFunc("/items/{id}", ok).Methods("GET")
+	router.HandleFunc("/items/{id}", ok).Methods("DELETE")
+	router.HandleFunc("/items/{id}/archive", ok).Methods("POST")
+
+	tests := []struct {
+		method        string
+		path          string
+		expectedAllow string
+	}{
+		{http.MethodPut, "/items", "GET, POST"},
+		{http.MethodPost, "/items/1", "GET, DELETE"},
+		{http.MethodGet, "/items/1/archive", "POST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("Expected status %d, got %d", http.StatusMethodNotAllowed, rr.Code)
+			}
+			if allow := rr.Header().Get("Allow"); allow != tt.expectedAllow {
+				t.Errorf("Expected Allow %q, got %q", tt.expectedAllow, allow)
+			}
+
+			var response APIResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Error == nil || *response.Error != "Method not allowed" {
+				t.Errorf("Expected method not allowed error, got %v", response.Error)
+			}
+		})
+	}
+}
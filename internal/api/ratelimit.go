@@ -0,0 +1,133 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/httpjson"
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+)
+
+// rateLimitIdleTTL is how long a client's bucket may sit unused before it is
+// dropped; an idle bucket has long since refilled, so dropping it is lossless
+const rateLimitIdleTTL = 10 * time.Minute
+
+// tokenBucket holds one client's available tokens as of last
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter tracks a token bucket per client IP
+type rateLimiter struct {
+	rate      float64
+	burst     float64
+	now       func() time.Time
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// newRateLimiter creates a limiter refilling rate tokens per second up to burst
+func newRateLimiter(rate float64, burst int, now func() time.Time) *rateLimiter {
+	return &rateLimiter{
+		rate:      rate,
+		burst:     float64(burst),
+		now:       now,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: now(),
+	}
+}
+
+// allow takes a token from the client's bucket. When none is available it
+// returns false and how long until the next token arrives.
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+
+	elapsed := now.Sub(bucket.last).Seconds()
+	bucket.tokens = math.Min(l.burst, bucket.tokens+elapsed*l.rate)
+	bucket.last = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets idle for longer than rateLimitIdleTTL. It runs at most
+// once per TTL, piggybacking on requests so no background goroutine is needed.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitIdleTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for client, bucket := range l.buckets {
+		if now.Sub(bucket.last) > rateLimitIdleTTL {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// clientIP identifies the caller by the connection's remote address.
+// Forwarding headers are ignored because hooks call the server directly and
+// the headers are trivially spoofed.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RateLimitMiddleware returns middleware that limits each client IP to rate
+// requests per second with bursts of up to burst requests. Requests over the
+// limit are rejected with 429 and a Retry-After header. A non-positive rate
+// or burst disables the limit. Failed responses are logged to logger, or the
+// standard logger when it is nil.
+func RateLimitMiddleware(rate float64, burst int, logger logging.Logger) func(http.Handler) http.Handler {
+	return rateLimitMiddleware(rate, burst, time.Now, logger)
+}
+
+// rateLimitMiddleware is RateLimitMiddleware with an injectable clock
+func rateLimitMiddleware(rate float64, burst int, now func() time.Time, logger logging.Logger) func(http.Handler) http.Handler {
+	if rate <= 0 || burst <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	logger = loggerOrDefault(logger)
+	limiter := newRateLimiter(rate, burst, now)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, wait := limiter.allow(clientIP(r))
+			if !allowed {
+				retryAfter := int(math.Ceil(wait.Seconds()))
+				if retryAfter < 1 {
+					retryAfter = 1
+				}
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				httpjson.Write(logger, w, http.StatusTooManyRequests, errorBody("Rate limit exceeded"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	handler := rateLimitMiddleware(1, 3, clock, logging.Nop{})(next)
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/messages/prompt", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// A burst up to the bucket size is allowed
+	for i := 0; i < 3; i++ {
+		if rr := send("10.0.0.1:5000"); rr.Code != http.StatusCreated {
+			t.Fatalf("Request %d: expected status %d, got %d", i+1, http.StatusCreated, rr.Code)
+		}
+	}
+
+	// The next request in the burst is rejected, even from another port
+	rr := send("10.0.0.1:5001")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d beyond the burst, got %d", http.StatusTooManyRequests, rr.Code)
+	}
+	if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", retryAfter)
+	}
+
+	// Other clients have their own bucket
+	if rr := send("10.0.0.2:5000"); rr.Code != http.StatusCreated {
+		t.Errorf("Expected another client to be unaffected, got %d", rr.Code)
+	}
+
+	// Tokens refill over time
+	now = now.Add(time.Second)
+	if rr := send("10.0.0.1:5000"); rr.Code != http.StatusCreated {
+		t.Errorf("Expected a refilled token to be accepted, got %d", rr.Code)
+	}
+}
+
+func TestRateLimiterSweepsIdleBuckets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(1, 1, func() time.Time { return now })
+
+	limiter.allow("10.0.0.1")
+	limiter.allow("10.0.0.2")
+
+	now = now.Add(rateLimitIdleTTL + time.Second)
+	limiter.allow("10.0.0.3")
+
+	if len(limiter.buckets) != 1 {
+		t.Errorf("Expected idle buckets to be dropped, got %d buckets", len(limiter.buckets))
+	}
+}
+
+func TestRateLimitMiddlewareDisabled(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	handler := RateLimitMiddleware(0, 0, logging.Nop{})(next)
+
+	for i := 0; i < 100; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/messages/prompt", nil))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected no limit when disabled, got %d", rr.Code)
+		}
+	}
+}
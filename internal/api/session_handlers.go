@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/models"
+	"github.com/claude-code-template/prompt-manager/internal/validation"
+	"github.com/gorilla/mux"
+)
+
+// Session handlers
+
+// ListSessionsHandler returns a paginated list of sessions with their
+// aggregate metrics, most recently active first
+func (s *Server) ListSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	page, perPage, ok := s.parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	offset := (page - 1) * perPage
+
+	sessions, err := s.db.ListSessions(perPage, offset)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to list sessions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	totalCount, err := s.db.GetSessionCount()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get session count: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	apiSessions := make([]models.Session, len(sessions))
+	for i := range sessions {
+		apiSessions[i] = ConvertSession(&sessions[i])
+	}
+
+	meta := newPagination(page, perPage, totalCount)
+
+	s.successResponse(w, apiSessions, meta)
+}
+
+// maxSessionStatsSize caps how many sessions one stats request may compare
+const maxSessionStatsSize = 100
+
+// GetSessionStatsHandler returns conversation, message, character and rating
+// aggregates for each requested session, in request order. Unknown sessions
+// are reported with zeros.
+func (s *Server) GetSessionStatsHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SessionIDs []string `json:"session_ids"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.SessionIDs) == 0 {
+		s.errorResponse(w, "session_ids must contain at least one session ID", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.SessionIDs) > maxSessionStatsSize {
+		s.errorResponse(w, fmt.Sprintf("Cannot compare more than %d sessions at once", maxSessionStatsSize), http.StatusBadRequest)
+		return
+	}
+
+	for _, sessionID := range req.SessionIDs {
+		if err := validation.ValidateSessionIDWithSeparators(sessionID, s.config.sessionIDSeparators()); err != nil {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	stats, err := s.db.GetSessionStats(req.SessionIDs)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get session stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, stats, nil)
+}
+
+// GetSessionGraphHandler returns the session's conversations in chronological
+// order with their message counts and time spans
+func (s *Server) GetSessionGraphHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sessionID, exists := vars["session_id"]
+	if !exists {
+		s.errorResponse(w, "Session ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := validation.ValidateSessionIDWithSeparators(sessionID, s.config.sessionIDSeparators()); err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	nodes, err := s.db.GetSessionGraph(sessionID)
+	if err != nil {
+		if errors.Is(err, database.ErrConversationNotFound) {
+			s.errorResponse(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to get session graph: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	graph := map[string]interface{}{
+		"session_id":    sessionID,
+		"conversations": nodes,
+	}
+
+	s.successResponse(w, graph, nil)
+}
+
+// GetSessionHandler returns metrics aggregated across a session's conversations
+func (s *Server) GetSessionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sessionID, exists := vars["session_id"]
+	if !exists {
+		s.errorResponse(w, "Session ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := validation.ValidateSessionIDWithSeparators(sessionID, s.config.sessionIDSeparators()); err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, err := s.db.GetSessionMetrics(sessionID)
+	if err != nil {
+		if errors.Is(err, database.ErrSessionNotFound) {
+			s.errorResponse(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to get session metrics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, ConvertSession(session), nil)
+}
+
+// UpdateSessionStatusHandler sets a session's status, typically to archive
+// it. Transitions the session does not allow, such as reactivating an
+// archived session, are rejected with a conflict.
+func (s *Server) UpdateSessionStatusHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sessionID, exists := vars["session_id"]
+	if !exists {
+		s.errorResponse(w, "Session ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := validation.ValidateSessionIDWithSeparators(sessionID, s.config.sessionIDSeparators()); err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+
+	status, err := models.ParseSessionStatus(req.Status)
+	if err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.SetSessionStatus(sessionID, status); err != nil {
+		if errors.Is(err, database.ErrSessionNotFound) {
+			s.errorResponse(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, database.ErrInvalidSessionTransition) {
+			s.errorResponse(w, fmt.Sprintf("Cannot change session status: %v", err), http.StatusConflict)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to update session status: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	result := map[string]interface{}{
+		"session_id": sessionID,
+		"status":     status,
+	}
+
+	s.successResponse(w, result, nil)
+}
@@ -0,0 +1,322 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestGetSessionGraph(t *testing.T) {
+	server := setupTestServer(t)
+
+	var ids []int
+	for i := 0; i < 3; i++ {
+		conv, err := server.db.CreateConversation("graph-session", nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		ids = append(ids, conv.ID)
+	}
+
+	if _, err := server.db.CreateMessage(ids[1], "prompt", "hello", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/sessions/{session_id}/graph", server.GetSessionGraphHandler)
+
+	req, err := http.NewRequest("GET", "/sessions/graph-session/graph", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response struct {
+		Success bool `json:"success"`
+		Data    struct {
+			SessionID     string `json:"session_id"`
+			Conversations []struct {
+				ConversationID int `json:"conversation_id"`
+				MessageCount   int `json:"message_count"`
+			} `json:"conversations"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	nodes := response.Data.Conversations
+	if len(nodes) != len(ids) {
+		t.Fatalf("Expected %d nodes, got %d", len(ids), len(nodes))
+	}
+
+	for i, node := range nodes {
+		if node.ConversationID != ids[i] {
+			t.Errorf("Node %d: expected conversation %d, got %d", i, ids[i], node.ConversationID)
+		}
+	}
+
+	if nodes[1].MessageCount != 1 {
+		t.Errorf("Expected 1 message on second node, got %d", nodes[1].MessageCount)
+	}
+
+	// Unknown session
+	req, _ = http.NewRequest("GET", "/sessions/unknown-session/graph", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestGetSession(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("metrics-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := server.db.CreateMessage(conv.ID, "prompt", "hello", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if err := server.db.UpsertSession("empty-session", nil); err != nil {
+		t.Fatalf("Failed to record session: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/sessions/{session_id}", server.GetSessionHandler)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedID     string
+		expectedCount  int
+	}{
+		{"existing session", "/sessions/metrics-session", http.StatusOK, "metrics-session", 1},
+		{"session without conversations", "/sessions/empty-session", http.StatusOK, "empty-session", 0},
+		{"unknown session", "/sessions/missing-session", http.StatusNotFound, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data struct {
+					SessionID         string `json:"session_id"`
+					ConversationCount int    `json:"conversation_count"`
+					TotalPromptCount  int    `json:"total_prompt_count"`
+					LastActivity      string `json:"last_activity"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			data := response.Data
+			if data.SessionID != tt.expectedID || data.ConversationCount != tt.expectedCount || data.TotalPromptCount != tt.expectedCount {
+				t.Errorf("Unexpected session metrics: %+v", data)
+			}
+			if data.LastActivity == "" {
+				t.Error("Expected last_activity to be set")
+			}
+		})
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	server := setupTestServer(t)
+
+	for _, sessionID := range []string{"session-a", "session-b", "session-b"} {
+		if _, err := server.db.CreateConversation(sessionID, nil, nil, nil); err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest("GET", "/sessions?per_page=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.ListSessionsHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	sessions, ok := response.Data.([]interface{})
+	if !ok || len(sessions) != 1 {
+		t.Fatalf("Expected one session on the first page, got %v", response.Data)
+	}
+
+	if response.Meta == nil || response.Meta.Total != 2 || response.Meta.TotalPages != 2 {
+		t.Errorf("Expected 2 sessions over 2 pages, got %+v", response.Meta)
+	}
+}
+
+func TestGetSessionStatsHandler(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("stats-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := server.db.CreateMessage(conv.ID, "prompt", "hello", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	tooMany := make([]string, maxSessionStatsSize+1)
+	for i := range tooMany {
+		tooMany[i] = "s"
+	}
+	tooManyBody, _ := json.Marshal(map[string][]string{"session_ids": tooMany})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"aggregates sessions", `{"session_ids": ["stats-session", "other-session"]}`, http.StatusOK},
+		{"empty list", `{"session_ids": []}`, http.StatusBadRequest},
+		{"invalid session id", `{"session_ids": ["bad id"]}`, http.StatusBadRequest},
+		{"too many sessions", string(tooManyBody), http.StatusBadRequest},
+		{"invalid json", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/sessions/stats", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(server.GetSessionStatsHandler).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data []struct {
+					SessionID         string `json:"session_id"`
+					ConversationCount int    `json:"conversation_count"`
+					MessageCount      int    `json:"message_count"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			if len(response.Data) != 2 {
+				t.Fatalf("Expected 2 sessions, got %+v", response.Data)
+			}
+			if response.Data[0].SessionID != "stats-session" || response.Data[0].ConversationCount != 1 || response.Data[0].MessageCount != 1 {
+				t.Errorf("Expected one conversation with one message, got %+v", response.Data[0])
+			}
+			if response.Data[1].SessionID != "other-session" || response.Data[1].ConversationCount != 0 {
+				t.Errorf("Expected zeros for an unknown session, got %+v", response.Data[1])
+			}
+		})
+	}
+}
+
+func TestUpdateSessionStatusHandler(t *testing.T) {
+	server := setupTestServer(t)
+
+	if _, err := server.db.CreateConversation("archive-session", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if err := server.db.UpsertSession("archive-session", nil); err != nil {
+		t.Fatalf("Failed to record session: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/sessions/{session_id}", server.GetSessionHandler).Methods("GET")
+	router.HandleFunc("/sessions/{session_id}/status", server.UpdateSessionStatusHandler).Methods("PUT")
+
+	// Steps run in order against the same session
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"invalid status", "/sessions/archive-session/status", `{"status":"paused"}`, http.StatusBadRequest},
+		{"invalid body", "/sessions/archive-session/status", `{`, http.StatusBadRequest},
+		{"unknown session", "/sessions/missing-session/status", `{"status":"archived"}`, http.StatusNotFound},
+		{"archive", "/sessions/archive-session/status", `{"status":"archived"}`, http.StatusOK},
+		{"archive again", "/sessions/archive-session/status", `{"status":"archived"}`, http.StatusOK},
+		{"reactivate archived", "/sessions/archive-session/status", `{"status":"active"}`, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("PUT", tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", status, tt.expectedStatus, rr.Body.String())
+			}
+		})
+	}
+
+	req, err := http.NewRequest("GET", "/sessions/archive-session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var response struct {
+		Data struct {
+			Status string `json:"status"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Data.Status != "archived" {
+		t.Errorf("Expected session status archived, got %q", response.Data.Status)
+	}
+}
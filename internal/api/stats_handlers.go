@@ -0,0 +1,341 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/export"
+	"github.com/claude-code-template/prompt-manager/internal/models"
+	"github.com/claude-code-template/prompt-manager/internal/validation"
+)
+
+// reportLowestRatedLimit caps how many low-rated conversations appear in reports
+const reportLowestRatedLimit = 5
+
+// defaultMostToolsLimit is how many conversations /stats/most-tools returns
+// when no limit is given
+const defaultMostToolsLimit = 10
+
+// Stats handlers
+
+// GetStatsReportHandler renders rating statistics as a shareable report
+func (s *Server) GetStatsReportHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "markdown"
+	}
+	if format != "markdown" {
+		s.errorResponse(w, fmt.Sprintf("Unsupported report format: %s", format), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := s.db.GetRatingStats()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get rating stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	unrated, err := s.db.GetUnratedConversationCount()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to count unrated conversations: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	lowest, err := s.db.GetLowestRatedConversations(reportLowestRatedLimit)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get lowest rated conversations: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	report := export.RatingReport{
+		GeneratedAt:          time.Now(),
+		UnratedConversations: unrated,
+	}
+	report.AverageRating, _ = stats["average_rating"].(float64)
+	report.TotalRatings, _ = stats["total_ratings"].(int)
+	report.Distribution, _ = stats["distribution"].(map[int]int)
+
+	for _, conv := range lowest {
+		report.LowestRated = append(report.LowestRated, export.LowRatedConversation{
+			ConversationID: conv.ConversationID,
+			Title:          conv.Title,
+			AverageRating:  conv.AverageRating,
+			RatingCount:    conv.RatingCount,
+			Comments:       conv.Comments,
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(export.RatingReportMarkdown(report)); err != nil {
+		s.config.logger().Errorf("Failed to write rating report: %v", err)
+	}
+}
+
+// GetDirectoryStatsHandler returns paginated aggregates per working directory
+func (s *Server) GetDirectoryStatsHandler(w http.ResponseWriter, r *http.Request) {
+	page, perPage, ok := s.parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	offset := (page - 1) * perPage
+
+	stats, err := s.db.GetDirectoryStats(perPage, offset)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get directory stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	totalCount, err := s.db.GetDirectoryCount()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get directory count: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	meta := newPagination(page, perPage, totalCount)
+
+	s.successResponse(w, stats, meta)
+}
+
+// GetRatingCoverageHandler returns how many conversations have at least one
+// rating, how many have none, and the rated percentage
+func (s *Server) GetRatingCoverageHandler(w http.ResponseWriter, r *http.Request) {
+	coverage, err := s.db.GetRatingCoverage()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get rating coverage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, coverage, nil)
+}
+
+// GetModelLatencyStatsHandler returns response latency statistics per model
+func (s *Server) GetModelLatencyStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.db.GetModelLatencyStats()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get model latency stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, stats, nil)
+}
+
+// GetToolDurationStatsHandler returns duration aggregates per tool, slowest in total first
+func (s *Server) GetToolDurationStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.db.GetToolDurationStats()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get tool duration stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, stats, nil)
+}
+
+// GetToolCallStatsHandler returns the total number of tool calls and how
+// often each tool was used
+func (s *Server) GetToolCallStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.db.GetToolCallStats()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get tool call stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, stats, nil)
+}
+
+// GetOverviewStatsHandler returns headline totals and the busiest working
+// directories for a dashboard
+func (s *Server) GetOverviewStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.db.GetOverviewStats()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get overview stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, stats, nil)
+}
+
+// conversationToolCountResponse pairs a conversation summary with its tool call count
+type conversationToolCountResponse struct {
+	Conversation models.ConversationSummary `json:"conversation"`
+	ToolCount    int                        `json:"tool_count"`
+}
+
+// GetMostToolsHandler returns the conversations with the most tool calls,
+// highest first. The optional limit parameter sets how many are returned.
+func (s *Server) GetMostToolsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := defaultMostToolsLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 1 || n > s.config.maxPageSize() {
+			s.errorResponse(w, fmt.Sprintf("limit must be between 1 and %d", s.config.maxPageSize()), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	results, err := s.db.GetMostToolConversations(limit)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get most tool conversations: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	conversations := make([]database.Conversation, len(results))
+	for i := range results {
+		conversations[i] = results[i].Conversation
+	}
+	summaries := ConvertConversationsToSummaries(conversations)
+
+	response := make([]conversationToolCountResponse, len(results))
+	for i := range results {
+		response[i] = conversationToolCountResponse{
+			Conversation: summaries[i],
+			ToolCount:    results[i].ToolCount,
+		}
+	}
+
+	s.successResponse(w, response, nil)
+}
+
+// GetActivityRangeHandler returns the earliest and latest message timestamps
+func (s *Server) GetActivityRangeHandler(w http.ResponseWriter, r *http.Request) {
+	activity, err := s.db.GetActivityRange()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get activity range: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, activity, nil)
+}
+
+// GetActivityHeatmapHandler returns message counts as a 7x24 matrix of
+// weekday (0 is Sunday) by hour of day, in UTC
+func (s *Server) GetActivityHeatmapHandler(w http.ResponseWriter, r *http.Request) {
+	heatmap, err := s.db.GetActivityHeatmap()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get activity heatmap: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, heatmap, nil)
+}
+
+// GetWeekdayStatsHandler returns conversation counts for each day of the
+// week, Sunday first, in UTC
+func (s *Server) GetWeekdayStatsHandler(w http.ResponseWriter, r *http.Request) {
+	weekdays, err := s.db.GetConversationsByWeekday()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get weekday stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, weekdays, nil)
+}
+
+// GetMessageSizeStatsHandler returns a histogram of message character counts
+// split by prompt and response. The optional buckets parameter takes
+// comma-separated ascending boundaries, e.g. buckets=100,500,1000.
+func (s *Server) GetMessageSizeStatsHandler(w http.ResponseWriter, r *http.Request) {
+	boundaries, err := parseBucketBoundaries(r.URL.Query().Get("buckets"), database.DefaultMessageSizeBuckets)
+	if err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := database.ValidateMessageSizeBuckets(boundaries); err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	buckets, err := s.db.GetMessageSizeDistribution(boundaries)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get message size distribution: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, buckets, nil)
+}
+
+// GetRatingByLengthHandler returns the average rating of rated conversations
+// grouped by prompt count. The optional buckets parameter takes
+// comma-separated ascending boundaries, e.g. buckets=5,20,50.
+func (s *Server) GetRatingByLengthHandler(w http.ResponseWriter, r *http.Request) {
+	boundaries, err := parseBucketBoundaries(r.URL.Query().Get("buckets"), database.DefaultPromptCountBuckets)
+	if err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := database.ValidatePromptCountBuckets(boundaries); err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	buckets, err := s.db.GetRatingByLength(boundaries)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get rating by length: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, buckets, nil)
+}
+
+// parseBucketBoundaries parses a comma-separated list of histogram
+// boundaries, returning defaults when the value is empty
+func parseBucketBoundaries(value string, defaults []int) ([]int, error) {
+	if value == "" {
+		return defaults, nil
+	}
+
+	var boundaries []int
+	for _, part := range strings.Split(value, ",") {
+		boundary, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid bucket boundary: %q", part)
+		}
+		boundaries = append(boundaries, boundary)
+	}
+
+	return boundaries, nil
+}
+
+// GetCreationRateHandler returns the number of conversations created per
+// interval (hour or day, defaulting to hour), optionally bounded by from/to
+func (s *Server) GetCreationRateHandler(w http.ResponseWriter, r *http.Request) {
+	interval := database.CreationRateHour
+	if intervalStr := r.URL.Query().Get("interval"); intervalStr != "" {
+		var err error
+		interval, err = database.ParseCreationRateInterval(intervalStr)
+		if err != nil {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	from, to, err := validation.ParseAndValidateTimeRange(
+		r.URL.Query().Get("from"),
+		r.URL.Query().Get("to"),
+	)
+	if err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	buckets, err := s.db.GetConversationCreationRate(interval, from, to)
+	if err != nil {
+		if errors.Is(err, database.ErrCreationRateRangeTooLarge) {
+			s.errorResponse(w, "Time range spans too many buckets for the interval", http.StatusBadRequest)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to get creation rate: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, buckets, nil)
+}
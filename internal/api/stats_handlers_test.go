@@ -0,0 +1,542 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/claude-code-template/prompt-manager/internal/database"
+)
+
+func TestGetStatsReport(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("test-session", stringPtr("Needs work"), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	if _, err := server.db.CreateConversation("unrated-session", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	if _, err := server.db.CreateConversationRating(conv.ID, 2, stringPtr("Too verbose")); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+
+	if _, err := server.db.CreateConversationRating(conv.ID, 4, nil); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "/stats/report?format=markdown", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.GetStatsReportHandler)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	if contentType := rr.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/markdown") {
+		t.Errorf("Expected markdown content type, got %s", contentType)
+	}
+
+	body := rr.Body.String()
+	expected := []string{
+		"Average rating: 3.00 / 5",
+		"Unrated conversations: 1",
+		"4 | ######",
+		"2 | ######",
+		"> Too verbose",
+	}
+
+	for _, want := range expected {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected report to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestGetStatsReportUnsupportedFormat(t *testing.T) {
+	server := setupTestServer(t)
+
+	req, err := http.NewRequest("GET", "/stats/report?format=pdf", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.GetStatsReportHandler)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestGetDirectoryStats(t *testing.T) {
+	server := setupTestServer(t)
+
+	for _, dir := range []string{"/work/a", "/work/a", "/work/b"} {
+		dir := dir
+		if _, err := server.db.CreateConversation("test-session", nil, &dir, nil); err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest("GET", "/stats/by-directory?per_page=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetDirectoryStatsHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	stats, ok := response.Data.([]interface{})
+	if !ok || len(stats) != 1 {
+		t.Fatalf("Expected one directory on the first page, got %v", response.Data)
+	}
+
+	first := stats[0].(map[string]interface{})
+	if first["directory"] != "/work/a" || first["conversation_count"] != float64(2) {
+		t.Errorf("Expected /work/a with 2 conversations first, got %v", first)
+	}
+
+	if response.Meta == nil || response.Meta.Total != 2 {
+		t.Errorf("Expected 2 directories in total, got %+v", response.Meta)
+	}
+}
+
+func TestGetActivityRangeEmpty(t *testing.T) {
+	server := setupTestServer(t)
+
+	req, err := http.NewRequest("GET", "/stats/activity-range", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetActivityRangeHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	activity, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected an activity range object, got %v", response.Data)
+	}
+
+	for _, field := range []string{"earliest", "latest", "span_seconds"} {
+		value, present := activity[field]
+		if !present || value != nil {
+			t.Errorf("Expected %s to be null on an empty database, got %v", field, value)
+		}
+	}
+}
+
+func TestGetMessageSizeStats(t *testing.T) {
+	server := setupTestServer(t)
+
+	tests := []struct {
+		name            string
+		query           string
+		expectedStatus  int
+		expectedBuckets int
+	}{
+		{"default buckets", "", http.StatusOK, len(database.DefaultMessageSizeBuckets) + 1},
+		{"custom buckets", "?buckets=50,200", http.StatusOK, 3},
+		{"non-numeric boundary", "?buckets=50,abc", http.StatusBadRequest, 0},
+		{"descending boundaries", "?buckets=200,50", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/stats/message-sizes"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(server.GetMessageSizeStatsHandler).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response APIResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			buckets, ok := response.Data.([]interface{})
+			if !ok || len(buckets) != tt.expectedBuckets {
+				t.Errorf("Expected %d buckets, got %v", tt.expectedBuckets, response.Data)
+			}
+		})
+	}
+}
+
+func TestGetRatingByLength(t *testing.T) {
+	server := setupTestServer(t)
+
+	short, err := server.db.CreateConversation("short-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	long, err := server.db.CreateConversation("long-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := server.db.CreateMessage(long.ID, "prompt", "prompt", nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+	if _, err := server.db.CreateConversationRating(short.ID, 2, nil); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+	if _, err := server.db.CreateConversationRating(long.ID, 5, nil); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "/stats/rating-by-length?buckets=5", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetRatingByLengthHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response struct {
+		Data []database.RatingLengthBucket `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Data) != 2 {
+		t.Fatalf("Expected 2 buckets, got %+v", response.Data)
+	}
+	for i, want := range []float64{2, 5} {
+		got := response.Data[i].AverageRating
+		if got == nil || *got != want {
+			t.Errorf("Bucket %d: expected average %v, got %v", i, want, got)
+		}
+	}
+
+	req, err = http.NewRequest("GET", "/stats/rating-by-length?buckets=abc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetRatingByLengthHandler).ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Expected status %d for invalid buckets, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestGetCreationRate(t *testing.T) {
+	server := setupTestServer(t)
+
+	if _, err := server.db.CreateConversation("rate-session", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+	}{
+		{"default interval", "", http.StatusOK},
+		{"daily interval", "?interval=day", http.StatusOK},
+		{"invalid interval", "?interval=minute", http.StatusBadRequest},
+		{"range too large", "?interval=hour&from=2000-01-01T00:00:00Z&to=2024-01-01T00:00:00Z", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/stats/creation-rate"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(server.GetCreationRateHandler).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.expectedCode)
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var response APIResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			buckets, ok := response.Data.([]interface{})
+			if !ok || len(buckets) != 1 {
+				t.Fatalf("Expected a single bucket, got %v", response.Data)
+			}
+			if count := buckets[0].(map[string]interface{})["count"]; count != float64(1) {
+				t.Errorf("Expected count 1, got %v", count)
+			}
+		})
+	}
+}
+
+func TestGetMostTools(t *testing.T) {
+	server := setupTestServer(t)
+
+	few, err := server.db.CreateConversation("few-tools", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	many, err := server.db.CreateConversation("many-tools", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	oneCall := `[{"name":"bash","arguments":{}}]`
+	threeCalls := `[{"name":"bash","arguments":{}},{"name":"read_file","arguments":{}},{"name":"grep","arguments":{}}]`
+	if _, err := server.db.CreateMessage(few.ID, "response", "answer", &oneCall, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := server.db.CreateMessage(many.ID, "response", "answer", &threeCalls, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "/stats/most-tools?limit=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetMostToolsHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response struct {
+		Data []struct {
+			Conversation struct {
+				ID int `json:"id"`
+			} `json:"conversation"`
+			ToolCount int `json:"tool_count"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Data) != 1 || response.Data[0].Conversation.ID != many.ID || response.Data[0].ToolCount != 3 {
+		t.Errorf("Expected conversation %d with 3 tool calls, got %+v", many.ID, response.Data)
+	}
+
+	req, err = http.NewRequest("GET", "/stats/most-tools?limit=0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetMostToolsHandler).ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid limit, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestGetToolCallStats(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("tool-stats", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	twoCalls := `[{"name":"bash","arguments":{}},{"name":"read_file","arguments":{}}]`
+	if _, err := server.db.CreateMessage(conv.ID, "response", "answer", &twoCalls, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := server.db.CreateMessage(conv.ID, "prompt", "question", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "/stats/tools", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetToolCallStatsHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response struct {
+		Data struct {
+			TotalCalls int            `json:"total_calls"`
+			ByName     map[string]int `json:"by_name"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if response.Data.TotalCalls != 2 {
+		t.Errorf("Expected 2 tool calls, got %d", response.Data.TotalCalls)
+	}
+	if response.Data.ByName["bash"] != 1 || response.Data.ByName["read_file"] != 1 || len(response.Data.ByName) != 2 {
+		t.Errorf("Expected one bash and one read_file call, got %v", response.Data.ByName)
+	}
+}
+
+func TestGetWeekdayStatsEmpty(t *testing.T) {
+	server := setupTestServer(t)
+
+	req, err := http.NewRequest("GET", "/stats/weekday", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetWeekdayStatsHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response struct {
+		Data []database.WeekdayCount `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Data) != 7 {
+		t.Fatalf("Expected 7 weekdays, got %v", response.Data)
+	}
+	if response.Data[0].Weekday != "Sunday" || response.Data[6].Weekday != "Saturday" {
+		t.Errorf("Expected Sunday through Saturday, got %v", response.Data)
+	}
+	for _, day := range response.Data {
+		if day.Count != 0 {
+			t.Errorf("Expected zero conversations on %s, got %d", day.Weekday, day.Count)
+		}
+	}
+}
+
+func TestGetOverviewStats(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("overview-stats", nil, stringPtr("/work/api"), nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := server.db.CreateMessage(conv.ID, "prompt", "question", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := server.db.CreateConversationRating(conv.ID, 4, nil); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "/stats/overview", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetOverviewStatsHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response struct {
+		Data database.OverviewStats `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	stats := response.Data
+	if stats.TotalConversations != 1 || stats.TotalMessages != 1 || stats.TotalRatings != 1 {
+		t.Errorf("Expected one conversation, message and rating, got %+v", stats)
+	}
+	if stats.AverageRating != 4 {
+		t.Errorf("Expected average rating 4, got %v", stats.AverageRating)
+	}
+	if stats.ConversationsLast7d != 1 || stats.ConversationsLast30d != 1 {
+		t.Errorf("Expected the conversation to count as recent, got %+v", stats)
+	}
+	if len(stats.TopDirectories) != 1 || stats.TopDirectories[0].Directory != "/work/api" {
+		t.Errorf("Expected /work/api as the top directory, got %v", stats.TopDirectories)
+	}
+}
+
+func TestGetActivityHeatmap(t *testing.T) {
+	server := setupTestServer(t)
+
+	req, err := http.NewRequest("GET", "/stats/heatmap", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetActivityHeatmapHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response struct {
+		Data struct {
+			Counts [][]int `json:"counts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	// The matrix is complete even without any messages
+	if len(response.Data.Counts) != 7 {
+		t.Fatalf("Expected 7 weekdays, got %d", len(response.Data.Counts))
+	}
+	for day, hours := range response.Data.Counts {
+		if len(hours) != 24 {
+			t.Errorf("Expected 24 hours for weekday %d, got %d", day, len(hours))
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/validation"
+	"github.com/gorilla/mux"
+)
+
+// tagRequest is the request body accepted when creating or updating a tag
+type tagRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	Color       *string `json:"color"`
+}
+
+// validateTagRequest validates the tag fields and sanitizes free text in place.
+// It writes an error response and returns false when the request is invalid.
+func (s *Server) validateTagRequest(w http.ResponseWriter, req *tagRequest) bool {
+	// Validate name
+	if err := validation.ValidateTagName(req.Name); err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return false
+		}
+		s.errorResponse(w, "Invalid tag name", http.StatusBadRequest)
+		return false
+	}
+
+	// Validate color
+	if err := validation.ValidateColor(req.Color); err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return false
+		}
+		s.errorResponse(w, "Invalid tag color", http.StatusBadRequest)
+		return false
+	}
+
+	// Sanitize strings
+	req.Name = validation.SanitizeString(req.Name, validation.MaxTagNameLength)
+	if req.Description != nil {
+		sanitized := validation.SanitizeString(*req.Description, validation.MaxTagDescriptionLength)
+		req.Description = &sanitized
+	}
+
+	return true
+}
+
+// Tag handlers
+
+// CreateTagHandler creates a new tag
+func (s *Server) CreateTagHandler(w http.ResponseWriter, r *http.Request) {
+	var req tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+
+	if !s.validateTagRequest(w, &req) {
+		return
+	}
+
+	tag, err := s.db.CreateTag(req.Name, req.Description, req.Color)
+	if err != nil {
+		if errors.Is(err, database.ErrTagAlreadyExists) {
+			s.errorResponse(w, "Tag already exists", http.StatusConflict)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to create tag: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	apiTag := ConvertTag(tag)
+
+	s.writeJSON(w, http.StatusCreated, successBody(apiTag, nil))
+}
+
+// ListTagsHandler returns all tags with their usage counts
+func (s *Server) ListTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := s.db.ListTags()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to list tags: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	apiTags := ConvertTags(tags)
+
+	s.successResponse(w, apiTags, nil)
+}
+
+// UpdateTagHandler updates a tag
+func (s *Server) UpdateTagHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Tag ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "tag_id")
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	var req tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+
+	if !s.validateTagRequest(w, &req) {
+		return
+	}
+
+	if err := s.db.UpdateTag(id, req.Name, req.Description, req.Color); err != nil {
+		if errors.Is(err, database.ErrTagNotFound) {
+			s.errorResponse(w, "Tag not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, database.ErrTagAlreadyExists) {
+			s.errorResponse(w, "Tag already exists", http.StatusConflict)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to update tag: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return updated tag
+	tag, err := s.db.GetTag(id)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get updated tag: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	apiTag := ConvertTag(tag)
+
+	s.successResponse(w, apiTag, nil)
+}
+
+// DeleteTagHandler deletes a tag
+func (s *Server) DeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Tag ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "tag_id")
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.DeleteTag(id); err != nil {
+		if errors.Is(err, database.ErrTagNotFound) {
+			s.errorResponse(w, "Tag not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to delete tag: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddConversationTagHandler attaches a tag to a conversation. Re-attaching an
+// existing tag succeeds with 200 instead of 201.
+func (s *Server) AddConversationTagHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "conversation_id")
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		TagID int `json:"tag_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validation.ValidateID(req.TagID, "tag_id"); err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	added, err := s.db.AddTagToConversation(id, req.TagID)
+	if err != nil {
+		if errors.Is(err, database.ErrConversationNotFound) {
+			s.errorResponse(w, "Conversation not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, database.ErrTagNotFound) {
+			s.errorResponse(w, "Tag not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to add tag to conversation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := s.db.GetConversationTags(id)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get conversation tags: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	apiTags := ConvertTags(tags)
+
+	status := http.StatusOK
+	if added {
+		status = http.StatusCreated
+	}
+	s.writeJSON(w, status, successBody(apiTags, nil))
+}
+
+// RemoveConversationTagHandler detaches a tag from a conversation
+func (s *Server) RemoveConversationTagHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, exists := vars["id"]
+	if !exists {
+		s.errorResponse(w, "Conversation ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "conversation_id")
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	tagID, err := validation.ParseAndValidateID(vars["tag_id"], "tag_id")
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return
+		}
+		s.errorResponse(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.RemoveTagFromConversation(id, tagID); err != nil {
+		if errors.Is(err, database.ErrConversationNotFound) {
+			s.errorResponse(w, "Conversation not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, database.ErrTagNotFound) {
+			s.errorResponse(w, "Tag not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, database.ErrConversationTagNotFound) {
+			s.errorResponse(w, "Tag is not attached to conversation", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to remove tag from conversation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseTagFilter resolves the tag, tag_id and tags query parameters into tag
+// IDs. tag and tag_id may be repeated; tags takes a comma-separated list of
+// IDs. It writes an error response and returns false when a tag is unknown.
+func (s *Server) parseTagFilter(w http.ResponseWriter, r *http.Request) ([]int, bool) {
+	query := r.URL.Query()
+
+	var tagIDs []int
+	for _, name := range query["tag"] {
+		tag, err := s.db.GetTagByName(name)
+		if err != nil {
+			if errors.Is(err, database.ErrTagNotFound) {
+				s.errorResponse(w, fmt.Sprintf("Unknown tag: %s", name), http.StatusBadRequest)
+				return nil, false
+			}
+			s.errorResponse(w, fmt.Sprintf("Failed to get tag: %v", err), http.StatusInternalServerError)
+			return nil, false
+		}
+		tagIDs = append(tagIDs, tag.ID)
+	}
+
+	idStrs := query["tag_id"]
+	for _, list := range query["tags"] {
+		idStrs = append(idStrs, strings.Split(list, ",")...)
+	}
+
+	for _, idStr := range idStrs {
+		id, err := validation.ParseAndValidateID(strings.TrimSpace(idStr), "tag_id")
+		if err != nil {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+
+		if _, err := s.db.GetTag(id); err != nil {
+			if errors.Is(err, database.ErrTagNotFound) {
+				s.errorResponse(w, fmt.Sprintf("Unknown tag ID: %d", id), http.StatusBadRequest)
+				return nil, false
+			}
+			s.errorResponse(w, fmt.Sprintf("Failed to get tag: %v", err), http.StatusInternalServerError)
+			return nil, false
+		}
+		tagIDs = append(tagIDs, id)
+	}
+
+	return tagIDs, true
+}
+
+// MergeTagsHandler merges a source tag into a target tag
+func (s *Server) MergeTagsHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SourceID int `json:"source_id"`
+		TargetID int `json:"target_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validation.ValidateID(req.SourceID, "source_id"); err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validation.ValidateID(req.TargetID, "target_id"); err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.SourceID == req.TargetID {
+		s.errorResponse(w, "Cannot merge a tag into itself", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.MergeTags(req.SourceID, req.TargetID); err != nil {
+		if errors.Is(err, database.ErrTagNotFound) {
+			s.errorResponse(w, "Tag not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to merge tags: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return merged tag with its updated usage count
+	tags, err := s.db.ListTags()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to list tags: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	for _, tag := range tags {
+		if tag.ID == req.TargetID {
+			s.successResponse(w, ConvertTag(&tag), nil)
+			return
+		}
+	}
+
+	s.errorResponse(w, "Tag not found", http.StatusNotFound)
+}
@@ -0,0 +1,541 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCreateTag(t *testing.T) {
+	server := setupTestServer(t)
+
+	reqBody := map[string]interface{}{
+		"name":        "bugfix",
+		"description": "  Bug fixing sessions  ",
+		"color":       "#FF0000",
+	}
+
+	body, _ := json.Marshal(reqBody)
+	req, err := http.NewRequest("POST", "/tags", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.CreateTagHandler)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+	}
+	if contentType := rr.Result().Header.Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", contentType)
+	}
+
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if !response.Success {
+		t.Error("Expected success=true in response")
+	}
+
+	data, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected response.Data to be a map")
+	}
+
+	if data["name"] != "bugfix" {
+		t.Errorf("Expected name=bugfix, got %v", data["name"])
+	}
+
+	if data["description"] != "Bug fixing sessions" {
+		t.Errorf("Expected sanitized description, got %v", data["description"])
+	}
+}
+
+func TestCreateTagDuplicate(t *testing.T) {
+	server := setupTestServer(t)
+
+	if _, err := server.db.CreateTag("bugfix", nil, nil); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"name": "bugfix"})
+	req, err := http.NewRequest("POST", "/tags", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.CreateTagHandler)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusConflict {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusConflict)
+	}
+}
+
+func TestCreateTagInvalidRequest(t *testing.T) {
+	server := setupTestServer(t)
+
+	tests := []struct {
+		name    string
+		reqBody map[string]interface{}
+	}{
+		{"missing name", map[string]interface{}{"color": "#FF0000"}},
+		{"invalid color", map[string]interface{}{"name": "bugfix", "color": "red"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.reqBody)
+			req, err := http.NewRequest("POST", "/tags", bytes.NewBuffer(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(server.CreateTagHandler)
+
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+			}
+
+			var response APIResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			if response.Error == nil {
+				t.Error("Expected error message in response")
+			}
+		})
+	}
+}
+
+func TestListTags(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("test-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	used, err := server.db.CreateTag("used", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	if _, err := server.db.CreateTag("unused", nil, nil); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	if _, err := server.db.Conn().Exec("INSERT INTO conversation_tags (conversation_id, tag_id) VALUES (?, ?)", conv.ID, used.ID); err != nil {
+		t.Fatalf("Failed to attach tag: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "/tags", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.ListTagsHandler)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	tags, ok := response.Data.([]interface{})
+	if !ok {
+		t.Fatal("Expected response.Data to be an array")
+	}
+
+	if len(tags) != 2 {
+		t.Fatalf("Expected 2 tags, got %d", len(tags))
+	}
+
+	usage := make(map[string]interface{})
+	for _, tag := range tags {
+		tagMap := tag.(map[string]interface{})
+		usage[tagMap["name"].(string)] = tagMap["usage_count"]
+	}
+
+	if usage["used"] != float64(1) {
+		t.Errorf("Expected usage_count=1 for used tag, got %v", usage["used"])
+	}
+
+	if usage["unused"] != float64(0) {
+		t.Errorf("Expected usage_count=0 for unused tag, got %v", usage["unused"])
+	}
+}
+
+func TestUpdateTag(t *testing.T) {
+	server := setupTestServer(t)
+
+	tag, err := server.db.CreateTag("bugfix", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"name": "bug-fix", "color": "#00FF00"})
+	req, err := http.NewRequest("PUT", "/tags/1", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/tags/{id}", server.UpdateTagHandler)
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	updated, err := server.db.GetTag(tag.ID)
+	if err != nil {
+		t.Fatalf("Failed to get tag: %v", err)
+	}
+
+	if updated.Name != "bug-fix" {
+		t.Errorf("Expected name=bug-fix, got %s", updated.Name)
+	}
+}
+
+func TestDeleteTagNotFound(t *testing.T) {
+	server := setupTestServer(t)
+
+	req, err := http.NewRequest("DELETE", "/tags/999", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/tags/{id}", server.DeleteTagHandler)
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestAddConversationTag(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("test-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	tag, err := server.db.CreateTag("bugfix", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/conversations/{id}/tags", server.AddConversationTagHandler)
+
+	tests := []struct {
+		name           string
+		path           string
+		tagID          int
+		expectedStatus int
+	}{
+		{"attach new tag", fmt.Sprintf("/conversations/%d/tags", conv.ID), tag.ID, http.StatusCreated},
+		{"attach existing tag", fmt.Sprintf("/conversations/%d/tags", conv.ID), tag.ID, http.StatusOK},
+		{"missing tag", fmt.Sprintf("/conversations/%d/tags", conv.ID), 999, http.StatusNotFound},
+		{"missing conversation", "/conversations/999/tags", tag.ID, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{"tag_id": tt.tagID})
+			req, err := http.NewRequest("POST", tt.path, bytes.NewBuffer(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+		})
+	}
+
+	// The conversation detail should now include the tag
+	req, err := http.NewRequest("GET", fmt.Sprintf("/conversations/%d", conv.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	router.HandleFunc("/conversations/{id}", server.GetConversationHandler)
+	router.ServeHTTP(rr, req)
+
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	data := response.Data.(map[string]interface{})
+	tags, ok := data["tags"].([]interface{})
+	if !ok || len(tags) != 1 {
+		t.Errorf("Expected 1 tag on conversation, got %v", data["tags"])
+	}
+}
+
+func TestRemoveConversationTag(t *testing.T) {
+	server := setupTestServer(t)
+
+	conv, err := server.db.CreateConversation("test-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	tag, err := server.db.CreateTag("bugfix", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	if _, err := server.db.AddTagToConversation(conv.ID, tag.ID); err != nil {
+		t.Fatalf("Failed to attach tag: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/conversations/{id}/tags/{tag_id}", server.RemoveConversationTagHandler)
+
+	path := fmt.Sprintf("/conversations/%d/tags/%d", conv.ID, tag.ID)
+	for _, expectedStatus := range []int{http.StatusNoContent, http.StatusNotFound} {
+		req, err := http.NewRequest("DELETE", path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != expectedStatus {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, expectedStatus)
+		}
+	}
+}
+
+func TestListConversationsByTag(t *testing.T) {
+	server := setupTestServer(t)
+
+	tag, err := server.db.CreateTag("bugfix", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	tagged, err := server.db.CreateConversation("session-1", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := server.db.CreateConversation("session-2", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := server.db.AddTagToConversation(tagged.ID, tag.ID); err != nil {
+		t.Fatalf("Failed to attach tag: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedTotal  int
+	}{
+		{"by tag name", "?tag=bugfix", http.StatusOK, 1},
+		{"by tag id", fmt.Sprintf("?tag_id=%d", tag.ID), http.StatusOK, 1},
+		{"unfiltered", "", http.StatusOK, 2},
+		{"unknown tag name", "?tag=missing", http.StatusBadRequest, 0},
+		{"unknown tag id", "?tag_id=999", http.StatusBadRequest, 0},
+		{"invalid tag id", "?tag_id=abc", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/conversations"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(server.ListConversationsHandler).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response APIResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			if response.Meta == nil || response.Meta.Total != tt.expectedTotal {
+				t.Errorf("Expected total %d, got %+v", tt.expectedTotal, response.Meta)
+			}
+		})
+	}
+}
+
+func TestListConversationsTagMode(t *testing.T) {
+	server := setupTestServer(t)
+
+	var tagIDs []int
+	for _, name := range []string{"backend", "frontend", "docs"} {
+		tag, err := server.db.CreateTag(name, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create tag: %v", err)
+		}
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	backend, frontend, docs := tagIDs[0], tagIDs[1], tagIDs[2]
+
+	// Conversations carry {backend}, {backend, frontend} and {docs}
+	for _, subset := range [][]int{{backend}, {backend, frontend}, {docs}} {
+		conv, err := server.db.CreateConversation("tag-mode-session", nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		for _, tagID := range subset {
+			if _, err := server.db.AddTagToConversation(conv.ID, tagID); err != nil {
+				t.Fatalf("Failed to attach tag: %v", err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedTotal  int
+	}{
+		{"all is the default", fmt.Sprintf("?tags=%d,%d", backend, frontend), http.StatusOK, 1},
+		{"all mode", fmt.Sprintf("?tags=%d,%d&tag_mode=all", backend, frontend), http.StatusOK, 1},
+		{"any mode", fmt.Sprintf("?tags=%d,%d&tag_mode=any", frontend, docs), http.StatusOK, 2},
+		{"any mode across every tag", fmt.Sprintf("?tags=%d,%d,%d&tag_mode=any", backend, frontend, docs), http.StatusOK, 3},
+		{"all mode with disjoint tags", fmt.Sprintf("?tags=%d,%d&tag_mode=all", backend, docs), http.StatusOK, 0},
+		{"invalid mode", fmt.Sprintf("?tags=%d&tag_mode=some", backend), http.StatusBadRequest, 0},
+		{"invalid tag id in list", fmt.Sprintf("?tags=%d,abc", backend), http.StatusBadRequest, 0},
+		{"unknown tag id in list", fmt.Sprintf("?tags=%d,999", backend), http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/conversations"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(server.ListConversationsHandler).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response APIResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			if response.Meta == nil || response.Meta.Total != tt.expectedTotal {
+				t.Errorf("Expected total %d, got %+v", tt.expectedTotal, response.Meta)
+			}
+		})
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	server := setupTestServer(t)
+
+	source, err := server.db.CreateTag("bug-fix", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	target, err := server.db.CreateTag("bugfix", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	conv, err := server.db.CreateConversation("test-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	for _, tagID := range []int{source.ID, target.ID} {
+		if _, err := server.db.AddTagToConversation(conv.ID, tagID); err != nil {
+			t.Fatalf("Failed to attach tag: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		sourceID       int
+		targetID       int
+		expectedStatus int
+	}{
+		{"merge into itself", source.ID, source.ID, http.StatusBadRequest},
+		{"missing source", 999, target.ID, http.StatusNotFound},
+		{"missing target", source.ID, 999, http.StatusNotFound},
+		{"merge", source.ID, target.ID, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]int{"source_id": tt.sourceID, "target_id": tt.targetID})
+			req, err := http.NewRequest("POST", "/tags/merge", bytes.NewBuffer(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(server.MergeTagsHandler).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
+			}
+		})
+	}
+
+	tags, err := server.db.GetConversationTags(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation tags: %v", err)
+	}
+	if len(tags) != 1 || tags[0].ID != target.ID {
+		t.Errorf("Expected associations consolidated onto target, got %v", tags)
+	}
+}
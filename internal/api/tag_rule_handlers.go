@@ -0,0 +1,213 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/claude-code-template/prompt-manager/internal/database"
+	"github.com/claude-code-template/prompt-manager/internal/models"
+	"github.com/claude-code-template/prompt-manager/internal/validation"
+	"github.com/gorilla/mux"
+)
+
+// tagRuleRequest is the request body accepted when creating or updating a tag rule
+type tagRuleRequest struct {
+	Pattern string `json:"pattern"`
+	Regex   bool   `json:"regex"`
+	TagID   int    `json:"tag_id"`
+}
+
+// validateTagRuleRequest checks the rule fields, including that regex
+// patterns compile. It writes an error response and returns false when the
+// request is invalid.
+func (s *Server) validateTagRuleRequest(w http.ResponseWriter, req *tagRuleRequest) bool {
+	if err := database.ValidateTagRulePattern(req.Pattern, req.Regex); err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	if err := validation.ValidateID(req.TagID, "tag_id"); err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+// parseTagRuleID reads the rule ID from the route. It writes an error
+// response and returns false when the ID is missing or invalid.
+func (s *Server) parseTagRuleID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	idStr, exists := mux.Vars(r)["id"]
+	if !exists {
+		s.errorResponse(w, "Tag rule ID is required", http.StatusBadRequest)
+		return 0, false
+	}
+
+	id, err := validation.ParseAndValidateID(idStr, "tag_rule_id")
+	if err != nil {
+		if validation.IsValidationError(err) {
+			s.validationErrorResponse(w, err)
+			return 0, false
+		}
+		s.errorResponse(w, "Invalid tag rule ID", http.StatusBadRequest)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// Tag rule handlers
+
+// CreateTagRuleHandler creates a rule that auto-applies a tag to matching conversations
+func (s *Server) CreateTagRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var req tagRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+
+	if !s.validateTagRuleRequest(w, &req) {
+		return
+	}
+
+	rule, err := s.db.CreateTagRule(req.Pattern, req.Regex, req.TagID)
+	if err != nil {
+		if errors.Is(err, database.ErrTagNotFound) {
+			s.errorResponse(w, "Tag not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to create tag rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, successBody(rule, nil))
+}
+
+// ListTagRulesHandler returns all tag rules
+func (s *Server) ListTagRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := s.db.ListTagRules()
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to list tag rules: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, rules, nil)
+}
+
+// GetTagRuleHandler returns a single tag rule
+func (s *Server) GetTagRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.parseTagRuleID(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := s.db.GetTagRule(id)
+	if err != nil {
+		if errors.Is(err, database.ErrTagRuleNotFound) {
+			s.errorResponse(w, "Tag rule not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to get tag rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, rule, nil)
+}
+
+// UpdateTagRuleHandler replaces a tag rule's pattern and tag
+func (s *Server) UpdateTagRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.parseTagRuleID(w, r)
+	if !ok {
+		return
+	}
+
+	var req tagRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+
+	if !s.validateTagRuleRequest(w, &req) {
+		return
+	}
+
+	if err := s.db.UpdateTagRule(id, req.Pattern, req.Regex, req.TagID); err != nil {
+		if errors.Is(err, database.ErrTagRuleNotFound) {
+			s.errorResponse(w, "Tag rule not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, database.ErrTagNotFound) {
+			s.errorResponse(w, "Tag not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to update tag rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	rule, err := s.db.GetTagRule(id)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to get updated tag rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, rule, nil)
+}
+
+// DeleteTagRuleHandler deletes a tag rule. Tags it already applied are kept.
+func (s *Server) DeleteTagRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.parseTagRuleID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.db.DeleteTagRule(id); err != nil {
+		if errors.Is(err, database.ErrTagRuleNotFound) {
+			s.errorResponse(w, "Tag rule not found", http.StatusNotFound)
+			return
+		}
+		s.errorResponse(w, fmt.Sprintf("Failed to delete tag rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tagRulePreviewResponse reports what a prospective tag rule would match
+type tagRulePreviewResponse struct {
+	MatchCount int                          `json:"match_count"`
+	Sample     []models.ConversationSummary `json:"sample"`
+	Complete   bool                         `json:"complete"`
+}
+
+// PreviewTagRuleHandler reports how many existing conversations a pattern
+// would match, with a sample, so overly broad rules can be caught before saving
+func (s *Server) PreviewTagRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Pattern string `json:"pattern"`
+		Regex   bool   `json:"regex"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := database.ValidateTagRulePattern(req.Pattern, req.Regex); err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	preview, err := s.db.PreviewTagRule(req.Pattern, req.Regex)
+	if err != nil {
+		s.errorResponse(w, fmt.Sprintf("Failed to preview tag rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.successResponse(w, tagRulePreviewResponse{
+		MatchCount: preview.MatchCount,
+		Sample:     ConvertConversationsToSummaries(preview.Sample),
+		Complete:   preview.Complete,
+	}, nil)
+}
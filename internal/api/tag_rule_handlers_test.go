@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTagRuleHandlers(t *testing.T) {
+	server := setupTestServer(t)
+
+	tag, err := server.db.CreateTag("database", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/tag-rules", server.CreateTagRuleHandler).Methods("POST")
+	router.HandleFunc("/tag-rules", server.ListTagRulesHandler).Methods("GET")
+	router.HandleFunc("/tag-rules/{id}", server.GetTagRuleHandler).Methods("GET")
+	router.HandleFunc("/tag-rules/{id}", server.UpdateTagRuleHandler).Methods("PUT")
+	router.HandleFunc("/tag-rules/{id}", server.DeleteTagRuleHandler).Methods("DELETE")
+
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req, err := http.NewRequest(method, path, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	invalid := []struct {
+		name         string
+		body         map[string]interface{}
+		expectedCode int
+	}{
+		{"empty pattern", map[string]interface{}{"pattern": " ", "tag_id": tag.ID}, http.StatusBadRequest},
+		{"invalid regex", map[string]interface{}{"pattern": "([", "regex": true, "tag_id": tag.ID}, http.StatusBadRequest},
+		{"missing tag", map[string]interface{}{"pattern": "sqlite", "tag_id": 9999}, http.StatusNotFound},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := send("POST", "/tag-rules", tt.body); rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	rr := send("POST", "/tag-rules", map[string]interface{}{"pattern": "sqlite", "tag_id": tag.ID})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	ruleID := int(response.Data.(map[string]interface{})["id"].(float64))
+
+	// A new matching message is tagged automatically
+	conv, err := server.db.CreateConversation("rule-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := server.db.CreateMessage(conv.ID, "prompt", "Tune SQLite pragmas", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	tags, err := server.db.GetConversationTags(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation tags: %v", err)
+	}
+	if len(tags) != 1 || tags[0].ID != tag.ID {
+		t.Errorf("Expected the rule's tag to be auto-applied, got %+v", tags)
+	}
+
+	path := fmt.Sprintf("/tag-rules/%d", ruleID)
+	if rr := send("PUT", path, map[string]interface{}{"pattern": `\bpostgres\b`, "regex": true, "tag_id": tag.ID}); rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d on update, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if rr := send("GET", path, nil); rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d on get, got %d", http.StatusOK, rr.Code)
+	}
+	if rr := send("DELETE", path, nil); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d on delete, got %d", http.StatusNoContent, rr.Code)
+	}
+	if rr := send("GET", path, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d after delete, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestPreviewTagRule(t *testing.T) {
+	server := setupTestServer(t)
+
+	for i, content := range []string{"Optimize the SQLite index", "Explain sqlite WAL mode", "Write a README"} {
+		conv, err := server.db.CreateConversation(fmt.Sprintf("preview-session-%d", i), nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		if _, err := server.db.CreateMessage(conv.ID, "prompt", content, nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		body          map[string]interface{}
+		expectedCode  int
+		expectedCount float64
+	}{
+		{"keyword", map[string]interface{}{"pattern": "sqlite"}, http.StatusOK, 2},
+		{"regex", map[string]interface{}{"pattern": "^Write", "regex": true}, http.StatusOK, 1},
+		{"invalid regex", map[string]interface{}{"pattern": "(?<", "regex": true}, http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.body)
+			req, err := http.NewRequest("POST", "/tag-rules/preview", bytes.NewBuffer(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(server.PreviewTagRuleHandler).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var response APIResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			data := response.Data.(map[string]interface{})
+			if data["match_count"] != tt.expectedCount {
+				t.Errorf("Expected match_count %v, got %v", tt.expectedCount, data["match_count"])
+			}
+			if sample, ok := data["sample"].([]interface{}); !ok || float64(len(sample)) != tt.expectedCount {
+				t.Errorf("Expected %v sampled conversations, got %v", tt.expectedCount, data["sample"])
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+	"github.com/claude-code-template/prompt-manager/internal/models"
+)
+
+// Rating webhook delivery settings. The queue absorbs bursts while the
+// worker is retrying; ratings arriving while it is full are dropped.
+const (
+	ratingWebhookQueueSize = 100
+	ratingWebhookAttempts  = 3
+	ratingWebhookBackoff   = 500 * time.Millisecond
+	ratingWebhookTimeout   = 5 * time.Second
+)
+
+// ratingNotifier posts new ratings to a webhook from a single background
+// worker, so slow or failing receivers never hold up API responses
+type ratingNotifier struct {
+	url     string
+	client  *http.Client
+	queue   chan models.Rating
+	backoff time.Duration
+	logger  logging.Logger
+}
+
+// newRatingNotifier starts a notifier delivering to url and reporting
+// failures to logger. It returns nil when url is empty, and a nil notifier
+// ignores every rating.
+func newRatingNotifier(url string, logger logging.Logger) *ratingNotifier {
+	if url == "" {
+		return nil
+	}
+
+	n := &ratingNotifier{
+		url:     url,
+		client:  &http.Client{Timeout: ratingWebhookTimeout},
+		queue:   make(chan models.Rating, ratingWebhookQueueSize),
+		backoff: ratingWebhookBackoff,
+		logger:  logger,
+	}
+	go n.run()
+	return n
+}
+
+// enqueue hands a rating to the worker without waiting for delivery
+func (n *ratingNotifier) enqueue(rating models.Rating) {
+	if n == nil {
+		return
+	}
+
+	select {
+	case n.queue <- rating:
+	default:
+		n.logger.Printf("Rating webhook queue full, dropping notification for rating %d", rating.ID)
+	}
+}
+
+// run delivers queued ratings one at a time
+func (n *ratingNotifier) run() {
+	for rating := range n.queue {
+		n.deliver(rating)
+	}
+}
+
+// deliver posts a rating, retrying with exponential backoff up to
+// ratingWebhookAttempts times before giving up
+func (n *ratingNotifier) deliver(rating models.Rating) {
+	body, err := json.Marshal(rating)
+	if err != nil {
+		n.logger.Errorf("Failed to encode rating %d for webhook: %v", rating.ID, err)
+		return
+	}
+
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		err := n.post(body)
+		if err == nil {
+			return
+		}
+		if attempt == ratingWebhookAttempts {
+			n.logger.Errorf("Giving up on rating webhook for rating %d after %d attempts: %v", rating.ID, attempt, err)
+			return
+		}
+		n.logger.Printf("Rating webhook for rating %d failed (attempt %d): %v", rating.ID, attempt, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post sends one delivery attempt, treating non-2xx responses as failures
+func (n *ratingNotifier) post(body []byte) error {
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// notifyRating queues a new rating for the rating webhook. It is a no-op when
+// Config.RatingWebhookURL is unset.
+func (s *Server) notifyRating(rating models.Rating) {
+	s.notifier.enqueue(rating)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/models"
+	"github.com/gorilla/mux"
+)
+
+func TestRatingWebhook(t *testing.T) {
+	// The receiver fails the first delivery so the retry path is exercised
+	var attempts atomic.Int32
+	received := make(chan models.Rating, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var rating models.Rating
+		if err := json.NewDecoder(r.Body).Decode(&rating); err != nil {
+			t.Errorf("Failed to decode webhook body: %v", err)
+		}
+		received <- rating
+	}))
+	defer receiver.Close()
+
+	logger := &recordingLogger{}
+	config := DefaultConfig()
+	config.RatingWebhookURL = receiver.URL
+	config.Logger = logger
+	server := NewServerWithConfig(setupTestServer(t).db, config)
+	server.notifier.backoff = time.Millisecond
+
+	conv, err := server.db.CreateConversation("webhook-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/conversations/{id}/ratings", server.CreateConversationRatingHandler)
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("/conversations/%d/ratings", conv.ID), strings.NewReader(`{"rating": 1, "comment": "Wrong answer"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+	}
+
+	select {
+	case rating := <-received:
+		if rating.Rating != 1 || rating.ConversationID == nil || *rating.ConversationID != conv.ID {
+			t.Errorf("Expected a 1-star rating for conversation %d, got %+v", conv.ID, rating)
+		}
+		if rating.Comment == nil || *rating.Comment != "Wrong answer" {
+			t.Errorf("Expected the rating comment in the webhook, got %v", rating.Comment)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the rating webhook")
+	}
+
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("Expected 2 delivery attempts, got %d", got)
+	}
+	if !logger.contains("failed (attempt 1): unexpected status 503") {
+		t.Errorf("Expected the failed attempt to be logged, got %q", logger.messages)
+	}
+}
+
+func TestRatingWebhookDisabled(t *testing.T) {
+	server := setupTestServer(t)
+
+	if server.notifier != nil {
+		t.Fatal("Expected no notifier without a webhook URL")
+	}
+
+	// Must not panic or block
+	server.notifyRating(models.Rating{ID: 1, Rating: 5})
+}
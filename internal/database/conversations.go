@@ -3,20 +3,45 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/claude-code-template/prompt-manager/internal/validation"
 )
 
 // Conversation represents a conversation record
 type Conversation struct {
-	ID               int       `json:"id"`
-	SessionID        string    `json:"session_id"`
-	Title            *string   `json:"title"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
-	PromptCount      int       `json:"prompt_count"`
-	TotalCharacters  int       `json:"total_characters"`
-	WorkingDirectory *string   `json:"working_directory"`
-	TranscriptPath   *string   `json:"transcript_path"`
+	ID               int        `json:"id"`
+	SessionID        string     `json:"session_id"`
+	Title            *string    `json:"title"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	PromptCount      int        `json:"prompt_count"`
+	TotalCharacters  int        `json:"total_characters"`
+	WorkingDirectory *string    `json:"working_directory"`
+	TranscriptPath   *string    `json:"transcript_path"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+	Tags             []Tag      `json:"tags,omitempty"`
+	// AvgResponseTimeMs is the mean execution time of the conversation's
+	// timed responses. It is only populated by listings and is nil when no
+	// response carries an execution time.
+	AvgResponseTimeMs *float64 `json:"avg_response_time_ms,omitempty"`
+	// AvgRating scores the conversation's ratings with the configured
+	// RatingAggregation. It is only populated by listings and is nil when
+	// the conversation is unrated.
+	AvgRating *float64 `json:"avg_rating,omitempty"`
+}
+
+// conversationListColumns selects a conversation row for listings, including
+// the average response time and the rating score under the configured
+// aggregation, both computed by correlated subqueries
+func (db *DB) conversationListColumns() string {
+	return `id, session_id, title, created_at, updated_at, prompt_count, total_characters, working_directory, transcript_path, deleted_at,
+		(SELECT AVG(m.execution_time) FROM messages m
+			WHERE m.conversation_id = conversations.id
+			AND m.message_type = 'response' AND m.execution_time IS NOT NULL),
+		` + ratingScoreExpr(db.ratingAggregation(), "conversations.id")
 }
 
 // Message represents a message record
@@ -29,6 +54,7 @@ type Message struct {
 	Timestamp      time.Time `json:"timestamp"`
 	ToolCalls      *string   `json:"tool_calls"`
 	ExecutionTime  *int      `json:"execution_time"`
+	Model          *string   `json:"model"`
 }
 
 // ConversationWithMessages includes messages in the conversation
@@ -37,29 +63,49 @@ type ConversationWithMessages struct {
 	Messages []Message `json:"messages"`
 }
 
-// CreateConversation inserts a new conversation
+// CreateConversation inserts a new conversation. MaxConversationsPerSession
+// is enforced by the insert itself, so concurrent calls cannot take a session
+// past the limit; when it blocks the insert ErrSessionConversationLimit is
+// returned.
 func (db *DB) CreateConversation(sessionID string, title *string, workingDir *string, transcriptPath *string) (*Conversation, error) {
-	query := `
+	defer db.observe("create_conversation", time.Now())
+
+	limit := db.config.MaxConversationsPerSession
+	insert := `
 	INSERT INTO conversations (session_id, title, working_directory, transcript_path)
-	VALUES (?, ?, ?, ?)
-	RETURNING id, session_id, title, created_at, updated_at, prompt_count, total_characters, working_directory, transcript_path`
+	SELECT ?, ?, ?, ?
+	WHERE ? <= 0 OR (
+		SELECT COUNT(*) FROM conversations
+		WHERE session_id = ? AND deleted_at IS NULL) < ?`
+	args := []interface{}{sessionID, title, workingDir, transcriptPath, limit, sessionID, limit}
+
+	query := insert + `
+	RETURNING id, session_id, title, created_at, updated_at, prompt_count, total_characters, working_directory, transcript_path, deleted_at`
 
 	var conv Conversation
-	err := db.conn.QueryRow(query, sessionID, title, workingDir, transcriptPath).Scan(
+	err := db.conn.QueryRow(query, args...).Scan(
 		&conv.ID, &conv.SessionID, &conv.Title, &conv.CreatedAt, &conv.UpdatedAt,
-		&conv.PromptCount, &conv.TotalCharacters, &conv.WorkingDirectory, &conv.TranscriptPath,
+		&conv.PromptCount, &conv.TotalCharacters, &conv.WorkingDirectory, &conv.TranscriptPath, &conv.DeletedAt,
 	)
-	
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionConversationLimit
+	}
+
 	if err != nil {
 		// Fallback for SQLite versions that don't support RETURNING
-		result, err := db.conn.Exec(
-			"INSERT INTO conversations (session_id, title, working_directory, transcript_path) VALUES (?, ?, ?, ?)",
-			sessionID, title, workingDir, transcriptPath,
-		)
+		result, err := db.conn.Exec(insert, args...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to insert conversation: %w", err)
 		}
 
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if inserted == 0 {
+			return nil, ErrSessionConversationLimit
+		}
+
 		id, err := result.LastInsertId()
 		if err != nil {
 			return nil, fmt.Errorf("failed to get last insert ID: %w", err)
@@ -72,16 +118,125 @@ func (db *DB) CreateConversation(sessionID string, title *string, workingDir *st
 	return &conv, nil
 }
 
-// GetConversation retrieves a conversation by ID
+// GetOrCreateConversationBySessionID returns the session's live conversation,
+// creating it first if there is none. The insert is guarded so that
+// concurrent calls for a new session create exactly one conversation.
+// When ConversationReuseWindow is set, a conversation whose last activity is
+// older than the window is left alone and a new one is started. The title is
+// only used when a conversation is created, which the returned bool reports.
+// MaxConversationsPerSession is enforced by the same guarded insert, so
+// concurrent calls cannot take a session past the limit; when it blocks the
+// insert ErrSessionConversationLimit is returned.
+func (db *DB) GetOrCreateConversationBySessionID(sessionID string, title *string, workingDir *string, transcriptPath *string) (*Conversation, bool, error) {
+	defer db.observe("get_or_create_conversation", time.Now())
+
+	query := `
+	INSERT INTO conversations (session_id, title, working_directory, transcript_path)
+	SELECT ?, ?, ?, ?
+	WHERE NOT EXISTS (
+		SELECT 1 FROM conversations
+		WHERE session_id = ? AND deleted_at IS NULL
+		AND (? IS NULL OR updated_at >= ?))
+	AND (? <= 0 OR (
+		SELECT COUNT(*) FROM conversations
+		WHERE session_id = ? AND deleted_at IS NULL) < ?)`
+
+	var activeSince interface{}
+	if window := db.config.ConversationReuseWindow; window > 0 {
+		activeSince = time.Now().Add(-window).UTC().Format(sqliteTimestampLayout)
+	}
+	limit := db.config.MaxConversationsPerSession
+
+	result, err := db.conn.Exec(query, sessionID, title, workingDir, transcriptPath,
+		sessionID, activeSince, activeSince, limit, sessionID, limit)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to insert conversation: %w", err)
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if inserted == 0 && limit > 0 {
+		// Nothing was inserted either because a reusable conversation exists
+		// or because the limit blocked a new one
+		var reusable bool
+		err := db.conn.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM conversations
+			WHERE session_id = ? AND deleted_at IS NULL
+			AND (? IS NULL OR updated_at >= ?))`, sessionID, activeSince, activeSince).Scan(&reusable)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to check for a reusable conversation: %w", err)
+		}
+		if !reusable {
+			return nil, false, ErrSessionConversationLimit
+		}
+	}
+
+	conv, err := db.GetConversationBySessionID(sessionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return conv, inserted > 0, nil
+}
+
+// checkSessionCharacterQuota returns ErrSessionQuotaExceeded when adding
+// characters to the conversation would take its session's live conversations
+// past the configured character quota
+func (db *DB) checkSessionCharacterQuota(tx *sql.Tx, conversationID, characters int) error {
+	quota := db.config.MaxCharactersPerSession
+	if quota <= 0 {
+		return nil
+	}
+
+	query := `
+	SELECT COALESCE(SUM(total_characters), 0)
+	FROM conversations
+	WHERE session_id = (SELECT session_id FROM conversations WHERE id = ?) AND deleted_at IS NULL`
+
+	var total int
+	if err := tx.QueryRow(query, conversationID).Scan(&total); err != nil {
+		return fmt.Errorf("failed to sum session characters: %w", err)
+	}
+
+	if total+characters > quota {
+		return ErrSessionQuotaExceeded
+	}
+
+	return nil
+}
+
+// GetConversation retrieves a conversation by ID. Soft-deleted conversations
+// are reported as ErrConversationNotFound.
 func (db *DB) GetConversation(id int) (*Conversation, error) {
+	defer db.observe("get_conversation", time.Now())
+
+	return db.getConversation(id, false)
+}
+
+// GetConversationIncludingDeleted retrieves a conversation by ID whether or
+// not it has been soft-deleted
+func (db *DB) GetConversationIncludingDeleted(id int) (*Conversation, error) {
+	return db.getConversation(id, true)
+}
+
+// getConversation retrieves a conversation by ID, optionally matching
+// soft-deleted rows
+func (db *DB) getConversation(id int, includeDeleted bool) (*Conversation, error) {
 	query := `
-	SELECT id, session_id, title, created_at, updated_at, prompt_count, total_characters, working_directory, transcript_path
+	SELECT id, session_id, title, created_at, updated_at, prompt_count, total_characters, working_directory, transcript_path, deleted_at
 	FROM conversations WHERE id = ?`
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
 
 	var conv Conversation
 	err := db.conn.QueryRow(query, id).Scan(
 		&conv.ID, &conv.SessionID, &conv.Title, &conv.CreatedAt, &conv.UpdatedAt,
-		&conv.PromptCount, &conv.TotalCharacters, &conv.WorkingDirectory, &conv.TranscriptPath,
+		&conv.PromptCount, &conv.TotalCharacters, &conv.WorkingDirectory, &conv.TranscriptPath, &conv.DeletedAt,
 	)
 	
 	if err != nil {
@@ -94,16 +249,19 @@ func (db *DB) GetConversation(id int) (*Conversation, error) {
 	return &conv, nil
 }
 
-// GetConversationBySessionID retrieves a conversation by session ID
+// GetConversationBySessionID retrieves a session's live conversation. When a
+// session has several, the most recently created one is returned.
 func (db *DB) GetConversationBySessionID(sessionID string) (*Conversation, error) {
 	query := `
-	SELECT id, session_id, title, created_at, updated_at, prompt_count, total_characters, working_directory, transcript_path
-	FROM conversations WHERE session_id = ?`
+	SELECT id, session_id, title, created_at, updated_at, prompt_count, total_characters, working_directory, transcript_path, deleted_at
+	FROM conversations WHERE session_id = ? AND deleted_at IS NULL
+	ORDER BY id DESC
+	LIMIT 1`
 
 	var conv Conversation
 	err := db.conn.QueryRow(query, sessionID).Scan(
 		&conv.ID, &conv.SessionID, &conv.Title, &conv.CreatedAt, &conv.UpdatedAt,
-		&conv.PromptCount, &conv.TotalCharacters, &conv.WorkingDirectory, &conv.TranscriptPath,
+		&conv.PromptCount, &conv.TotalCharacters, &conv.WorkingDirectory, &conv.TranscriptPath, &conv.DeletedAt,
 	)
 	
 	if err != nil {
@@ -118,6 +276,15 @@ func (db *DB) GetConversationBySessionID(sessionID string) (*Conversation, error
 
 // GetConversationWithMessages retrieves a conversation with its messages
 func (db *DB) GetConversationWithMessages(id int) (*ConversationWithMessages, error) {
+	return db.GetConversationWithMessagesByType(id, "", MessageOrderAsc)
+}
+
+// GetConversationWithMessagesByType retrieves a conversation with only its
+// messages of the given type, in the given order; an empty type includes
+// every message. The conversation's counts always cover all of its messages.
+func (db *DB) GetConversationWithMessagesByType(id int, messageType string, order MessageOrder) (*ConversationWithMessages, error) {
+	defer db.observe("get_conversation_with_messages", time.Now())
+
 	// Get conversation
 	conv, err := db.GetConversation(id)
 	if err != nil {
@@ -125,23 +292,32 @@ func (db *DB) GetConversationWithMessages(id int) (*ConversationWithMessages, er
 	}
 
 	// Get messages
-	messages, err := db.GetMessagesByConversation(id)
+	messages, err := db.GetMessagesByConversationAndType(id, messageType, order)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get messages: %w", err)
 	}
 
+	// Get tags
+	tags, err := db.GetConversationTags(id)
+	if err != nil {
+		return nil, err
+	}
+	conv.Tags = tags
+
 	return &ConversationWithMessages{
 		Conversation: *conv,
 		Messages:     messages,
 	}, nil
 }
 
-// GetConversationCount returns the total number of conversations
-func (db *DB) GetConversationCount() (int, error) {
-	query := "SELECT COUNT(*) FROM conversations"
+// GetConversationCount returns the number of conversations matching the filter.
+// A nil filter counts every conversation that has not been soft-deleted.
+func (db *DB) GetConversationCount(filter *ConversationFilter) (int, error) {
+	where, args := filter.whereClause()
+	query := "SELECT COUNT(*) FROM conversations" + where
 	
 	var count int
-	err := db.conn.QueryRow(query).Scan(&count)
+	err := db.conn.QueryRow(query, args...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get conversation count: %w", err)
 	}
@@ -149,18 +325,103 @@ func (db *DB) GetConversationCount() (int, error) {
 	return count, nil
 }
 
-// ListConversations retrieves conversations with pagination
-func (db *DB) ListConversations(limit, offset int) ([]Conversation, error) {
+// ListConversations retrieves conversations matching the filter with pagination.
+// A nil filter lists every conversation that has not been soft-deleted.
+func (db *DB) ListConversations(filter *ConversationFilter, limit, offset int) ([]Conversation, error) {
+	defer db.observe("list_conversations", time.Now())
+
+	where, args := filter.whereClause()
 	query := `
-	SELECT id, session_id, title, created_at, updated_at, prompt_count, total_characters, working_directory, transcript_path
-	FROM conversations 
-	ORDER BY updated_at DESC
+	SELECT ` + db.conversationListColumns() + `
+	FROM conversations` + where + `
+	` + filter.orderByClause(db.ratingAggregation()) + `
 	LIMIT ? OFFSET ?`
 
-	rows, err := db.conn.Query(query, limit, offset)
+	conversations, err := db.queryConversations(query, append(args, limit, offset)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list conversations: %w", err)
 	}
+
+	if err := db.attachConversationTags(conversations); err != nil {
+		return nil, err
+	}
+
+	return conversations, nil
+}
+
+// ListConversationsBySession retrieves every conversation recorded for a session
+// in chronological order
+func (db *DB) ListConversationsBySession(sessionID string) ([]Conversation, error) {
+	query := `
+	SELECT ` + db.conversationListColumns() + `
+	FROM conversations
+	WHERE session_id = ? AND deleted_at IS NULL
+	ORDER BY created_at ASC, id ASC`
+
+	conversations, err := db.queryConversations(query, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations by session: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// ListLinkedConversations retrieves the other conversations sharing the given
+// conversation's transcript path, in chronological order. It returns no
+// conversations unless LinkByTranscriptPath is enabled.
+func (db *DB) ListLinkedConversations(id int) ([]Conversation, error) {
+	conv, err := db.GetConversation(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !db.config.LinkByTranscriptPath || conv.TranscriptPath == nil || *conv.TranscriptPath == "" {
+		return []Conversation{}, nil
+	}
+
+	query := `
+	SELECT ` + db.conversationListColumns() + `
+	FROM conversations
+	WHERE transcript_path = ? AND id != ? AND deleted_at IS NULL
+	ORDER BY created_at ASC, id ASC`
+
+	conversations, err := db.queryConversations(query, *conv.TranscriptPath, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list linked conversations: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// ListConversationsByTag retrieves conversations carrying a tag with pagination
+func (db *DB) ListConversationsByTag(tagID, limit, offset int) ([]Conversation, error) {
+	return db.ListConversations(&ConversationFilter{TagIDs: []int{tagID}}, limit, offset)
+}
+
+// GetConversationCountByTag returns the number of conversations carrying a tag
+func (db *DB) GetConversationCountByTag(tagID int) (int, error) {
+	return db.GetConversationCount(&ConversationFilter{TagIDs: []int{tagID}})
+}
+
+// ListConversationsByWorkingDirectory retrieves the conversations recorded in
+// a directory with pagination, across every session that used it
+func (db *DB) ListConversationsByWorkingDirectory(dir string, limit, offset int) ([]Conversation, error) {
+	return db.ListConversations(&ConversationFilter{WorkingDirectory: dir}, limit, offset)
+}
+
+// GetConversationCountByWorkingDirectory returns the number of conversations
+// recorded in a directory
+func (db *DB) GetConversationCountByWorkingDirectory(dir string) (int, error) {
+	return db.GetConversationCount(&ConversationFilter{WorkingDirectory: dir})
+}
+
+// queryConversations runs a conversation query selecting
+// conversationListColumns and scans every row
+func (db *DB) queryConversations(query string, args ...interface{}) ([]Conversation, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var conversations []Conversation
@@ -168,7 +429,8 @@ func (db *DB) ListConversations(limit, offset int) ([]Conversation, error) {
 		var conv Conversation
 		err := rows.Scan(
 			&conv.ID, &conv.SessionID, &conv.Title, &conv.CreatedAt, &conv.UpdatedAt,
-			&conv.PromptCount, &conv.TotalCharacters, &conv.WorkingDirectory, &conv.TranscriptPath,
+			&conv.PromptCount, &conv.TotalCharacters, &conv.WorkingDirectory, &conv.TranscriptPath, &conv.DeletedAt,
+			&conv.AvgResponseTimeMs, &conv.AvgRating,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan conversation: %w", err)
@@ -176,48 +438,93 @@ func (db *DB) ListConversations(limit, offset int) ([]Conversation, error) {
 		conversations = append(conversations, conv)
 	}
 
-	return conversations, nil
+	return conversations, rows.Err()
 }
 
 // UpdateConversationTitle updates the title of a conversation
 func (db *DB) UpdateConversationTitle(id int, title string) error {
-	query := "UPDATE conversations SET title = ? WHERE id = ?"
-	result, err := db.conn.Exec(query, title, id)
+	return db.UpdateConversationMetadata(id, &title, nil, nil)
+}
+
+// AutoTitleMaxLength is the number of characters kept from a prompt's first
+// line when it becomes a conversation's title
+const AutoTitleMaxLength = 80
+
+// AutoTitleConversation titles an untitled conversation after the first line
+// of its first prompt. Conversations that already have a title are left as
+// they are.
+func (db *DB) AutoTitleConversation(convID int, firstPrompt string) error {
+	title := titleFromPrompt(firstPrompt)
+	if title == "" {
+		return nil
+	}
+
+	result, err := db.conn.Exec(
+		"UPDATE conversations SET title = ? WHERE id = ? AND (title IS NULL OR title = '') AND deleted_at IS NULL",
+		title, convID,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to update conversation title: %w", err)
+		return fmt.Errorf("failed to auto-title conversation: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("failed to get affected rows: %w", err)
 	}
-
 	if rowsAffected == 0 {
-		return ErrConversationNotFound
+		// Either the conversation is already titled or it does not exist
+		_, err := db.GetConversation(convID)
+		return err
 	}
 
 	return nil
 }
 
-// DeleteConversation deletes a conversation and its messages
-func (db *DB) DeleteConversation(id int) error {
-	// Start transaction
-	tx, err := db.conn.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+// titleFromPrompt derives a title from the first non-blank line of a prompt,
+// cut to AutoTitleMaxLength characters
+func titleFromPrompt(prompt string) string {
+	for _, line := range strings.Split(prompt, "\n") {
+		line = validation.CollapseWhitespace(validation.SanitizeString(line, validation.MaxContentLength))
+		if line == "" {
+			continue
+		}
+		if utf8.RuneCountInString(line) > AutoTitleMaxLength {
+			line = strings.TrimSpace(string([]rune(line)[:AutoTitleMaxLength-3])) + "..."
+		}
+		return validation.SanitizeString(line, validation.MaxTitleLength)
 	}
-	defer tx.Rollback()
+	return ""
+}
 
-	// Delete messages first (due to foreign key)
-	_, err = tx.Exec("DELETE FROM messages WHERE conversation_id = ?", id)
-	if err != nil {
-		return fmt.Errorf("failed to delete messages: %w", err)
+// UpdateConversationMetadata updates a conversation's title, working directory
+// and transcript path. Only non-nil fields are changed. Soft-deleted
+// conversations are reported as not found.
+func (db *DB) UpdateConversationMetadata(id int, title, workingDir, transcriptPath *string) error {
+	var assignments []string
+	var args []interface{}
+
+	if title != nil {
+		assignments = append(assignments, "title = ?")
+		args = append(args, *title)
+	}
+	if workingDir != nil {
+		assignments = append(assignments, "working_directory = ?")
+		args = append(args, *workingDir)
+	}
+	if transcriptPath != nil {
+		assignments = append(assignments, "transcript_path = ?")
+		args = append(args, *transcriptPath)
 	}
 
-	// Delete conversation
-	result, err := tx.Exec("DELETE FROM conversations WHERE id = ?", id)
+	if len(assignments) == 0 {
+		_, err := db.GetConversation(id)
+		return err
+	}
+
+	query := "UPDATE conversations SET " + strings.Join(assignments, ", ") + " WHERE id = ? AND deleted_at IS NULL"
+	result, err := db.conn.Exec(query, append(args, id)...)
 	if err != nil {
-		return fmt.Errorf("failed to delete conversation: %w", err)
+		return fmt.Errorf("failed to update conversation: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -229,29 +536,225 @@ func (db *DB) DeleteConversation(id int) error {
 		return ErrConversationNotFound
 	}
 
-	return tx.Commit()
+	return nil
+}
+
+// DeleteConversation soft-deletes a conversation by stamping deleted_at. Its
+// messages are kept so the conversation can be restored.
+func (db *DB) DeleteConversation(id int) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		query := "UPDATE conversations SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL"
+		result, err := tx.Exec(query, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete conversation: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return ErrConversationNotFound
+		}
+
+		return db.deleteEmptySession(tx, id)
+	})
+}
+
+// deleteEmptySession removes the session row of a just-deleted conversation
+// when DeleteEmptySessions is enabled and the session has no live
+// conversations left
+func (db *DB) deleteEmptySession(tx *sql.Tx, conversationID int) error {
+	if !db.config.DeleteEmptySessions {
+		return nil
+	}
+
+	query := `
+	DELETE FROM sessions
+	WHERE session_id = (SELECT session_id FROM conversations WHERE id = ?)
+	AND NOT EXISTS (
+		SELECT 1 FROM conversations c
+		WHERE c.session_id = sessions.session_id AND c.deleted_at IS NULL
+	)`
+
+	if _, err := tx.Exec(query, conversationID); err != nil {
+		return fmt.Errorf("failed to delete empty session: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteConversations soft-deletes several conversations in a single
+// transaction, returning how many of the given IDs were deleted.
+// IDs that do not exist or are already deleted are skipped rather than
+// treated as errors. The rows stay in place until they are purged.
+func (db *DB) DeleteConversations(ids []int) (int, error) {
+	deleted := 0
+	err := db.WithTx(func(tx *sql.Tx) error {
+		for _, id := range ids {
+			result, err := tx.Exec(
+				"UPDATE conversations SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", id,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to delete conversation: %w", err)
+			}
+
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to get affected rows: %w", err)
+			}
+			deleted += int(rowsAffected)
+
+			if rowsAffected > 0 {
+				if err := db.deleteEmptySession(tx, id); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// PurgeDeletedConversations permanently removes conversations soft-deleted at
+// or before the cutoff, along with their messages, ratings and tag links, and
+// returns how many were removed. When more than IncrementalVacuumThreshold
+// conversations are purged the freed pages are returned to the filesystem; a
+// failure there is returned alongside the count of the committed purge.
+func (db *DB) PurgeDeletedConversations(before time.Time) (int, error) {
+	defer db.observe("purge_deleted_conversations", time.Now())
+
+	cutoff := before.UTC().Format(sqliteTimestampLayout)
+	purgeable := "SELECT id FROM conversations WHERE deleted_at IS NOT NULL AND deleted_at <= ?"
+
+	// Dependent rows are removed explicitly rather than relying on foreign
+	// key cascades being enabled on the connection
+	var purged int64
+	err := db.WithTx(func(tx *sql.Tx) error {
+		dependents := []string{
+			"DELETE FROM ratings WHERE message_id IN (SELECT id FROM messages WHERE conversation_id IN (" + purgeable + "))",
+			"DELETE FROM ratings WHERE conversation_id IN (" + purgeable + ")",
+			"DELETE FROM conversation_tags WHERE conversation_id IN (" + purgeable + ")",
+			"DELETE FROM messages WHERE conversation_id IN (" + purgeable + ")",
+		}
+		for _, query := range dependents {
+			if _, err := tx.Exec(query, cutoff); err != nil {
+				return fmt.Errorf("failed to purge conversation data: %w", err)
+			}
+		}
+
+		result, err := tx.Exec("DELETE FROM conversations WHERE id IN ("+purgeable+")", cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to purge conversations: %w", err)
+		}
+		purged, err = result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if threshold := db.config.IncrementalVacuumThreshold; threshold > 0 && int(purged) > threshold {
+		if err := db.incrementalVacuum(); err != nil {
+			return int(purged), err
+		}
+	}
+
+	return int(purged), nil
+}
+
+// RestoreConversation clears a conversation's soft deletion. Restoring a
+// conversation that is not deleted is a no-op.
+func (db *DB) RestoreConversation(id int) error {
+	if _, err := db.GetConversationIncludingDeleted(id); err != nil {
+		return err
+	}
+
+	_, err := db.conn.Exec("UPDATE conversations SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return fmt.Errorf("failed to restore conversation: %w", err)
+	}
+
+	return nil
 }
 
 // CreateMessage inserts a new message
 func (db *DB) CreateMessage(conversationID int, messageType, content string, toolCalls *string, executionTime *int) (*Message, error) {
+	return db.CreateMessageWithModel(conversationID, messageType, content, toolCalls, executionTime, nil)
+}
+
+// CreateMessageWithModel inserts a new message attributed to the model that
+// produced it
+func (db *DB) CreateMessageWithModel(conversationID int, messageType, content string, toolCalls *string, executionTime *int, model *string) (*Message, error) {
+	return db.CreateMessageFromInput(conversationID, MessageInput{
+		MessageType:   messageType,
+		Content:       content,
+		ToolCalls:     toolCalls,
+		ExecutionTime: executionTime,
+		Model:         model,
+	})
+}
+
+// CreateMessageFromInput inserts a new message. A nil Timestamp uses the
+// current server time. The session's character quota is checked, and the
+// conversation's stats updated and matching tag rules applied, in the same
+// transaction.
+func (db *DB) CreateMessageFromInput(conversationID int, input MessageInput) (*Message, error) {
+	defer db.observe("create_message", time.Now())
+
+	messageType, content := input.MessageType, input.Content
+	toolCalls, executionTime, model := input.ToolCalls, input.ExecutionTime, input.Model
 	characterCount := len(content)
+
+	var timestamp interface{}
+	if input.Timestamp != nil {
+		timestamp = input.Timestamp.UTC().Format(sqliteTimestampLayout)
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Check up front so a missing conversation is reported as such rather
+	// than as a foreign key violation on the insert
+	var exists int
+	if err := tx.QueryRow("SELECT 1 FROM conversations WHERE id = ?", conversationID).Scan(&exists); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrConversationNotFound
+		}
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	if err := db.checkSessionCharacterQuota(tx, conversationID, characterCount); err != nil {
+		return nil, err
+	}
 	
 	query := `
-	INSERT INTO messages (conversation_id, message_type, content, character_count, tool_calls, execution_time)
-	VALUES (?, ?, ?, ?, ?, ?)
-	RETURNING id, conversation_id, message_type, content, character_count, timestamp, tool_calls, execution_time`
+	INSERT INTO messages (conversation_id, message_type, content, character_count, tool_calls, execution_time, model, timestamp)
+	VALUES (?, ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))
+	RETURNING id, conversation_id, message_type, content, character_count, timestamp, tool_calls, execution_time, model`
 
 	var msg Message
-	err := db.conn.QueryRow(query, conversationID, messageType, content, characterCount, toolCalls, executionTime).Scan(
+	err = tx.QueryRow(query, conversationID, messageType, content, characterCount, toolCalls, executionTime, model, timestamp).Scan(
 		&msg.ID, &msg.ConversationID, &msg.MessageType, &msg.Content,
-		&msg.CharacterCount, &msg.Timestamp, &msg.ToolCalls, &msg.ExecutionTime,
+		&msg.CharacterCount, &msg.Timestamp, &msg.ToolCalls, &msg.ExecutionTime, &msg.Model,
 	)
 	
 	if err != nil {
 		// Fallback for SQLite versions that don't support RETURNING
-		result, err := db.conn.Exec(
-			"INSERT INTO messages (conversation_id, message_type, content, character_count, tool_calls, execution_time) VALUES (?, ?, ?, ?, ?, ?)",
-			conversationID, messageType, content, characterCount, toolCalls, executionTime,
+		result, err := tx.Exec(
+			"INSERT INTO messages (conversation_id, message_type, content, character_count, tool_calls, execution_time, model, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))",
+			conversationID, messageType, content, characterCount, toolCalls, executionTime, model, timestamp,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to insert message: %w", err)
@@ -263,7 +766,50 @@ func (db *DB) CreateMessage(conversationID int, messageType, content string, too
 		}
 
 		// Fetch the created message
-		return db.GetMessage(int(id))
+		err = tx.QueryRow(`
+		SELECT id, conversation_id, message_type, content, character_count, timestamp, tool_calls, execution_time, model
+		FROM messages WHERE id = ?`, id).Scan(
+			&msg.ID, &msg.ConversationID, &msg.MessageType, &msg.Content,
+			&msg.CharacterCount, &msg.Timestamp, &msg.ToolCalls, &msg.ExecutionTime, &msg.Model,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get created message: %w", err)
+		}
+	}
+
+	// Only prompts count towards prompt_count; every message adds characters
+	promptIncrement := 0
+	if messageType == "prompt" {
+		promptIncrement = 1
+	}
+
+	result, err := tx.Exec(`
+	UPDATE conversations
+	SET prompt_count = prompt_count + ?,
+		total_characters = total_characters + ?,
+		updated_at = CURRENT_TIMESTAMP
+	WHERE id = ?`,
+		promptIncrement, characterCount, conversationID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update conversation stats: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrConversationNotFound
+	}
+
+	if _, err := applyTagRules(tx, conversationID, content); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return &msg, nil
@@ -272,18 +818,18 @@ func (db *DB) CreateMessage(conversationID int, messageType, content string, too
 // GetMessage retrieves a message by ID
 func (db *DB) GetMessage(id int) (*Message, error) {
 	query := `
-	SELECT id, conversation_id, message_type, content, character_count, timestamp, tool_calls, execution_time
+	SELECT id, conversation_id, message_type, content, character_count, timestamp, tool_calls, execution_time, model
 	FROM messages WHERE id = ?`
 
 	var msg Message
 	err := db.conn.QueryRow(query, id).Scan(
 		&msg.ID, &msg.ConversationID, &msg.MessageType, &msg.Content,
-		&msg.CharacterCount, &msg.Timestamp, &msg.ToolCalls, &msg.ExecutionTime,
+		&msg.CharacterCount, &msg.Timestamp, &msg.ToolCalls, &msg.ExecutionTime, &msg.Model,
 	)
 	
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("message not found")
+			return nil, ErrMessageNotFound
 		}
 		return nil, fmt.Errorf("failed to get message: %w", err)
 	}
@@ -293,13 +839,25 @@ func (db *DB) GetMessage(id int) (*Message, error) {
 
 // GetMessagesByConversation retrieves all messages for a conversation
 func (db *DB) GetMessagesByConversation(conversationID int) ([]Message, error) {
+	return db.GetMessagesByConversationAndType(conversationID, "", MessageOrderAsc)
+}
+
+// GetMessagesByConversationAndType retrieves a conversation's messages of the
+// given type; an empty type includes every message. Messages are returned
+// oldest first unless order is MessageOrderDesc.
+func (db *DB) GetMessagesByConversationAndType(conversationID int, messageType string, order MessageOrder) ([]Message, error) {
+	direction := "ASC"
+	if order == MessageOrderDesc {
+		direction = "DESC"
+	}
+
 	query := `
-	SELECT id, conversation_id, message_type, content, character_count, timestamp, tool_calls, execution_time
+	SELECT id, conversation_id, message_type, content, character_count, timestamp, tool_calls, execution_time, model
 	FROM messages 
-	WHERE conversation_id = ?
-	ORDER BY timestamp ASC`
+	WHERE conversation_id = ? AND (? = '' OR message_type = ?)
+	ORDER BY timestamp ` + direction + `, id ` + direction
 
-	rows, err := db.conn.Query(query, conversationID)
+	rows, err := db.conn.Query(query, conversationID, messageType, messageType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get messages: %w", err)
 	}
@@ -310,7 +868,7 @@ func (db *DB) GetMessagesByConversation(conversationID int) ([]Message, error) {
 		var msg Message
 		err := rows.Scan(
 			&msg.ID, &msg.ConversationID, &msg.MessageType, &msg.Content,
-			&msg.CharacterCount, &msg.Timestamp, &msg.ToolCalls, &msg.ExecutionTime,
+			&msg.CharacterCount, &msg.Timestamp, &msg.ToolCalls, &msg.ExecutionTime, &msg.Model,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan message: %w", err)
@@ -1,73 +1,205 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+	"github.com/claude-code-template/prompt-manager/internal/metrics"
+	"github.com/claude-code-template/prompt-manager/internal/models"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // DB wraps the database connection with additional functionality
 type DB struct {
-	conn *sql.DB
-	path string
+	conn   *sql.DB
+	path   string
+	config *Config
 }
 
 // Config holds database configuration
 type Config struct {
-	DatabasePath    string
-	MigrationsDir   string
-	MaxOpenConns    int
-	MaxIdleConns    int
+	// DatabasePath is the SQLite file to open, or MemoryPath
+	DatabasePath string
+
+	// MigrationsDir holds the SQL migration files applied by RunMigrations
+	MigrationsDir string
+
+	// MaxOpenConns and MaxIdleConns size the connection pool. SQLite works
+	// best with a single writer, so both default to one.
+	MaxOpenConns int
+	MaxIdleConns int
+
+	// ConnMaxLifetime and ConnMaxIdleTime recycle pooled connections. Zero
+	// keeps a connection for as long as the pool does.
 	ConnMaxLifetime time.Duration
 	ConnMaxIdleTime time.Duration
-	BusyTimeout     time.Duration
-	WALMode         bool
-	Synchronous     string
-	CacheSize       int
+
+	// BusyTimeout is how long a statement waits for another connection's
+	// lock before failing with SQLITE_BUSY
+	BusyTimeout time.Duration
+
+	// WALMode opens the database in write-ahead logging mode, letting
+	// readers proceed while a write is in progress. It needs a database file.
+	WALMode bool
+
+	// Synchronous is the PRAGMA synchronous mode (OFF, NORMAL, FULL or
+	// EXTRA), trading durability against write speed
+	Synchronous string
+
+	// CacheSize is the page cache size in kibibytes
+	CacheSize int
+
+	// IncludeDeletedInStats counts soft-deleted conversations in aggregate stats
+	IncludeDeletedInStats bool
+
+	// MaxConversationsPerSession caps how many conversations a single session
+	// may create, catching runaway integrations. Zero means unlimited.
+	MaxConversationsPerSession int
+
+	// MaxCharactersPerSession caps the total characters stored across a
+	// session's live conversations; messages that would exceed it are
+	// rejected. Zero means unlimited.
+	MaxCharactersPerSession int
+
+	// LinkByTranscriptPath treats conversations that share a transcript path
+	// as one logical session (e.g. a resumed session with a new session ID)
+	LinkByTranscriptPath bool
+
+	// RatingAggregation selects how a conversation's ratings are combined
+	// into one score in stats (mean, median or latest)
+	RatingAggregation models.RatingAggregation
+
+	// DeleteEmptySessions removes a session's row once its last live
+	// conversation is deleted. By default the row is kept as session history.
+	DeleteEmptySessions bool
+
+	// IncrementalVacuumThreshold reclaims free pages with an incremental
+	// vacuum after a purge removes more than this many conversations.
+	// Non-zero values open the database with auto_vacuum=INCREMENTAL, which
+	// only takes effect on new files; existing ones must be converted once
+	// with EnableIncrementalVacuum. Zero disables compaction.
+	IncrementalVacuumThreshold int
+
+	// ConversationReuseWindow limits how long a session's conversation keeps
+	// receiving hook messages: once its last activity is older than this, the
+	// next hook message starts a new conversation. Zero always reuses.
+	ConversationReuseWindow time.Duration
+
+	// MigrationLockTimeout is how long RunMigrations waits for another
+	// instance to finish migrating the same database. Zero gives up at once
+	// if the lock is held.
+	MigrationLockTimeout time.Duration
+
+	// MigrationLockStaleAfter is how old a migration lock may get before it
+	// is treated as left behind by a crashed instance and taken over. Zero
+	// uses DefaultMigrationLockStaleAfter.
+	MigrationLockStaleAfter time.Duration
+
+	// Metrics receives the duration of the main read and write operations.
+	// Nil disables timing.
+	Metrics metrics.Recorder
+
+	// Logger receives migration progress and failures that cannot be
+	// returned to a caller. Nil uses the standard logger.
+	Logger logging.Logger
 }
 
 // DefaultConfig returns default database configuration optimized for SQLite
 func DefaultConfig() *Config {
 	return &Config{
-		DatabasePath:    "data/prompt_manager.db",
-		MigrationsDir:   "database/migrations",
-		MaxOpenConns:    1,                    // SQLite works best with single writer
-		MaxIdleConns:    1,                    // Keep connection alive
-		ConnMaxLifetime: 0,                    // No connection lifetime limit
-		ConnMaxIdleTime: 30 * time.Minute,    // Close idle connections after 30 minutes
-		BusyTimeout:     30 * time.Second,     // Wait up to 30 seconds for lock
-		WALMode:         true,                 // Use WAL mode for better concurrency
-		Synchronous:     "NORMAL",             // Balance between safety and performance
-		CacheSize:       10000,                // 10MB cache (10000 pages * 1KB)
+		DatabasePath:         "data/prompt_manager.db",
+		MigrationsDir:        "database/migrations",
+		MaxOpenConns:         1,
+		MaxIdleConns:         1,
+		ConnMaxIdleTime:      30 * time.Minute,
+		BusyTimeout:          30 * time.Second,
+		WALMode:              true,
+		Synchronous:          "NORMAL",
+		CacheSize:            10000,
+		RatingAggregation:    models.RatingAggregationMean,
+		MigrationLockTimeout: 30 * time.Second,
 	}
 }
 
 // ProductionConfig returns production-optimized database configuration
 func ProductionConfig(dbPath string) *Config {
-	return &Config{
-		DatabasePath:    dbPath,
-		MigrationsDir:   "database/migrations",
-		MaxOpenConns:    1,                    // SQLite single writer
-		MaxIdleConns:    1,                    // Keep connection alive
-		ConnMaxLifetime: 0,                    // No connection lifetime limit
-		ConnMaxIdleTime: 10 * time.Minute,    // Shorter idle time in production
-		BusyTimeout:     60 * time.Second,     // Longer timeout for production
-		WALMode:         true,                 // WAL mode for better performance
-		Synchronous:     "NORMAL",             // Good balance for production
-		CacheSize:       20000,                // 20MB cache for production
+	config := DefaultConfig()
+	config.DatabasePath = dbPath
+	config.ConnMaxIdleTime = 10 * time.Minute
+	config.BusyTimeout = 60 * time.Second
+	config.CacheSize = 20000
+	config.MigrationLockTimeout = 2 * time.Minute // Allow slow migrations on large databases
+	return config
+}
+
+// MemoryPath opens a private in-memory database that disappears when the
+// DB is closed
+const MemoryPath = ":memory:"
+
+// MemoryConfig returns configuration for an in-memory database, for tests
+// and ephemeral use. Each connection to an in-memory database sees its own
+// empty database, so exactly one connection is opened and never recycled.
+func MemoryConfig() *Config {
+	config := DefaultConfig()
+	config.DatabasePath = MemoryPath
+	config.ConnMaxIdleTime = 0      // Closing the connection discards the data
+	config.WALMode = false          // WAL needs a database file
+	config.Synchronous = "OFF"      // Nothing to sync to disk
+	config.MigrationLockTimeout = 0 // No other instance can share the database
+	return config
+}
+
+// isInMemory reports whether path names an in-memory database, either
+// ":memory:" or a URI such as "file::memory:?cache=shared"
+func isInMemory(path string) bool {
+	return path == MemoryPath ||
+		strings.HasPrefix(path, "file::memory:") ||
+		strings.HasPrefix(path, MemoryPath+"?") ||
+		(strings.HasPrefix(path, "file:") && strings.Contains(path, "mode=memory"))
+}
+
+// synchronousModes are the values SQLite accepts for PRAGMA synchronous
+var synchronousModes = []string{"OFF", "NORMAL", "FULL", "EXTRA"}
+
+// Validate checks the settings that are interpolated into SQLite pragmas or
+// the connection string, so a typo fails loudly instead of being silently
+// ignored by SQLite. The journal mode needs no check since WALMode can only
+// select WAL or SQLite's default.
+func (c *Config) Validate() error {
+	if c.Synchronous != "" && !slices.Contains(synchronousModes, strings.ToUpper(c.Synchronous)) {
+		return fmt.Errorf("invalid synchronous mode %q: must be one of %s", c.Synchronous, strings.Join(synchronousModes, ", "))
+	}
+
+	if c.CacheSize < 0 {
+		return fmt.Errorf("cache size cannot be negative")
+	}
+
+	if c.BusyTimeout < 0 {
+		return fmt.Errorf("busy timeout cannot be negative")
 	}
+
+	return nil
 }
 
 // New creates a new database connection with optimized SQLite settings
 func New(config *Config) (*DB, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid database config: %w", err)
+	}
+
 	// Ensure database directory exists
-	dir := filepath.Dir(config.DatabasePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	if !isInMemory(config.DatabasePath) {
+		dir := filepath.Dir(config.DatabasePath)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
 	}
 
 	// Build connection string with SQLite pragmas
@@ -98,8 +230,20 @@ func New(config *Config) (*DB, error) {
 	}
 
 	db := &DB{
-		conn: conn,
-		path: config.DatabasePath,
+		conn:   conn,
+		path:   config.DatabasePath,
+		config: config,
+	}
+
+	if config.IncrementalVacuumThreshold > 0 {
+		mode, err := db.autoVacuumMode()
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if mode != autoVacuumIncremental {
+			db.logger().Printf("Incremental vacuum is configured but the database uses auto_vacuum mode %d; run EnableIncrementalVacuum once to convert it", mode)
+		}
 	}
 
 	return db, nil
@@ -107,26 +251,36 @@ func New(config *Config) (*DB, error) {
 
 // buildConnectionString constructs SQLite connection string with pragmas
 func buildConnectionString(config *Config) string {
-	connStr := config.DatabasePath + "?"
-	
+	// URI paths such as "file::memory:?cache=shared" already carry a query
+	separator := "?"
+	if strings.Contains(config.DatabasePath, "?") {
+		separator = "&"
+	}
+	connStr := config.DatabasePath + separator
+
 	// Enable foreign keys
-	connStr += "foreign_keys=1"
-	
+	connStr += "_foreign_keys=1"
+
 	// Set busy timeout
 	if config.BusyTimeout > 0 {
 		connStr += fmt.Sprintf("&_busy_timeout=%d", int(config.BusyTimeout/time.Millisecond))
 	}
-	
+
 	// Enable WAL mode if configured
 	if config.WALMode {
 		connStr += "&_journal_mode=WAL"
 	}
-	
+
 	// Set synchronous mode
 	if config.Synchronous != "" {
 		connStr += fmt.Sprintf("&_sync=%s", config.Synchronous)
 	}
-	
+
+	// New database files are created ready for incremental vacuum
+	if config.IncrementalVacuumThreshold > 0 {
+		connStr += "&_auto_vacuum=incremental"
+	}
+
 	return connStr
 }
 
@@ -142,7 +296,7 @@ func applySQLiteOptimizations(conn *sql.DB, config *Config) error {
 		{"mmap_size", 268435456, "Enable memory-mapped I/O (256MB)"},
 		{"optimize", nil, "Optimize database"},
 	}
-	
+
 	for _, opt := range optimizations {
 		var query string
 		if opt.value != nil {
@@ -150,12 +304,12 @@ func applySQLiteOptimizations(conn *sql.DB, config *Config) error {
 		} else {
 			query = fmt.Sprintf("PRAGMA %s", opt.pragma)
 		}
-		
+
 		if _, err := conn.Exec(query); err != nil {
 			return fmt.Errorf("failed to apply %s: %w", opt.desc, err)
 		}
 	}
-	
+
 	return nil
 }
 
@@ -172,6 +326,34 @@ func (db *DB) Conn() *sql.DB {
 	return db.conn
 }
 
+// WithTx runs fn inside a transaction, committing when it returns nil and
+// rolling back when it returns an error or panics. Panics are re-raised
+// after the rollback.
+func (db *DB) WithTx(fn func(*sql.Tx) error) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 // RunMigrations executes database migrations from the migrations directory
 func (db *DB) RunMigrations(migrationsDir string) error {
 	// Create migrations table if it doesn't exist
@@ -180,11 +362,19 @@ func (db *DB) RunMigrations(migrationsDir string) error {
 		version TEXT PRIMARY KEY,
 		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);`
-	
+
 	if _, err := db.conn.Exec(createMigrationsTable); err != nil {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
+	// Hold the migration lock so instances sharing the database migrate one
+	// at a time; later instances see the migrations as already applied
+	release, err := db.acquireMigrationLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Find migration files
 	files, err := filepath.Glob(filepath.Join(migrationsDir, "*.up.sql"))
 	if err != nil {
@@ -193,14 +383,14 @@ func (db *DB) RunMigrations(migrationsDir string) error {
 
 	for _, file := range files {
 		version := extractVersionFromFilename(file)
-		
+
 		// Check if migration already applied
 		var count int
 		err := db.conn.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE version = ?", version).Scan(&count)
 		if err != nil {
 			return fmt.Errorf("failed to check migration status: %w", err)
 		}
-		
+
 		if count > 0 {
 			continue // Skip already applied migration
 		}
@@ -231,25 +421,62 @@ func (db *DB) RunMigrations(migrationsDir string) error {
 			return fmt.Errorf("failed to commit migration %s: %w", file, err)
 		}
 
-		fmt.Printf("Applied migration: %s\n", version)
+		db.logger().Printf("Applied migration: %s", version)
 	}
 
 	return nil
 }
 
+// statsConversationFilter returns a SQL condition on the given conversations
+// alias that excludes soft-deleted conversations from aggregates, unless the
+// configuration opts in to including them
+func (db *DB) statsConversationFilter(alias string) string {
+	if db.config != nil && db.config.IncludeDeletedInStats {
+		return "1 = 1"
+	}
+	return alias + ".deleted_at IS NULL"
+}
+
+// observe reports how long an operation started at start took to the
+// configured metrics recorder, if any
+func (db *DB) observe(operation string, start time.Time) {
+	if db.config != nil && db.config.Metrics != nil {
+		db.config.Metrics.ObserveDBOperation(operation, time.Since(start))
+	}
+}
+
+// logger returns the configured logger, falling back to the standard logger
+func (db *DB) logger() logging.Logger {
+	if db.config != nil && db.config.Logger != nil {
+		return db.config.Logger
+	}
+	return logging.Default()
+}
+
+// ratingAggregation returns the configured method for scoring a
+// conversation's ratings
+func (db *DB) ratingAggregation() models.RatingAggregation {
+	if db.config != nil {
+		return db.config.RatingAggregation
+	}
+	return models.RatingAggregationMean
+}
+
 // Health checks database connectivity and returns status
 func (db *DB) Health() error {
 	if db.conn == nil {
 		return fmt.Errorf("database connection is nil")
 	}
-	
+
 	return db.conn.Ping()
 }
 
 // Stats returns database statistics including SQLite-specific metrics
 func (db *DB) Stats() (map[string]interface{}, error) {
+	defer db.observe("stats", time.Now())
+
 	stats := make(map[string]interface{})
-	
+
 	// Count conversations
 	var conversationCount int
 	err := db.conn.QueryRow("SELECT COUNT(*) FROM conversations").Scan(&conversationCount)
@@ -274,23 +501,25 @@ func (db *DB) Stats() (map[string]interface{}, error) {
 	}
 	stats["ratings"] = ratingCount
 
-	// Database file size
-	if info, err := os.Stat(db.path); err == nil {
-		stats["database_size_bytes"] = info.Size()
+	// Database file size; in-memory databases have no file
+	if !isInMemory(db.path) {
+		if info, err := os.Stat(db.path); err == nil {
+			stats["database_size_bytes"] = info.Size()
+		}
 	}
 
 	// Connection pool stats
 	dbStats := db.conn.Stats()
 	stats["connection_pool"] = map[string]interface{}{
-		"max_open_connections":     dbStats.MaxOpenConnections,
-		"open_connections":         dbStats.OpenConnections,
-		"in_use":                  dbStats.InUse,
-		"idle":                    dbStats.Idle,
-		"wait_count":              dbStats.WaitCount,
-		"wait_duration_ms":        dbStats.WaitDuration.Milliseconds(),
-		"max_idle_closed":         dbStats.MaxIdleClosed,
-		"max_idle_time_closed":    dbStats.MaxIdleTimeClosed,
-		"max_lifetime_closed":     dbStats.MaxLifetimeClosed,
+		"max_open_connections": dbStats.MaxOpenConnections,
+		"open_connections":     dbStats.OpenConnections,
+		"in_use":               dbStats.InUse,
+		"idle":                 dbStats.Idle,
+		"wait_count":           dbStats.WaitCount,
+		"wait_duration_ms":     dbStats.WaitDuration.Milliseconds(),
+		"max_idle_closed":      dbStats.MaxIdleClosed,
+		"max_idle_time_closed": dbStats.MaxIdleTimeClosed,
+		"max_lifetime_closed":  dbStats.MaxLifetimeClosed,
 	}
 
 	// SQLite-specific stats
@@ -305,11 +534,11 @@ func (db *DB) Stats() (map[string]interface{}, error) {
 // getSQLiteStats retrieves SQLite-specific statistics and settings
 func (db *DB) getSQLiteStats() (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
-	
+
 	// SQLite pragma values to check
 	pragmas := []string{
 		"journal_mode",
-		"synchronous", 
+		"synchronous",
 		"cache_size",
 		"temp_store",
 		"mmap_size",
@@ -318,7 +547,7 @@ func (db *DB) getSQLiteStats() (map[string]interface{}, error) {
 		"freelist_count",
 		"foreign_keys",
 	}
-	
+
 	for _, pragma := range pragmas {
 		var value string
 		query := fmt.Sprintf("PRAGMA %s", pragma)
@@ -329,10 +558,80 @@ func (db *DB) getSQLiteStats() (map[string]interface{}, error) {
 		}
 		stats[pragma] = value
 	}
-	
+
 	return stats, nil
 }
 
+// DefaultMigrationLockStaleAfter is how old a migration lock must be before
+// another instance takes it over, comfortably longer than any migration
+const DefaultMigrationLockStaleAfter = 10 * time.Minute
+
+// migrationLockPollInterval is how often a waiting instance retries the
+// migration lock
+const migrationLockPollInterval = 50 * time.Millisecond
+
+// acquireMigrationLock takes the advisory migration lock, a single row in
+// migration_lock claimed with a conditional insert, waiting up to
+// MigrationLockTimeout for another holder to release it. A lock acquired
+// more than MigrationLockStaleAfter ago belongs to an instance that crashed
+// mid-migration and is taken over. The returned function releases the lock.
+func (db *DB) acquireMigrationLock() (func(), error) {
+	createLockTable := `
+	CREATE TABLE IF NOT EXISTS migration_lock (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		owner TEXT NOT NULL,
+		acquired_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+	if _, err := db.conn.Exec(createLockTable); err != nil {
+		return nil, fmt.Errorf("failed to create migration lock table: %w", err)
+	}
+
+	var timeout time.Duration
+	staleAfter := DefaultMigrationLockStaleAfter
+	if db.config != nil {
+		timeout = db.config.MigrationLockTimeout
+		if db.config.MigrationLockStaleAfter > 0 {
+			staleAfter = db.config.MigrationLockStaleAfter
+		}
+	}
+	staleModifier := fmt.Sprintf("-%d seconds", int(staleAfter.Seconds()))
+	owner := fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+	deadline := time.Now().Add(timeout)
+
+	for {
+		stale, err := db.conn.Exec("DELETE FROM migration_lock WHERE id = 1 AND acquired_at < datetime('now', ?)", staleModifier)
+		if err != nil {
+			return nil, fmt.Errorf("failed to clear stale migration lock: %w", err)
+		}
+		if cleared, err := stale.RowsAffected(); err == nil && cleared > 0 {
+			db.logger().Printf("Taking over a migration lock held for more than %s", staleAfter)
+		}
+
+		result, err := db.conn.Exec("INSERT OR IGNORE INTO migration_lock (id, owner) VALUES (1, ?)", owner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		acquired, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if acquired == 1 {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			return nil, ErrMigrationLockTimeout
+		}
+		time.Sleep(migrationLockPollInterval)
+	}
+
+	release := func() {
+		if _, err := db.conn.Exec("DELETE FROM migration_lock WHERE id = 1 AND owner = ?", owner); err != nil {
+			db.logger().Errorf("Failed to release migration lock: %v", err)
+		}
+	}
+	return release, nil
+}
+
 // extractVersionFromFilename extracts version number from migration filename
 // e.g., "001_initial_schema.up.sql" -> "001"
 func extractVersionFromFilename(filename string) string {
@@ -341,4 +640,63 @@ func extractVersionFromFilename(filename string) string {
 		return base[:3]
 	}
 	return base
-}
\ No newline at end of file
+}
+
+// autoVacuumIncremental is the value PRAGMA auto_vacuum reports in
+// incremental mode
+const autoVacuumIncremental = 2
+
+// autoVacuumMode reports the database's PRAGMA auto_vacuum setting
+func (db *DB) autoVacuumMode() (int, error) {
+	var mode int
+	if err := db.conn.QueryRow("PRAGMA auto_vacuum").Scan(&mode); err != nil {
+		return 0, fmt.Errorf("failed to read auto_vacuum mode: %w", err)
+	}
+	return mode, nil
+}
+
+// EnableIncrementalVacuum converts a database created without incremental
+// auto-vacuum. The mode only takes effect after a full VACUUM, which rewrites
+// the whole file under an exclusive lock, so this is a one-off maintenance
+// step rather than something done on open. Databases already in incremental
+// mode are left alone.
+func (db *DB) EnableIncrementalVacuum() error {
+	mode, err := db.autoVacuumMode()
+	if err != nil {
+		return err
+	}
+	if mode == autoVacuumIncremental {
+		return nil
+	}
+
+	// The pragma and the VACUUM must run on the same connection
+	conn, err := db.conn.Conn(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(context.Background(), "PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+		return fmt.Errorf("failed to enable incremental vacuum: %w", err)
+	}
+	if _, err := conn.ExecContext(context.Background(), "VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+
+	return nil
+}
+
+// incrementalVacuum returns all free pages to the filesystem. Each step of
+// the pragma frees one page, so the statement is run to completion.
+func (db *DB) incrementalVacuum() error {
+	rows, err := db.conn.Query("PRAGMA incremental_vacuum")
+	if err != nil {
+		return fmt.Errorf("failed to run incremental vacuum: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+	}
+
+	return rows.Err()
+}
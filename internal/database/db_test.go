@@ -1,11 +1,25 @@
 package database
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
-func setupTestDB(t *testing.T) *DB {
+func setupTestDB(t testing.TB) *DB {
+	return setupTestDBWithConfig(t, nil)
+}
+
+// setupTestDBWithConfig creates a migrated test database, letting the caller
+// adjust the configuration before the database is opened
+func setupTestDBWithConfig(t testing.TB, configure func(*Config)) *DB {
 	// Create temp database file
 	tmpfile, err := os.CreateTemp("", "test_*.db")
 	if err != nil {
@@ -17,6 +31,9 @@ func setupTestDB(t *testing.T) *DB {
 		DatabasePath:  tmpfile.Name(),
 		MigrationsDir: "../../database/migrations",
 	}
+	if configure != nil {
+		configure(config)
+	}
 
 	db, err := New(config)
 	if err != nil {
@@ -107,7 +124,7 @@ func TestConversationCRUD(t *testing.T) {
 	}
 
 	// List conversations
-	conversations, err := db.ListConversations(10, 0)
+	conversations, err := db.ListConversations(nil, 10, 0)
 	if err != nil {
 		t.Fatalf("Failed to list conversations: %v", err)
 	}
@@ -288,6 +305,56 @@ func TestMessageCRUD(t *testing.T) {
 	}
 }
 
+func TestMessageInsertUpdatesConversationStats(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("stats-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	messages := []struct {
+		messageType string
+		content     string
+	}{
+		{"prompt", "First prompt"},
+		{"response", "A fairly long response to the first prompt"},
+		{"prompt", "Second prompt"},
+		{"response", "Short reply"},
+		{"response", "Follow-up response"},
+	}
+
+	expectedCharacters := 0
+	for _, m := range messages {
+		if _, err := db.CreateMessage(conv.ID, m.messageType, m.content, nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		expectedCharacters += len(m.content)
+	}
+
+	updated, err := db.GetConversation(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+
+	if updated.PromptCount != 2 {
+		t.Errorf("Expected prompt count 2, got %d", updated.PromptCount)
+	}
+
+	if updated.TotalCharacters != expectedCharacters {
+		t.Errorf("Expected total characters %d, got %d", expectedCharacters, updated.TotalCharacters)
+	}
+
+	if updated.UpdatedAt.Before(conv.UpdatedAt) {
+		t.Errorf("Expected updated_at to advance, got %v before %v", updated.UpdatedAt, conv.UpdatedAt)
+	}
+
+	// Messages for a missing conversation leave no partial writes behind
+	if _, err := db.CreateMessage(999, "prompt", "orphan", nil, nil); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound, got %v", err)
+	}
+}
+
 func TestRatingCRUD(t *testing.T) {
 	db := setupTestDB(t)
 
@@ -405,4 +472,1326 @@ func TestInvalidRating(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for rating 6")
 	}
-}
\ No newline at end of file
+}
+
+func TestSessionConversationLimit(t *testing.T) {
+	db := setupTestDBWithConfig(t, func(config *Config) {
+		config.MaxConversationsPerSession = 2
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := db.CreateConversation("limited-session", nil, nil, nil); err != nil {
+			t.Fatalf("Failed to create conversation %d: %v", i+1, err)
+		}
+	}
+
+	_, err := db.CreateConversation("limited-session", nil, nil, nil)
+	if !errors.Is(err, ErrSessionConversationLimit) {
+		t.Errorf("Expected ErrSessionConversationLimit, got %v", err)
+	}
+
+	// Other sessions are unaffected
+	if _, err := db.CreateConversation("other-session", nil, nil, nil); err != nil {
+		t.Errorf("Expected other session to be unaffected, got %v", err)
+	}
+}
+
+func TestSessionConversationLimitConcurrent(t *testing.T) {
+	const limit = 3
+	db := setupTestDBWithConfig(t, func(config *Config) {
+		config.MaxConversationsPerSession = limit
+		config.BusyTimeout = 5 * time.Second
+		config.WALMode = true
+	})
+
+	const callers = 10
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = db.CreateConversation("racing-session", nil, nil, nil)
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, ErrSessionConversationLimit):
+			t.Errorf("Expected ErrSessionConversationLimit, got %v", err)
+		}
+	}
+	if created != limit {
+		t.Errorf("Expected %d conversations to be created, got %d", limit, created)
+	}
+
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM conversations WHERE session_id = ?", "racing-session").Scan(&count); err != nil {
+		t.Fatalf("Failed to count conversations: %v", err)
+	}
+	if count != limit {
+		t.Errorf("Expected the session to hold %d conversations, got %d", limit, count)
+	}
+}
+
+func TestSessionCharacterQuota(t *testing.T) {
+	db := setupTestDBWithConfig(t, func(config *Config) {
+		config.MaxCharactersPerSession = 10
+	})
+
+	first, err := db.CreateConversation("quota-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	second, err := db.CreateConversation("quota-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	if _, err := db.CreateMessage(first.ID, "prompt", "123456", nil, nil); err != nil {
+		t.Fatalf("Failed to create message within quota: %v", err)
+	}
+
+	// The quota spans every conversation in the session
+	_, err = db.CreateMessage(second.ID, "prompt", "12345", nil, nil)
+	if !errors.Is(err, ErrSessionQuotaExceeded) {
+		t.Errorf("Expected ErrSessionQuotaExceeded, got %v", err)
+	}
+
+	_, err = db.CreateMessagesBatch(second.ID, []MessageInput{
+		{MessageType: "prompt", Content: "12"},
+		{MessageType: "response", Content: "345"},
+	})
+	if !errors.Is(err, ErrSessionQuotaExceeded) {
+		t.Errorf("Expected ErrSessionQuotaExceeded for batch, got %v", err)
+	}
+
+	if _, err := db.CreateMessage(second.ID, "response", "1234", nil, nil); err != nil {
+		t.Errorf("Expected message filling the quota exactly to succeed, got %v", err)
+	}
+
+	// Other sessions are unaffected
+	other, err := db.CreateConversation("other-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateMessage(other.ID, "prompt", "123456789", nil, nil); err != nil {
+		t.Errorf("Expected other session to be unaffected, got %v", err)
+	}
+}
+
+func TestListLinkedConversations(t *testing.T) {
+	shared := "/tmp/transcripts/shared.jsonl"
+	other := "/tmp/transcripts/other.jsonl"
+
+	setup := func(t *testing.T, link bool) (*DB, []*Conversation) {
+		db := setupTestDBWithConfig(t, func(config *Config) {
+			config.LinkByTranscriptPath = link
+		})
+
+		var convs []*Conversation
+		for _, c := range []struct {
+			sessionID string
+			path      *string
+		}{
+			{"original-session", &shared},
+			{"resumed-session", &shared},
+			{"unrelated-session", &other},
+		} {
+			conv, err := db.CreateConversation(c.sessionID, nil, nil, c.path)
+			if err != nil {
+				t.Fatalf("Failed to create conversation: %v", err)
+			}
+			convs = append(convs, conv)
+		}
+		return db, convs
+	}
+
+	t.Run("shared transcript path links conversations", func(t *testing.T) {
+		db, convs := setup(t, true)
+
+		linked, err := db.ListLinkedConversations(convs[0].ID)
+		if err != nil {
+			t.Fatalf("Failed to list linked conversations: %v", err)
+		}
+		if len(linked) != 1 || linked[0].ID != convs[1].ID {
+			t.Errorf("Expected conversation %d to be linked, got %v", convs[1].ID, linked)
+		}
+
+		linked, err = db.ListLinkedConversations(convs[2].ID)
+		if err != nil {
+			t.Fatalf("Failed to list linked conversations: %v", err)
+		}
+		if len(linked) != 0 {
+			t.Errorf("Expected distinct transcript path to have no links, got %v", linked)
+		}
+	})
+
+	t.Run("linking disabled", func(t *testing.T) {
+		db, convs := setup(t, false)
+
+		linked, err := db.ListLinkedConversations(convs[0].ID)
+		if err != nil {
+			t.Fatalf("Failed to list linked conversations: %v", err)
+		}
+		if len(linked) != 0 {
+			t.Errorf("Expected no links when disabled, got %v", linked)
+		}
+	})
+
+	t.Run("missing conversation", func(t *testing.T) {
+		db, _ := setup(t, true)
+
+		if _, err := db.ListLinkedConversations(999); !errors.Is(err, ErrConversationNotFound) {
+			t.Errorf("Expected ErrConversationNotFound, got %v", err)
+		}
+	})
+}
+
+func TestDeleteConversations(t *testing.T) {
+	db := setupTestDB(t)
+
+	var ids []int
+	for i := 0; i < 3; i++ {
+		conv, err := db.CreateConversation("bulk-session", nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		if _, err := db.CreateMessage(conv.ID, "prompt", "hello", nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		ids = append(ids, conv.ID)
+	}
+
+	deleted, err := db.DeleteConversations([]int{ids[0], ids[1], 999})
+	if err != nil {
+		t.Fatalf("Failed to delete conversations: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 conversations deleted, got %d", deleted)
+	}
+
+	count, err := db.GetConversationCount(nil)
+	if err != nil {
+		t.Fatalf("Failed to count conversations: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 remaining conversation, got %d", count)
+	}
+
+	// Soft deletion keeps the messages of deleted conversations
+	var messageCount int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM messages").Scan(&messageCount); err != nil {
+		t.Fatalf("Failed to count messages: %v", err)
+	}
+	if messageCount != 3 {
+		t.Errorf("Expected messages of deleted conversations to be kept, %d remain", messageCount)
+	}
+
+	// Already deleted conversations are not counted again
+	deleted, err = db.DeleteConversations([]int{ids[0]})
+	if err != nil {
+		t.Fatalf("Failed to delete conversations: %v", err)
+	}
+	if deleted != 0 {
+		t.Errorf("Expected no conversations deleted twice, got %d", deleted)
+	}
+}
+
+func TestSoftDeleteConversation(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("soft-delete-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateMessage(conv.ID, "prompt", "keep me", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	if err := db.DeleteConversation(conv.ID); err != nil {
+		t.Fatalf("Failed to delete conversation: %v", err)
+	}
+
+	if _, err := db.GetConversation(conv.ID); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound for soft-deleted conversation, got %v", err)
+	}
+
+	if err := db.DeleteConversation(conv.ID); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound deleting twice, got %v", err)
+	}
+
+	deleted, err := db.GetConversationIncludingDeleted(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get soft-deleted conversation: %v", err)
+	}
+	if deleted.DeletedAt == nil {
+		t.Error("Expected deleted_at to be set")
+	}
+
+	live, err := db.ListConversations(nil, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list conversations: %v", err)
+	}
+	if len(live) != 0 {
+		t.Errorf("Expected soft-deleted conversation to be hidden, got %d", len(live))
+	}
+
+	all, err := db.ListConversations(&ConversationFilter{IncludeDeleted: true}, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list conversations: %v", err)
+	}
+	if len(all) != 1 || all[0].DeletedAt == nil {
+		t.Errorf("Expected soft-deleted conversation when including deleted, got %v", all)
+	}
+
+	if err := db.RestoreConversation(conv.ID); err != nil {
+		t.Fatalf("Failed to restore conversation: %v", err)
+	}
+
+	restored, err := db.GetConversationWithMessages(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get restored conversation: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Errorf("Expected deleted_at to be cleared, got %v", restored.DeletedAt)
+	}
+	if len(restored.Messages) != 1 {
+		t.Errorf("Expected messages to survive soft deletion, got %d", len(restored.Messages))
+	}
+
+	// Restoring a live conversation is a no-op; a missing one is not found
+	if err := db.RestoreConversation(conv.ID); err != nil {
+		t.Errorf("Expected restoring a live conversation to succeed, got %v", err)
+	}
+	if err := db.RestoreConversation(999); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound, got %v", err)
+	}
+}
+
+func TestListConversationsAvgResponseTime(t *testing.T) {
+	db := setupTestDB(t)
+
+	timed, err := db.CreateConversation("timed-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	untimed, err := db.CreateConversation("untimed-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	fast, slow, prompt := 100, 300, 5000
+	messages := []struct {
+		convID        int
+		messageType   string
+		executionTime *int
+	}{
+		{timed.ID, "prompt", &prompt}, // prompts never count
+		{timed.ID, "response", &fast},
+		{timed.ID, "response", &slow},
+		{timed.ID, "response", nil},
+		{untimed.ID, "response", nil},
+	}
+	for _, m := range messages {
+		if _, err := db.CreateMessage(m.convID, m.messageType, "content", nil, m.executionTime); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	conversations, err := db.ListConversations(nil, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list conversations: %v", err)
+	}
+
+	byID := make(map[int]Conversation)
+	for _, conv := range conversations {
+		byID[conv.ID] = conv
+	}
+
+	if avg := byID[timed.ID].AvgResponseTimeMs; avg == nil || *avg != 200 {
+		t.Errorf("Expected average response time 200ms, got %v", avg)
+	}
+	if avg := byID[untimed.ID].AvgResponseTimeMs; avg != nil {
+		t.Errorf("Expected nil average response time, got %v", *avg)
+	}
+}
+
+func TestUpdateConversationMetadata(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("metadata-session", stringPtr("Title"), stringPtr("/work"), stringPtr("/transcript.jsonl"))
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	if err := db.UpdateConversationMetadata(conv.ID, nil, stringPtr("/new-work"), nil); err != nil {
+		t.Fatalf("Failed to update conversation metadata: %v", err)
+	}
+
+	updated, err := db.GetConversation(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	if updated.Title == nil || *updated.Title != "Title" {
+		t.Errorf("Expected title to be unchanged, got %v", updated.Title)
+	}
+	if updated.WorkingDirectory == nil || *updated.WorkingDirectory != "/new-work" {
+		t.Errorf("Expected working directory /new-work, got %v", updated.WorkingDirectory)
+	}
+	if updated.TranscriptPath == nil || *updated.TranscriptPath != "/transcript.jsonl" {
+		t.Errorf("Expected transcript path to be unchanged, got %v", updated.TranscriptPath)
+	}
+
+	if err := db.UpdateConversationMetadata(99999, stringPtr("Missing"), nil, nil); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound, got %v", err)
+	}
+	if err := db.UpdateConversationMetadata(99999, nil, nil, nil); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound for an empty update, got %v", err)
+	}
+
+	if err := db.DeleteConversation(conv.ID); err != nil {
+		t.Fatalf("Failed to delete conversation: %v", err)
+	}
+	if err := db.UpdateConversationMetadata(conv.ID, stringPtr("Deleted"), nil, nil); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound for a deleted conversation, got %v", err)
+	}
+	if err := db.UpdateConversationMetadata(conv.ID, nil, nil, nil); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound for an empty update of a deleted conversation, got %v", err)
+	}
+
+	deleted, err := db.GetConversationIncludingDeleted(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get deleted conversation: %v", err)
+	}
+	if deleted.Title == nil || *deleted.Title != "Title" {
+		t.Errorf("Expected deleted conversation's title to be unchanged, got %v", deleted.Title)
+	}
+}
+
+func TestAutoTitleConversation(t *testing.T) {
+	db := setupTestDB(t)
+
+	tests := []struct {
+		name     string
+		existing *string
+		prompt   string
+		expected *string
+	}{
+		{"first line becomes the title", nil, "\n  Fix the   login bug\nDetails follow", stringPtr("Fix the login bug")},
+		{"long lines are truncated", nil, strings.Repeat("a", 100), stringPtr(strings.Repeat("a", AutoTitleMaxLength-3) + "...")},
+		{"existing title is kept", stringPtr("Chosen"), "Another prompt", stringPtr("Chosen")},
+		{"blank prompt leaves no title", nil, " \n\t", nil},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, err := db.CreateConversation(fmt.Sprintf("auto-title-%d", i), tt.existing, nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to create conversation: %v", err)
+			}
+
+			if err := db.AutoTitleConversation(conv.ID, tt.prompt); err != nil {
+				t.Fatalf("Failed to auto-title conversation: %v", err)
+			}
+
+			updated, err := db.GetConversation(conv.ID)
+			if err != nil {
+				t.Fatalf("Failed to get conversation: %v", err)
+			}
+			switch {
+			case tt.expected == nil && updated.Title != nil:
+				t.Errorf("Expected no title, got %q", *updated.Title)
+			case tt.expected != nil && (updated.Title == nil || *updated.Title != *tt.expected):
+				t.Errorf("Expected title %q, got %v", *tt.expected, updated.Title)
+			}
+		})
+	}
+
+	if err := db.AutoTitleConversation(99999, "Prompt"); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound, got %v", err)
+	}
+}
+
+func TestDeleteConversationEmptySession(t *testing.T) {
+	tests := []struct {
+		name        string
+		deleteEmpty bool
+		expectKept  bool
+	}{
+		{"session retained by default", false, true},
+		{"session removed when enabled", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDBWithConfig(t, func(c *Config) {
+				c.DeleteEmptySessions = tt.deleteEmpty
+			})
+
+			first, err := db.CreateConversation("cleanup-session", nil, nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to create conversation: %v", err)
+			}
+			second, err := db.CreateConversation("cleanup-session", nil, nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to create conversation: %v", err)
+			}
+			if _, err := db.conn.Exec("INSERT INTO sessions (session_id) VALUES (?)", "cleanup-session"); err != nil {
+				t.Fatalf("Failed to insert session: %v", err)
+			}
+
+			sessionExists := func() bool {
+				var count int
+				if err := db.conn.QueryRow("SELECT COUNT(*) FROM sessions WHERE session_id = ?", "cleanup-session").Scan(&count); err != nil {
+					t.Fatalf("Failed to count sessions: %v", err)
+				}
+				return count > 0
+			}
+
+			// The session still has a live conversation, so it is always kept
+			if err := db.DeleteConversation(first.ID); err != nil {
+				t.Fatalf("Failed to delete conversation: %v", err)
+			}
+			if !sessionExists() {
+				t.Fatal("Expected session to be kept while it has live conversations")
+			}
+
+			if err := db.DeleteConversation(second.ID); err != nil {
+				t.Fatalf("Failed to delete conversation: %v", err)
+			}
+			if kept := sessionExists(); kept != tt.expectKept {
+				t.Errorf("Expected session kept=%v after deleting its last conversation, got %v", tt.expectKept, kept)
+			}
+		})
+	}
+}
+
+func TestPurgeDeletedConversationsIncrementalVacuum(t *testing.T) {
+	tests := []struct {
+		name          string
+		purgeCount    int
+		expectReclaim bool
+	}{
+		{"below threshold keeps free pages", 3, false},
+		{"above threshold reclaims free pages", 6, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDBWithConfig(t, func(c *Config) {
+				c.IncrementalVacuumThreshold = 5
+			})
+
+			mode, err := db.autoVacuumMode()
+			if err != nil {
+				t.Fatalf("Failed to read auto_vacuum: %v", err)
+			}
+			if mode != autoVacuumIncremental {
+				t.Fatalf("Expected a new database to use incremental auto_vacuum, got mode %d", mode)
+			}
+
+			var ids []int
+			for i := 0; i < tt.purgeCount; i++ {
+				conv, err := db.CreateConversation("vacuum-session", nil, nil, nil)
+				if err != nil {
+					t.Fatalf("Failed to create conversation: %v", err)
+				}
+				if _, err := db.CreateMessage(conv.ID, "response", strings.Repeat("x", 50000), nil, nil); err != nil {
+					t.Fatalf("Failed to create message: %v", err)
+				}
+				ids = append(ids, conv.ID)
+			}
+
+			// Soft deletion keeps the rows, so no pages are freed
+			before := freelistCount(t, db)
+			if _, err := db.DeleteConversations(ids); err != nil {
+				t.Fatalf("Failed to delete conversations: %v", err)
+			}
+			if after := freelistCount(t, db); after != before {
+				t.Errorf("Expected soft deletion to leave the freelist at %d pages, got %d", before, after)
+			}
+
+			purged, err := db.PurgeDeletedConversations(time.Now())
+			if err != nil {
+				t.Fatalf("Failed to purge conversations: %v", err)
+			}
+			if purged != tt.purgeCount {
+				t.Errorf("Expected %d conversations purged, got %d", tt.purgeCount, purged)
+			}
+
+			var messages int
+			if err := db.conn.QueryRow("SELECT COUNT(*) FROM messages").Scan(&messages); err != nil {
+				t.Fatalf("Failed to count messages: %v", err)
+			}
+			if messages != 0 {
+				t.Errorf("Expected purged conversations' messages to be removed, got %d", messages)
+			}
+
+			after := freelistCount(t, db)
+			if tt.expectReclaim && after != 0 {
+				t.Errorf("Expected the purge to reclaim every free page, got %d", after)
+			}
+			if !tt.expectReclaim && after == 0 {
+				t.Error("Expected the purge below the threshold to leave free pages")
+			}
+		})
+	}
+}
+
+func TestPurgeDeletedConversationsCutoff(t *testing.T) {
+	db := setupTestDB(t)
+
+	live, err := db.CreateConversation("purge-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	old, err := db.CreateConversation("purge-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	recent, err := db.CreateConversation("purge-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	if _, err := db.conn.Exec("UPDATE conversations SET deleted_at = datetime('now', '-2 days') WHERE id = ?", old.ID); err != nil {
+		t.Fatalf("Failed to soft-delete conversation: %v", err)
+	}
+	if err := db.DeleteConversation(recent.ID); err != nil {
+		t.Fatalf("Failed to delete conversation: %v", err)
+	}
+
+	purged, err := db.PurgeDeletedConversations(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to purge conversations: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 conversation purged, got %d", purged)
+	}
+
+	if _, err := db.GetConversationIncludingDeleted(old.ID); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected the old conversation to be purged, got %v", err)
+	}
+	if _, err := db.GetConversationIncludingDeleted(recent.ID); err != nil {
+		t.Errorf("Expected the recently deleted conversation to be kept, got %v", err)
+	}
+	if _, err := db.GetConversation(live.ID); err != nil {
+		t.Errorf("Expected the live conversation to be kept, got %v", err)
+	}
+}
+
+func TestEnableIncrementalVacuum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db := setupTestDBWithConfig(t, func(c *Config) {
+		c.DatabasePath = path
+	})
+	db.Close()
+
+	// Opening an existing database with a threshold must not rewrite it
+	logger := &capturingLogger{}
+	reopened := setupTestDBWithConfig(t, func(c *Config) {
+		c.DatabasePath = path
+		c.IncrementalVacuumThreshold = 5
+		c.Logger = logger
+	})
+
+	mode, err := reopened.autoVacuumMode()
+	if err != nil {
+		t.Fatalf("Failed to read auto_vacuum: %v", err)
+	}
+	if mode == autoVacuumIncremental {
+		t.Fatal("Expected the existing database to keep its auto_vacuum mode until converted")
+	}
+	logger.mu.Lock()
+	warned := len(logger.messages) > 0 && strings.Contains(logger.messages[0], "EnableIncrementalVacuum")
+	logger.mu.Unlock()
+	if !warned {
+		t.Errorf("Expected a warning that the database needs converting, got %q", logger.messages)
+	}
+
+	if err := reopened.EnableIncrementalVacuum(); err != nil {
+		t.Fatalf("Failed to enable incremental vacuum: %v", err)
+	}
+	if mode, err := reopened.autoVacuumMode(); err != nil || mode != autoVacuumIncremental {
+		t.Errorf("Expected incremental auto_vacuum after converting, got mode %d (%v)", mode, err)
+	}
+}
+
+func freelistCount(t *testing.T, db *DB) int {
+	var count int
+	if err := db.conn.QueryRow("PRAGMA freelist_count").Scan(&count); err != nil {
+		t.Fatalf("Failed to read freelist_count: %v", err)
+	}
+	return count
+}
+
+func TestGetOrCreateConversationReuseWindow(t *testing.T) {
+	tests := []struct {
+		name        string
+		window      time.Duration
+		expectReuse bool
+	}{
+		{"always reused by default", 0, true},
+		{"reused within window", 48 * time.Hour, true},
+		{"new conversation after window", time.Hour, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDBWithConfig(t, func(c *Config) {
+				c.ConversationReuseWindow = tt.window
+			})
+
+			// Inserted directly since updates refresh updated_at via a trigger
+			lastActivity := time.Now().Add(-24 * time.Hour).UTC().Format(sqliteTimestampLayout)
+			result, err := db.conn.Exec(
+				"INSERT INTO conversations (session_id, created_at, updated_at) VALUES (?, ?, ?)",
+				"reused-session", lastActivity, lastActivity,
+			)
+			if err != nil {
+				t.Fatalf("Failed to insert conversation: %v", err)
+			}
+			oldID, err := result.LastInsertId()
+			if err != nil {
+				t.Fatalf("Failed to get conversation ID: %v", err)
+			}
+
+			conv, created, err := db.GetOrCreateConversationBySessionID("reused-session", nil, nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to get conversation: %v", err)
+			}
+
+			if reused := int64(conv.ID) == oldID; reused != tt.expectReuse {
+				t.Errorf("Expected reuse %v, got conversation %d for old conversation %d", tt.expectReuse, conv.ID, oldID)
+			}
+			if created == tt.expectReuse {
+				t.Errorf("Expected created %v, got %v", !tt.expectReuse, created)
+			}
+
+			// Later messages keep going to the same conversation
+			again, created, err := db.GetOrCreateConversationBySessionID("reused-session", nil, nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to get conversation: %v", err)
+			}
+			if created {
+				t.Error("Expected the second call not to create a conversation")
+			}
+			if again.ID != conv.ID {
+				t.Errorf("Expected conversation %d to be reused, got %d", conv.ID, again.ID)
+			}
+		})
+	}
+}
+
+func TestPatchRating(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("patch-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	comment := "Original comment"
+	rating, err := db.CreateConversationRating(conv.ID, 4, &comment)
+	if err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+
+	// Only the comment changes
+	newComment := "Revised comment"
+	if err := db.PatchRating(rating.ID, nil, &newComment); err != nil {
+		t.Fatalf("Failed to patch comment: %v", err)
+	}
+	patched, err := db.GetRating(rating.ID)
+	if err != nil {
+		t.Fatalf("Failed to get rating: %v", err)
+	}
+	if patched.Rating != 4 || patched.Comment == nil || *patched.Comment != newComment {
+		t.Errorf("Expected rating 4 with comment %q, got %d with %v", newComment, patched.Rating, patched.Comment)
+	}
+
+	// Only the score changes
+	if err := db.PatchRating(rating.ID, intPtr(2), nil); err != nil {
+		t.Fatalf("Failed to patch score: %v", err)
+	}
+	patched, err = db.GetRating(rating.ID)
+	if err != nil {
+		t.Fatalf("Failed to get rating: %v", err)
+	}
+	if patched.Rating != 2 || patched.Comment == nil || *patched.Comment != newComment {
+		t.Errorf("Expected rating 2 with comment %q, got %d with %v", newComment, patched.Rating, patched.Comment)
+	}
+
+	if err := db.PatchRating(rating.ID, intPtr(6), nil); err == nil {
+		t.Error("Expected an error for an out-of-range rating")
+	}
+	if err := db.PatchRating(999, intPtr(3), nil); !errors.Is(err, ErrRatingNotFound) {
+		t.Errorf("Expected ErrRatingNotFound, got %v", err)
+	}
+	if err := db.PatchRating(999, nil, nil); !errors.Is(err, ErrRatingNotFound) {
+		t.Errorf("Expected ErrRatingNotFound for an empty patch, got %v", err)
+	}
+}
+
+// openUnmigratedTestDBs opens n connections to one fresh database file
+// without running migrations
+func openUnmigratedTestDBs(t *testing.T, n int, lockTimeout time.Duration) []*DB {
+	path := filepath.Join(t.TempDir(), "shared.db")
+
+	var dbs []*DB
+	for i := 0; i < n; i++ {
+		db, err := New(&Config{
+			DatabasePath:         path,
+			MigrationsDir:        "../../database/migrations",
+			BusyTimeout:          5 * time.Second,
+			WALMode:              true,
+			MigrationLockTimeout: lockTimeout,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		dbs = append(dbs, db)
+	}
+	return dbs
+}
+
+func TestRunMigrationsConcurrently(t *testing.T) {
+	dbs := openUnmigratedTestDBs(t, 2, 10*time.Second)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(dbs))
+	for i, db := range dbs {
+		wg.Add(1)
+		go func(i int, db *DB) {
+			defer wg.Done()
+			errs[i] = db.RunMigrations(db.config.MigrationsDir)
+		}(i, db)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Instance %d failed to run migrations: %v", i, err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dbs[0].config.MigrationsDir, "*.up.sql"))
+	if err != nil {
+		t.Fatalf("Failed to find migration files: %v", err)
+	}
+
+	var applied int
+	if err := dbs[0].conn.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&applied); err != nil {
+		t.Fatalf("Failed to count applied migrations: %v", err)
+	}
+	if applied != len(files) {
+		t.Errorf("Expected %d applied migrations, got %d", len(files), applied)
+	}
+
+	var locks int
+	if err := dbs[0].conn.QueryRow("SELECT COUNT(*) FROM migration_lock").Scan(&locks); err != nil {
+		t.Fatalf("Failed to count migration locks: %v", err)
+	}
+	if locks != 0 {
+		t.Errorf("Expected the migration lock to be released, got %d rows", locks)
+	}
+}
+
+func TestRunMigrationsLockTimeout(t *testing.T) {
+	dbs := openUnmigratedTestDBs(t, 1, 100*time.Millisecond)
+	db := dbs[0]
+
+	release, err := db.acquireMigrationLock()
+	if err != nil {
+		t.Fatalf("Failed to acquire migration lock: %v", err)
+	}
+
+	if err := db.RunMigrations(db.config.MigrationsDir); !errors.Is(err, ErrMigrationLockTimeout) {
+		t.Fatalf("Expected ErrMigrationLockTimeout while the lock is held, got %v", err)
+	}
+
+	release()
+	if err := db.RunMigrations(db.config.MigrationsDir); err != nil {
+		t.Errorf("Expected migrations to run once the lock is released, got %v", err)
+	}
+}
+
+// operationRecorder counts the database operations it is told about
+type operationRecorder struct {
+	mu         sync.Mutex
+	operations map[string]int
+}
+
+func (r *operationRecorder) ObserveRequest(handler string, status int, duration time.Duration) {}
+
+func (r *operationRecorder) ObserveDBOperation(operation string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.operations[operation]++
+}
+
+func TestOperationMetrics(t *testing.T) {
+	recorder := &operationRecorder{operations: make(map[string]int)}
+	db := setupTestDBWithConfig(t, func(config *Config) {
+		config.Metrics = recorder
+	})
+
+	conv, _, err := db.GetOrCreateConversationBySessionID("metrics-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateMessage(conv.ID, "prompt", "hello", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := db.Stats(); err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+
+	for _, operation := range []string{"get_or_create_conversation", "create_message", "stats"} {
+		if recorder.operations[operation] != 1 {
+			t.Errorf("Expected 1 %s observation, got %d", operation, recorder.operations[operation])
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		wantErr   string
+	}{
+		{"default config", func(c *Config) {}, ""},
+		{"lowercase synchronous", func(c *Config) { c.Synchronous = "full" }, ""},
+		{"empty synchronous", func(c *Config) { c.Synchronous = "" }, ""},
+		{"misspelled synchronous", func(c *Config) { c.Synchronous = "NROMAL" }, `invalid synchronous mode "NROMAL"`},
+		{"injected synchronous", func(c *Config) { c.Synchronous = "OFF&_foo=1" }, "invalid synchronous mode"},
+		{"negative cache size", func(c *Config) { c.CacheSize = -1 }, "cache size cannot be negative"},
+		{"negative busy timeout", func(c *Config) { c.BusyTimeout = -time.Second }, "busy timeout cannot be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.configure(config)
+
+			err := config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected config to be valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultConfig()
+	config.DatabasePath = filepath.Join(dir, "nested", "test.db")
+	config.Synchronous = "NROMAL"
+
+	if _, err := New(config); err == nil {
+		t.Fatal("Expected New to reject an invalid synchronous mode")
+	}
+
+	// Validation happens before anything is created on disk
+	if _, err := os.Stat(filepath.Join(dir, "nested")); !os.IsNotExist(err) {
+		t.Errorf("Expected no database directory to be created, got %v", err)
+	}
+}
+
+func TestInMemoryDatabase(t *testing.T) {
+	for _, path := range []string{MemoryPath, "file::memory:?cache=shared"} {
+		t.Run(path, func(t *testing.T) {
+			config := MemoryConfig()
+			config.DatabasePath = path
+			config.MigrationsDir = "../../database/migrations"
+
+			db, err := New(config)
+			if err != nil {
+				t.Fatalf("Failed to open in-memory database: %v", err)
+			}
+			defer db.Close()
+
+			if err := db.RunMigrations(config.MigrationsDir); err != nil {
+				t.Fatalf("Failed to run migrations: %v", err)
+			}
+
+			conv, err := db.CreateConversation("memory-session", stringPtr("In memory"), nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to create conversation: %v", err)
+			}
+
+			got, err := db.GetConversation(conv.ID)
+			if err != nil {
+				t.Fatalf("Failed to read conversation back: %v", err)
+			}
+			if got.SessionID != "memory-session" || got.Title == nil || *got.Title != "In memory" {
+				t.Errorf("Expected the stored conversation, got %+v", got)
+			}
+
+			stats, err := db.Stats()
+			if err != nil {
+				t.Fatalf("Failed to get stats: %v", err)
+			}
+			if stats["conversations"] != 1 {
+				t.Errorf("Expected 1 conversation in stats, got %v", stats["conversations"])
+			}
+			if _, ok := stats["database_size_bytes"]; ok {
+				t.Error("Expected no database file size for an in-memory database")
+			}
+		})
+	}
+
+	// No directory named after the in-memory path is created
+	if _, err := os.Stat("file:"); !os.IsNotExist(err) {
+		t.Errorf("Expected no directory for the in-memory URI, got %v", err)
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	db := setupTestDB(t)
+
+	insert := func(tx *sql.Tx, sessionID string) error {
+		_, err := tx.Exec("INSERT INTO conversations (session_id) VALUES (?)", sessionID)
+		return err
+	}
+	count := func(sessionID string) int {
+		var n int
+		if err := db.conn.QueryRow("SELECT COUNT(*) FROM conversations WHERE session_id = ?", sessionID).Scan(&n); err != nil {
+			t.Fatalf("Failed to count conversations: %v", err)
+		}
+		return n
+	}
+
+	t.Run("commits on success", func(t *testing.T) {
+		err := db.WithTx(func(tx *sql.Tx) error {
+			return insert(tx, "tx-commit")
+		})
+		if err != nil {
+			t.Fatalf("WithTx() error = %v", err)
+		}
+		if got := count("tx-commit"); got != 1 {
+			t.Errorf("Expected the insert to be committed, got %d rows", got)
+		}
+	})
+
+	t.Run("rolls back on error", func(t *testing.T) {
+		errFailed := errors.New("second step failed")
+		err := db.WithTx(func(tx *sql.Tx) error {
+			if err := insert(tx, "tx-error"); err != nil {
+				return err
+			}
+			return errFailed
+		})
+		if !errors.Is(err, errFailed) {
+			t.Fatalf("Expected the callback's error, got %v", err)
+		}
+		if got := count("tx-error"); got != 0 {
+			t.Errorf("Expected the insert to be rolled back, got %d rows", got)
+		}
+	})
+
+	t.Run("rolls back on panic", func(t *testing.T) {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected the panic to be re-raised")
+				}
+			}()
+			db.WithTx(func(tx *sql.Tx) error {
+				if err := insert(tx, "tx-panic"); err != nil {
+					return err
+				}
+				panic("boom")
+			})
+		}()
+
+		if got := count("tx-panic"); got != 0 {
+			t.Errorf("Expected the insert to be rolled back, got %d rows", got)
+		}
+
+		// The connection is usable again after the rollback
+		if err := db.WithTx(func(tx *sql.Tx) error { return insert(tx, "tx-after-panic") }); err != nil {
+			t.Errorf("WithTx() after panic error = %v", err)
+		}
+	})
+}
+
+func TestListConversationsByWorkingDirectory(t *testing.T) {
+	db := setupTestDB(t)
+
+	dirs := map[string]string{
+		"repo-a":        "/home/dev/repo",
+		"repo-b":        "/home/dev/repo/",
+		"repo-sub":      "/home/dev/repo/cmd",
+		"repo-sibling":  "/home/dev/repo2",
+		"repo-case":     "/home/dev/Repo/cmd",
+		"repo-wildcard": "/home/dev/re_o/x",
+	}
+	for sessionID, dir := range dirs {
+		if _, err := db.CreateConversation(sessionID, nil, stringPtr(dir), nil); err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+	}
+	if _, err := db.CreateConversation("no-dir", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	sessions := func(conversations []Conversation) []string {
+		var ids []string
+		for _, conv := range conversations {
+			ids = append(ids, conv.SessionID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	t.Run("exact match across sessions", func(t *testing.T) {
+		conversations, err := db.ListConversationsByWorkingDirectory("/home/dev/repo/", 10, 0)
+		if err != nil {
+			t.Fatalf("ListConversationsByWorkingDirectory() error = %v", err)
+		}
+		if got := strings.Join(sessions(conversations), ","); got != "repo-a,repo-b" {
+			t.Errorf("Expected repo-a and repo-b, got %s", got)
+		}
+
+		count, err := db.GetConversationCountByWorkingDirectory("/home/dev/repo")
+		if err != nil {
+			t.Fatalf("GetConversationCountByWorkingDirectory() error = %v", err)
+		}
+		if count != 2 {
+			t.Errorf("Expected count 2, got %d", count)
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		first, err := db.ListConversationsByWorkingDirectory("/home/dev/repo", 1, 0)
+		if err != nil {
+			t.Fatalf("ListConversationsByWorkingDirectory() error = %v", err)
+		}
+		second, err := db.ListConversationsByWorkingDirectory("/home/dev/repo", 1, 1)
+		if err != nil {
+			t.Fatalf("ListConversationsByWorkingDirectory() error = %v", err)
+		}
+		if len(first) != 1 || len(second) != 1 || first[0].ID == second[0].ID {
+			t.Errorf("Expected two distinct single-item pages, got %v and %v", sessions(first), sessions(second))
+		}
+	})
+
+	t.Run("subtree match", func(t *testing.T) {
+		filter := &ConversationFilter{WorkingDirectory: "/home/dev/repo", WorkingDirectoryPrefix: true}
+		conversations, err := db.ListConversations(filter, 10, 0)
+		if err != nil {
+			t.Fatalf("ListConversations() error = %v", err)
+		}
+		if got := strings.Join(sessions(conversations), ","); got != "repo-a,repo-b,repo-sub" {
+			t.Errorf("Expected the directory and its subdirectories only, got %s", got)
+		}
+
+		count, err := db.GetConversationCount(filter)
+		if err != nil {
+			t.Fatalf("GetConversationCount() error = %v", err)
+		}
+		if count != 3 {
+			t.Errorf("Expected count 3, got %d", count)
+		}
+	})
+
+	t.Run("wildcards are literal", func(t *testing.T) {
+		filter := &ConversationFilter{WorkingDirectory: "/home/dev/re_o", WorkingDirectoryPrefix: true}
+		conversations, err := db.ListConversations(filter, 10, 0)
+		if err != nil {
+			t.Fatalf("ListConversations() error = %v", err)
+		}
+		if got := strings.Join(sessions(conversations), ","); got != "repo-wildcard" {
+			t.Errorf("Expected only repo-wildcard, got %s", got)
+		}
+	})
+}
+
+// capturingLogger records log messages for assertions
+type capturingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *capturingLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+func (l *capturingLogger) Errorf(format string, v ...interface{}) {
+	l.Printf("ERROR: "+format, v...)
+}
+
+func TestMigrationLogging(t *testing.T) {
+	logger := &capturingLogger{}
+	setupTestDBWithConfig(t, func(config *Config) {
+		config.Logger = logger
+	})
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	if len(logger.messages) == 0 || logger.messages[0] != "Applied migration: 001" {
+		t.Errorf("Expected applied migrations to be logged through the configured logger, got %q", logger.messages)
+	}
+}
+
+// copyMigrations copies the up migrations whose version is at most through
+// into dir, so a test can migrate a database in stages
+func copyMigrations(t *testing.T, dir, through string) {
+	files, err := filepath.Glob("../../database/migrations/*.up.sql")
+	if err != nil {
+		t.Fatalf("Failed to find migration files: %v", err)
+	}
+	for _, file := range files {
+		if extractVersionFromFilename(filepath.Base(file)) > through {
+			continue
+		}
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read migration %s: %v", file, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(file)), contents, 0o644); err != nil {
+			t.Fatalf("Failed to copy migration %s: %v", file, err)
+		}
+	}
+}
+
+func TestConversationStatsMigrationKeepsUpdatedAt(t *testing.T) {
+	migrationsDir := t.TempDir()
+	copyMigrations(t, migrationsDir, "003")
+
+	db, err := New(&Config{
+		DatabasePath:  filepath.Join(t.TempDir(), "stats.db"),
+		MigrationsDir: migrationsDir,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.RunMigrations(migrationsDir); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// The old stats trigger counts the response as a prompt, leaving a
+	// count the migration has to correct
+	setup := []string{
+		`INSERT INTO conversations (id, session_id) VALUES (1, 'with-messages'), (2, 'without-messages')`,
+		`INSERT INTO messages (conversation_id, message_type, content, character_count) VALUES
+			(1, 'prompt', 'hello', 5), (1, 'response', 'hi', 2)`,
+	}
+	for _, stmt := range setup {
+		if _, err := db.conn.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed database: %v", err)
+		}
+	}
+
+	// Back-date updated_at without the timestamp trigger resetting it
+	var triggerSQL string
+	if err := db.conn.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'trigger' AND name = 'update_conversation_timestamp'").Scan(&triggerSQL); err != nil {
+		t.Fatalf("Failed to read timestamp trigger: %v", err)
+	}
+	backdate := []string{
+		"DROP TRIGGER update_conversation_timestamp",
+		"UPDATE conversations SET updated_at = '2024-01-01 00:00:00'",
+		triggerSQL,
+	}
+	for _, stmt := range backdate {
+		if _, err := db.conn.Exec(stmt); err != nil {
+			t.Fatalf("Failed to back-date conversations: %v", err)
+		}
+	}
+
+	copyMigrations(t, migrationsDir, "004")
+	if err := db.RunMigrations(migrationsDir); err != nil {
+		t.Fatalf("Failed to run migration 004: %v", err)
+	}
+
+	rows, err := db.conn.Query("SELECT id, prompt_count, updated_at FROM conversations ORDER BY id")
+	if err != nil {
+		t.Fatalf("Failed to read conversations: %v", err)
+	}
+	defer rows.Close()
+
+	expectedCounts := map[int]int{1: 1, 2: 0}
+	for rows.Next() {
+		var id, promptCount int
+		var updatedAt time.Time
+		if err := rows.Scan(&id, &promptCount, &updatedAt); err != nil {
+			t.Fatalf("Failed to scan conversation: %v", err)
+		}
+		if promptCount != expectedCounts[id] {
+			t.Errorf("Conversation %d: expected prompt_count %d, got %d", id, expectedCounts[id], promptCount)
+		}
+		if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !updatedAt.Equal(want) {
+			t.Errorf("Conversation %d: expected updated_at to stay %v, got %v", id, want, updatedAt)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Failed to read conversations: %v", err)
+	}
+
+	// The timestamp trigger must be back in place
+	if _, err := db.conn.Exec("UPDATE conversations SET title = 'Touched' WHERE id = 2"); err != nil {
+		t.Fatalf("Failed to update conversation: %v", err)
+	}
+	var updatedAt time.Time
+	if err := db.conn.QueryRow("SELECT updated_at FROM conversations WHERE id = 2").Scan(&updatedAt); err != nil {
+		t.Fatalf("Failed to read conversation: %v", err)
+	}
+	if updatedAt.Year() == 2024 {
+		t.Error("Expected the timestamp trigger to be recreated by the migration")
+	}
+}
+
+func TestForeignKeysEnabled(t *testing.T) {
+	db := setupTestDB(t)
+
+	var enabled int
+	if err := db.conn.QueryRow("PRAGMA foreign_keys").Scan(&enabled); err != nil {
+		t.Fatalf("Failed to read foreign_keys: %v", err)
+	}
+	if enabled != 1 {
+		t.Errorf("Expected PRAGMA foreign_keys = 1, got %d", enabled)
+	}
+}
+
+func TestRunMigrationsStaleLock(t *testing.T) {
+	dbs := openUnmigratedTestDBs(t, 1, 100*time.Millisecond)
+	db := dbs[0]
+	db.config.MigrationLockStaleAfter = time.Minute
+
+	// Simulate an instance that crashed while holding the lock
+	if _, err := db.acquireMigrationLock(); err != nil {
+		t.Fatalf("Failed to acquire migration lock: %v", err)
+	}
+	if _, err := db.conn.Exec("UPDATE migration_lock SET owner = 'crashed', acquired_at = datetime('now', '-5 minutes')"); err != nil {
+		t.Fatalf("Failed to age migration lock: %v", err)
+	}
+
+	if err := db.RunMigrations(db.config.MigrationsDir); err != nil {
+		t.Fatalf("Expected a stale lock to be taken over, got %v", err)
+	}
+
+	var locks int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM migration_lock").Scan(&locks); err != nil {
+		t.Fatalf("Failed to count migration locks: %v", err)
+	}
+	if locks != 0 {
+		t.Errorf("Expected the migration lock to be released, got %d rows", locks)
+	}
+}
@@ -1,9 +1,33 @@
 package database
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
 
 // Define sentinel errors for common database conditions
 var (
-	ErrConversationNotFound = errors.New("conversation not found")
-	ErrRatingNotFound       = errors.New("rating not found")
-)
\ No newline at end of file
+	ErrConversationNotFound      = errors.New("conversation not found")
+	ErrRatingNotFound            = errors.New("rating not found")
+	ErrMessageNotFound           = errors.New("message not found")
+	ErrTagNotFound               = errors.New("tag not found")
+	ErrTagAlreadyExists          = errors.New("tag already exists")
+	ErrConversationTagNotFound   = errors.New("tag not attached to conversation")
+	ErrSessionConversationLimit  = errors.New("session conversation limit reached")
+	ErrCreationRateRangeTooLarge = errors.New("creation rate range spans too many buckets")
+	ErrTagRuleNotFound           = errors.New("tag rule not found")
+	ErrSessionQuotaExceeded      = errors.New("session character quota exceeded")
+	ErrMigrationLockTimeout      = errors.New("timed out waiting for the migration lock")
+	ErrSessionNotFound           = errors.New("session not found")
+	ErrInvalidSessionTransition  = errors.New("invalid session status transition")
+)
+
+// isUniqueViolation reports whether err is a SQLite UNIQUE constraint failure
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+	return false
+}
\ No newline at end of file
@@ -0,0 +1,340 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/models"
+)
+
+// sqliteTimestampLayout matches the format SQLite uses for CURRENT_TIMESTAMP,
+// so bound times compare correctly against default column values
+const sqliteTimestampLayout = "2006-01-02 15:04:05"
+
+// ConversationFilter narrows the conversations returned by ListConversations
+// and counted by GetConversationCount. Zero-valued fields are ignored.
+type ConversationFilter struct {
+	// CreatedAfter keeps conversations created at or after this time
+	CreatedAfter *time.Time
+	// CreatedBefore keeps conversations created at or before this time
+	CreatedBefore *time.Time
+	// TagIDs keeps conversations carrying these tags, combined per TagMode
+	TagIDs []int
+	// TagMode selects whether every tag or any tag must match; the zero
+	// value requires every tag
+	TagMode TagMatchMode
+	// Sort orders the results; the zero value orders by updated_at descending
+	Sort SortOption
+	// IncludeDeleted keeps soft-deleted conversations in the results
+	IncludeDeleted bool
+	// AwaitingResponse keeps conversations whose latest message is a prompt
+	AwaitingResponse bool
+	// ToolName keeps conversations where at least one message called this tool
+	ToolName string
+	// WorkingDirectory keeps conversations recorded in this directory,
+	// ignoring trailing slashes
+	WorkingDirectory string
+	// WorkingDirectoryPrefix also keeps conversations recorded in
+	// directories below WorkingDirectory
+	WorkingDirectoryPrefix bool
+}
+
+// TagMatchMode controls how multiple tags in a filter are combined
+type TagMatchMode string
+
+const (
+	TagMatchAll TagMatchMode = "all"
+	TagMatchAny TagMatchMode = "any"
+)
+
+// ParseTagMatchMode parses a tag_mode parameter, which must be "all" or "any"
+func ParseTagMatchMode(value string) (TagMatchMode, error) {
+	switch mode := TagMatchMode(value); mode {
+	case TagMatchAll, TagMatchAny:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid tag mode %q: must be all or any", value)
+	}
+}
+
+// MessageOrder sets whether a conversation's messages are returned oldest or
+// newest first
+type MessageOrder string
+
+const (
+	MessageOrderAsc  MessageOrder = "asc"
+	MessageOrderDesc MessageOrder = "desc"
+)
+
+// ParseMessageOrder parses an order parameter, which must be "asc" or "desc"
+func ParseMessageOrder(value string) (MessageOrder, error) {
+	switch order := MessageOrder(value); order {
+	case MessageOrderAsc, MessageOrderDesc:
+		return order, nil
+	default:
+		return "", fmt.Errorf("invalid order %q: must be asc or desc", value)
+	}
+}
+
+// SortField is a conversation column that listings may be ordered by
+type SortField string
+
+const (
+	SortByCreatedAt       SortField = "created_at"
+	SortByUpdatedAt       SortField = "updated_at"
+	SortByPromptCount     SortField = "prompt_count"
+	SortByTotalCharacters SortField = "total_characters"
+	SortByRating          SortField = "rating"
+)
+
+// sortableFields is the allowlist of columns accepted by ParseSortOption
+var sortableFields = []SortField{SortByCreatedAt, SortByUpdatedAt, SortByPromptCount, SortByTotalCharacters, SortByRating}
+
+// SortOption describes the ordering of a conversation listing
+type SortOption struct {
+	Field     SortField
+	Ascending bool
+}
+
+// ParseSortOption parses a sort parameter of the form "field" or
+// "field:asc"/"field:desc". Fields default to descending order and must be
+// one of the allowlisted columns.
+func ParseSortOption(value string) (SortOption, error) {
+	field, direction, hasDirection := strings.Cut(value, ":")
+
+	var option SortOption
+	for _, allowed := range sortableFields {
+		if SortField(field) == allowed {
+			option.Field = allowed
+		}
+	}
+
+	if option.Field == "" {
+		allowed := make([]string, len(sortableFields))
+		for i, f := range sortableFields {
+			allowed[i] = string(f)
+		}
+		return SortOption{}, fmt.Errorf("invalid sort field %q: must be one of %s", field, strings.Join(allowed, ", "))
+	}
+
+	if hasDirection {
+		switch direction {
+		case "asc":
+			option.Ascending = true
+		case "desc":
+			option.Ascending = false
+		default:
+			return SortOption{}, fmt.Errorf("invalid sort direction %q: must be asc or desc", direction)
+		}
+	}
+
+	return option, nil
+}
+
+// orderByClause renders the ORDER BY clause for the option. Only allowlisted
+// fields are ever interpolated; anything else falls back to the default order.
+// Sorting by rating orders on each conversation's score under the method.
+func (o SortOption) orderByClause(method models.RatingAggregation) string {
+	field := SortByUpdatedAt
+	for _, allowed := range sortableFields {
+		if o.Field == allowed {
+			field = allowed
+		}
+	}
+
+	direction := "DESC"
+	if o.Ascending && o.Field != "" {
+		direction = "ASC"
+	}
+
+	// Unrated conversations sort last in either direction, and ties go to
+	// the most recently active conversation
+	if field == SortByRating {
+		score := ratingScoreExpr(method, "conversations.id")
+		return fmt.Sprintf("ORDER BY %s IS NULL, %s %s, updated_at DESC, id DESC", score, score, direction)
+	}
+
+	return fmt.Sprintf("ORDER BY %s %s, id %s", field, direction, direction)
+}
+
+// orderByClause renders the ORDER BY clause for the filter's sort option
+func (f *ConversationFilter) orderByClause(method models.RatingAggregation) string {
+	if f == nil {
+		return SortOption{}.orderByClause(method)
+	}
+	return f.Sort.orderByClause(method)
+}
+
+// whereClause builds the WHERE clause and arguments for the filter. A nil
+// filter only excludes soft-deleted conversations.
+func (f *ConversationFilter) whereClause() (string, []interface{}) {
+	if f == nil {
+		return "\n\tWHERE deleted_at IS NULL", nil
+	}
+
+	var conditions []string
+	var args []interface{}
+
+	if !f.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	if f.CreatedAfter != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, f.CreatedAfter.UTC().Format(sqliteTimestampLayout))
+	}
+
+	if f.CreatedBefore != nil {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, f.CreatedBefore.UTC().Format(sqliteTimestampLayout))
+	}
+
+	if len(f.TagIDs) > 0 {
+		condition, tagArgs := tagFilterCondition(f.TagIDs, f.TagMode)
+		conditions = append(conditions, condition)
+		args = append(args, tagArgs...)
+	}
+
+	if f.AwaitingResponse {
+		conditions = append(conditions, awaitingResponseCondition)
+	}
+
+	if f.ToolName != "" {
+		conditions = append(conditions, toolUsedCondition)
+		args = append(args, f.ToolName)
+	}
+
+	if f.WorkingDirectory != "" {
+		condition, dirArgs := workingDirectoryCondition(f.WorkingDirectory, f.WorkingDirectoryPrefix)
+		conditions = append(conditions, condition)
+		args = append(args, dirArgs...)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return "\n\tWHERE " + strings.Join(conditions, " AND "), args
+}
+
+// awaitingResponseCondition matches conversations whose most recent message
+// is a prompt, i.e. no response was recorded after the user's last prompt
+const awaitingResponseCondition = `(
+		SELECT m.message_type FROM messages m
+		WHERE m.conversation_id = conversations.id
+		ORDER BY m.timestamp DESC, m.id DESC
+		LIMIT 1) = 'prompt'`
+
+// toolUsedCondition matches conversations with a message whose stored tool
+// calls include one with the bound name
+const toolUsedCondition = `EXISTS (
+		SELECT 1 FROM messages m
+		` + toolCallsJoin + `
+		WHERE m.conversation_id = conversations.id
+		AND json_extract(tc.value, '$.name') = ?)`
+
+// workingDirectoryCondition matches conversations recorded in dir, ignoring
+// trailing slashes on either side. With subtree set it also matches every
+// directory below dir, but not siblings that merely share its prefix. The
+// comparison is case-sensitive, unlike LIKE.
+func workingDirectoryCondition(dir string, subtree bool) (string, []interface{}) {
+	dir = strings.TrimRight(dir, "/")
+
+	if !subtree {
+		return "(working_directory <> '' AND RTRIM(working_directory, '/') = ?)", []interface{}{dir}
+	}
+
+	below := dir + "/"
+	condition := `(working_directory <> '' AND (
+		RTRIM(working_directory, '/') = ? OR substr(working_directory, 1, length(?)) = ?))`
+	return condition, []interface{}{dir, below, below}
+}
+
+// tagFilterCondition builds a condition matching conversations that carry
+// all of the given tags, or at least one of them in TagMatchAny mode.
+// Duplicate tag IDs are ignored.
+func tagFilterCondition(tagIDs []int, mode TagMatchMode) (string, []interface{}) {
+	seen := make(map[int]bool)
+	var args []interface{}
+	for _, id := range tagIDs {
+		if !seen[id] {
+			seen[id] = true
+			args = append(args, id)
+		}
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+
+	if mode == TagMatchAny {
+		condition := `id IN (
+		SELECT conversation_id FROM conversation_tags
+		WHERE tag_id IN (` + placeholders + `))`
+		return condition, args
+	}
+
+	condition := `id IN (
+		SELECT conversation_id FROM conversation_tags
+		WHERE tag_id IN (` + placeholders + `)
+		GROUP BY conversation_id
+		HAVING COUNT(DISTINCT tag_id) = ?)`
+
+	return condition, append(args, len(args))
+}
+
+// MessageFilter narrows the messages returned by ListMessages and counted by
+// GetMessageCount. Zero-valued fields are ignored; a zero Limit returns every
+// matching message.
+type MessageFilter struct {
+	// MessageType keeps only prompts or only responses
+	MessageType string
+	// SessionID keeps messages from conversations in this session
+	SessionID string
+	// From keeps messages sent at or after this time
+	From *time.Time
+	// To keeps messages sent at or before this time
+	To *time.Time
+	// HasToolCalls keeps messages with (true) or without (false) tool calls
+	HasToolCalls *bool
+	Limit        int
+	Offset       int
+}
+
+// whereClause builds the WHERE clause and arguments for the filter. Messages
+// are always joined to their conversation as c, and messages belonging to
+// soft-deleted conversations are excluded.
+func (f MessageFilter) whereClause() (string, []interface{}) {
+	conditions := []string{"c.deleted_at IS NULL"}
+	var args []interface{}
+
+	if f.MessageType != "" {
+		conditions = append(conditions, "m.message_type = ?")
+		args = append(args, f.MessageType)
+	}
+
+	if f.SessionID != "" {
+		conditions = append(conditions, "c.session_id = ?")
+		args = append(args, f.SessionID)
+	}
+
+	if f.From != nil {
+		conditions = append(conditions, "m.timestamp >= ?")
+		args = append(args, f.From.UTC().Format(sqliteTimestampLayout))
+	}
+
+	if f.To != nil {
+		conditions = append(conditions, "m.timestamp <= ?")
+		args = append(args, f.To.UTC().Format(sqliteTimestampLayout))
+	}
+
+	if f.HasToolCalls != nil {
+		hasToolCalls := "m.tool_calls IS NOT NULL AND m.tool_calls NOT IN ('', 'null', '[]')"
+		if *f.HasToolCalls {
+			conditions = append(conditions, hasToolCalls)
+		} else {
+			conditions = append(conditions, "NOT ("+hasToolCalls+")")
+		}
+	}
+
+	return "\n\tWHERE " + strings.Join(conditions, " AND "), args
+}
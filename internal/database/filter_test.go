@@ -0,0 +1,319 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListConversationsDateFilter(t *testing.T) {
+	db := setupTestDB(t)
+
+	createdAt := []string{"2024-01-01 09:00:00", "2024-02-01 09:00:00", "2024-03-01 09:00:00"}
+	for _, ts := range createdAt {
+		conv, err := db.CreateConversation("date-session", nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		if _, err := db.conn.Exec("UPDATE conversations SET created_at = ? WHERE id = ?", ts, conv.ID); err != nil {
+			t.Fatalf("Failed to backdate conversation: %v", err)
+		}
+	}
+
+	jan := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	// Same instant as feb, expressed in another zone
+	febOffset := feb.In(time.FixedZone("UTC+2", 2*60*60))
+
+	tests := []struct {
+		name     string
+		filter   *ConversationFilter
+		expected int
+	}{
+		{"nil filter", nil, 3},
+		{"created after", &ConversationFilter{CreatedAfter: &feb}, 2},
+		{"created before", &ConversationFilter{CreatedBefore: &feb}, 2},
+		{"inclusive range", &ConversationFilter{CreatedAfter: &jan, CreatedBefore: &feb}, 2},
+		{"offset time zone", &ConversationFilter{CreatedAfter: &febOffset, CreatedBefore: &febOffset}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversations, err := db.ListConversations(tt.filter, 10, 0)
+			if err != nil {
+				t.Fatalf("Failed to list conversations: %v", err)
+			}
+			if len(conversations) != tt.expected {
+				t.Errorf("Expected %d conversations, got %d", tt.expected, len(conversations))
+			}
+
+			count, err := db.GetConversationCount(tt.filter)
+			if err != nil {
+				t.Fatalf("Failed to count conversations: %v", err)
+			}
+			if count != tt.expected {
+				t.Errorf("Expected count %d, got %d", tt.expected, count)
+			}
+		})
+	}
+}
+
+func TestParseSortOption(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		expected  SortOption
+		expectErr bool
+	}{
+		{"field only", "created_at", SortOption{Field: SortByCreatedAt}, false},
+		{"ascending", "prompt_count:asc", SortOption{Field: SortByPromptCount, Ascending: true}, false},
+		{"descending", "total_characters:desc", SortOption{Field: SortByTotalCharacters}, false},
+		{"rating", "rating", SortOption{Field: SortByRating}, false},
+		{"unknown field", "title", SortOption{}, true},
+		{"injection attempt", "id; DROP TABLE conversations", SortOption{}, true},
+		{"unknown direction", "updated_at:up", SortOption{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option, err := ParseSortOption(tt.value)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("ParseSortOption() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if option != tt.expected {
+				t.Errorf("ParseSortOption() = %+v, expected %+v", option, tt.expected)
+			}
+		})
+	}
+}
+
+func TestListConversationsSort(t *testing.T) {
+	db := setupTestDB(t)
+
+	var ids []int
+	for i, content := range []string{"a", "bb", "ccc"} {
+		conv, err := db.CreateConversation("sort-session", nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		// Give each conversation a distinct prompt count
+		for j := 0; j <= i; j++ {
+			if _, err := db.CreateMessage(conv.ID, "prompt", content, nil, nil); err != nil {
+				t.Fatalf("Failed to create message: %v", err)
+			}
+		}
+		ids = append(ids, conv.ID)
+	}
+
+	tests := []struct {
+		name     string
+		sort     SortOption
+		expected []int
+	}{
+		{"prompt count ascending", SortOption{Field: SortByPromptCount, Ascending: true}, []int{ids[0], ids[1], ids[2]}},
+		{"prompt count descending", SortOption{Field: SortByPromptCount}, []int{ids[2], ids[1], ids[0]}},
+		{"total characters descending", SortOption{Field: SortByTotalCharacters}, []int{ids[2], ids[1], ids[0]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversations, err := db.ListConversations(&ConversationFilter{Sort: tt.sort}, 10, 0)
+			if err != nil {
+				t.Fatalf("Failed to list conversations: %v", err)
+			}
+			if len(conversations) != len(tt.expected) {
+				t.Fatalf("Expected %d conversations, got %d", len(tt.expected), len(conversations))
+			}
+			for i, conv := range conversations {
+				if conv.ID != tt.expected[i] {
+					t.Errorf("Position %d: expected conversation %d, got %d", i, tt.expected[i], conv.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestListConversationsSortByRating(t *testing.T) {
+	db := setupTestDB(t)
+
+	best, err := db.CreateConversation("rating-sort", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateConversationRating(best.ID, 5, nil); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+
+	// Rated through its messages, averaging 3 and recently active
+	recent, err := db.CreateConversation("rating-sort", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	for _, rating := range []int{2, 4} {
+		message, err := db.CreateMessage(recent.ID, "response", "answer", nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if _, err := db.CreateMessageRating(message.ID, rating, nil); err != nil {
+			t.Fatalf("Failed to create rating: %v", err)
+		}
+	}
+
+	// Also averaging 3, but idle for years, so it loses the tie
+	result, err := db.conn.Exec(
+		"INSERT INTO conversations (session_id, created_at, updated_at) VALUES (?, ?, ?)",
+		"rating-sort", "2020-01-01 00:00:00", "2020-01-01 00:00:00",
+	)
+	if err != nil {
+		t.Fatalf("Failed to insert conversation: %v", err)
+	}
+	staleID, _ := result.LastInsertId()
+	if _, err := db.CreateConversationRating(int(staleID), 3, nil); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+
+	unrated, err := db.CreateConversation("rating-sort", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		sort     SortOption
+		expected []int
+	}{
+		{"descending", SortOption{Field: SortByRating}, []int{best.ID, recent.ID, int(staleID), unrated.ID}},
+		{"ascending keeps unrated last", SortOption{Field: SortByRating, Ascending: true}, []int{recent.ID, int(staleID), best.ID, unrated.ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversations, err := db.ListConversations(&ConversationFilter{Sort: tt.sort}, 10, 0)
+			if err != nil {
+				t.Fatalf("Failed to list conversations: %v", err)
+			}
+			if len(conversations) != len(tt.expected) {
+				t.Fatalf("Expected %d conversations, got %d", len(tt.expected), len(conversations))
+			}
+			for i, conv := range conversations {
+				if conv.ID != tt.expected[i] {
+					t.Errorf("Position %d: expected conversation %d, got %d", i, tt.expected[i], conv.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestListConversationsAwaitingResponse(t *testing.T) {
+	db := setupTestDB(t)
+
+	waiting, err := db.CreateConversation("waiting-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	answered, err := db.CreateConversation("answered-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateConversation("empty-session", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	messages := []struct {
+		convID      int
+		messageType string
+	}{
+		{waiting.ID, "prompt"},
+		{waiting.ID, "response"},
+		{waiting.ID, "prompt"},
+		{answered.ID, "prompt"},
+		{answered.ID, "response"},
+	}
+	for _, m := range messages {
+		if _, err := db.CreateMessage(m.convID, m.messageType, "content", nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	filter := &ConversationFilter{AwaitingResponse: true}
+	conversations, err := db.ListConversations(filter, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list conversations: %v", err)
+	}
+	if len(conversations) != 1 || conversations[0].ID != waiting.ID {
+		t.Fatalf("Expected only conversation %d, got %+v", waiting.ID, conversations)
+	}
+
+	count, err := db.GetConversationCount(filter)
+	if err != nil {
+		t.Fatalf("Failed to count conversations: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected count 1, got %d", count)
+	}
+}
+
+func TestListConversationsByToolName(t *testing.T) {
+	db := setupTestDB(t)
+
+	toolCalls := map[string]string{
+		"bash-session":   `[{"name":"Bash","arguments":{}},{"name":"Read","arguments":{}}]`,
+		"read-session":   `[{"name":"Read","arguments":{}}]`,
+		"grep-session":   `[{"name":"Grep","arguments":{}}]`,
+		"broken-session": `not json`,
+	}
+	ids := make(map[string]int)
+	for sessionID, calls := range toolCalls {
+		conv, err := db.CreateConversation(sessionID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		calls := calls
+		if _, err := db.CreateMessage(conv.ID, "response", "answer", &calls, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		ids[sessionID] = conv.ID
+	}
+	if _, err := db.CreateConversation("no-tools-session", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	tests := []struct {
+		toolName string
+		expected []string
+	}{
+		{"Read", []string{"bash-session", "read-session"}},
+		{"Bash", []string{"bash-session"}},
+		{"Write", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.toolName, func(t *testing.T) {
+			filter := &ConversationFilter{ToolName: tt.toolName}
+			conversations, err := db.ListConversations(filter, 10, 0)
+			if err != nil {
+				t.Fatalf("Failed to list conversations: %v", err)
+			}
+
+			got := make(map[int]bool)
+			for _, conv := range conversations {
+				got[conv.ID] = true
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %d conversations, got %+v", len(tt.expected), conversations)
+			}
+			for _, sessionID := range tt.expected {
+				if !got[ids[sessionID]] {
+					t.Errorf("Expected %s conversation in results", sessionID)
+				}
+			}
+
+			count, err := db.GetConversationCount(filter)
+			if err != nil {
+				t.Fatalf("Failed to count conversations: %v", err)
+			}
+			if count != len(tt.expected) {
+				t.Errorf("Expected count %d, got %d", len(tt.expected), count)
+			}
+		})
+	}
+}
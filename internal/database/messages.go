@@ -0,0 +1,527 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLite rejects statements with more bound parameters than
+// SQLITE_MAX_VARIABLE_NUMBER, which defaults to 999 before SQLite 3.32.
+// Multi-row INSERTs are chunked so that each stays within that default.
+const (
+	sqliteMaxVariables   = 999
+	messageInsertColumns = 8
+	batchInsertRows      = sqliteMaxVariables / messageInsertColumns
+)
+
+// MessageInput describes a message to insert with CreateMessagesBatch.
+// A nil Timestamp uses the database's current time.
+type MessageInput struct {
+	MessageType   string
+	Content       string
+	ToolCalls     *string
+	ExecutionTime *int
+	Model         *string
+	Timestamp     *time.Time
+}
+
+// MessageNeighbors holds the IDs of the messages immediately before and after
+// a message within its conversation. Either ID is nil at a conversation boundary.
+type MessageNeighbors struct {
+	MessageID  int  `json:"message_id"`
+	PreviousID *int `json:"previous_id"`
+	NextID     *int `json:"next_id"`
+}
+
+// GetMessageNeighbors returns the preceding and following message IDs for a
+// message, ordered by timestamp (then ID) within the same conversation
+func (db *DB) GetMessageNeighbors(id int) (*MessageNeighbors, error) {
+	if _, err := db.GetMessage(id); err != nil {
+		return nil, err
+	}
+
+	previousQuery := `
+	SELECT p.id
+	FROM messages m
+	JOIN messages p ON p.conversation_id = m.conversation_id
+	WHERE m.id = ? AND (p.timestamp < m.timestamp OR (p.timestamp = m.timestamp AND p.id < m.id))
+	ORDER BY p.timestamp DESC, p.id DESC
+	LIMIT 1`
+
+	nextQuery := `
+	SELECT n.id
+	FROM messages m
+	JOIN messages n ON n.conversation_id = m.conversation_id
+	WHERE m.id = ? AND (n.timestamp > m.timestamp OR (n.timestamp = m.timestamp AND n.id > m.id))
+	ORDER BY n.timestamp ASC, n.id ASC
+	LIMIT 1`
+
+	neighbors := &MessageNeighbors{MessageID: id}
+
+	previousID, err := db.queryOptionalID(previousQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous message: %w", err)
+	}
+	neighbors.PreviousID = previousID
+
+	nextID, err := db.queryOptionalID(nextQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get next message: %w", err)
+	}
+	neighbors.NextID = nextID
+
+	return neighbors, nil
+}
+
+// queryOptionalID runs a single-column ID query, returning nil when no row matches
+func (db *DB) queryOptionalID(query string, args ...interface{}) (*int, error) {
+	var id int
+	err := db.conn.QueryRow(query, args...).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &id, nil
+}
+
+// ConversationCompleteness reports how well a conversation's prompts are
+// matched by responses. Score is the fraction of prompts that were answered.
+type ConversationCompleteness struct {
+	ConversationID    int     `json:"conversation_id"`
+	PromptCount       int     `json:"prompt_count"`
+	ResponseCount     int     `json:"response_count"`
+	UnansweredPrompts int     `json:"unanswered_prompts"`
+	Score             float64 `json:"score"`
+}
+
+// GetConversationCompleteness pairs a conversation's messages in order and
+// counts prompts that were not followed by a response before the next prompt,
+// which usually means the response hook failed to fire
+func (db *DB) GetConversationCompleteness(id int) (*ConversationCompleteness, error) {
+	if _, err := db.GetConversation(id); err != nil {
+		return nil, err
+	}
+
+	messages, err := db.GetMessagesByConversation(id)
+	if err != nil {
+		return nil, err
+	}
+
+	completeness := &ConversationCompleteness{ConversationID: id}
+
+	awaitingResponse := false
+	for _, msg := range messages {
+		switch msg.MessageType {
+		case "prompt":
+			completeness.PromptCount++
+			if awaitingResponse {
+				completeness.UnansweredPrompts++
+			}
+			awaitingResponse = true
+		case "response":
+			completeness.ResponseCount++
+			awaitingResponse = false
+		}
+	}
+	if awaitingResponse {
+		completeness.UnansweredPrompts++
+	}
+
+	completeness.Score = 1.0
+	if completeness.PromptCount > 0 {
+		answered := completeness.PromptCount - completeness.UnansweredPrompts
+		completeness.Score = float64(answered) / float64(completeness.PromptCount)
+	}
+
+	return completeness, nil
+}
+
+// ConversationContentStats breaks a conversation's message totals down by
+// message type
+type ConversationContentStats struct {
+	ConversationID     int `json:"conversation_id"`
+	PromptCharacters   int `json:"prompt_characters"`
+	ResponseCharacters int `json:"response_characters"`
+	PromptCount        int `json:"prompt_count"`
+	ResponseCount      int `json:"response_count"`
+	WordCount          int `json:"word_count"`
+}
+
+// GetConversationContentStats returns character and message counts per
+// message type for a conversation, along with the total number of words
+// across all of its messages
+func (db *DB) GetConversationContentStats(id int) (*ConversationContentStats, error) {
+	if _, err := db.GetConversation(id); err != nil {
+		return nil, err
+	}
+
+	messages, err := db.GetMessagesByConversation(id)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &ConversationContentStats{ConversationID: id}
+	for _, msg := range messages {
+		switch msg.MessageType {
+		case "prompt":
+			stats.PromptCount++
+			stats.PromptCharacters += msg.CharacterCount
+		case "response":
+			stats.ResponseCount++
+			stats.ResponseCharacters += msg.CharacterCount
+		}
+		// strings.Fields splits on Unicode whitespace, so multi-byte
+		// characters never break a word apart
+		stats.WordCount += len(strings.Fields(msg.Content))
+	}
+
+	return stats, nil
+}
+
+// TimelineEntry is a compact view of a message for rendering a conversation
+// timeline without loading message content
+type TimelineEntry struct {
+	MessageID int       `json:"message_id"`
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	CharCount int       `json:"char_count"`
+}
+
+// GetConversationTimeline returns the type, timestamp and size of each of a
+// conversation's messages in order, selecting only those columns
+func (db *DB) GetConversationTimeline(id int) ([]TimelineEntry, error) {
+	if _, err := db.GetConversation(id); err != nil {
+		return nil, err
+	}
+
+	query := `
+	SELECT id, message_type, timestamp, character_count
+	FROM messages
+	WHERE conversation_id = ?
+	ORDER BY timestamp ASC, id ASC`
+
+	rows, err := db.conn.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation timeline: %w", err)
+	}
+	defer rows.Close()
+
+	timeline := []TimelineEntry{}
+	for rows.Next() {
+		var entry TimelineEntry
+		if err := rows.Scan(&entry.MessageID, &entry.Type, &entry.Timestamp, &entry.CharCount); err != nil {
+			return nil, fmt.Errorf("failed to scan timeline entry: %w", err)
+		}
+		timeline = append(timeline, entry)
+	}
+
+	return timeline, rows.Err()
+}
+
+// CreateMessagesBatch inserts many messages into a conversation inside one
+// transaction using multi-row INSERTs, then recomputes the conversation's
+// stats once and applies tag rules. Any failure rolls back the whole batch.
+// Multi-row INSERT ... RETURNING needs SQLite 3.35 or later; older versions
+// fall back to inserting the rows one at a time.
+func (db *DB) CreateMessagesBatch(conversationID int, msgs []MessageInput) ([]Message, error) {
+	if _, err := db.GetConversation(conversationID); err != nil {
+		return nil, err
+	}
+
+	if len(msgs) == 0 {
+		return []Message{}, nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	characters := 0
+	for _, m := range msgs {
+		characters += len(m.Content)
+	}
+	if err := db.checkSessionCharacterQuota(tx, conversationID, characters); err != nil {
+		return nil, err
+	}
+
+	messages := make([]Message, 0, len(msgs))
+	for start := 0; start < len(msgs); start += batchInsertRows {
+		end := start + batchInsertRows
+		if end > len(msgs) {
+			end = len(msgs)
+		}
+
+		inserted, err := insertMessageRows(tx, conversationID, msgs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, inserted...)
+	}
+
+	if err := recomputeConversationStats(tx, conversationID); err != nil {
+		return nil, err
+	}
+
+	for _, m := range msgs {
+		if _, err := applyTagRules(tx, conversationID, m.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return messages, nil
+}
+
+// insertMessageRows inserts a chunk of messages with a single statement,
+// falling back to one statement per row where RETURNING is unsupported
+func insertMessageRows(tx *sql.Tx, conversationID int, msgs []MessageInput) ([]Message, error) {
+	messages, err := insertMessageRowsReturning(tx, conversationID, msgs)
+	if err != nil {
+		// Fallback for SQLite versions that don't support RETURNING
+		return insertMessageRowsOneByOne(tx, conversationID, msgs)
+	}
+	return messages, nil
+}
+
+// insertMessageRowsReturning inserts a chunk of messages with one multi-row
+// INSERT ... RETURNING statement
+func insertMessageRowsReturning(tx *sql.Tx, conversationID int, msgs []MessageInput) ([]Message, error) {
+	placeholders := make([]string, len(msgs))
+	args := make([]interface{}, 0, len(msgs)*messageInsertColumns)
+	for i, m := range msgs {
+		placeholders[i] = "(?, ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))"
+		args = append(args, messageInsertArgs(conversationID, m)...)
+	}
+
+	query := `
+	INSERT INTO messages (conversation_id, message_type, content, character_count, tool_calls, execution_time, model, timestamp)
+	VALUES ` + strings.Join(placeholders, ", ") + `
+	RETURNING id, conversation_id, message_type, content, character_count, timestamp, tool_calls, execution_time, model`
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]Message, 0, len(msgs))
+	for rows.Next() {
+		var msg Message
+		err := rows.Scan(
+			&msg.ID, &msg.ConversationID, &msg.MessageType, &msg.Content,
+			&msg.CharacterCount, &msg.Timestamp, &msg.ToolCalls, &msg.ExecutionTime, &msg.Model,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to insert messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// insertMessageRowsOneByOne inserts a chunk of messages with one INSERT per
+// row, reading each row back by its ID
+func insertMessageRowsOneByOne(tx *sql.Tx, conversationID int, msgs []MessageInput) ([]Message, error) {
+	messages := make([]Message, 0, len(msgs))
+	for _, m := range msgs {
+		result, err := tx.Exec(
+			"INSERT INTO messages (conversation_id, message_type, content, character_count, tool_calls, execution_time, model, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))",
+			messageInsertArgs(conversationID, m)...,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert message: %w", err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+
+		var msg Message
+		err = tx.QueryRow(`
+		SELECT id, conversation_id, message_type, content, character_count, timestamp, tool_calls, execution_time, model
+		FROM messages WHERE id = ?`, id).Scan(
+			&msg.ID, &msg.ConversationID, &msg.MessageType, &msg.Content,
+			&msg.CharacterCount, &msg.Timestamp, &msg.ToolCalls, &msg.ExecutionTime, &msg.Model,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get created message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// messageInsertArgs returns the messageInsertColumns values bound for one
+// inserted message
+func messageInsertArgs(conversationID int, m MessageInput) []interface{} {
+	var timestamp interface{}
+	if m.Timestamp != nil {
+		timestamp = m.Timestamp.UTC().Format(sqliteTimestampLayout)
+	}
+
+	return []interface{}{
+		conversationID, m.MessageType, m.Content, len(m.Content),
+		m.ToolCalls, m.ExecutionTime, m.Model, timestamp,
+	}
+}
+
+// recomputeConversationStats rebuilds a conversation's message totals from
+// its messages in a single update
+func recomputeConversationStats(tx *sql.Tx, conversationID int) error {
+	query := `
+	UPDATE conversations SET
+		prompt_count = (SELECT COUNT(*) FROM messages WHERE conversation_id = ? AND message_type = 'prompt'),
+		total_characters = (SELECT COALESCE(SUM(character_count), 0) FROM messages WHERE conversation_id = ?),
+		updated_at = CURRENT_TIMESTAMP
+	WHERE id = ?`
+
+	result, err := tx.Exec(query, conversationID, conversationID, conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation stats: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrConversationNotFound
+	}
+
+	return nil
+}
+
+// ListMessages returns messages across all conversations matching the
+// filter, newest first
+func (db *DB) ListMessages(filter MessageFilter) ([]Message, error) {
+	where, args := filter.whereClause()
+
+	query := `
+	SELECT m.id, m.conversation_id, m.message_type, m.content, m.character_count, m.timestamp, m.tool_calls, m.execution_time, m.model
+	FROM messages m
+	JOIN conversations c ON c.id = m.conversation_id` + where + `
+	ORDER BY m.timestamp DESC, m.id DESC`
+
+	if filter.Limit > 0 {
+		query += "\n\tLIMIT ? OFFSET ?"
+		args = append(args, filter.Limit, filter.Offset)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	for rows.Next() {
+		var msg Message
+		err := rows.Scan(
+			&msg.ID, &msg.ConversationID, &msg.MessageType, &msg.Content,
+			&msg.CharacterCount, &msg.Timestamp, &msg.ToolCalls, &msg.ExecutionTime, &msg.Model,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// GetMessageCount returns the number of messages matching the filter,
+// ignoring its Limit and Offset
+func (db *DB) GetMessageCount(filter MessageFilter) (int, error) {
+	where, args := filter.whereClause()
+
+	query := `
+	SELECT COUNT(*)
+	FROM messages m
+	JOIN conversations c ON c.id = m.conversation_id` + where
+
+	var count int
+	if err := db.conn.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteMessage removes a message and its ratings, and takes it out of its
+// conversation's prompt_count and total_characters. Messages of soft-deleted
+// conversations are reported as not found, leaving them intact for a
+// restore.
+func (db *DB) DeleteMessage(id int) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var conversationID, characterCount int
+	var messageType string
+	err = tx.QueryRow(`
+	SELECT m.conversation_id, m.message_type, m.character_count
+	FROM messages m
+	JOIN conversations c ON c.id = m.conversation_id
+	WHERE m.id = ? AND c.deleted_at IS NULL`, id,
+	).Scan(&conversationID, &messageType, &characterCount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrMessageNotFound
+		}
+		return fmt.Errorf("failed to get message: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM ratings WHERE message_id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete message ratings: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM messages WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+
+	promptDecrement := 0
+	if messageType == "prompt" {
+		promptDecrement = 1
+	}
+
+	_, err = tx.Exec(`
+	UPDATE conversations
+	SET prompt_count = MAX(prompt_count - ?, 0),
+		total_characters = MAX(total_characters - ?, 0),
+		updated_at = CURRENT_TIMESTAMP
+	WHERE id = ?`,
+		promptDecrement, characterCount, conversationID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation stats: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
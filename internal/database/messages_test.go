@@ -0,0 +1,571 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetConversationCompleteness(t *testing.T) {
+	tests := []struct {
+		name               string
+		sequence           []string
+		expectedUnanswered int
+		expectedScore      float64
+	}{
+		{"empty conversation", nil, 0, 1.0},
+		{"balanced", []string{"prompt", "response", "prompt", "response"}, 0, 1.0},
+		{"dangling final prompt", []string{"prompt", "response", "prompt"}, 1, 0.5},
+		{"consecutive prompts", []string{"prompt", "prompt", "response"}, 1, 0.5},
+		{"multiple responses per prompt", []string{"prompt", "response", "response"}, 0, 1.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+
+			conv, err := db.CreateConversation("completeness-session", nil, nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to create conversation: %v", err)
+			}
+
+			for _, msgType := range tt.sequence {
+				if _, err := db.CreateMessage(conv.ID, msgType, "content", nil, nil); err != nil {
+					t.Fatalf("Failed to create message: %v", err)
+				}
+			}
+
+			completeness, err := db.GetConversationCompleteness(conv.ID)
+			if err != nil {
+				t.Fatalf("Failed to get completeness: %v", err)
+			}
+
+			if completeness.UnansweredPrompts != tt.expectedUnanswered {
+				t.Errorf("Expected %d unanswered prompts, got %d", tt.expectedUnanswered, completeness.UnansweredPrompts)
+			}
+
+			if completeness.Score != tt.expectedScore {
+				t.Errorf("Expected score %v, got %v", tt.expectedScore, completeness.Score)
+			}
+		})
+	}
+}
+
+func TestGetConversationTimeline(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("timeline-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	// Inserted out of order to show the timeline follows timestamps
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	later := start.Add(time.Minute)
+	inputs := []MessageInput{
+		{MessageType: "response", Content: "the answer", Timestamp: &later},
+		{MessageType: "prompt", Content: "question", Timestamp: &start},
+	}
+	messages, err := db.CreateMessagesBatch(conv.ID, inputs)
+	if err != nil {
+		t.Fatalf("Failed to create messages batch: %v", err)
+	}
+
+	timeline, err := db.GetConversationTimeline(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get timeline: %v", err)
+	}
+
+	expected := []TimelineEntry{
+		{MessageID: messages[1].ID, Type: "prompt", Timestamp: start, CharCount: len("question")},
+		{MessageID: messages[0].ID, Type: "response", Timestamp: later, CharCount: len("the answer")},
+	}
+	if len(timeline) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(timeline))
+	}
+	for i, want := range expected {
+		got := timeline[i]
+		if got.MessageID != want.MessageID || got.Type != want.Type || !got.Timestamp.Equal(want.Timestamp) || got.CharCount != want.CharCount {
+			t.Errorf("Entry %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	if _, err := db.GetConversationTimeline(conv.ID + 1); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound, got %v", err)
+	}
+}
+
+func TestGetConversationContentStats(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("content-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	empty, err := db.GetConversationContentStats(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if *empty != (ConversationContentStats{ConversationID: conv.ID}) {
+		t.Errorf("Expected zero stats for an empty conversation, got %+v", empty)
+	}
+
+	messages := []struct {
+		messageType string
+		content     string
+	}{
+		{"prompt", "Explain  goroutines\nplease"},
+		{"response", "Goroutines are lightweight threads."},
+		{"prompt", "日本語\u3000テキスト café"}, // ideographic space between words
+	}
+	for _, m := range messages {
+		if _, err := db.CreateMessage(conv.ID, m.messageType, m.content, nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	stats, err := db.GetConversationContentStats(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+
+	expected := ConversationContentStats{
+		ConversationID:     conv.ID,
+		PromptCharacters:   len(messages[0].content) + len(messages[2].content),
+		ResponseCharacters: len(messages[1].content),
+		PromptCount:        2,
+		ResponseCount:      1,
+		WordCount:          3 + 4 + 3,
+	}
+	if *stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *stats)
+	}
+
+	if _, err := db.GetConversationContentStats(conv.ID + 1); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound, got %v", err)
+	}
+}
+
+func TestCreateMessagesBatch(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("backfill-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	// More values than SQLite binds in one statement, so the batch must be
+	// split across several INSERTs
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var inputs []MessageInput
+	totalCharacters := 0
+	for i := 0; i < sqliteMaxVariables+5; i++ {
+		msgType := "prompt"
+		if i%2 == 1 {
+			msgType = "response"
+		}
+		timestamp := start.Add(time.Duration(i) * time.Second)
+		content := fmt.Sprintf("message %d", i)
+		totalCharacters += len(content)
+		inputs = append(inputs, MessageInput{MessageType: msgType, Content: content, Timestamp: &timestamp})
+	}
+
+	messages, err := db.CreateMessagesBatch(conv.ID, inputs)
+	if err != nil {
+		t.Fatalf("Failed to create messages batch: %v", err)
+	}
+
+	if len(messages) != len(inputs) {
+		t.Fatalf("Expected %d messages, got %d", len(inputs), len(messages))
+	}
+
+	if !messages[0].Timestamp.Equal(start) {
+		t.Errorf("Expected first timestamp %v, got %v", start, messages[0].Timestamp)
+	}
+	for i, msg := range messages {
+		if msg.Content != inputs[i].Content {
+			t.Fatalf("Expected message %d to be %q, got %q", i, inputs[i].Content, msg.Content)
+		}
+	}
+
+	updated, err := db.GetConversation(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	if updated.TotalCharacters != totalCharacters {
+		t.Errorf("Expected total characters %d, got %d", totalCharacters, updated.TotalCharacters)
+	}
+}
+
+func TestCreateMessagesBatchRollback(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("backfill-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	inputs := []MessageInput{
+		{MessageType: "prompt", Content: "valid"},
+		{MessageType: "invalid", Content: "violates the message_type check"},
+	}
+
+	if _, err := db.CreateMessagesBatch(conv.ID, inputs); err == nil {
+		t.Fatal("Expected error for invalid message type")
+	}
+
+	messages, err := db.GetMessagesByConversation(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get messages: %v", err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("Expected whole batch to roll back, found %d messages", len(messages))
+	}
+}
+
+func TestInsertMessageRowsOneByOne(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("fallback-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	timestamp := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	inputs := []MessageInput{
+		{MessageType: "prompt", Content: "first", Timestamp: &timestamp},
+		{MessageType: "response", Content: "second", ExecutionTime: intPtr(42)},
+	}
+
+	var messages []Message
+	err = db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		messages, err = insertMessageRowsOneByOne(tx, conv.ID, inputs)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert messages one by one: %v", err)
+	}
+
+	if len(messages) != len(inputs) {
+		t.Fatalf("Expected %d messages, got %d", len(inputs), len(messages))
+	}
+	if messages[0].Content != "first" || !messages[0].Timestamp.Equal(timestamp) {
+		t.Errorf("Unexpected first message: %+v", messages[0])
+	}
+	if messages[1].Content != "second" || messages[1].ExecutionTime == nil || *messages[1].ExecutionTime != 42 {
+		t.Errorf("Unexpected second message: %+v", messages[1])
+	}
+	if messages[1].ID <= messages[0].ID {
+		t.Errorf("Expected IDs in insertion order, got %d then %d", messages[0].ID, messages[1].ID)
+	}
+}
+
+func benchmarkMessageInputs(n int) []MessageInput {
+	inputs := make([]MessageInput, n)
+	for i := range inputs {
+		inputs[i] = MessageInput{MessageType: "prompt", Content: fmt.Sprintf("backfilled message %d", i)}
+	}
+	return inputs
+}
+
+func BenchmarkCreateMessagesLoop(b *testing.B) {
+	db := setupTestDB(b)
+	inputs := benchmarkMessageInputs(200)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		conv, err := db.CreateConversation("bench-session", nil, nil, nil)
+		if err != nil {
+			b.Fatalf("Failed to create conversation: %v", err)
+		}
+		for _, m := range inputs {
+			if _, err := db.CreateMessage(conv.ID, m.MessageType, m.Content, nil, nil); err != nil {
+				b.Fatalf("Failed to create message: %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkCreateMessagesBatch(b *testing.B) {
+	db := setupTestDB(b)
+	inputs := benchmarkMessageInputs(200)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		conv, err := db.CreateConversation("bench-session", nil, nil, nil)
+		if err != nil {
+			b.Fatalf("Failed to create conversation: %v", err)
+		}
+		if _, err := db.CreateMessagesBatch(conv.ID, inputs); err != nil {
+			b.Fatalf("Failed to create messages batch: %v", err)
+		}
+	}
+}
+
+func TestListMessages(t *testing.T) {
+	db := setupTestDB(t)
+
+	alpha, err := db.CreateConversation("alpha-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	beta, err := db.CreateConversation("beta-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	deleted, err := db.CreateConversation("deleted-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(hours int) *time.Time {
+		ts := base.Add(time.Duration(hours) * time.Hour)
+		return &ts
+	}
+	toolCalls := stringPtr(`[{"name":"read_file"}]`)
+
+	if _, err := db.CreateMessagesBatch(alpha.ID, []MessageInput{
+		{MessageType: "prompt", Content: "alpha prompt", Timestamp: at(0)},
+		{MessageType: "response", Content: "alpha response", ToolCalls: toolCalls, Timestamp: at(1)},
+	}); err != nil {
+		t.Fatalf("Failed to create messages: %v", err)
+	}
+	if _, err := db.CreateMessagesBatch(beta.ID, []MessageInput{
+		{MessageType: "prompt", Content: "beta prompt", Timestamp: at(2)},
+		{MessageType: "response", Content: "beta response", ToolCalls: stringPtr("[]"), Timestamp: at(3)},
+	}); err != nil {
+		t.Fatalf("Failed to create messages: %v", err)
+	}
+	if _, err := db.CreateMessagesBatch(deleted.ID, []MessageInput{
+		{MessageType: "prompt", Content: "deleted prompt", Timestamp: at(4)},
+	}); err != nil {
+		t.Fatalf("Failed to create messages: %v", err)
+	}
+	if err := db.DeleteConversation(deleted.ID); err != nil {
+		t.Fatalf("Failed to delete conversation: %v", err)
+	}
+
+	withToolCalls, withoutToolCalls := true, false
+
+	tests := []struct {
+		name     string
+		filter   MessageFilter
+		expected []string
+	}{
+		{"no filter", MessageFilter{}, []string{"beta response", "beta prompt", "alpha response", "alpha prompt"}},
+		{"by type", MessageFilter{MessageType: "prompt"}, []string{"beta prompt", "alpha prompt"}},
+		{"by session", MessageFilter{SessionID: "alpha-session"}, []string{"alpha response", "alpha prompt"}},
+		{"from", MessageFilter{From: at(2)}, []string{"beta response", "beta prompt"}},
+		{"to", MessageFilter{To: at(1)}, []string{"alpha response", "alpha prompt"}},
+		{"time range", MessageFilter{From: at(1), To: at(2)}, []string{"beta prompt", "alpha response"}},
+		{"with tool calls", MessageFilter{HasToolCalls: &withToolCalls}, []string{"alpha response"}},
+		{"without tool calls", MessageFilter{HasToolCalls: &withoutToolCalls}, []string{"beta response", "beta prompt", "alpha prompt"}},
+		{"type and session", MessageFilter{MessageType: "response", SessionID: "beta-session"}, []string{"beta response"}},
+		{"type and tool calls", MessageFilter{MessageType: "response", HasToolCalls: &withoutToolCalls}, []string{"beta response"}},
+		{"session and time range", MessageFilter{SessionID: "alpha-session", From: at(1), To: at(3)}, []string{"alpha response"}},
+		{"deleted session", MessageFilter{SessionID: "deleted-session"}, []string{}},
+		{"paginated", MessageFilter{Limit: 2, Offset: 1}, []string{"beta prompt", "alpha response"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := db.ListMessages(tt.filter)
+			if err != nil {
+				t.Fatalf("Failed to list messages: %v", err)
+			}
+
+			got := make([]string, len(messages))
+			for i, msg := range messages {
+				got[i] = msg.Content
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected messages %v, got %v", tt.expected, got)
+			}
+
+			count, err := db.GetMessageCount(tt.filter)
+			if err != nil {
+				t.Fatalf("Failed to count messages: %v", err)
+			}
+
+			expectedCount := len(tt.expected)
+			if tt.filter.Limit > 0 {
+				unpaged := tt.filter
+				unpaged.Limit, unpaged.Offset = 0, 0
+				all, err := db.ListMessages(unpaged)
+				if err != nil {
+					t.Fatalf("Failed to list messages: %v", err)
+				}
+				expectedCount = len(all)
+			}
+			if count != expectedCount {
+				t.Errorf("Expected count %d, got %d", expectedCount, count)
+			}
+		})
+	}
+}
+
+func TestDeleteMessage(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("delete-message-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	prompt, err := db.CreateMessage(conv.ID, "prompt", "question", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+	response, err := db.CreateMessage(conv.ID, "response", "the answer", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create response: %v", err)
+	}
+
+	if _, err := db.CreateMessageRating(response.ID, 2, nil); err != nil {
+		t.Fatalf("Failed to rate response: %v", err)
+	}
+
+	if err := db.DeleteMessage(response.ID); err != nil {
+		t.Fatalf("Failed to delete response: %v", err)
+	}
+
+	var ratings int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM ratings WHERE message_id = ?", response.ID).Scan(&ratings); err != nil {
+		t.Fatalf("Failed to count ratings: %v", err)
+	}
+	if ratings != 0 {
+		t.Errorf("Expected the response's ratings to be deleted, found %d", ratings)
+	}
+
+	updated, err := db.GetConversation(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	if updated.PromptCount != 1 || updated.TotalCharacters != len("question") {
+		t.Errorf("Expected 1 prompt and %d characters, got %d and %d", len("question"), updated.PromptCount, updated.TotalCharacters)
+	}
+
+	// Deleting the last message leaves the counts at zero
+	if err := db.DeleteMessage(prompt.ID); err != nil {
+		t.Fatalf("Failed to delete prompt: %v", err)
+	}
+
+	updated, err = db.GetConversation(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	if updated.PromptCount != 0 || updated.TotalCharacters != 0 {
+		t.Errorf("Expected zeroed counts, got %d prompts and %d characters", updated.PromptCount, updated.TotalCharacters)
+	}
+
+	if _, err := db.GetMessage(prompt.ID); err != ErrMessageNotFound {
+		t.Errorf("Expected deleted message to be gone, got %v", err)
+	}
+	if err := db.DeleteMessage(prompt.ID); err != ErrMessageNotFound {
+		t.Errorf("Expected ErrMessageNotFound, got %v", err)
+	}
+
+	// Messages of a soft-deleted conversation are kept for a restore
+	kept, err := db.CreateMessage(conv.ID, "prompt", "kept", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+	if err := db.DeleteConversation(conv.ID); err != nil {
+		t.Fatalf("Failed to delete conversation: %v", err)
+	}
+	if err := db.DeleteMessage(kept.ID); err != ErrMessageNotFound {
+		t.Errorf("Expected ErrMessageNotFound for a deleted conversation, got %v", err)
+	}
+	if _, err := db.GetMessage(kept.ID); err != nil {
+		t.Errorf("Expected message of a deleted conversation to be kept, got %v", err)
+	}
+}
+
+func TestGetMessagesByConversationOrder(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("ordered-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	// Inserted out of chronological order
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var ids []int
+	for _, offset := range []int{2, 0, 1} {
+		ts := base.Add(time.Duration(offset) * time.Minute)
+		msg, err := db.CreateMessageFromInput(conv.ID, MessageInput{MessageType: "prompt", Content: "content", Timestamp: &ts})
+		if err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		ids = append(ids, msg.ID)
+	}
+
+	tests := []struct {
+		order    MessageOrder
+		expected []int
+	}{
+		{MessageOrderAsc, []int{ids[1], ids[2], ids[0]}},
+		{MessageOrderDesc, []int{ids[0], ids[2], ids[1]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			messages, err := db.GetMessagesByConversationAndType(conv.ID, "", tt.order)
+			if err != nil {
+				t.Fatalf("Failed to get messages: %v", err)
+			}
+			if len(messages) != len(tt.expected) {
+				t.Fatalf("Expected %d messages, got %d", len(tt.expected), len(messages))
+			}
+			for i, msg := range messages {
+				if msg.ID != tt.expected[i] {
+					t.Errorf("Position %d: expected message %d, got %d", i, tt.expected[i], msg.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestGetMessagesByConversationAndType(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("typed-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	for _, messageType := range []string{"prompt", "response", "prompt"} {
+		if _, err := db.CreateMessage(conv.ID, messageType, "content", nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	tests := []struct {
+		messageType string
+		expected    int
+	}{
+		{"", 3},
+		{"prompt", 2},
+		{"response", 1},
+	}
+
+	for _, tt := range tests {
+		messages, err := db.GetMessagesByConversationAndType(conv.ID, tt.messageType, MessageOrderAsc)
+		if err != nil {
+			t.Fatalf("Failed to get messages: %v", err)
+		}
+		if len(messages) != tt.expected {
+			t.Errorf("Expected %d messages for type %q, got %d", tt.expected, tt.messageType, len(messages))
+		}
+		for _, msg := range messages {
+			if tt.messageType != "" && msg.MessageType != tt.messageType {
+				t.Errorf("Expected only %s messages, got %s", tt.messageType, msg.MessageType)
+			}
+		}
+	}
+}
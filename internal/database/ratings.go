@@ -3,7 +3,10 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/models"
 )
 
 // Rating represents a rating record
@@ -19,6 +22,8 @@ type Rating struct {
 
 // CreateConversationRating creates a rating for a conversation
 func (db *DB) CreateConversationRating(conversationID int, rating int, comment *string) (*Rating, error) {
+	defer db.observe("create_rating", time.Now())
+
 	if rating < 1 || rating > 5 {
 		return nil, fmt.Errorf("rating must be between 1 and 5")
 	}
@@ -170,6 +175,36 @@ func (db *DB) GetMessageRatings(messageID int) ([]Rating, error) {
 	return ratings, nil
 }
 
+// RatingsVersion summarizes the ratings on a conversation and its messages.
+// It changes whenever a rating is added, removed or edited, so callers can
+// use it to detect changes without loading every rating.
+type RatingsVersion struct {
+	Count       int
+	Sum         int
+	MaxID       int
+	LastUpdated string
+}
+
+// GetConversationRatingsVersion returns the RatingsVersion of a conversation,
+// covering both conversation and message ratings
+func (db *DB) GetConversationRatingsVersion(conversationID int) (RatingsVersion, error) {
+	query := `
+	SELECT COUNT(*), COALESCE(SUM(rating), 0), COALESCE(MAX(id), 0), COALESCE(MAX(updated_at), '')
+	FROM ratings
+	WHERE conversation_id = ?
+	OR message_id IN (SELECT id FROM messages WHERE conversation_id = ?)`
+
+	var version RatingsVersion
+	err := db.conn.QueryRow(query, conversationID, conversationID).Scan(
+		&version.Count, &version.Sum, &version.MaxID, &version.LastUpdated,
+	)
+	if err != nil {
+		return RatingsVersion{}, fmt.Errorf("failed to get ratings version: %w", err)
+	}
+
+	return version, nil
+}
+
 // UpdateRating updates a rating's score and comment
 func (db *DB) UpdateRating(id int, rating int, comment *string) error {
 	if rating < 1 || rating > 5 {
@@ -194,6 +229,47 @@ func (db *DB) UpdateRating(id int, rating int, comment *string) error {
 	return nil
 }
 
+// PatchRating updates a rating's score and comment. Only non-nil fields are
+// changed.
+func (db *DB) PatchRating(id int, rating *int, comment *string) error {
+	var assignments []string
+	var args []interface{}
+
+	if rating != nil {
+		if *rating < 1 || *rating > 5 {
+			return fmt.Errorf("rating must be between 1 and 5")
+		}
+		assignments = append(assignments, "rating = ?")
+		args = append(args, *rating)
+	}
+	if comment != nil {
+		assignments = append(assignments, "comment = ?")
+		args = append(args, *comment)
+	}
+
+	if len(assignments) == 0 {
+		_, err := db.GetRating(id)
+		return err
+	}
+
+	query := "UPDATE ratings SET " + strings.Join(assignments, ", ") + ", updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+	result, err := db.conn.Exec(query, append(args, id)...)
+	if err != nil {
+		return fmt.Errorf("failed to update rating: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrRatingNotFound
+	}
+
+	return nil
+}
+
 // DeleteRating deletes a rating
 func (db *DB) DeleteRating(id int) error {
 	query := "DELETE FROM ratings WHERE id = ?"
@@ -218,16 +294,29 @@ func (db *DB) DeleteRating(id int) error {
 func (db *DB) GetRatingStats() (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
 
-	// Average rating
+	// Resolve each rating to its conversation so soft-deleted data can be excluded
+	ratingsSource := `
+	ratings r
+	LEFT JOIN messages m ON m.id = r.message_id
+	JOIN conversations c ON c.id = COALESCE(r.conversation_id, m.conversation_id)
+	WHERE ` + db.statsConversationFilter("c")
+
+	// Average rating, the mean of the rated conversations' scores under the
+	// configured aggregation
 	var avgRating float64
-	err := db.conn.QueryRow("SELECT AVG(rating) FROM ratings").Scan(&avgRating)
+	err := db.conn.QueryRow(`
+	SELECT COALESCE(AVG(score), 0) FROM (
+		SELECT ` + ratingScoreExpr(db.ratingAggregation(), "c.id") + ` AS score
+		FROM conversations c
+		WHERE ` + db.statsConversationFilter("c") + `
+	)`).Scan(&avgRating)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to get average rating: %w", err)
 	}
 	stats["average_rating"] = avgRating
 
 	// Rating distribution
-	rows, err := db.conn.Query("SELECT rating, COUNT(*) FROM ratings GROUP BY rating ORDER BY rating")
+	rows, err := db.conn.Query("SELECT r.rating, COUNT(*) FROM " + ratingsSource + " GROUP BY r.rating ORDER BY r.rating")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get rating distribution: %w", err)
 	}
@@ -245,11 +334,168 @@ func (db *DB) GetRatingStats() (map[string]interface{}, error) {
 
 	// Total ratings
 	var totalRatings int
-	err = db.conn.QueryRow("SELECT COUNT(*) FROM ratings").Scan(&totalRatings)
+	err = db.conn.QueryRow("SELECT COUNT(*) FROM " + ratingsSource).Scan(&totalRatings)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count ratings: %w", err)
 	}
 	stats["total_ratings"] = totalRatings
 
 	return stats, nil
-}
\ No newline at end of file
+}
+
+// conversationRatingsSource selects the ratings of the conversation
+// identified by %s, counting ratings on its messages as well as on the
+// conversation itself
+const conversationRatingsSource = `ratings r
+			LEFT JOIN messages m ON m.id = r.message_id
+			WHERE COALESCE(r.conversation_id, m.conversation_id) = %s`
+
+// ratingScoreExpr computes a conversation's rating score under the method,
+// the SQL counterpart of RatingAggregation.Aggregate. conversationID is the
+// column identifying the conversation in the enclosing query. The score is
+// NULL for unrated conversations.
+func ratingScoreExpr(method models.RatingAggregation, conversationID string) string {
+	source := fmt.Sprintf(conversationRatingsSource, conversationID)
+
+	switch method {
+	case models.RatingAggregationMedian:
+		return `(SELECT AVG(rating) FROM (
+			SELECT r.rating, ROW_NUMBER() OVER (ORDER BY r.rating) AS position, COUNT(*) OVER () AS total
+			FROM ` + source + `)
+		WHERE position IN ((total + 1) / 2, (total + 2) / 2))`
+	case models.RatingAggregationLatest:
+		return `(SELECT r.rating * 1.0 FROM ` + source + `
+		ORDER BY r.created_at DESC, r.id DESC LIMIT 1)`
+	default:
+		return `(SELECT AVG(r.rating) FROM ` + source + `)`
+	}
+}
+
+// ConversationRatingSummary aggregates the ratings attached to a single conversation.
+// AverageRating holds the score produced by the configured RatingAggregation.
+type ConversationRatingSummary struct {
+	ConversationID int      `json:"conversation_id"`
+	Title          *string  `json:"title"`
+	AverageRating  float64  `json:"average_rating"`
+	RatingCount    int      `json:"rating_count"`
+	Comments       []string `json:"comments"`
+}
+
+// GetUnratedConversationCount returns the number of conversations without any
+// rating, on the conversation or on one of its messages
+func (db *DB) GetUnratedConversationCount() (int, error) {
+	query := `
+	SELECT COUNT(*) FROM conversations c
+	WHERE NOT EXISTS (SELECT 1 FROM ` + fmt.Sprintf(conversationRatingsSource, "c.id") + `)
+	AND ` + db.statsConversationFilter("c")
+
+	var count int
+	if err := db.conn.QueryRow(query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count unrated conversations: %w", err)
+	}
+
+	return count, nil
+}
+
+// RatingCoverage reports how many conversations have been rated
+type RatingCoverage struct {
+	RatedCount      int     `json:"rated_count"`
+	UnratedCount    int     `json:"unrated_count"`
+	CoveragePercent float64 `json:"coverage_percent"`
+}
+
+// GetRatingCoverage counts the conversations with and without at least one
+// rating in a single pass. Ratings on a conversation's messages count towards
+// it. CoveragePercent is zero when there are no conversations.
+func (db *DB) GetRatingCoverage() (*RatingCoverage, error) {
+	query := `
+	SELECT
+		COALESCE(SUM(rated), 0),
+		COALESCE(SUM(1 - rated), 0)
+	FROM (
+		SELECT EXISTS (SELECT 1 FROM ` + fmt.Sprintf(conversationRatingsSource, "c.id") + `) AS rated
+		FROM conversations c
+		WHERE ` + db.statsConversationFilter("c") + `
+	)`
+
+	coverage := &RatingCoverage{}
+	if err := db.conn.QueryRow(query).Scan(&coverage.RatedCount, &coverage.UnratedCount); err != nil {
+		return nil, fmt.Errorf("failed to get rating coverage: %w", err)
+	}
+
+	if total := coverage.RatedCount + coverage.UnratedCount; total > 0 {
+		coverage.CoveragePercent = float64(coverage.RatedCount) * 100 / float64(total)
+	}
+
+	return coverage, nil
+}
+
+// GetLowestRatedConversations returns the rated conversations with the lowest
+// score under the configured rating aggregation, including their rating
+// comments. Ratings on a conversation's messages count towards it.
+func (db *DB) GetLowestRatedConversations(limit int) ([]ConversationRatingSummary, error) {
+	query := `
+	SELECT id, title, score, rating_count
+	FROM (
+		SELECT c.id, c.title,
+			` + ratingScoreExpr(db.ratingAggregation(), "c.id") + ` AS score,
+			(SELECT COUNT(*) FROM ` + fmt.Sprintf(conversationRatingsSource, "c.id") + `) AS rating_count
+		FROM conversations c
+		WHERE ` + db.statsConversationFilter("c") + `
+	)
+	WHERE rating_count > 0
+	ORDER BY score ASC, id ASC
+	LIMIT ?`
+
+	rows, err := db.conn.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lowest rated conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []ConversationRatingSummary
+	for rows.Next() {
+		var summary ConversationRatingSummary
+		if err := rows.Scan(&summary.ConversationID, &summary.Title, &summary.AverageRating, &summary.RatingCount); err != nil {
+			return nil, fmt.Errorf("failed to scan rating summary: %w", err)
+		}
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rating summaries: %w", err)
+	}
+	rows.Close()
+
+	commentsQuery := `
+	SELECT r.comment FROM ` + fmt.Sprintf(conversationRatingsSource, "?") + `
+	AND r.comment IS NOT NULL AND r.comment != ''
+	ORDER BY r.created_at DESC, r.id DESC`
+
+	for i := range summaries {
+		if err := db.loadRatingComments(commentsQuery, &summaries[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return summaries, nil
+}
+
+// loadRatingComments fills in a summary's non-empty rating comments, newest
+// first
+func (db *DB) loadRatingComments(query string, summary *ConversationRatingSummary) error {
+	rows, err := db.conn.Query(query, summary.ConversationID)
+	if err != nil {
+		return fmt.Errorf("failed to get rating comments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var comment string
+		if err := rows.Scan(&comment); err != nil {
+			return fmt.Errorf("failed to scan rating comment: %w", err)
+		}
+		summary.Comments = append(summary.Comments, comment)
+	}
+
+	return rows.Err()
+}
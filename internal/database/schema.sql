@@ -11,7 +11,8 @@ CREATE TABLE IF NOT EXISTS conversations (
     prompt_count INTEGER DEFAULT 0,
     total_characters INTEGER DEFAULT 0,
     working_directory TEXT,
-    transcript_path TEXT
+    transcript_path TEXT,
+    deleted_at TIMESTAMP -- set when the conversation is soft-deleted
 );
 
 -- Messages table - stores individual prompts and responses
@@ -24,6 +25,7 @@ CREATE TABLE IF NOT EXISTS messages (
     timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     tool_calls TEXT, -- JSON array of tool calls for responses
     execution_time INTEGER, -- milliseconds
+    model TEXT, -- model that produced a response, when reported by the hook
     FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
 );
 
@@ -62,12 +64,23 @@ CREATE TABLE IF NOT EXISTS conversation_tags (
     FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
 );
 
+-- Tag rules table - keyword or regex patterns that auto-apply a tag
+CREATE TABLE IF NOT EXISTS tag_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    pattern TEXT NOT NULL,
+    is_regex BOOLEAN NOT NULL DEFAULT 0,
+    tag_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
 -- Sessions table - tracks Claude Code sessions with metadata
 CREATE TABLE IF NOT EXISTS sessions (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     session_id TEXT UNIQUE NOT NULL,
     start_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     end_time TIMESTAMP,
+    duration_ms INTEGER, -- how long the session ran, recorded when it ends
     conversation_count INTEGER DEFAULT 0,
     total_prompt_count INTEGER DEFAULT 0,
     avg_response_time INTEGER DEFAULT 0,
@@ -78,25 +91,17 @@ CREATE TABLE IF NOT EXISTS sessions (
 -- Indexes for performance
 CREATE INDEX IF NOT EXISTS idx_conversations_session_id ON conversations(session_id);
 CREATE INDEX IF NOT EXISTS idx_conversations_created_at ON conversations(created_at);
-CREATE INDEX IF NOT EXISTS idx_messages_conversation_id ON messages(conversation_id);
+CREATE INDEX IF NOT EXISTS idx_conversations_deleted_at ON conversations(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_timestamp ON messages(conversation_id, timestamp);
 CREATE INDEX IF NOT EXISTS idx_messages_timestamp ON messages(timestamp);
+CREATE INDEX IF NOT EXISTS idx_messages_model ON messages(model);
 CREATE INDEX IF NOT EXISTS idx_ratings_conversation_id ON ratings(conversation_id);
 CREATE INDEX IF NOT EXISTS idx_ratings_message_id ON ratings(message_id);
+CREATE INDEX IF NOT EXISTS idx_tag_rules_tag_id ON tag_rules(tag_id);
 CREATE INDEX IF NOT EXISTS idx_sessions_session_id ON sessions(session_id);
 CREATE INDEX IF NOT EXISTS idx_sessions_start_time ON sessions(start_time);
 
 -- Triggers to maintain conversation metadata
-CREATE TRIGGER IF NOT EXISTS update_conversation_stats
-    AFTER INSERT ON messages
-    FOR EACH ROW
-BEGIN
-    UPDATE conversations 
-    SET prompt_count = prompt_count + 1,
-        total_characters = total_characters + NEW.character_count,
-        updated_at = CURRENT_TIMESTAMP
-    WHERE id = NEW.conversation_id;
-END;
-
 CREATE TRIGGER IF NOT EXISTS update_conversation_timestamp
     AFTER UPDATE ON conversations
     FOR EACH ROW
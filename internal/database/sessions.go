@@ -0,0 +1,439 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/models"
+)
+
+// SessionGraphNode describes one conversation in a session timeline
+type SessionGraphNode struct {
+	ConversationID int        `json:"conversation_id"`
+	Title          *string    `json:"title"`
+	CreatedAt      time.Time  `json:"created_at"`
+	MessageCount   int        `json:"message_count"`
+	FirstMessageAt *time.Time `json:"first_message_at"`
+	LastMessageAt  *time.Time `json:"last_message_at"`
+}
+
+// GetSessionGraph returns the conversations of a session in chronological
+// order, each with its message count and the time span its messages cover.
+// It returns ErrConversationNotFound when the session has no conversations.
+func (db *DB) GetSessionGraph(sessionID string) ([]SessionGraphNode, error) {
+	conversations, err := db.ListConversationsBySession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conversations) == 0 {
+		return nil, ErrConversationNotFound
+	}
+
+	nodes := make([]SessionGraphNode, 0, len(conversations))
+	for _, conv := range conversations {
+		node := SessionGraphNode{
+			ConversationID: conv.ID,
+			Title:          conv.Title,
+			CreatedAt:      conv.CreatedAt,
+		}
+
+		if err := db.loadMessageTimeBounds(&node); err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// loadMessageTimeBounds fills in the message count and first/last message
+// timestamps for a graph node
+func (db *DB) loadMessageTimeBounds(node *SessionGraphNode) error {
+	err := db.conn.QueryRow(
+		"SELECT COUNT(*) FROM messages WHERE conversation_id = ?", node.ConversationID,
+	).Scan(&node.MessageCount)
+	if err != nil {
+		return fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	if node.MessageCount == 0 {
+		return nil
+	}
+
+	// Select the raw column rather than MIN/MAX so the driver still parses it as a time
+	firstQuery := `
+	SELECT timestamp FROM messages WHERE conversation_id = ?
+	ORDER BY timestamp ASC, id ASC LIMIT 1`
+
+	lastQuery := `
+	SELECT timestamp FROM messages WHERE conversation_id = ?
+	ORDER BY timestamp DESC, id DESC LIMIT 1`
+
+	var first, last time.Time
+	if err := db.conn.QueryRow(firstQuery, node.ConversationID).Scan(&first); err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get first message time: %w", err)
+	}
+	if err := db.conn.QueryRow(lastQuery, node.ConversationID).Scan(&last); err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get last message time: %w", err)
+	}
+
+	node.FirstMessageAt = &first
+	node.LastMessageAt = &last
+
+	return nil
+}
+
+// Session aggregates activity across every conversation sharing a session ID
+type Session struct {
+	SessionID         string    `json:"session_id"`
+	ConversationCount int       `json:"conversation_count"`
+	TotalPromptCount  int       `json:"total_prompt_count"`
+	AvgResponseTime   int       `json:"avg_response_time"` // milliseconds
+	StartTime         time.Time `json:"start_time"`
+	LastActivity      time.Time `json:"last_activity"`
+	// Status, EndTime and DurationMs come from the sessions row. Status is
+	// empty for sessions recorded before session events were tracked, and
+	// the timing fields stay nil until the session ends.
+	Status     models.SessionStatus `json:"status,omitempty"`
+	EndTime    *time.Time           `json:"end_time,omitempty"`
+	DurationMs *int64               `json:"duration_ms,omitempty"`
+}
+
+// sessionMetricsQuery aggregates live conversations per session. Callers
+// append further conditions before the GROUP BY.
+const sessionMetricsQuery = `
+	SELECT
+		c.session_id,
+		COUNT(*),
+		COALESCE(SUM(c.prompt_count), 0),
+		MIN(c.created_at),
+		MAX(c.updated_at),
+		COALESCE((
+			SELECT AVG(m.execution_time)
+			FROM messages m
+			JOIN conversations rc ON rc.id = m.conversation_id
+			WHERE rc.session_id = c.session_id AND rc.deleted_at IS NULL
+			AND m.message_type = 'response' AND m.execution_time IS NOT NULL
+		), 0),
+		COALESCE((SELECT s.status FROM sessions s WHERE s.session_id = c.session_id), ''),
+		(SELECT s.end_time FROM sessions s WHERE s.session_id = c.session_id),
+		(SELECT s.duration_ms FROM sessions s WHERE s.session_id = c.session_id)
+	FROM conversations c
+	WHERE c.deleted_at IS NULL`
+
+// scanSession scans a row produced by sessionMetricsQuery
+func scanSession(scanner interface{ Scan(...interface{}) error }) (*Session, error) {
+	var session Session
+	var startTime, lastActivity string
+	var avgResponseTime float64
+	var status string
+	var endTime sql.NullTime
+	var durationMs sql.NullInt64
+
+	// MIN and MAX lose the column type, so those times come back as text
+	err := scanner.Scan(
+		&session.SessionID, &session.ConversationCount, &session.TotalPromptCount,
+		&startTime, &lastActivity, &avgResponseTime, &status, &endTime, &durationMs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.StartTime, err = parseSQLiteTimestamp(startTime); err != nil {
+		return nil, err
+	}
+	if session.LastActivity, err = parseSQLiteTimestamp(lastActivity); err != nil {
+		return nil, err
+	}
+	session.AvgResponseTime = int(math.Round(avgResponseTime))
+	session.Status = models.SessionStatus(status)
+	if endTime.Valid {
+		session.EndTime = &endTime.Time
+	}
+	if durationMs.Valid {
+		session.DurationMs = &durationMs.Int64
+	}
+
+	return &session, nil
+}
+
+// sessionLookupQuery loads one session from its sessions row, if any, with
+// the aggregates of its live conversations left-joined on. A session known
+// only from its conversations still matches, and one with a row but no
+// conversations reports zero counts and falls back to the row's times.
+const sessionLookupQuery = `
+	SELECT
+		k.session_id,
+		COALESCE(agg.conversation_count, 0),
+		COALESCE(agg.prompt_count, 0),
+		COALESCE(agg.first_created, s.start_time),
+		COALESCE(agg.last_updated, s.end_time, s.start_time),
+		COALESCE((
+			SELECT AVG(m.execution_time)
+			FROM messages m
+			JOIN conversations rc ON rc.id = m.conversation_id
+			WHERE rc.session_id = k.session_id AND rc.deleted_at IS NULL
+			AND m.message_type = 'response' AND m.execution_time IS NOT NULL
+		), 0),
+		COALESCE(s.status, ''),
+		s.end_time,
+		s.duration_ms
+	FROM (SELECT ? AS session_id) k
+	LEFT JOIN sessions s ON s.session_id = k.session_id
+	LEFT JOIN (
+		SELECT
+			c.session_id,
+			COUNT(*) AS conversation_count,
+			SUM(c.prompt_count) AS prompt_count,
+			MIN(c.created_at) AS first_created,
+			MAX(c.updated_at) AS last_updated
+		FROM conversations c
+		WHERE c.deleted_at IS NULL AND c.session_id = ?
+		GROUP BY c.session_id
+	) agg ON agg.session_id = k.session_id
+	WHERE s.id IS NOT NULL OR agg.session_id IS NOT NULL`
+
+// GetSessionMetrics aggregates the live conversations recorded for a session.
+// The average response time only covers responses with an execution time.
+// A session with a sessions row but no live conversations is returned with
+// zero counts. It returns ErrSessionNotFound when the session has neither.
+func (db *DB) GetSessionMetrics(sessionID string) (*Session, error) {
+	session, err := scanSession(db.conn.QueryRow(sessionLookupQuery, sessionID, sessionID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session metrics: %w", err)
+	}
+
+	return session, nil
+}
+
+// ListSessions returns the metrics of every session with live conversations,
+// most recently active first
+func (db *DB) ListSessions(limit, offset int) ([]Session, error) {
+	query := sessionMetricsQuery + `
+	GROUP BY c.session_id
+	ORDER BY MAX(c.updated_at) DESC, c.session_id ASC
+	LIMIT ? OFFSET ?`
+
+	rows, err := db.conn.Query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, *session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// SessionStats aggregates the live conversations of one session for
+// comparison across sessions. AverageRating is the mean of the rated
+// conversations' scores under the configured rating aggregation.
+type SessionStats struct {
+	SessionID         string  `json:"session_id"`
+	ConversationCount int     `json:"conversation_count"`
+	MessageCount      int     `json:"message_count"`
+	TotalCharacters   int     `json:"total_characters"`
+	AverageRating     float64 `json:"average_rating"`
+}
+
+// GetSessionStats aggregates each of the given sessions in a single query.
+// Results follow the order of sessionIDs with duplicates removed; sessions
+// without live conversations are reported with zeros.
+func (db *DB) GetSessionStats(sessionIDs []string) ([]SessionStats, error) {
+	stats := make([]SessionStats, 0, len(sessionIDs))
+	index := make(map[string]int, len(sessionIDs))
+	args := make([]interface{}, 0, len(sessionIDs))
+	for _, id := range sessionIDs {
+		if _, seen := index[id]; seen {
+			continue
+		}
+		index[id] = len(stats)
+		stats = append(stats, SessionStats{SessionID: id})
+		args = append(args, id)
+	}
+	if len(args) == 0 {
+		return stats, nil
+	}
+
+	// Aggregate per conversation first so joins to messages and ratings
+	// cannot inflate the conversation-level sums
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+	query := `
+	SELECT
+		session_id,
+		COUNT(*),
+		COALESCE(SUM(message_count), 0),
+		COALESCE(SUM(total_characters), 0),
+		COALESCE(AVG(rating_score), 0)
+	FROM (
+		SELECT
+			c.session_id,
+			c.total_characters,
+			(SELECT COUNT(*) FROM messages m WHERE m.conversation_id = c.id) AS message_count,
+			` + ratingScoreExpr(db.ratingAggregation(), "c.id") + ` AS rating_score
+		FROM conversations c
+		WHERE c.deleted_at IS NULL AND c.session_id IN (` + placeholders + `)
+	)
+	GROUP BY session_id`
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session stats: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s SessionStats
+		if err := rows.Scan(&s.SessionID, &s.ConversationCount, &s.MessageCount, &s.TotalCharacters, &s.AverageRating); err != nil {
+			return nil, fmt.Errorf("failed to scan session stats: %w", err)
+		}
+		stats[index[s.SessionID]] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get session stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// GetSessionCount returns the number of sessions with live conversations
+func (db *DB) GetSessionCount() (int, error) {
+	var count int
+	err := db.conn.QueryRow("SELECT COUNT(DISTINCT session_id) FROM conversations WHERE deleted_at IS NULL").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get session count: %w", err)
+	}
+
+	return count, nil
+}
+
+// UpsertSession records a session in the sessions table if it is not there
+// yet. New sessions start active, and start when their first conversation
+// was created; a session first seen at its end would otherwise report no
+// duration. A working directory, when given, replaces the stored one.
+func (db *DB) UpsertSession(sessionID string, workingDirectory *string) error {
+	query := `
+	INSERT INTO sessions (session_id, working_directory, status, start_time)
+	VALUES (?, ?, ?, COALESCE((SELECT MIN(created_at) FROM conversations WHERE session_id = ?), CURRENT_TIMESTAMP))
+	ON CONFLICT(session_id) DO UPDATE SET
+		working_directory = COALESCE(excluded.working_directory, sessions.working_directory)`
+
+	if _, err := db.conn.Exec(query, sessionID, workingDirectory, models.SessionStatusActive, sessionID); err != nil {
+		return fmt.Errorf("failed to upsert session: %w", err)
+	}
+
+	return nil
+}
+
+// GetSessionStatus returns the recorded status of a session. It returns
+// ErrSessionNotFound when the session has no sessions row.
+func (db *DB) GetSessionStatus(sessionID string) (models.SessionStatus, error) {
+	var status string
+	err := db.conn.QueryRow("SELECT status FROM sessions WHERE session_id = ?", sessionID).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrSessionNotFound
+		}
+		return "", fmt.Errorf("failed to get session status: %w", err)
+	}
+
+	return models.SessionStatus(status), nil
+}
+
+// SetSessionStatus moves a session to a new status. Completing a session
+// records its end time and reactivating it clears the end time. It returns
+// ErrSessionNotFound when the session has no sessions row and
+// ErrInvalidSessionTransition when the current status cannot move to status.
+func (db *DB) SetSessionStatus(sessionID string, status models.SessionStatus) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		var current string
+		err := tx.QueryRow("SELECT status FROM sessions WHERE session_id = ?", sessionID).Scan(&current)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrSessionNotFound
+			}
+			return fmt.Errorf("failed to get session status: %w", err)
+		}
+
+		if !models.SessionStatus(current).CanTransitionTo(status) {
+			return fmt.Errorf("%w: %s to %s", ErrInvalidSessionTransition, current, status)
+		}
+
+		query := `
+		UPDATE sessions SET
+			status = ?,
+			end_time = CASE
+				WHEN ? = 'active' THEN NULL
+				WHEN ? = 'completed' THEN CURRENT_TIMESTAMP
+				ELSE end_time
+			END
+		WHERE session_id = ?`
+
+		if _, err := tx.Exec(query, status, status, status, sessionID); err != nil {
+			return fmt.Errorf("failed to set session status: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// SessionEnd is the timing recorded when a session ends
+type SessionEnd struct {
+	EndTime    time.Time `json:"end_time"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// RecordSessionEnd stores when a session ended and how long it ran. When
+// durationMs is nil the duration is measured from the session's start time
+// to endTime, and never reported as negative. It returns ErrSessionNotFound
+// when the session has no sessions row.
+func (db *DB) RecordSessionEnd(sessionID string, endTime time.Time, durationMs *int64) (*SessionEnd, error) {
+	end := &SessionEnd{EndTime: endTime.UTC().Truncate(time.Second)}
+
+	err := db.WithTx(func(tx *sql.Tx) error {
+		var startTime time.Time
+		err := tx.QueryRow("SELECT start_time FROM sessions WHERE session_id = ?", sessionID).Scan(&startTime)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrSessionNotFound
+			}
+			return fmt.Errorf("failed to get session start time: %w", err)
+		}
+
+		if durationMs != nil {
+			end.DurationMs = *durationMs
+		} else {
+			end.DurationMs = max(end.EndTime.Sub(startTime).Milliseconds(), 0)
+		}
+
+		_, err = tx.Exec(
+			"UPDATE sessions SET end_time = ?, duration_ms = ? WHERE session_id = ?",
+			end.EndTime.Format(sqliteTimestampLayout), end.DurationMs, sessionID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record session end: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return end, nil
+}
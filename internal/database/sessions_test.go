@@ -0,0 +1,426 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/models"
+)
+
+func TestGetSessionGraph(t *testing.T) {
+	db := setupTestDB(t)
+
+	first, err := db.CreateConversation("graph-session", stringPtr("First"), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	second, err := db.CreateConversation("graph-session", stringPtr("Second"), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateConversation("other-session", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	for _, content := range []string{"prompt one", "response one"} {
+		if _, err := db.CreateMessage(first.ID, "prompt", content, nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	nodes, err := db.GetSessionGraph("graph-session")
+	if err != nil {
+		t.Fatalf("Failed to get session graph: %v", err)
+	}
+
+	if len(nodes) != 2 {
+		t.Fatalf("Expected 2 nodes, got %d", len(nodes))
+	}
+
+	if nodes[0].ConversationID != first.ID || nodes[1].ConversationID != second.ID {
+		t.Errorf("Expected nodes in creation order [%d %d], got [%d %d]",
+			first.ID, second.ID, nodes[0].ConversationID, nodes[1].ConversationID)
+	}
+
+	if nodes[0].MessageCount != 2 {
+		t.Errorf("Expected 2 messages on first node, got %d", nodes[0].MessageCount)
+	}
+	if nodes[0].FirstMessageAt == nil || nodes[0].LastMessageAt == nil {
+		t.Fatal("Expected time bounds on first node")
+	}
+	if nodes[0].LastMessageAt.Before(*nodes[0].FirstMessageAt) {
+		t.Errorf("Expected last message time not before first, got %v < %v",
+			*nodes[0].LastMessageAt, *nodes[0].FirstMessageAt)
+	}
+
+	if nodes[1].MessageCount != 0 || nodes[1].FirstMessageAt != nil {
+		t.Errorf("Expected empty second node, got %+v", nodes[1])
+	}
+
+	if _, err := db.GetSessionGraph("missing-session"); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound, got %v", err)
+	}
+}
+
+func TestGetSessionMetrics(t *testing.T) {
+	db := setupTestDB(t)
+
+	first, err := db.CreateConversation("metrics-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	second, err := db.CreateConversation("metrics-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateConversation("other-session", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	messages := []struct {
+		convID        int
+		messageType   string
+		executionTime *int
+	}{
+		{first.ID, "prompt", nil},
+		{first.ID, "response", intPtr(100)},
+		{second.ID, "prompt", nil},
+		{second.ID, "prompt", nil},
+		{second.ID, "response", intPtr(301)},
+		{second.ID, "response", nil}, // excluded: no execution time
+	}
+	for _, m := range messages {
+		if _, err := db.CreateMessage(m.convID, m.messageType, "content", nil, m.executionTime); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	session, err := db.GetSessionMetrics("metrics-session")
+	if err != nil {
+		t.Fatalf("Failed to get session metrics: %v", err)
+	}
+
+	if session.ConversationCount != 2 {
+		t.Errorf("Expected 2 conversations, got %d", session.ConversationCount)
+	}
+	if session.TotalPromptCount != 3 {
+		t.Errorf("Expected 3 prompts, got %d", session.TotalPromptCount)
+	}
+	if session.AvgResponseTime != 201 {
+		t.Errorf("Expected average response time 201ms, got %d", session.AvgResponseTime)
+	}
+	if session.StartTime.IsZero() || session.LastActivity.Before(session.StartTime) {
+		t.Errorf("Expected start %v to precede last activity %v", session.StartTime, session.LastActivity)
+	}
+
+	if _, err := db.GetSessionMetrics("missing-session"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	db := setupTestDB(t)
+
+	// Create sessions in order, then touch the first so it becomes most recent
+	var first *Conversation
+	for _, sessionID := range []string{"session-a", "session-b", "session-b"} {
+		conv, err := db.CreateConversation(sessionID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		if _, err := db.CreateMessage(conv.ID, "prompt", "hello", nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if first == nil {
+			first = conv
+		}
+	}
+
+	if _, err := db.conn.Exec("UPDATE conversations SET updated_at = datetime('now', '+1 hour') WHERE id = ?", first.ID); err != nil {
+		t.Fatalf("Failed to touch conversation: %v", err)
+	}
+
+	sessions, err := db.ListSessions(10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+	}
+
+	if sessions[0].SessionID != "session-a" {
+		t.Errorf("Expected most recently active session first, got %s", sessions[0].SessionID)
+	}
+
+	b := sessions[1]
+	if b.SessionID != "session-b" || b.ConversationCount != 2 || b.TotalPromptCount != 2 {
+		t.Errorf("Unexpected aggregates for session-b: %+v", b)
+	}
+
+	page, err := db.ListSessions(1, 1)
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	if len(page) != 1 || page[0].SessionID != "session-b" {
+		t.Errorf("Expected session-b on the second page, got %+v", page)
+	}
+
+	count, err := db.GetSessionCount()
+	if err != nil {
+		t.Fatalf("Failed to count sessions: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 sessions, got %d", count)
+	}
+}
+
+func TestGetSessionStats(t *testing.T) {
+	db := setupTestDB(t)
+
+	// Session "alpha" has two conversations: one with a rated prompt, one
+	// rated directly
+	first, err := db.CreateConversation("alpha", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	message, err := db.CreateMessage(first.ID, "prompt", "hello", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := db.CreateMessage(first.ID, "response", "hi there", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := db.CreateMessageRating(message.ID, 2, nil); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+	second, err := db.CreateConversation("alpha", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateConversationRating(second.ID, 5, nil); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+
+	// Session "beta" has one unrated conversation
+	beta, err := db.CreateConversation("beta", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateMessage(beta.ID, "prompt", "four", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	stats, err := db.GetSessionStats([]string{"beta", "missing", "alpha", "beta"})
+	if err != nil {
+		t.Fatalf("Failed to get session stats: %v", err)
+	}
+
+	expected := []SessionStats{
+		{SessionID: "beta", ConversationCount: 1, MessageCount: 1, TotalCharacters: 4},
+		{SessionID: "missing"},
+		{SessionID: "alpha", ConversationCount: 2, MessageCount: 2, TotalCharacters: 13, AverageRating: 3.5},
+	}
+	if len(stats) != len(expected) {
+		t.Fatalf("Expected %d sessions, got %+v", len(expected), stats)
+	}
+	for i, want := range expected {
+		if stats[i] != want {
+			t.Errorf("Session %d: expected %+v, got %+v", i, want, stats[i])
+		}
+	}
+}
+
+func TestSetSessionStatus(t *testing.T) {
+	db := setupTestDB(t)
+
+	if err := db.SetSessionStatus("status-session", models.SessionStatusCompleted); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("Expected ErrSessionNotFound before the session is recorded, got %v", err)
+	}
+
+	if err := db.UpsertSession("status-session", stringPtr("/work")); err != nil {
+		t.Fatalf("UpsertSession() error = %v", err)
+	}
+	// A second upsert must not reset the status or clear the directory
+	if err := db.SetSessionStatus("status-session", models.SessionStatusCompleted); err != nil {
+		t.Fatalf("SetSessionStatus() error = %v", err)
+	}
+	if err := db.UpsertSession("status-session", nil); err != nil {
+		t.Fatalf("UpsertSession() error = %v", err)
+	}
+
+	status, err := db.GetSessionStatus("status-session")
+	if err != nil {
+		t.Fatalf("GetSessionStatus() error = %v", err)
+	}
+	if status != models.SessionStatusCompleted {
+		t.Errorf("Expected status completed after upsert, got %q", status)
+	}
+
+	var workingDirectory *string
+	var endTime *string
+	err = db.conn.QueryRow("SELECT working_directory, end_time FROM sessions WHERE session_id = ?", "status-session").Scan(&workingDirectory, &endTime)
+	if err != nil {
+		t.Fatalf("Failed to read session row: %v", err)
+	}
+	if workingDirectory == nil || *workingDirectory != "/work" {
+		t.Errorf("Expected working directory to be kept, got %v", workingDirectory)
+	}
+	if endTime == nil {
+		t.Error("Expected completing the session to record an end time")
+	}
+
+	if err := db.SetSessionStatus("status-session", models.SessionStatusArchived); err != nil {
+		t.Fatalf("SetSessionStatus() error = %v", err)
+	}
+	if err := db.SetSessionStatus("status-session", models.SessionStatusActive); !errors.Is(err, ErrInvalidSessionTransition) {
+		t.Errorf("Expected ErrInvalidSessionTransition reactivating an archived session, got %v", err)
+	}
+
+	status, err = db.GetSessionStatus("status-session")
+	if err != nil {
+		t.Fatalf("GetSessionStatus() error = %v", err)
+	}
+	if status != models.SessionStatusArchived {
+		t.Errorf("Expected status to stay archived, got %q", status)
+	}
+}
+
+func TestGetSessionMetricsStatus(t *testing.T) {
+	db := setupTestDB(t)
+
+	if _, err := db.CreateConversation("tracked-session", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateConversation("untracked-session", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if err := db.UpsertSession("tracked-session", nil); err != nil {
+		t.Fatalf("UpsertSession() error = %v", err)
+	}
+
+	tracked, err := db.GetSessionMetrics("tracked-session")
+	if err != nil {
+		t.Fatalf("GetSessionMetrics() error = %v", err)
+	}
+	if tracked.Status != models.SessionStatusActive {
+		t.Errorf("Expected tracked session to be active, got %q", tracked.Status)
+	}
+
+	untracked, err := db.GetSessionMetrics("untracked-session")
+	if err != nil {
+		t.Fatalf("GetSessionMetrics() error = %v", err)
+	}
+	if untracked.Status != "" {
+		t.Errorf("Expected no status for an untracked session, got %q", untracked.Status)
+	}
+
+	// A session row without conversations is still found
+	if err := db.UpsertSession("empty-session", nil); err != nil {
+		t.Fatalf("UpsertSession() error = %v", err)
+	}
+	empty, err := db.GetSessionMetrics("empty-session")
+	if err != nil {
+		t.Fatalf("GetSessionMetrics() error = %v", err)
+	}
+	if empty.SessionID != "empty-session" || empty.ConversationCount != 0 || empty.TotalPromptCount != 0 {
+		t.Errorf("Expected an empty session with zero counts, got %+v", empty)
+	}
+	if empty.Status != models.SessionStatusActive {
+		t.Errorf("Expected empty session to be active, got %q", empty.Status)
+	}
+	if empty.StartTime.IsZero() || !empty.LastActivity.Equal(empty.StartTime) {
+		t.Errorf("Expected start and last activity from the session row, got %v and %v", empty.StartTime, empty.LastActivity)
+	}
+}
+
+func TestRecordSessionEnd(t *testing.T) {
+	db := setupTestDB(t)
+
+	if _, err := db.RecordSessionEnd("ended-session", time.Now(), nil); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("Expected ErrSessionNotFound before the session is recorded, got %v", err)
+	}
+
+	if _, err := db.CreateConversation("ended-session", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if err := db.UpsertSession("ended-session", nil); err != nil {
+		t.Fatalf("UpsertSession() error = %v", err)
+	}
+
+	start := time.Now().UTC().Add(-90 * time.Second).Truncate(time.Second)
+	if _, err := db.conn.Exec("UPDATE sessions SET start_time = ? WHERE session_id = ?", start.Format(sqliteTimestampLayout), "ended-session"); err != nil {
+		t.Fatalf("Failed to back-date session: %v", err)
+	}
+
+	t.Run("measured from start", func(t *testing.T) {
+		endTime := start.Add(90 * time.Second)
+		end, err := db.RecordSessionEnd("ended-session", endTime, nil)
+		if err != nil {
+			t.Fatalf("RecordSessionEnd() error = %v", err)
+		}
+		if end.DurationMs != 90000 {
+			t.Errorf("Expected a duration of 90000ms, got %d", end.DurationMs)
+		}
+
+		session, err := db.GetSessionMetrics("ended-session")
+		if err != nil {
+			t.Fatalf("GetSessionMetrics() error = %v", err)
+		}
+		if session.EndTime == nil || !session.EndTime.Equal(endTime) {
+			t.Errorf("Expected end time %v, got %v", endTime, session.EndTime)
+		}
+		if session.DurationMs == nil || *session.DurationMs != 90000 {
+			t.Errorf("Expected duration 90000ms to be reported, got %v", session.DurationMs)
+		}
+	})
+
+	t.Run("reported duration", func(t *testing.T) {
+		reported := int64(1234)
+		end, err := db.RecordSessionEnd("ended-session", time.Now(), &reported)
+		if err != nil {
+			t.Fatalf("RecordSessionEnd() error = %v", err)
+		}
+		if end.DurationMs != reported {
+			t.Errorf("Expected the reported duration %d, got %d", reported, end.DurationMs)
+		}
+	})
+
+	t.Run("end before start", func(t *testing.T) {
+		end, err := db.RecordSessionEnd("ended-session", start.Add(-time.Minute), nil)
+		if err != nil {
+			t.Fatalf("RecordSessionEnd() error = %v", err)
+		}
+		if end.DurationMs != 0 {
+			t.Errorf("Expected a negative duration to be clamped to 0, got %d", end.DurationMs)
+		}
+	})
+}
+
+func TestRecordSessionEndWithoutSessionStart(t *testing.T) {
+	db := setupTestDB(t)
+
+	// The session's only trace before it ends is a conversation from two
+	// minutes ago; no SessionStart ever created its sessions row
+	created := time.Now().UTC().Add(-2 * time.Minute).Truncate(time.Second)
+	if _, err := db.conn.Exec(
+		"INSERT INTO conversations (session_id, created_at, updated_at) VALUES (?, ?, ?)",
+		"late-session", created.Format(sqliteTimestampLayout), created.Format(sqliteTimestampLayout),
+	); err != nil {
+		t.Fatalf("Failed to insert conversation: %v", err)
+	}
+
+	if err := db.UpsertSession("late-session", nil); err != nil {
+		t.Fatalf("UpsertSession() error = %v", err)
+	}
+
+	endTime := created.Add(2 * time.Minute)
+	end, err := db.RecordSessionEnd("late-session", endTime, nil)
+	if err != nil {
+		t.Fatalf("RecordSessionEnd() error = %v", err)
+	}
+	if end.DurationMs != 120000 {
+		t.Errorf("Expected the duration to be measured from the first conversation, got %dms", end.DurationMs)
+	}
+}
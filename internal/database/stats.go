@@ -0,0 +1,864 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// normalizedDirectoryExpr normalizes a conversation's working directory for
+// grouping: trailing slashes are dropped and empty values become NULL
+const normalizedDirectoryExpr = `
+	CASE
+		WHEN c.working_directory IS NULL OR c.working_directory = '' THEN NULL
+		WHEN RTRIM(c.working_directory, '/') = '' THEN '/'
+		ELSE RTRIM(c.working_directory, '/')
+	END`
+
+// DirectoryStats aggregates conversation activity for one working directory.
+// Directory is nil for conversations recorded without a working directory.
+type DirectoryStats struct {
+	Directory         *string `json:"directory"`
+	ConversationCount int     `json:"conversation_count"`
+	MessageCount      int     `json:"message_count"`
+	TotalCharacters   int     `json:"total_characters"`
+	AverageRating     float64 `json:"average_rating"`
+	RatingCount       int     `json:"rating_count"`
+}
+
+// GetDirectoryStats returns per-working-directory aggregates ordered by
+// conversation count, busiest first
+func (db *DB) GetDirectoryStats(limit, offset int) ([]DirectoryStats, error) {
+	// Aggregate per conversation first so joins to messages and ratings
+	// cannot inflate the conversation-level sums
+	query := `
+	SELECT
+		directory,
+		COUNT(*),
+		COALESCE(SUM(message_count), 0),
+		COALESCE(SUM(total_characters), 0),
+		COALESCE(SUM(rating_sum) * 1.0 / NULLIF(SUM(rating_count), 0), 0),
+		COALESCE(SUM(rating_count), 0)
+	FROM (
+		SELECT
+			` + normalizedDirectoryExpr + ` AS directory,
+			c.total_characters,
+			(SELECT COUNT(*) FROM messages m WHERE m.conversation_id = c.id) AS message_count,
+			(SELECT COALESCE(SUM(r.rating), 0) FROM ratings r LEFT JOIN messages m ON m.id = r.message_id
+				WHERE COALESCE(r.conversation_id, m.conversation_id) = c.id) AS rating_sum,
+			(SELECT COUNT(*) FROM ratings r LEFT JOIN messages m ON m.id = r.message_id
+				WHERE COALESCE(r.conversation_id, m.conversation_id) = c.id) AS rating_count
+		FROM conversations c
+		WHERE ` + db.statsConversationFilter("c") + `
+	)
+	GROUP BY directory
+	ORDER BY COUNT(*) DESC, directory ASC
+	LIMIT ? OFFSET ?`
+
+	rows, err := db.conn.Query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get directory stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []DirectoryStats
+	for rows.Next() {
+		var s DirectoryStats
+		err := rows.Scan(
+			&s.Directory, &s.ConversationCount, &s.MessageCount,
+			&s.TotalCharacters, &s.AverageRating, &s.RatingCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan directory stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get directory stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// GetDirectoryCount returns the number of distinct normalized working directories
+func (db *DB) GetDirectoryCount() (int, error) {
+	query := `
+	SELECT COUNT(*) FROM (
+		SELECT DISTINCT ` + normalizedDirectoryExpr + `
+		FROM conversations c
+		WHERE ` + db.statsConversationFilter("c") + `
+	)`
+
+	var count int
+	if err := db.conn.QueryRow(query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to get directory count: %w", err)
+	}
+
+	return count, nil
+}
+
+// unknownModel is the bucket for responses recorded without a model
+const unknownModel = "unknown"
+
+// ModelLatencyStats summarizes response execution times for one model
+type ModelLatencyStats struct {
+	Model         string  `json:"model"`
+	ResponseCount int     `json:"response_count"`
+	AverageMs     float64 `json:"average_ms"`
+	P95Ms         int     `json:"p95_ms"`
+}
+
+// GetModelLatencyStats returns the average and 95th percentile execution time
+// of response messages per model, ignoring responses without an execution time
+func (db *DB) GetModelLatencyStats() ([]ModelLatencyStats, error) {
+	latencySource := `
+	messages m
+	JOIN conversations c ON c.id = m.conversation_id
+	WHERE m.message_type = 'response' AND m.execution_time IS NOT NULL
+	AND ` + db.statsConversationFilter("c")
+
+	query := `
+	SELECT COALESCE(m.model, ?) AS model_bucket, COUNT(*), AVG(m.execution_time)
+	FROM ` + latencySource + `
+	GROUP BY model_bucket
+	ORDER BY model_bucket ASC`
+
+	rows, err := db.conn.Query(query, unknownModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model latency stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []ModelLatencyStats
+	for rows.Next() {
+		var s ModelLatencyStats
+		if err := rows.Scan(&s.Model, &s.ResponseCount, &s.AverageMs); err != nil {
+			return nil, fmt.Errorf("failed to scan model latency stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	rows.Close()
+
+	// SQLite has no percentile function, so pick the nearest-rank value per model
+	p95Query := `
+	SELECT m.execution_time
+	FROM ` + latencySource + ` AND COALESCE(m.model, ?) = ?
+	ORDER BY m.execution_time ASC
+	LIMIT 1 OFFSET ?`
+
+	for i := range stats {
+		rank := int(math.Ceil(0.95 * float64(stats[i].ResponseCount)))
+		err := db.conn.QueryRow(p95Query, unknownModel, stats[i].Model, rank-1).Scan(&stats[i].P95Ms)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get p95 latency for model %s: %w", stats[i].Model, err)
+		}
+	}
+
+	return stats, nil
+}
+
+// unknownTool is the bucket for tool calls recorded without a name
+const unknownTool = "unknown"
+
+// toolCallsJoin joins each tool call stored with message m as tc, skipping
+// messages whose tool_calls is not a JSON array and entries that are not objects
+const toolCallsJoin = `JOIN json_each(CASE WHEN json_valid(m.tool_calls) AND json_type(m.tool_calls) = 'array' THEN m.tool_calls ELSE '[]' END) tc
+		ON tc.type = 'object'`
+
+// toolDurationExpr reads a tool call's duration in whole milliseconds
+const toolDurationExpr = `CAST(json_extract(tc.value, '$.duration') AS INTEGER)`
+
+// ToolDurationStats summarizes the recorded durations of one tool's calls
+type ToolDurationStats struct {
+	ToolName  string  `json:"tool_name"`
+	CallCount int     `json:"call_count"`
+	TotalMs   int     `json:"total_ms"`
+	AverageMs float64 `json:"average_ms"`
+	MinMs     int     `json:"min_ms"`
+	MaxMs     int     `json:"max_ms"`
+}
+
+// GetToolDurationStats returns the total, average, min and max duration per
+// tool name, ordered by total duration descending. Tool calls are read from
+// the JSON stored with each message; calls without a duration are ignored.
+func (db *DB) GetToolDurationStats() ([]ToolDurationStats, error) {
+	query := `
+	SELECT COALESCE(json_extract(tc.value, '$.name'), ?) AS tool_name,
+		COUNT(*),
+		SUM(` + toolDurationExpr + `) AS total_ms,
+		AVG(` + toolDurationExpr + `),
+		MIN(` + toolDurationExpr + `),
+		MAX(` + toolDurationExpr + `)
+	FROM messages m
+	JOIN conversations c ON c.id = m.conversation_id
+	` + toolCallsJoin + `
+	WHERE json_type(tc.value, '$.duration') IN ('integer', 'real')
+	AND ` + db.statsConversationFilter("c") + `
+	GROUP BY tool_name
+	ORDER BY total_ms DESC, tool_name ASC`
+
+	rows, err := db.conn.Query(query, unknownTool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool duration stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []ToolDurationStats{}
+	for rows.Next() {
+		var s ToolDurationStats
+		if err := rows.Scan(&s.ToolName, &s.CallCount, &s.TotalMs, &s.AverageMs, &s.MinMs, &s.MaxMs); err != nil {
+			return nil, fmt.Errorf("failed to scan tool duration stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
+
+// ToolCallStats counts recorded tool calls in total and per tool name
+type ToolCallStats struct {
+	TotalCalls int            `json:"total_calls"`
+	ByName     map[string]int `json:"by_name"`
+}
+
+// GetToolCallStats returns how many tool calls have been recorded across all
+// messages, broken down by tool name. Messages without tool calls contribute
+// nothing; calls recorded without a name are counted as "unknown".
+func (db *DB) GetToolCallStats() (*ToolCallStats, error) {
+	query := `
+	SELECT COALESCE(json_extract(tc.value, '$.name'), ?) AS tool_name, COUNT(*)
+	FROM messages m
+	JOIN conversations c ON c.id = m.conversation_id
+	` + toolCallsJoin + `
+	WHERE ` + db.statsConversationFilter("c") + `
+	GROUP BY tool_name`
+
+	rows, err := db.conn.Query(query, unknownTool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool call stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := &ToolCallStats{ByName: map[string]int{}}
+	for rows.Next() {
+		var name string
+		var count int
+		if err := rows.Scan(&name, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan tool call stats: %w", err)
+		}
+		stats.ByName[name] = count
+		stats.TotalCalls += count
+	}
+
+	return stats, rows.Err()
+}
+
+// ConversationToolCount pairs a conversation with its total number of tool calls
+type ConversationToolCount struct {
+	Conversation Conversation `json:"conversation"`
+	ToolCount    int          `json:"tool_count"`
+}
+
+// GetMostToolConversations returns up to limit conversations with the most
+// tool calls across their messages, highest first. Tool calls are read from
+// the JSON stored with each message; conversations without any are omitted.
+func (db *DB) GetMostToolConversations(limit int) ([]ConversationToolCount, error) {
+	query := `
+	SELECT ` + db.conversationListColumns() + `, t.tool_count
+	FROM conversations
+	JOIN (
+		SELECT m.conversation_id, COUNT(*) AS tool_count
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		` + toolCallsJoin + `
+		WHERE ` + db.statsConversationFilter("c") + `
+		GROUP BY m.conversation_id
+	) t ON t.conversation_id = conversations.id
+	ORDER BY t.tool_count DESC, conversations.id ASC
+	LIMIT ?`
+
+	rows, err := db.conn.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get most tool conversations: %w", err)
+	}
+	defer rows.Close()
+
+	results := []ConversationToolCount{}
+	for rows.Next() {
+		var r ConversationToolCount
+		conv := &r.Conversation
+		err := rows.Scan(
+			&conv.ID, &conv.SessionID, &conv.Title, &conv.CreatedAt, &conv.UpdatedAt,
+			&conv.PromptCount, &conv.TotalCharacters, &conv.WorkingDirectory, &conv.TranscriptPath, &conv.DeletedAt,
+			&conv.AvgResponseTimeMs, &conv.AvgRating, &r.ToolCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan most tool conversation: %w", err)
+		}
+		results = append(results, r)
+	}
+
+	return results, rows.Err()
+}
+
+// ActivityRange reports the timestamps of the earliest and latest messages.
+// All fields are nil when no messages have been recorded.
+type ActivityRange struct {
+	Earliest    *time.Time `json:"earliest"`
+	Latest      *time.Time `json:"latest"`
+	SpanSeconds *int64     `json:"span_seconds"`
+}
+
+// GetActivityRange returns the span of recorded message activity
+func (db *DB) GetActivityRange() (*ActivityRange, error) {
+	query := `
+	SELECT MIN(m.timestamp), MAX(m.timestamp)
+	FROM messages m
+	JOIN conversations c ON c.id = m.conversation_id
+	WHERE ` + db.statsConversationFilter("c")
+
+	// MIN and MAX lose the column's declared type, so the driver hands back
+	// the stored text rather than a time.Time
+	var earliest, latest sql.NullString
+	if err := db.conn.QueryRow(query).Scan(&earliest, &latest); err != nil {
+		return nil, fmt.Errorf("failed to get activity range: %w", err)
+	}
+
+	activity := &ActivityRange{}
+	if !earliest.Valid || !latest.Valid {
+		return activity, nil
+	}
+
+	start, err := parseSQLiteTimestamp(earliest.String)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseSQLiteTimestamp(latest.String)
+	if err != nil {
+		return nil, err
+	}
+
+	span := int64(end.Sub(start) / time.Second)
+	activity.Earliest = &start
+	activity.Latest = &end
+	activity.SpanSeconds = &span
+
+	return activity, nil
+}
+
+// ActivityHeatmap counts messages by day of week and hour of day in UTC.
+// Counts[d][h] holds the messages sent on weekday d (0 is Sunday) during
+// hour h; every cell is present, with zero for hours without activity.
+type ActivityHeatmap struct {
+	Counts [7][24]int `json:"counts"`
+}
+
+// GetActivityHeatmap buckets every message by the weekday and hour of its timestamp
+func (db *DB) GetActivityHeatmap() (*ActivityHeatmap, error) {
+	query := `
+	SELECT CAST(strftime('%w', m.timestamp) AS INTEGER) AS weekday,
+		CAST(strftime('%H', m.timestamp) AS INTEGER) AS hour,
+		COUNT(*)
+	FROM messages m
+	JOIN conversations c ON c.id = m.conversation_id
+	WHERE ` + db.statsConversationFilter("c") + `
+	GROUP BY weekday, hour`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activity heatmap: %w", err)
+	}
+	defer rows.Close()
+
+	heatmap := &ActivityHeatmap{}
+	for rows.Next() {
+		var weekday, hour sql.NullInt64
+		var count int
+		if err := rows.Scan(&weekday, &hour, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan activity heatmap: %w", err)
+		}
+		// Timestamps strftime cannot parse yield NULL and are skipped
+		if !weekday.Valid || !hour.Valid || weekday.Int64 < 0 || weekday.Int64 > 6 || hour.Int64 < 0 || hour.Int64 > 23 {
+			continue
+		}
+		heatmap.Counts[weekday.Int64][hour.Int64] += count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get activity heatmap: %w", err)
+	}
+
+	return heatmap, nil
+}
+
+// WeekdayCount is the number of conversations created on one day of the week
+type WeekdayCount struct {
+	Weekday string `json:"weekday"`
+	Count   int    `json:"count"`
+}
+
+// GetConversationsByWeekday counts conversations by the UTC weekday they were
+// created on. All seven days are returned, Sunday first, with zero for days
+// without conversations.
+func (db *DB) GetConversationsByWeekday() ([]WeekdayCount, error) {
+	query := `
+	SELECT CAST(strftime('%w', c.created_at) AS INTEGER) AS weekday, COUNT(*)
+	FROM conversations c
+	WHERE ` + db.statsConversationFilter("c") + `
+	GROUP BY weekday`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversations by weekday: %w", err)
+	}
+	defer rows.Close()
+
+	var counts [7]int
+	for rows.Next() {
+		var weekday sql.NullInt64
+		var count int
+		if err := rows.Scan(&weekday, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan weekday count: %w", err)
+		}
+		// Timestamps strftime cannot parse yield NULL and are skipped
+		if !weekday.Valid || weekday.Int64 < 0 || weekday.Int64 > 6 {
+			continue
+		}
+		counts[weekday.Int64] += count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get conversations by weekday: %w", err)
+	}
+
+	weekdays := make([]WeekdayCount, len(counts))
+	for day, count := range counts {
+		weekdays[day] = WeekdayCount{Weekday: time.Weekday(day).String(), Count: count}
+	}
+
+	return weekdays, nil
+}
+
+// parseSQLiteTimestamp parses a timestamp stored as text using the same
+// layouts the sqlite3 driver accepts for DATETIME columns
+func parseSQLiteTimestamp(value string) (time.Time, error) {
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("failed to parse timestamp %q", value)
+}
+
+// DefaultMessageSizeBuckets are the character-count boundaries used when a
+// caller does not choose its own
+var DefaultMessageSizeBuckets = []int{100, 500, 1000, 5000}
+
+// MaxMessageSizeBuckets caps how many boundaries a histogram may use
+const MaxMessageSizeBuckets = 20
+
+// MessageSizeBucket counts the prompts and responses whose character count
+// falls in [Min, Max). Max is nil for the open-ended last bucket.
+type MessageSizeBucket struct {
+	Min           int  `json:"min"`
+	Max           *int `json:"max"`
+	PromptCount   int  `json:"prompt_count"`
+	ResponseCount int  `json:"response_count"`
+}
+
+// ValidateMessageSizeBuckets checks that boundaries are positive and strictly
+// ascending, and that there are not too many of them
+func ValidateMessageSizeBuckets(boundaries []int) error {
+	return validateBucketBoundaries(boundaries, MaxMessageSizeBuckets)
+}
+
+// validateBucketBoundaries checks that there are between one and max
+// positive, strictly ascending histogram boundaries
+func validateBucketBoundaries(boundaries []int, max int) error {
+	if len(boundaries) == 0 {
+		return fmt.Errorf("at least one bucket boundary is required")
+	}
+	if len(boundaries) > max {
+		return fmt.Errorf("at most %d bucket boundaries are allowed", max)
+	}
+
+	for i, boundary := range boundaries {
+		if boundary <= 0 {
+			return fmt.Errorf("bucket boundaries must be positive, got %d", boundary)
+		}
+		if i > 0 && boundary <= boundaries[i-1] {
+			return fmt.Errorf("bucket boundaries must be strictly ascending")
+		}
+	}
+
+	return nil
+}
+
+// GetMessageSizeDistribution returns a histogram of message character counts
+// split by message type. The boundaries split the range into len+1 buckets,
+// the last of which is open-ended.
+func (db *DB) GetMessageSizeDistribution(boundaries []int) ([]MessageSizeBucket, error) {
+	if err := ValidateMessageSizeBuckets(boundaries); err != nil {
+		return nil, err
+	}
+
+	var bucketExpr strings.Builder
+	var args []interface{}
+	bucketExpr.WriteString("CASE")
+	for i, boundary := range boundaries {
+		bucketExpr.WriteString(" WHEN m.character_count < ? THEN ?")
+		args = append(args, boundary, i)
+	}
+	bucketExpr.WriteString(" ELSE ? END")
+	args = append(args, len(boundaries))
+
+	query := `
+	SELECT ` + bucketExpr.String() + ` AS bucket, m.message_type, COUNT(*)
+	FROM messages m
+	JOIN conversations c ON c.id = m.conversation_id
+	WHERE ` + db.statsConversationFilter("c") + `
+	GROUP BY bucket, m.message_type`
+
+	buckets := make([]MessageSizeBucket, len(boundaries)+1)
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].Min = boundaries[i-1]
+		}
+		if i < len(boundaries) {
+			upper := boundaries[i]
+			buckets[i].Max = &upper
+		}
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message size distribution: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket, count int
+		var messageType string
+		if err := rows.Scan(&bucket, &messageType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan message size bucket: %w", err)
+		}
+
+		switch messageType {
+		case "prompt":
+			buckets[bucket].PromptCount = count
+		case "response":
+			buckets[bucket].ResponseCount = count
+		}
+	}
+
+	return buckets, rows.Err()
+}
+
+// DefaultPromptCountBuckets are the prompt-count boundaries used to group
+// conversations by length when a caller does not choose its own
+var DefaultPromptCountBuckets = []int{5, 20, 50}
+
+// MaxPromptCountBuckets caps how many boundaries a length grouping may use
+const MaxPromptCountBuckets = 20
+
+// RatingLengthBucket averages the rating scores of conversations whose prompt
+// count falls in [Min, Max). Max is nil for the open-ended last bucket, and
+// AverageRating is nil when no conversation in the bucket is rated.
+type RatingLengthBucket struct {
+	Min               int      `json:"min"`
+	Max               *int     `json:"max"`
+	ConversationCount int      `json:"conversation_count"`
+	RatingCount       int      `json:"rating_count"`
+	AverageRating     *float64 `json:"average_rating"`
+}
+
+// ValidatePromptCountBuckets checks that boundaries are positive and strictly
+// ascending, and that there are not too many of them
+func ValidatePromptCountBuckets(boundaries []int) error {
+	return validateBucketBoundaries(boundaries, MaxPromptCountBuckets)
+}
+
+// GetRatingByLength returns the average score of rated conversations grouped
+// by prompt count. The boundaries split the range into len+1 buckets, the
+// last of which is open-ended; unrated conversations are left out.
+func (db *DB) GetRatingByLength(boundaries []int) ([]RatingLengthBucket, error) {
+	if err := ValidatePromptCountBuckets(boundaries); err != nil {
+		return nil, err
+	}
+
+	var bucketExpr strings.Builder
+	var args []interface{}
+	bucketExpr.WriteString("CASE")
+	for i, boundary := range boundaries {
+		bucketExpr.WriteString(" WHEN c.prompt_count < ? THEN ?")
+		args = append(args, boundary, i)
+	}
+	bucketExpr.WriteString(" ELSE ? END")
+	args = append(args, len(boundaries))
+
+	// Each conversation contributes its score under the configured
+	// aggregation, so heavily rated conversations do not dominate a bucket
+	query := `
+	SELECT bucket, COUNT(*), SUM(rating_count), AVG(score)
+	FROM (
+		SELECT ` + bucketExpr.String() + ` AS bucket,
+			(SELECT COUNT(*) FROM ` + fmt.Sprintf(conversationRatingsSource, "c.id") + `) AS rating_count,
+			` + ratingScoreExpr(db.ratingAggregation(), "c.id") + ` AS score
+		FROM conversations c
+		WHERE ` + db.statsConversationFilter("c") + `
+	)
+	WHERE rating_count > 0
+	GROUP BY bucket`
+
+	buckets := make([]RatingLengthBucket, len(boundaries)+1)
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].Min = boundaries[i-1]
+		}
+		if i < len(boundaries) {
+			upper := boundaries[i]
+			buckets[i].Max = &upper
+		}
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rating by length: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket, conversations, ratings int
+		var average float64
+		if err := rows.Scan(&bucket, &conversations, &ratings, &average); err != nil {
+			return nil, fmt.Errorf("failed to scan rating length bucket: %w", err)
+		}
+		buckets[bucket].ConversationCount = conversations
+		buckets[bucket].RatingCount = ratings
+		buckets[bucket].AverageRating = &average
+	}
+
+	return buckets, rows.Err()
+}
+
+// CreationRateInterval is the bucket width used by GetConversationCreationRate
+type CreationRateInterval string
+
+const (
+	CreationRateHour CreationRateInterval = "hour"
+	CreationRateDay  CreationRateInterval = "day"
+)
+
+// MaxCreationRateBuckets caps how many buckets a creation rate query may span
+const MaxCreationRateBuckets = 5000
+
+// ParseCreationRateInterval parses an interval parameter, which must be hour or day
+func ParseCreationRateInterval(value string) (CreationRateInterval, error) {
+	switch interval := CreationRateInterval(value); interval {
+	case CreationRateHour, CreationRateDay:
+		return interval, nil
+	default:
+		return "", fmt.Errorf("invalid interval %q: must be hour or day", value)
+	}
+}
+
+// bucketFormat returns the strftime format that truncates a timestamp to the interval
+func (i CreationRateInterval) bucketFormat() string {
+	if i == CreationRateDay {
+		return "%Y-%m-%d 00:00:00"
+	}
+	return "%Y-%m-%d %H:00:00"
+}
+
+// truncate returns the start of the bucket containing t
+func (i CreationRateInterval) truncate(t time.Time) time.Time {
+	t = t.UTC()
+	if i == CreationRateDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(time.Hour)
+}
+
+// next returns the start of the bucket following start
+func (i CreationRateInterval) next(start time.Time) time.Time {
+	if i == CreationRateDay {
+		return start.AddDate(0, 0, 1)
+	}
+	return start.Add(time.Hour)
+}
+
+// CreationRateBucket counts the conversations created in the bucket
+// beginning at Start
+type CreationRateBucket struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+// GetConversationCreationRate counts conversations created per interval
+// between from and to. Either bound may be nil, in which case the range
+// extends to the earliest or latest conversation. Buckets with no
+// conversations are reported with a zero count.
+func (db *DB) GetConversationCreationRate(interval CreationRateInterval, from, to *time.Time) ([]CreationRateBucket, error) {
+	conditions := []string{db.statsConversationFilter("c")}
+	var args []interface{}
+	if from != nil {
+		conditions = append(conditions, "c.created_at >= ?")
+		args = append(args, from.UTC().Format(sqliteTimestampLayout))
+	}
+	if to != nil {
+		conditions = append(conditions, "c.created_at <= ?")
+		args = append(args, to.UTC().Format(sqliteTimestampLayout))
+	}
+
+	query := `
+	SELECT strftime('` + interval.bucketFormat() + `', c.created_at) AS bucket, COUNT(*)
+	FROM conversations c
+	WHERE ` + strings.Join(conditions, " AND ") + `
+	GROUP BY bucket
+	ORDER BY bucket`
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation creation rate: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[time.Time]int)
+	var earliest, latest time.Time
+	for rows.Next() {
+		var bucket string
+		var count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan creation rate bucket: %w", err)
+		}
+
+		start, err := parseSQLiteTimestamp(bucket)
+		if err != nil {
+			return nil, err
+		}
+		if len(counts) == 0 {
+			earliest = start
+		}
+		latest = start
+		counts[start] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get conversation creation rate: %w", err)
+	}
+
+	if from != nil {
+		earliest = interval.truncate(*from)
+	}
+	if to != nil {
+		latest = interval.truncate(*to)
+	}
+	if (from == nil || to == nil) && len(counts) == 0 {
+		return []CreationRateBucket{}, nil
+	}
+
+	buckets := []CreationRateBucket{}
+	for start := earliest; !start.After(latest); start = interval.next(start) {
+		if len(buckets) == MaxCreationRateBuckets {
+			return nil, ErrCreationRateRangeTooLarge
+		}
+		buckets = append(buckets, CreationRateBucket{Start: start, Count: counts[start]})
+	}
+
+	return buckets, nil
+}
+
+// OverviewTopDirectories is the number of working directories reported in
+// OverviewStats
+const OverviewTopDirectories = 5
+
+// DirectoryConversationCount is the number of conversations recorded in one
+// working directory
+type DirectoryConversationCount struct {
+	Directory         string `json:"directory"`
+	ConversationCount int    `json:"conversation_count"`
+}
+
+// OverviewStats summarizes the whole database for a dashboard
+type OverviewStats struct {
+	TotalConversations   int                          `json:"total_conversations"`
+	TotalMessages        int                          `json:"total_messages"`
+	TotalRatings         int                          `json:"total_ratings"`
+	AverageRating        float64                      `json:"average_rating"`
+	ConversationsLast7d  int                          `json:"conversations_last_7_days"`
+	ConversationsLast30d int                          `json:"conversations_last_30_days"`
+	TopDirectories       []DirectoryConversationCount `json:"top_directories"`
+}
+
+// GetOverviewStats returns headline totals and the busiest working
+// directories. The average rating is the mean of the rated conversations'
+// scores under the configured aggregation. An empty database yields zero
+// values.
+func (db *DB) GetOverviewStats() (*OverviewStats, error) {
+	now := time.Now().UTC()
+	totalsQuery := `
+	SELECT
+		(SELECT COUNT(*) FROM conversations c WHERE ` + db.statsConversationFilter("c") + `),
+		(SELECT COUNT(*) FROM messages m JOIN conversations c ON c.id = m.conversation_id
+			WHERE ` + db.statsConversationFilter("c") + `),
+		COUNT(*),
+		(SELECT COALESCE(AVG(score), 0) FROM (
+			SELECT ` + ratingScoreExpr(db.ratingAggregation(), "c.id") + ` AS score
+			FROM conversations c WHERE ` + db.statsConversationFilter("c") + `)),
+		(SELECT COUNT(*) FROM conversations c WHERE ` + db.statsConversationFilter("c") + ` AND c.created_at >= ?),
+		(SELECT COUNT(*) FROM conversations c WHERE ` + db.statsConversationFilter("c") + ` AND c.created_at >= ?)
+	FROM (
+		SELECT r.rating
+		FROM ratings r
+		LEFT JOIN messages m ON m.id = r.message_id
+		JOIN conversations c ON c.id = COALESCE(r.conversation_id, m.conversation_id)
+		WHERE ` + db.statsConversationFilter("c") + `
+	) rated`
+
+	stats := &OverviewStats{TopDirectories: []DirectoryConversationCount{}}
+	err := db.conn.QueryRow(totalsQuery,
+		now.AddDate(0, 0, -7).Format(sqliteTimestampLayout),
+		now.AddDate(0, 0, -30).Format(sqliteTimestampLayout),
+	).Scan(
+		&stats.TotalConversations, &stats.TotalMessages, &stats.TotalRatings,
+		&stats.AverageRating, &stats.ConversationsLast7d, &stats.ConversationsLast30d,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overview totals: %w", err)
+	}
+
+	directoriesQuery := `
+	SELECT directory, COUNT(*)
+	FROM (
+		SELECT ` + normalizedDirectoryExpr + ` AS directory
+		FROM conversations c
+		WHERE ` + db.statsConversationFilter("c") + `
+	)
+	WHERE directory IS NOT NULL
+	GROUP BY directory
+	ORDER BY COUNT(*) DESC, directory ASC
+	LIMIT ?`
+
+	rows, err := db.conn.Query(directoriesQuery, OverviewTopDirectories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overview directories: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d DirectoryConversationCount
+		if err := rows.Scan(&d.Directory, &d.ConversationCount); err != nil {
+			return nil, fmt.Errorf("failed to scan overview directory: %w", err)
+		}
+		stats.TopDirectories = append(stats.TopDirectories, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get overview directories: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,1064 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/models"
+)
+
+func TestRatingStatsExcludeSoftDeleted(t *testing.T) {
+	tests := []struct {
+		name            string
+		includeDeleted  bool
+		expectedTotal   int
+		expectedAverage float64
+	}{
+		{"soft-deleted excluded by default", false, 1, 5},
+		{"soft-deleted included when configured", true, 2, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDBWithConfig(t, func(c *Config) {
+				c.IncludeDeletedInStats = tt.includeDeleted
+			})
+
+			live, err := db.CreateConversation("live-session", nil, nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to create conversation: %v", err)
+			}
+
+			deleted, err := db.CreateConversation("deleted-session", nil, nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to create conversation: %v", err)
+			}
+
+			if _, err := db.CreateConversationRating(live.ID, 5, nil); err != nil {
+				t.Fatalf("Failed to create rating: %v", err)
+			}
+
+			if _, err := db.CreateConversationRating(deleted.ID, 1, nil); err != nil {
+				t.Fatalf("Failed to create rating: %v", err)
+			}
+
+			if _, err := db.conn.Exec("UPDATE conversations SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?", deleted.ID); err != nil {
+				t.Fatalf("Failed to soft-delete conversation: %v", err)
+			}
+
+			stats, err := db.GetRatingStats()
+			if err != nil {
+				t.Fatalf("Failed to get rating stats: %v", err)
+			}
+
+			if stats["total_ratings"] != tt.expectedTotal {
+				t.Errorf("Expected %d total ratings, got %v", tt.expectedTotal, stats["total_ratings"])
+			}
+
+			if stats["average_rating"] != tt.expectedAverage {
+				t.Errorf("Expected average rating %v, got %v", tt.expectedAverage, stats["average_rating"])
+			}
+
+			distribution := stats["distribution"].(map[int]int)
+			if _, ok := distribution[1]; ok != tt.includeDeleted {
+				t.Errorf("Expected rating 1 in distribution = %v, got %v", tt.includeDeleted, distribution)
+			}
+		})
+	}
+}
+
+func TestGetDirectoryStats(t *testing.T) {
+	db := setupTestDB(t)
+
+	projectA := "/work/project-a"
+	projectATrailing := "/work/project-a/"
+	projectB := "/work/project-b"
+
+	a1, err := db.CreateConversation("session-a1", nil, &projectA, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	a2, err := db.CreateConversation("session-a2", nil, &projectATrailing, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	b1, err := db.CreateConversation("session-b1", nil, &projectB, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	for _, convID := range []int{a1.ID, a1.ID, a2.ID, b1.ID} {
+		if _, err := db.CreateMessage(convID, "prompt", "hello", nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	for _, r := range []struct{ convID, rating int }{{a1.ID, 5}, {a2.ID, 2}, {b1.ID, 4}} {
+		if _, err := db.CreateConversationRating(r.convID, r.rating, nil); err != nil {
+			t.Fatalf("Failed to create rating: %v", err)
+		}
+	}
+
+	stats, err := db.GetDirectoryStats(10, 0)
+	if err != nil {
+		t.Fatalf("Failed to get directory stats: %v", err)
+	}
+
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 directories, got %d", len(stats))
+	}
+
+	a := stats[0]
+	if a.Directory == nil || *a.Directory != projectA {
+		t.Fatalf("Expected busiest directory %s first, got %v", projectA, a.Directory)
+	}
+	if a.ConversationCount != 2 || a.MessageCount != 3 || a.TotalCharacters != 15 {
+		t.Errorf("Unexpected aggregates for %s: %+v", projectA, a)
+	}
+	if a.AverageRating != 3.5 || a.RatingCount != 2 {
+		t.Errorf("Expected average rating 3.5 from 2 ratings, got %v from %d", a.AverageRating, a.RatingCount)
+	}
+
+	b := stats[1]
+	if b.Directory == nil || *b.Directory != projectB {
+		t.Fatalf("Expected %s second, got %v", projectB, b.Directory)
+	}
+	if b.ConversationCount != 1 || b.MessageCount != 1 || b.AverageRating != 4 {
+		t.Errorf("Unexpected aggregates for %s: %+v", projectB, b)
+	}
+
+	count, err := db.GetDirectoryCount()
+	if err != nil {
+		t.Fatalf("Failed to count directories: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 directories, got %d", count)
+	}
+}
+
+func TestGetModelLatencyStats(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("latency-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	fast := "fast-model"
+	slow := "slow-model"
+
+	responses := []struct {
+		model         *string
+		executionTime *int
+	}{
+		{&fast, intPtr(100)},
+		{&fast, intPtr(200)},
+		{&fast, intPtr(300)},
+		{&slow, intPtr(1000)},
+		{&slow, intPtr(3000)},
+		{&slow, nil}, // excluded: no execution time
+		{nil, intPtr(500)},
+	}
+
+	for _, r := range responses {
+		if _, err := db.CreateMessageWithModel(conv.ID, "response", "answer", nil, r.executionTime, r.model); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	// Prompts never count towards latency
+	if _, err := db.CreateMessage(conv.ID, "prompt", "question", nil, intPtr(9999)); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	stats, err := db.GetModelLatencyStats()
+	if err != nil {
+		t.Fatalf("Failed to get model latency stats: %v", err)
+	}
+
+	expected := []ModelLatencyStats{
+		{Model: "fast-model", ResponseCount: 3, AverageMs: 200, P95Ms: 300},
+		{Model: "slow-model", ResponseCount: 2, AverageMs: 2000, P95Ms: 3000},
+		{Model: "unknown", ResponseCount: 1, AverageMs: 500, P95Ms: 500},
+	}
+
+	if len(stats) != len(expected) {
+		t.Fatalf("Expected %d models, got %d: %+v", len(expected), len(stats), stats)
+	}
+
+	for i, want := range expected {
+		if stats[i] != want {
+			t.Errorf("Expected %+v, got %+v", want, stats[i])
+		}
+	}
+}
+
+func TestGetToolDurationStats(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("tool-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	toolCalls := []string{
+		`[{"name":"bash","arguments":{},"duration":100},{"name":"read_file","arguments":{},"duration":10}]`,
+		`[{"name":"bash","arguments":{},"duration":300},{"name":"read_file","arguments":{},"duration":30}]`,
+		`[{"name":"bash","arguments":{}},{"name":"grep","arguments":{},"duration":50.4}]`, // bash call without a duration
+		`not json`,
+	}
+	for _, calls := range toolCalls {
+		calls := calls
+		if _, err := db.CreateMessage(conv.ID, "response", "answer", &calls, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	stats, err := db.GetToolDurationStats()
+	if err != nil {
+		t.Fatalf("Failed to get tool duration stats: %v", err)
+	}
+
+	expected := []ToolDurationStats{
+		{ToolName: "bash", CallCount: 2, TotalMs: 400, AverageMs: 200, MinMs: 100, MaxMs: 300},
+		{ToolName: "grep", CallCount: 1, TotalMs: 50, AverageMs: 50, MinMs: 50, MaxMs: 50},
+		{ToolName: "read_file", CallCount: 2, TotalMs: 40, AverageMs: 20, MinMs: 10, MaxMs: 30},
+	}
+
+	if len(stats) != len(expected) {
+		t.Fatalf("Expected %d tools, got %d: %+v", len(expected), len(stats), stats)
+	}
+
+	for i, want := range expected {
+		if stats[i] != want {
+			t.Errorf("Expected %+v, got %+v", want, stats[i])
+		}
+	}
+}
+
+func TestGetToolCallStats(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("tool-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	toolCalls := []*string{
+		stringPtr(`[{"name":"bash","arguments":{}},{"name":"read_file","arguments":{}}]`),
+		stringPtr(`[{"name":"bash","arguments":{}},{"arguments":{}}]`), // call without a name
+		nil,
+	}
+	for _, calls := range toolCalls {
+		if _, err := db.CreateMessage(conv.ID, "response", "answer", calls, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	stats, err := db.GetToolCallStats()
+	if err != nil {
+		t.Fatalf("Failed to get tool call stats: %v", err)
+	}
+
+	if stats.TotalCalls != 4 {
+		t.Errorf("Expected 4 tool calls, got %d", stats.TotalCalls)
+	}
+
+	expected := map[string]int{"bash": 2, "read_file": 1, unknownTool: 1}
+	if len(stats.ByName) != len(expected) {
+		t.Fatalf("Expected %d tools, got %v", len(expected), stats.ByName)
+	}
+	for name, count := range expected {
+		if stats.ByName[name] != count {
+			t.Errorf("Expected %d %s calls, got %d", count, name, stats.ByName[name])
+		}
+	}
+}
+
+func TestGetMostToolConversations(t *testing.T) {
+	db := setupTestDB(t)
+
+	toolCalls := func(n int) *string {
+		calls := make([]string, n)
+		for i := range calls {
+			calls[i] = `{"name":"bash","arguments":{}}`
+		}
+		s := "[" + strings.Join(calls, ",") + "]"
+		return &s
+	}
+
+	volumes := map[string][]int{
+		"light-session":    {1},
+		"heavy-session":    {3, 4},
+		"medium-session":   {2, 2},
+		"no-tools-session": {0},
+	}
+	ids := make(map[string]int)
+	for sessionID, perMessage := range volumes {
+		conv, err := db.CreateConversation(sessionID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		ids[sessionID] = conv.ID
+		for _, n := range perMessage {
+			var calls *string
+			if n > 0 {
+				calls = toolCalls(n)
+			}
+			if _, err := db.CreateMessage(conv.ID, "response", "answer", calls, nil); err != nil {
+				t.Fatalf("Failed to create message: %v", err)
+			}
+		}
+	}
+
+	results, err := db.GetMostToolConversations(10)
+	if err != nil {
+		t.Fatalf("Failed to get most tool conversations: %v", err)
+	}
+
+	expected := []struct {
+		sessionID string
+		count     int
+	}{
+		{"heavy-session", 7},
+		{"medium-session", 4},
+		{"light-session", 1},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d conversations, got %d: %+v", len(expected), len(results), results)
+	}
+	for i, want := range expected {
+		if results[i].Conversation.ID != ids[want.sessionID] || results[i].ToolCount != want.count {
+			t.Errorf("Position %d: expected %s with %d tool calls, got %s with %d",
+				i, want.sessionID, want.count, results[i].Conversation.SessionID, results[i].ToolCount)
+		}
+	}
+
+	top, err := db.GetMostToolConversations(1)
+	if err != nil {
+		t.Fatalf("Failed to get most tool conversations: %v", err)
+	}
+	if len(top) != 1 || top[0].Conversation.ID != ids["heavy-session"] {
+		t.Errorf("Expected only heavy-session, got %+v", top)
+	}
+
+	// Entries that are not tool call objects are not counted
+	mixed, err := db.CreateConversation("mixed-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateMessage(mixed.ID, "response", "answer", stringPtr(`["bash", 1, null, {"name":"bash"}]`), nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	results, err = db.GetMostToolConversations(10)
+	if err != nil {
+		t.Fatalf("Failed to get most tool conversations: %v", err)
+	}
+	for _, r := range results {
+		if r.Conversation.ID == mixed.ID && r.ToolCount != 1 {
+			t.Errorf("Expected mixed-session to count 1 tool call, got %d", r.ToolCount)
+		}
+	}
+}
+
+func TestGetActivityHeatmap(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("heatmap-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	timestamps := []time.Time{
+		time.Date(2024, 1, 1, 9, 15, 0, 0, time.UTC),  // Monday 09:00
+		time.Date(2024, 1, 8, 9, 45, 0, 0, time.UTC),  // Monday 09:00
+		time.Date(2024, 1, 7, 23, 59, 0, 0, time.UTC), // Sunday 23:00
+		time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC),   // Saturday 00:00
+	}
+	for _, ts := range timestamps {
+		ts := ts
+		if _, err := db.CreateMessageFromInput(conv.ID, MessageInput{MessageType: "prompt", Content: "hi", Timestamp: &ts}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	heatmap, err := db.GetActivityHeatmap()
+	if err != nil {
+		t.Fatalf("Failed to get activity heatmap: %v", err)
+	}
+
+	var expected [7][24]int
+	expected[1][9] = 2
+	expected[0][23] = 1
+	expected[6][0] = 1
+
+	if heatmap.Counts != expected {
+		t.Errorf("Expected heatmap %v, got %v", expected, heatmap.Counts)
+	}
+}
+
+func TestGetConversationsByWeekday(t *testing.T) {
+	db := setupTestDB(t)
+
+	createdAt := []time.Time{
+		time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),   // Monday
+		time.Date(2024, 1, 8, 17, 0, 0, 0, time.UTC),  // Monday
+		time.Date(2024, 1, 7, 23, 59, 0, 0, time.UTC), // Sunday
+		time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),   // Friday
+	}
+	for i, ts := range createdAt {
+		conv, err := db.CreateConversation(fmt.Sprintf("weekday-session-%d", i), nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		_, err = db.conn.Exec("UPDATE conversations SET created_at = ? WHERE id = ?", ts.Format(sqliteTimestampLayout), conv.ID)
+		if err != nil {
+			t.Fatalf("Failed to backdate conversation: %v", err)
+		}
+	}
+
+	weekdays, err := db.GetConversationsByWeekday()
+	if err != nil {
+		t.Fatalf("Failed to get conversations by weekday: %v", err)
+	}
+
+	expected := []WeekdayCount{
+		{"Sunday", 1}, {"Monday", 2}, {"Tuesday", 0}, {"Wednesday", 0},
+		{"Thursday", 0}, {"Friday", 1}, {"Saturday", 0},
+	}
+	if len(weekdays) != len(expected) {
+		t.Fatalf("Expected %d weekdays, got %v", len(expected), weekdays)
+	}
+	for i, want := range expected {
+		if weekdays[i] != want {
+			t.Errorf("Weekday %d: expected %+v, got %+v", i, want, weekdays[i])
+		}
+	}
+}
+
+func TestGetActivityRange(t *testing.T) {
+	db := setupTestDB(t)
+
+	empty, err := db.GetActivityRange()
+	if err != nil {
+		t.Fatalf("Failed to get activity range: %v", err)
+	}
+	if empty.Earliest != nil || empty.Latest != nil || empty.SpanSeconds != nil {
+		t.Errorf("Expected empty range on empty database, got %+v", empty)
+	}
+
+	conv, err := db.CreateConversation("range-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	earliest := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	middle := time.Date(2024, 3, 2, 12, 30, 0, 0, time.UTC)
+	latest := time.Date(2024, 3, 4, 18, 15, 0, 0, time.UTC)
+
+	_, err = db.CreateMessagesBatch(conv.ID, []MessageInput{
+		{MessageType: "prompt", Content: "middle", Timestamp: &middle},
+		{MessageType: "prompt", Content: "latest", Timestamp: &latest},
+		{MessageType: "prompt", Content: "earliest", Timestamp: &earliest},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create messages: %v", err)
+	}
+
+	activity, err := db.GetActivityRange()
+	if err != nil {
+		t.Fatalf("Failed to get activity range: %v", err)
+	}
+
+	if activity.Earliest == nil || !activity.Earliest.Equal(earliest) {
+		t.Errorf("Expected earliest %v, got %v", earliest, activity.Earliest)
+	}
+	if activity.Latest == nil || !activity.Latest.Equal(latest) {
+		t.Errorf("Expected latest %v, got %v", latest, activity.Latest)
+	}
+
+	expectedSpan := int64(latest.Sub(earliest).Seconds())
+	if activity.SpanSeconds == nil || *activity.SpanSeconds != expectedSpan {
+		t.Errorf("Expected span %d seconds, got %v", expectedSpan, activity.SpanSeconds)
+	}
+}
+
+func TestGetRatingCoverage(t *testing.T) {
+	db := setupTestDB(t)
+
+	empty, err := db.GetRatingCoverage()
+	if err != nil {
+		t.Fatalf("Failed to get rating coverage: %v", err)
+	}
+	if *empty != (RatingCoverage{}) {
+		t.Errorf("Expected zero coverage on an empty database, got %+v", empty)
+	}
+
+	var ids []int
+	for i := 0; i < 4; i++ {
+		conv, err := db.CreateConversation(fmt.Sprintf("coverage-session-%d", i), nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		ids = append(ids, conv.ID)
+	}
+
+	// One conversation is rated twice, so it must only count once
+	for _, id := range []int{ids[0], ids[0], ids[1]} {
+		if _, err := db.CreateConversationRating(id, 4, nil); err != nil {
+			t.Fatalf("Failed to create rating: %v", err)
+		}
+	}
+
+	// Soft-deleted conversations are excluded, rated or not
+	deleted, err := db.CreateConversation("coverage-deleted", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if err := db.DeleteConversation(deleted.ID); err != nil {
+		t.Fatalf("Failed to delete conversation: %v", err)
+	}
+
+	coverage, err := db.GetRatingCoverage()
+	if err != nil {
+		t.Fatalf("Failed to get rating coverage: %v", err)
+	}
+
+	expected := RatingCoverage{RatedCount: 2, UnratedCount: 2, CoveragePercent: 50}
+	if *coverage != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *coverage)
+	}
+}
+
+func TestRatingCoverageCountsMessageRatings(t *testing.T) {
+	db := setupTestDB(t)
+
+	messageRated, err := db.CreateConversation("message-rated-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	msg, err := db.CreateMessage(messageRated.ID, "response", "answer", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := db.CreateMessageRating(msg.ID, 3, nil); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+	if _, err := db.CreateConversation("unrated-session", nil, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	unrated, err := db.GetUnratedConversationCount()
+	if err != nil {
+		t.Fatalf("Failed to count unrated conversations: %v", err)
+	}
+	if unrated != 1 {
+		t.Errorf("Expected 1 unrated conversation, got %d", unrated)
+	}
+
+	coverage, err := db.GetRatingCoverage()
+	if err != nil {
+		t.Fatalf("Failed to get rating coverage: %v", err)
+	}
+	expected := RatingCoverage{RatedCount: 1, UnratedCount: 1, CoveragePercent: 50}
+	if *coverage != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *coverage)
+	}
+}
+
+func TestLowestRatedConversationsAggregation(t *testing.T) {
+	tests := []struct {
+		method        models.RatingAggregation
+		expectedFirst string
+		expectedScore float64
+	}{
+		{models.RatingAggregationMean, "mixed", 11.0 / 3},
+		{models.RatingAggregationMedian, "steady", 4},
+		{models.RatingAggregationLatest, "steady", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method), func(t *testing.T) {
+			db := setupTestDBWithConfig(t, func(c *Config) {
+				c.RatingAggregation = tt.method
+			})
+
+			ratingsByTitle := map[string][]int{
+				"mixed":  {1, 5, 5},
+				"steady": {4, 4},
+			}
+			for _, title := range []string{"mixed", "steady"} {
+				title := title
+				conv, err := db.CreateConversation("aggregation-session", &title, nil, nil)
+				if err != nil {
+					t.Fatalf("Failed to create conversation: %v", err)
+				}
+				for _, rating := range ratingsByTitle[title] {
+					if _, err := db.CreateConversationRating(conv.ID, rating, nil); err != nil {
+						t.Fatalf("Failed to create rating: %v", err)
+					}
+				}
+			}
+
+			summaries, err := db.GetLowestRatedConversations(1)
+			if err != nil {
+				t.Fatalf("Failed to get lowest rated conversations: %v", err)
+			}
+
+			if len(summaries) != 1 {
+				t.Fatalf("Expected 1 summary, got %d", len(summaries))
+			}
+
+			first := summaries[0]
+			if first.Title == nil || *first.Title != tt.expectedFirst {
+				t.Errorf("Expected %s to rank lowest, got %v", tt.expectedFirst, first.Title)
+			}
+			if math.Abs(first.AverageRating-tt.expectedScore) > 0.005 {
+				t.Errorf("Expected score %.2f, got %.2f", tt.expectedScore, first.AverageRating)
+			}
+			if first.RatingCount != len(ratingsByTitle[tt.expectedFirst]) {
+				t.Errorf("Expected %d ratings, got %d", len(ratingsByTitle[tt.expectedFirst]), first.RatingCount)
+			}
+		})
+	}
+}
+
+func TestLowestRatedConversationsCountsMessageRatings(t *testing.T) {
+	db := setupTestDB(t)
+
+	rated, err := db.CreateConversation("rated-session", stringPtr("conversation rated"), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateConversationRating(rated.ID, 4, nil); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+
+	messageRated, err := db.CreateConversation("rated-session", stringPtr("message rated"), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	msg, err := db.CreateMessage(messageRated.ID, "response", "answer", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := db.CreateMessageRating(msg.ID, 2, stringPtr("too slow")); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+
+	summaries, err := db.GetLowestRatedConversations(10)
+	if err != nil {
+		t.Fatalf("Failed to get lowest rated conversations: %v", err)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 summaries, got %+v", summaries)
+	}
+
+	first := summaries[0]
+	if first.ConversationID != messageRated.ID || first.AverageRating != 2 || first.RatingCount != 1 {
+		t.Errorf("Expected the message-rated conversation to rank lowest with score 2, got %+v", first)
+	}
+	if len(first.Comments) != 1 || first.Comments[0] != "too slow" {
+		t.Errorf("Expected the message rating's comment, got %v", first.Comments)
+	}
+}
+
+func TestRatingAggregationAcrossStats(t *testing.T) {
+	tests := []struct {
+		method      models.RatingAggregation
+		mixedScore  float64
+		steadyScore float64
+	}{
+		{models.RatingAggregationMean, 11.0 / 3, 4},
+		{models.RatingAggregationMedian, 5, 4},
+		{models.RatingAggregationLatest, 5, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method), func(t *testing.T) {
+			db := setupTestDBWithConfig(t, func(c *Config) {
+				c.RatingAggregation = tt.method
+			})
+
+			ratingsByTitle := map[string][]int{
+				"mixed":  {1, 5, 5},
+				"steady": {4, 4},
+			}
+			for _, title := range []string{"mixed", "steady"} {
+				title := title
+				conv, err := db.CreateConversation("aggregation-session", &title, nil, nil)
+				if err != nil {
+					t.Fatalf("Failed to create conversation: %v", err)
+				}
+				for _, rating := range ratingsByTitle[title] {
+					if _, err := db.CreateConversationRating(conv.ID, rating, nil); err != nil {
+						t.Fatalf("Failed to create rating: %v", err)
+					}
+				}
+			}
+
+			expected := map[string]float64{"mixed": tt.mixedScore, "steady": tt.steadyScore}
+			overall := (tt.mixedScore + tt.steadyScore) / 2
+			closeTo := func(got, want float64) bool { return math.Abs(got-want) < 0.005 }
+
+			// Listings score each conversation and sort on that score
+			conversations, err := db.ListConversations(&ConversationFilter{Sort: SortOption{Field: SortByRating}}, 10, 0)
+			if err != nil {
+				t.Fatalf("Failed to list conversations: %v", err)
+			}
+			if len(conversations) != 2 {
+				t.Fatalf("Expected 2 conversations, got %d", len(conversations))
+			}
+			for _, conv := range conversations {
+				if conv.AvgRating == nil || !closeTo(*conv.AvgRating, expected[*conv.Title]) {
+					t.Errorf("Expected %s to score %.2f, got %v", *conv.Title, expected[*conv.Title], conv.AvgRating)
+				}
+			}
+			if *conversations[0].AvgRating < *conversations[1].AvgRating {
+				t.Errorf("Expected the higher score first, got %s then %s", *conversations[0].Title, *conversations[1].Title)
+			}
+
+			ratingStats, err := db.GetRatingStats()
+			if err != nil {
+				t.Fatalf("Failed to get rating stats: %v", err)
+			}
+			if got := ratingStats["average_rating"].(float64); !closeTo(got, overall) {
+				t.Errorf("Expected rating stats average %.2f, got %.2f", overall, got)
+			}
+
+			overview, err := db.GetOverviewStats()
+			if err != nil {
+				t.Fatalf("Failed to get overview stats: %v", err)
+			}
+			if !closeTo(overview.AverageRating, overall) {
+				t.Errorf("Expected overview average %.2f, got %.2f", overall, overview.AverageRating)
+			}
+
+			sessionStats, err := db.GetSessionStats([]string{"aggregation-session"})
+			if err != nil {
+				t.Fatalf("Failed to get session stats: %v", err)
+			}
+			if !closeTo(sessionStats[0].AverageRating, overall) {
+				t.Errorf("Expected session average %.2f, got %.2f", overall, sessionStats[0].AverageRating)
+			}
+
+			buckets, err := db.GetRatingByLength([]int{5})
+			if err != nil {
+				t.Fatalf("Failed to get rating by length: %v", err)
+			}
+			if buckets[0].AverageRating == nil || !closeTo(*buckets[0].AverageRating, overall) {
+				t.Errorf("Expected bucket average %.2f, got %v", overall, buckets[0].AverageRating)
+			}
+		})
+	}
+}
+
+func TestGetMessageSizeDistribution(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("sizes-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	messages := []struct {
+		messageType string
+		size        int
+	}{
+		{"prompt", 10},
+		{"prompt", 99},
+		{"prompt", 100}, // boundaries belong to the next bucket
+		{"response", 250},
+		{"response", 800},
+		{"response", 5000},
+	}
+	for _, m := range messages {
+		if _, err := db.CreateMessage(conv.ID, m.messageType, strings.Repeat("x", m.size), nil, nil); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	buckets, err := db.GetMessageSizeDistribution([]int{100, 500})
+	if err != nil {
+		t.Fatalf("Failed to get message size distribution: %v", err)
+	}
+
+	expected := []struct {
+		min, prompts, responses int
+		max                     *int
+	}{
+		{0, 2, 0, intPtr(100)},
+		{100, 1, 1, intPtr(500)},
+		{500, 0, 2, nil},
+	}
+
+	if len(buckets) != len(expected) {
+		t.Fatalf("Expected %d buckets, got %d", len(expected), len(buckets))
+	}
+
+	for i, want := range expected {
+		got := buckets[i]
+		if got.Min != want.min || got.PromptCount != want.prompts || got.ResponseCount != want.responses {
+			t.Errorf("Bucket %d: expected %+v, got %+v", i, want, got)
+		}
+		if (got.Max == nil) != (want.max == nil) || (got.Max != nil && *got.Max != *want.max) {
+			t.Errorf("Bucket %d: expected max %v, got %v", i, want.max, got.Max)
+		}
+	}
+
+	for _, invalid := range [][]int{nil, {0}, {500, 100}, {100, 100}} {
+		if _, err := db.GetMessageSizeDistribution(invalid); err == nil {
+			t.Errorf("Expected error for boundaries %v", invalid)
+		}
+	}
+}
+
+func TestGetRatingByLength(t *testing.T) {
+	db := setupTestDB(t)
+
+	conversations := []struct {
+		prompts int
+		ratings []int
+	}{
+		{2, []int{2}},
+		{3, []int{3}},
+		{30, []int{5, 4}},
+		{40, nil}, // unrated conversations are left out
+	}
+	for i, c := range conversations {
+		conv, err := db.CreateConversation(fmt.Sprintf("length-session-%d", i), nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		for p := 0; p < c.prompts; p++ {
+			if _, err := db.CreateMessage(conv.ID, "prompt", "prompt", nil, nil); err != nil {
+				t.Fatalf("Failed to create message: %v", err)
+			}
+		}
+		for _, rating := range c.ratings {
+			if _, err := db.CreateConversationRating(conv.ID, rating, nil); err != nil {
+				t.Fatalf("Failed to create rating: %v", err)
+			}
+		}
+	}
+
+	buckets, err := db.GetRatingByLength([]int{5, 20})
+	if err != nil {
+		t.Fatalf("Failed to get rating by length: %v", err)
+	}
+
+	expected := []struct {
+		min, conversations, ratings int
+		max                         *int
+		average                     *float64
+	}{
+		{0, 2, 2, intPtr(5), float64Ptr(2.5)},
+		{5, 0, 0, intPtr(20), nil},
+		{20, 1, 2, nil, float64Ptr(4.5)},
+	}
+
+	if len(buckets) != len(expected) {
+		t.Fatalf("Expected %d buckets, got %d", len(expected), len(buckets))
+	}
+
+	for i, want := range expected {
+		got := buckets[i]
+		if got.Min != want.min || got.ConversationCount != want.conversations || got.RatingCount != want.ratings {
+			t.Errorf("Bucket %d: expected %+v, got %+v", i, want, got)
+		}
+		if (got.Max == nil) != (want.max == nil) || (got.Max != nil && *got.Max != *want.max) {
+			t.Errorf("Bucket %d: expected max %v, got %v", i, want.max, got.Max)
+		}
+		if (got.AverageRating == nil) != (want.average == nil) || (got.AverageRating != nil && *got.AverageRating != *want.average) {
+			t.Errorf("Bucket %d: expected average %v, got %v", i, want.average, got.AverageRating)
+		}
+	}
+
+	for _, invalid := range [][]int{nil, {0}, {20, 5}} {
+		if _, err := db.GetRatingByLength(invalid); err == nil {
+			t.Errorf("Expected error for boundaries %v", invalid)
+		}
+	}
+}
+
+func TestGetConversationCreationRate(t *testing.T) {
+	db := setupTestDB(t)
+
+	empty, err := db.GetConversationCreationRate(CreationRateHour, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to get creation rate: %v", err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("Expected no buckets on empty database, got %+v", empty)
+	}
+
+	createdAt := []time.Time{
+		time.Date(2024, 3, 1, 9, 5, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 9, 55, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 3, 8, 0, 0, 0, time.UTC),
+	}
+	for i, ts := range createdAt {
+		conv, err := db.CreateConversation(fmt.Sprintf("rate-session-%d", i), nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		_, err = db.conn.Exec("UPDATE conversations SET created_at = ? WHERE id = ?", ts.Format(sqliteTimestampLayout), conv.ID)
+		if err != nil {
+			t.Fatalf("Failed to backdate conversation: %v", err)
+		}
+	}
+
+	hour := func(day, h int) time.Time { return time.Date(2024, 3, day, h, 0, 0, 0, time.UTC) }
+	from := hour(1, 8)
+	to := hour(1, 13)
+
+	tests := []struct {
+		name     string
+		interval CreationRateInterval
+		from, to *time.Time
+		expected []CreationRateBucket
+	}{
+		{
+			name:     "hourly within range",
+			interval: CreationRateHour,
+			from:     &from,
+			to:       &to,
+			expected: []CreationRateBucket{
+				{hour(1, 8), 0}, {hour(1, 9), 2}, {hour(1, 10), 0},
+				{hour(1, 11), 0}, {hour(1, 12), 1}, {hour(1, 13), 0},
+			},
+		},
+		{
+			name:     "daily across all conversations",
+			interval: CreationRateDay,
+			expected: []CreationRateBucket{
+				{hour(1, 0), 3}, {hour(2, 0), 0}, {hour(3, 0), 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buckets, err := db.GetConversationCreationRate(tt.interval, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Failed to get creation rate: %v", err)
+			}
+
+			if len(buckets) != len(tt.expected) {
+				t.Fatalf("Expected %d buckets, got %d: %+v", len(tt.expected), len(buckets), buckets)
+			}
+			for i, want := range tt.expected {
+				if !buckets[i].Start.Equal(want.Start) || buckets[i].Count != want.Count {
+					t.Errorf("Bucket %d: expected %+v, got %+v", i, want, buckets[i])
+				}
+			}
+		})
+	}
+
+	start := hour(1, 0)
+	end := start.AddDate(1, 0, 0)
+	if _, err := db.GetConversationCreationRate(CreationRateHour, &start, &end); err != ErrCreationRateRangeTooLarge {
+		t.Errorf("Expected ErrCreationRateRangeTooLarge, got %v", err)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+func TestGetOverviewStats(t *testing.T) {
+	db := setupTestDB(t)
+
+	t.Run("empty database", func(t *testing.T) {
+		stats, err := db.GetOverviewStats()
+		if err != nil {
+			t.Fatalf("Failed to get overview stats: %v", err)
+		}
+		if stats.TotalConversations != 0 || stats.TotalMessages != 0 || stats.TotalRatings != 0 ||
+			stats.AverageRating != 0 || stats.ConversationsLast7d != 0 || stats.ConversationsLast30d != 0 {
+			t.Errorf("Expected zero values, got %+v", stats)
+		}
+		if stats.TopDirectories == nil || len(stats.TopDirectories) != 0 {
+			t.Errorf("Expected an empty directory list, got %v", stats.TopDirectories)
+		}
+	})
+
+	conversations := []struct {
+		dir     *string
+		ageDays int
+	}{
+		{stringPtr("/work/api"), 0},
+		{stringPtr("/work/api/"), 10},
+		{stringPtr("/work/web"), 40},
+		{nil, 0},
+	}
+	var ids []int
+	for i, c := range conversations {
+		conv, err := db.CreateConversation(fmt.Sprintf("overview-session-%d", i), nil, c.dir, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		createdAt := time.Now().UTC().AddDate(0, 0, -c.ageDays)
+		_, err = db.conn.Exec("UPDATE conversations SET created_at = ? WHERE id = ?", createdAt.Format(sqliteTimestampLayout), conv.ID)
+		if err != nil {
+			t.Fatalf("Failed to backdate conversation: %v", err)
+		}
+		ids = append(ids, conv.ID)
+	}
+
+	message, err := db.CreateMessage(ids[0], "response", "answer", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := db.CreateMessage(ids[1], "prompt", "question", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := db.CreateConversationRating(ids[0], 5, nil); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+	if _, err := db.CreateMessageRating(message.ID, 2, nil); err != nil {
+		t.Fatalf("Failed to create rating: %v", err)
+	}
+
+	stats, err := db.GetOverviewStats()
+	if err != nil {
+		t.Fatalf("Failed to get overview stats: %v", err)
+	}
+
+	if stats.TotalConversations != 4 {
+		t.Errorf("Expected 4 conversations, got %d", stats.TotalConversations)
+	}
+	if stats.TotalMessages != 2 {
+		t.Errorf("Expected 2 messages, got %d", stats.TotalMessages)
+	}
+	if stats.TotalRatings != 2 || stats.AverageRating != 3.5 {
+		t.Errorf("Expected 2 ratings averaging 3.5, got %d averaging %v", stats.TotalRatings, stats.AverageRating)
+	}
+	if stats.ConversationsLast7d != 2 {
+		t.Errorf("Expected 2 conversations in the last 7 days, got %d", stats.ConversationsLast7d)
+	}
+	if stats.ConversationsLast30d != 3 {
+		t.Errorf("Expected 3 conversations in the last 30 days, got %d", stats.ConversationsLast30d)
+	}
+
+	expected := []DirectoryConversationCount{
+		{Directory: "/work/api", ConversationCount: 2},
+		{Directory: "/work/web", ConversationCount: 1},
+	}
+	if len(stats.TopDirectories) != len(expected) {
+		t.Fatalf("Expected %d directories, got %v", len(expected), stats.TopDirectories)
+	}
+	for i, want := range expected {
+		if stats.TopDirectories[i] != want {
+			t.Errorf("Directory %d: expected %+v, got %+v", i, want, stats.TopDirectories[i])
+		}
+	}
+}
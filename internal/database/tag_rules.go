@@ -0,0 +1,338 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxTagRulePatternLength caps the length of a tag rule pattern
+const maxTagRulePatternLength = 500
+
+// TagRule automatically attaches a tag to a conversation when one of its
+// messages matches the pattern. Keyword patterns match case-insensitively
+// anywhere in the content; regex patterns use Go's RE2 syntax.
+type TagRule struct {
+	ID        int       `json:"id"`
+	Pattern   string    `json:"pattern"`
+	IsRegex   bool      `json:"is_regex"`
+	TagID     int       `json:"tag_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ValidateTagRulePattern checks that a pattern is non-empty, not too long and,
+// for regex rules, compiles
+func ValidateTagRulePattern(pattern string, isRegex bool) error {
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("pattern is required")
+	}
+
+	if len(pattern) > maxTagRulePatternLength {
+		return fmt.Errorf("pattern cannot exceed %d characters", maxTagRulePatternLength)
+	}
+
+	if isRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid regex pattern: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// matcher returns a function reporting whether content matches the rule
+func (r TagRule) matcher() (func(string) bool, error) {
+	if r.IsRegex {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern for tag rule %d: %w", r.ID, err)
+		}
+		return re.MatchString, nil
+	}
+
+	keyword := strings.ToLower(r.Pattern)
+	return func(content string) bool {
+		return strings.Contains(strings.ToLower(content), keyword)
+	}, nil
+}
+
+// CreateTagRule stores a new rule for an existing tag
+func (db *DB) CreateTagRule(pattern string, isRegex bool, tagID int) (*TagRule, error) {
+	if err := ValidateTagRulePattern(pattern, isRegex); err != nil {
+		return nil, err
+	}
+	if _, err := db.GetTag(tagID); err != nil {
+		return nil, err
+	}
+
+	query := `
+	INSERT INTO tag_rules (pattern, is_regex, tag_id)
+	VALUES (?, ?, ?)
+	RETURNING id, pattern, is_regex, tag_id, created_at`
+
+	var rule TagRule
+	err := db.conn.QueryRow(query, pattern, isRegex, tagID).Scan(
+		&rule.ID, &rule.Pattern, &rule.IsRegex, &rule.TagID, &rule.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert tag rule: %w", err)
+	}
+
+	return &rule, nil
+}
+
+// GetTagRule retrieves a tag rule by ID
+func (db *DB) GetTagRule(id int) (*TagRule, error) {
+	query := `
+	SELECT id, pattern, is_regex, tag_id, created_at
+	FROM tag_rules WHERE id = ?`
+
+	var rule TagRule
+	err := db.conn.QueryRow(query, id).Scan(
+		&rule.ID, &rule.Pattern, &rule.IsRegex, &rule.TagID, &rule.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTagRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to get tag rule: %w", err)
+	}
+
+	return &rule, nil
+}
+
+// ListTagRules retrieves all tag rules in creation order
+func (db *DB) ListTagRules() ([]TagRule, error) {
+	return queryTagRules(db.conn, `
+	SELECT r.id, r.pattern, r.is_regex, r.tag_id, r.created_at
+	FROM tag_rules r
+	ORDER BY r.id ASC`)
+}
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx
+type rowQuerier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryTagRules runs a tag rule query on the connection or transaction
+func queryTagRules(q rowQuerier, query string) ([]TagRule, error) {
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tag rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []TagRule{}
+	for rows.Next() {
+		var rule TagRule
+		if err := rows.Scan(&rule.ID, &rule.Pattern, &rule.IsRegex, &rule.TagID, &rule.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tag rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tag rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// UpdateTagRule replaces a rule's pattern and tag
+func (db *DB) UpdateTagRule(id int, pattern string, isRegex bool, tagID int) error {
+	if err := ValidateTagRulePattern(pattern, isRegex); err != nil {
+		return err
+	}
+	if _, err := db.GetTag(tagID); err != nil {
+		return err
+	}
+
+	query := "UPDATE tag_rules SET pattern = ?, is_regex = ?, tag_id = ? WHERE id = ?"
+	result, err := db.conn.Exec(query, pattern, isRegex, tagID, id)
+	if err != nil {
+		return fmt.Errorf("failed to update tag rule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrTagRuleNotFound
+	}
+
+	return nil
+}
+
+// DeleteTagRule deletes a tag rule
+func (db *DB) DeleteTagRule(id int) error {
+	result, err := db.conn.Exec("DELETE FROM tag_rules WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag rule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrTagRuleNotFound
+	}
+
+	return nil
+}
+
+// ApplyTagRules attaches to the conversation every tag whose rule matches the
+// content, returning the IDs of newly attached tags
+func (db *DB) ApplyTagRules(conversationID int, content string) ([]int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	applied, err := applyTagRules(tx, conversationID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return applied, nil
+}
+
+// applyTagRules matches the content against every rule whose tag still exists
+// and attaches the matching tags within the transaction
+func applyTagRules(tx *sql.Tx, conversationID int, content string) ([]int, error) {
+	rules, err := queryTagRules(tx, `
+	SELECT r.id, r.pattern, r.is_regex, r.tag_id, r.created_at
+	FROM tag_rules r
+	JOIN tags t ON t.id = r.tag_id
+	ORDER BY r.id ASC`)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []int
+	for _, rule := range rules {
+		matches, err := rule.matcher()
+		if err != nil {
+			return nil, err
+		}
+		if !matches(content) {
+			continue
+		}
+
+		result, err := tx.Exec(
+			"INSERT OR IGNORE INTO conversation_tags (conversation_id, tag_id) VALUES (?, ?)",
+			conversationID, rule.TagID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply tag rule %d: %w", rule.ID, err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if rowsAffected > 0 {
+			applied = append(applied, rule.TagID)
+		}
+	}
+
+	return applied, nil
+}
+
+const (
+	// tagRulePreviewSampleSize caps how many matching conversations a preview returns
+	tagRulePreviewSampleSize = 10
+	// tagRulePreviewTimeout bounds how long a preview may scan stored messages
+	tagRulePreviewTimeout = 5 * time.Second
+)
+
+// TagRulePreview describes which existing conversations a prospective rule
+// would match. Complete is false when the scan hit its time limit, in which
+// case MatchCount is a lower bound.
+type TagRulePreview struct {
+	MatchCount int            `json:"match_count"`
+	Sample     []Conversation `json:"sample"`
+	Complete   bool           `json:"complete"`
+}
+
+// PreviewTagRule counts the live conversations with at least one message
+// matching the pattern and returns a sample of them, without storing a rule.
+// Go's RE2 engine guarantees linear-time matching, and the scan as a whole is
+// bounded by a timeout so broad patterns over large histories stay cheap.
+func (db *DB) PreviewTagRule(pattern string, isRegex bool) (*TagRulePreview, error) {
+	if err := ValidateTagRulePattern(pattern, isRegex); err != nil {
+		return nil, err
+	}
+
+	matches, err := TagRule{Pattern: pattern, IsRegex: isRegex}.matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tagRulePreviewTimeout)
+	defer cancel()
+
+	query := `
+	SELECT m.conversation_id, m.content
+	FROM messages m
+	JOIN conversations c ON c.id = m.conversation_id
+	WHERE c.deleted_at IS NULL
+	ORDER BY m.conversation_id DESC`
+
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan messages: %w", err)
+	}
+
+	preview := &TagRulePreview{Sample: []Conversation{}, Complete: true}
+	var sampleIDs []int
+	matched := make(map[int]bool)
+	for rows.Next() {
+		var convID int
+		var content string
+		if err := rows.Scan(&convID, &content); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+
+		if matched[convID] || !matches(content) {
+			continue
+		}
+		matched[convID] = true
+		if len(sampleIDs) < tagRulePreviewSampleSize {
+			sampleIDs = append(sampleIDs, convID)
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+
+	if ctx.Err() != nil {
+		preview.Complete = false
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to scan messages: %w", err)
+	}
+	preview.MatchCount = len(matched)
+
+	// Rows are closed before loading the sample, as the pool may hold a single connection
+	for _, id := range sampleIDs {
+		conv, err := db.GetConversation(id)
+		if err != nil {
+			return nil, err
+		}
+		preview.Sample = append(preview.Sample, *conv)
+	}
+
+	return preview, nil
+}
@@ -0,0 +1,222 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTagRuleCRUD(t *testing.T) {
+	db := setupTestDB(t)
+
+	tag, err := db.CreateTag("database", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	rule, err := db.CreateTagRule("sqlite", false, tag.ID)
+	if err != nil {
+		t.Fatalf("Failed to create tag rule: %v", err)
+	}
+
+	if err := db.UpdateTagRule(rule.ID, `(?i)\bsql(ite)?\b`, true, tag.ID); err != nil {
+		t.Fatalf("Failed to update tag rule: %v", err)
+	}
+
+	updated, err := db.GetTagRule(rule.ID)
+	if err != nil {
+		t.Fatalf("Failed to get tag rule: %v", err)
+	}
+	if !updated.IsRegex || updated.Pattern != `(?i)\bsql(ite)?\b` {
+		t.Errorf("Expected updated regex rule, got %+v", updated)
+	}
+
+	if _, err := db.CreateTagRule("([", true, tag.ID); err == nil {
+		t.Error("Expected error for a regex that does not compile")
+	}
+
+	if _, err := db.CreateTagRule("keyword", false, 9999); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound, got %v", err)
+	}
+
+	if err := db.DeleteTagRule(rule.ID); err != nil {
+		t.Fatalf("Failed to delete tag rule: %v", err)
+	}
+
+	if _, err := db.GetTagRule(rule.ID); !errors.Is(err, ErrTagRuleNotFound) {
+		t.Errorf("Expected ErrTagRuleNotFound, got %v", err)
+	}
+
+	rules, err := db.ListTagRules()
+	if err != nil {
+		t.Fatalf("Failed to list tag rules: %v", err)
+	}
+	if len(rules) != 0 {
+		t.Errorf("Expected no tag rules, got %d", len(rules))
+	}
+}
+
+func TestDeleteTagRemovesTagRules(t *testing.T) {
+	db := setupTestDB(t)
+
+	tag, err := db.CreateTag("removed", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	kept, err := db.CreateTag("kept", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	if _, err := db.CreateTagRule("first", false, tag.ID); err != nil {
+		t.Fatalf("Failed to create tag rule: %v", err)
+	}
+	if _, err := db.CreateTagRule("second", false, tag.ID); err != nil {
+		t.Fatalf("Failed to create tag rule: %v", err)
+	}
+
+	if err := db.DeleteTag(tag.ID); err != nil {
+		t.Fatalf("Failed to delete tag: %v", err)
+	}
+
+	rules, err := db.ListTagRules()
+	if err != nil {
+		t.Fatalf("Failed to list tag rules: %v", err)
+	}
+	if len(rules) != 0 {
+		t.Errorf("Expected no tag rules after deleting their tag, got %+v", rules)
+	}
+
+	// Rules of other tags are left alone
+	rule, err := db.CreateTagRule("third", false, kept.ID)
+	if err != nil {
+		t.Fatalf("Failed to create tag rule: %v", err)
+	}
+	if err := db.DeleteTag(tag.ID); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound deleting the tag again, got %v", err)
+	}
+	if _, err := db.GetTagRule(rule.ID); err != nil {
+		t.Errorf("Expected the other tag's rule to remain, got %v", err)
+	}
+}
+
+func TestTagRulesAppliedOnMessageCreate(t *testing.T) {
+	db := setupTestDB(t)
+
+	databaseTag, err := db.CreateTag("database", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	testingTag, err := db.CreateTag("testing", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	if _, err := db.CreateTagRule("SQLite", false, databaseTag.ID); err != nil {
+		t.Fatalf("Failed to create tag rule: %v", err)
+	}
+	if _, err := db.CreateTagRule(`_test\.go\b`, true, testingTag.ID); err != nil {
+		t.Fatalf("Failed to create tag rule: %v", err)
+	}
+
+	matching, err := db.CreateConversation("matching-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	other, err := db.CreateConversation("other-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	if _, err := db.CreateMessage(matching.ID, "prompt", "Why is my sqlite query slow?", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if _, err := db.CreateMessage(other.ID, "prompt", "Rename this variable", nil, nil); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	tags, err := db.GetConversationTags(matching.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation tags: %v", err)
+	}
+	if len(tags) != 1 || tags[0].ID != databaseTag.ID {
+		t.Errorf("Expected only the database tag to be auto-applied, got %+v", tags)
+	}
+
+	tags, err = db.GetConversationTags(other.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation tags: %v", err)
+	}
+	if len(tags) != 0 {
+		t.Errorf("Expected no tags on the non-matching conversation, got %+v", tags)
+	}
+
+	applied, err := db.ApplyTagRules(matching.ID, "Update db_test.go and the SQLite schema")
+	if err != nil {
+		t.Fatalf("Failed to apply tag rules: %v", err)
+	}
+	if len(applied) != 1 || applied[0] != testingTag.ID {
+		t.Errorf("Expected only the testing tag to be newly applied, got %v", applied)
+	}
+}
+
+func TestPreviewTagRule(t *testing.T) {
+	db := setupTestDB(t)
+
+	contents := map[string][]string{
+		"preview-a": {"Fix the failing migration", "Done, the migration runs"},
+		"preview-b": {"Add a MIGRATION for ratings"},
+		"preview-c": {"Rename a variable"},
+	}
+	for sessionID, messages := range contents {
+		conv, err := db.CreateConversation(sessionID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		for _, content := range messages {
+			if _, err := db.CreateMessage(conv.ID, "prompt", content, nil, nil); err != nil {
+				t.Fatalf("Failed to create message: %v", err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name          string
+		pattern       string
+		isRegex       bool
+		expectedCount int
+	}{
+		{"keyword is case-insensitive", "migration", false, 2},
+		{"regex is case-sensitive", `\bmigration\b`, true, 1},
+		{"no matches", "deploy", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preview, err := db.PreviewTagRule(tt.pattern, tt.isRegex)
+			if err != nil {
+				t.Fatalf("Failed to preview tag rule: %v", err)
+			}
+
+			if preview.MatchCount != tt.expectedCount {
+				t.Errorf("Expected %d matching conversations, got %d", tt.expectedCount, preview.MatchCount)
+			}
+			if len(preview.Sample) != tt.expectedCount {
+				t.Errorf("Expected %d sampled conversations, got %d", tt.expectedCount, len(preview.Sample))
+			}
+			if !preview.Complete {
+				t.Error("Expected the preview scan to complete")
+			}
+		})
+	}
+
+	if _, err := db.PreviewTagRule("([", true); err == nil {
+		t.Error("Expected error for a regex that does not compile")
+	}
+
+	rules, err := db.ListTagRules()
+	if err != nil {
+		t.Fatalf("Failed to list tag rules: %v", err)
+	}
+	if len(rules) != 0 {
+		t.Errorf("Expected preview not to store a rule, got %d rules", len(rules))
+	}
+}
@@ -0,0 +1,391 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxTagNameLength mirrors the limit enforced by models.Tag.Validate
+const maxTagNameLength = 50
+
+// Tag represents a tag record
+type Tag struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	Color       *string   `json:"color"`
+	CreatedAt   time.Time `json:"created_at"`
+	UsageCount  int       `json:"usage_count"`
+}
+
+// validateTag enforces tag constraints at the database boundary
+func validateTag(name string, color *string) error {
+	if name == "" {
+		return fmt.Errorf("tag name is required")
+	}
+
+	if len(name) > maxTagNameLength {
+		return fmt.Errorf("tag name cannot exceed %d characters", maxTagNameLength)
+	}
+
+	if color != nil && *color != "" && !isHexColor(*color) {
+		return fmt.Errorf("tag color must be a valid hex color code (e.g., #FF0000)")
+	}
+
+	return nil
+}
+
+// isHexColor reports whether color has the form #RRGGBB
+func isHexColor(color string) bool {
+	if len(color) != 7 || color[0] != '#' {
+		return false
+	}
+
+	for i := 1; i < 7; i++ {
+		c := color[i]
+		if !((c >= '0' && c <= '9') || (c >= 'A' && c <= 'F') || (c >= 'a' && c <= 'f')) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CreateTag inserts a new tag
+func (db *DB) CreateTag(name string, description, color *string) (*Tag, error) {
+	if err := validateTag(name, color); err != nil {
+		return nil, err
+	}
+
+	query := `
+	INSERT INTO tags (name, description, color)
+	VALUES (?, ?, ?)
+	RETURNING id, name, description, color, created_at`
+
+	var tag Tag
+	err := db.conn.QueryRow(query, name, description, color).Scan(
+		&tag.ID, &tag.Name, &tag.Description, &tag.Color, &tag.CreatedAt,
+	)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrTagAlreadyExists
+		}
+
+		// Fallback for SQLite versions that don't support RETURNING
+		result, err := db.conn.Exec(
+			"INSERT INTO tags (name, description, color) VALUES (?, ?, ?)",
+			name, description, color,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return nil, ErrTagAlreadyExists
+			}
+			return nil, fmt.Errorf("failed to insert tag: %w", err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+
+		return db.GetTag(int(id))
+	}
+
+	return &tag, nil
+}
+
+// GetTag retrieves a tag by ID
+func (db *DB) GetTag(id int) (*Tag, error) {
+	query := `
+	SELECT id, name, description, color, created_at
+	FROM tags WHERE id = ?`
+
+	var tag Tag
+	err := db.conn.QueryRow(query, id).Scan(
+		&tag.ID, &tag.Name, &tag.Description, &tag.Color, &tag.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTagNotFound
+		}
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+
+	return &tag, nil
+}
+
+// GetTagByName retrieves a tag by its unique name
+func (db *DB) GetTagByName(name string) (*Tag, error) {
+	query := `
+	SELECT id, name, description, color, created_at
+	FROM tags WHERE name = ?`
+
+	var tag Tag
+	err := db.conn.QueryRow(query, name).Scan(
+		&tag.ID, &tag.Name, &tag.Description, &tag.Color, &tag.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTagNotFound
+		}
+		return nil, fmt.Errorf("failed to get tag by name: %w", err)
+	}
+
+	return &tag, nil
+}
+
+// ListTags retrieves all tags ordered by name, with the number of
+// conversations each tag is attached to
+func (db *DB) ListTags() ([]Tag, error) {
+	query := `
+	SELECT t.id, t.name, t.description, t.color, t.created_at, COUNT(ct.conversation_id)
+	FROM tags t
+	LEFT JOIN conversation_tags ct ON ct.tag_id = t.id
+	GROUP BY t.id
+	ORDER BY t.name ASC`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var tag Tag
+		err := rows.Scan(
+			&tag.ID, &tag.Name, &tag.Description, &tag.Color, &tag.CreatedAt, &tag.UsageCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// UpdateTag updates a tag's name, description and color
+func (db *DB) UpdateTag(id int, name string, description, color *string) error {
+	if err := validateTag(name, color); err != nil {
+		return err
+	}
+
+	query := "UPDATE tags SET name = ?, description = ?, color = ? WHERE id = ?"
+	result, err := db.conn.Exec(query, name, description, color, id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrTagAlreadyExists
+		}
+		return fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrTagNotFound
+	}
+
+	return nil
+}
+
+// DeleteTag deletes a tag along with its tag rules and detaches it from
+// every conversation
+func (db *DB) DeleteTag(id int) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		// Dependent rows are removed explicitly rather than relying on
+		// foreign key cascades being enabled on the connection
+		if _, err := tx.Exec("DELETE FROM conversation_tags WHERE tag_id = ?", id); err != nil {
+			return fmt.Errorf("failed to detach tag: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM tag_rules WHERE tag_id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete tag rules: %w", err)
+		}
+
+		result, err := tx.Exec("DELETE FROM tags WHERE id = ?", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete tag: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return ErrTagNotFound
+		}
+
+		return nil
+	})
+}
+
+// AddTagToConversation attaches a tag to a conversation. Attaching a tag that is
+// already present is not an error; the returned bool reports whether a new
+// association was created.
+func (db *DB) AddTagToConversation(convID, tagID int) (bool, error) {
+	if _, err := db.GetConversation(convID); err != nil {
+		return false, err
+	}
+	if _, err := db.GetTag(tagID); err != nil {
+		return false, err
+	}
+
+	query := "INSERT OR IGNORE INTO conversation_tags (conversation_id, tag_id) VALUES (?, ?)"
+	result, err := db.conn.Exec(query, convID, tagID)
+	if err != nil {
+		return false, fmt.Errorf("failed to add tag to conversation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RemoveTagFromConversation detaches a tag from a conversation
+func (db *DB) RemoveTagFromConversation(convID, tagID int) error {
+	if _, err := db.GetConversation(convID); err != nil {
+		return err
+	}
+	if _, err := db.GetTag(tagID); err != nil {
+		return err
+	}
+
+	query := "DELETE FROM conversation_tags WHERE conversation_id = ? AND tag_id = ?"
+	result, err := db.conn.Exec(query, convID, tagID)
+	if err != nil {
+		return fmt.Errorf("failed to remove tag from conversation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrConversationTagNotFound
+	}
+
+	return nil
+}
+
+// GetConversationTags retrieves the tags attached to a conversation
+func (db *DB) GetConversationTags(convID int) ([]Tag, error) {
+	tagsByConversation, err := db.getTagsForConversations([]int{convID})
+	if err != nil {
+		return nil, err
+	}
+	return tagsByConversation[convID], nil
+}
+
+// attachConversationTags loads the tags for a page of conversations in one query
+func (db *DB) attachConversationTags(conversations []Conversation) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(conversations))
+	for i := range conversations {
+		ids[i] = conversations[i].ID
+	}
+
+	tagsByConversation, err := db.getTagsForConversations(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range conversations {
+		conversations[i].Tags = tagsByConversation[conversations[i].ID]
+	}
+
+	return nil
+}
+
+// getTagsForConversations returns the tags attached to each of the given conversations
+func (db *DB) getTagsForConversations(convIDs []int) (map[int][]Tag, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(convIDs)), ",")
+	args := make([]interface{}, len(convIDs))
+	for i, id := range convIDs {
+		args[i] = id
+	}
+
+	query := `
+	SELECT ct.conversation_id, t.id, t.name, t.description, t.color, t.created_at
+	FROM conversation_tags ct
+	JOIN tags t ON t.id = ct.tag_id
+	WHERE ct.conversation_id IN (` + placeholders + `)
+	ORDER BY t.name ASC`
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation tags: %w", err)
+	}
+	defer rows.Close()
+
+	tagsByConversation := make(map[int][]Tag)
+	for rows.Next() {
+		var convID int
+		var tag Tag
+		err := rows.Scan(
+			&convID, &tag.ID, &tag.Name, &tag.Description, &tag.Color, &tag.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan conversation tag: %w", err)
+		}
+		tagsByConversation[convID] = append(tagsByConversation[convID], tag)
+	}
+
+	return tagsByConversation, nil
+}
+
+// MergeTags moves every conversation association from the source tag onto the
+// target tag, skipping conversations that already carry the target, and then
+// deletes the source tag. The whole merge runs in a single transaction.
+func (db *DB) MergeTags(sourceID, targetID int) error {
+	if sourceID == targetID {
+		return fmt.Errorf("cannot merge a tag into itself")
+	}
+
+	if _, err := db.GetTag(sourceID); err != nil {
+		return err
+	}
+	if _, err := db.GetTag(targetID); err != nil {
+		return err
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+	INSERT OR IGNORE INTO conversation_tags (conversation_id, tag_id)
+	SELECT conversation_id, ? FROM conversation_tags WHERE tag_id = ?`,
+		targetID, sourceID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reassign tag associations: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM conversation_tags WHERE tag_id = ?", sourceID); err != nil {
+		return fmt.Errorf("failed to remove source tag associations: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM tags WHERE id = ?", sourceID); err != nil {
+		return fmt.Errorf("failed to delete source tag: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,391 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTagCRUD(t *testing.T) {
+	db := setupTestDB(t)
+
+	description := "Bug fixing sessions"
+	color := "#FF0000"
+
+	// Create tag
+	tag, err := db.CreateTag("bugfix", &description, &color)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	if tag.Name != "bugfix" {
+		t.Errorf("Expected name bugfix, got %s", tag.Name)
+	}
+
+	if tag.Color == nil || *tag.Color != color {
+		t.Errorf("Expected color %s, got %v", color, tag.Color)
+	}
+
+	// Get tag by ID and name
+	retrieved, err := db.GetTag(tag.ID)
+	if err != nil {
+		t.Fatalf("Failed to get tag: %v", err)
+	}
+
+	if retrieved.ID != tag.ID {
+		t.Errorf("Expected ID %d, got %d", tag.ID, retrieved.ID)
+	}
+
+	byName, err := db.GetTagByName("bugfix")
+	if err != nil {
+		t.Fatalf("Failed to get tag by name: %v", err)
+	}
+
+	if byName.ID != tag.ID {
+		t.Errorf("Expected ID %d, got %d", tag.ID, byName.ID)
+	}
+
+	// Update tag
+	newColor := "#00ff00"
+	err = db.UpdateTag(tag.ID, "bug-fix", nil, &newColor)
+	if err != nil {
+		t.Fatalf("Failed to update tag: %v", err)
+	}
+
+	updated, err := db.GetTag(tag.ID)
+	if err != nil {
+		t.Fatalf("Failed to get updated tag: %v", err)
+	}
+
+	if updated.Name != "bug-fix" {
+		t.Errorf("Expected updated name bug-fix, got %s", updated.Name)
+	}
+
+	if updated.Description != nil {
+		t.Errorf("Expected description to be cleared, got %v", *updated.Description)
+	}
+
+	// List tags
+	if _, err := db.CreateTag("analysis", nil, nil); err != nil {
+		t.Fatalf("Failed to create second tag: %v", err)
+	}
+
+	tags, err := db.ListTags()
+	if err != nil {
+		t.Fatalf("Failed to list tags: %v", err)
+	}
+
+	if len(tags) != 2 {
+		t.Fatalf("Expected 2 tags, got %d", len(tags))
+	}
+
+	if tags[0].Name != "analysis" {
+		t.Errorf("Expected tags ordered by name, got %s first", tags[0].Name)
+	}
+
+	// Delete tag
+	if err := db.DeleteTag(tag.ID); err != nil {
+		t.Fatalf("Failed to delete tag: %v", err)
+	}
+
+	if _, err := db.GetTag(tag.ID); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound after delete, got %v", err)
+	}
+
+	if err := db.DeleteTag(tag.ID); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound deleting missing tag, got %v", err)
+	}
+}
+
+func TestDeleteTagDetachesConversations(t *testing.T) {
+	db := setupTestDB(t)
+
+	tag, err := db.CreateTag("detached", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	for _, sessionID := range []string{"tagged-a", "tagged-b"} {
+		conv, err := db.CreateConversation(sessionID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		if _, err := db.AddTagToConversation(conv.ID, tag.ID); err != nil {
+			t.Fatalf("Failed to tag conversation: %v", err)
+		}
+	}
+
+	if err := db.DeleteTag(tag.ID); err != nil {
+		t.Fatalf("Failed to delete tag: %v", err)
+	}
+
+	var leftover int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM conversation_tags WHERE tag_id = ?", tag.ID).Scan(&leftover); err != nil {
+		t.Fatalf("Failed to count conversation tags: %v", err)
+	}
+	if leftover != 0 {
+		t.Errorf("Expected no conversation_tags rows for the deleted tag, found %d", leftover)
+	}
+}
+
+func TestCreateTagDuplicateName(t *testing.T) {
+	db := setupTestDB(t)
+
+	if _, err := db.CreateTag("refactor", nil, nil); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	_, err := db.CreateTag("refactor", nil, nil)
+	if !errors.Is(err, ErrTagAlreadyExists) {
+		t.Errorf("Expected ErrTagAlreadyExists, got %v", err)
+	}
+
+	other, err := db.CreateTag("other", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	err = db.UpdateTag(other.ID, "refactor", nil, nil)
+	if !errors.Is(err, ErrTagAlreadyExists) {
+		t.Errorf("Expected ErrTagAlreadyExists on rename collision, got %v", err)
+	}
+}
+
+func TestInvalidTag(t *testing.T) {
+	db := setupTestDB(t)
+
+	badColor := "red"
+	shortColor := "#FFF"
+	badHex := "#GG0000"
+
+	tests := []struct {
+		name    string
+		tagName string
+		color   *string
+	}{
+		{"empty name", "", nil},
+		{"name too long", strings.Repeat("a", 51), nil},
+		{"color without hash", "tag", &badColor},
+		{"short color", "tag", &shortColor},
+		{"non-hex color", "tag", &badHex},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := db.CreateTag(tt.tagName, nil, tt.color); err == nil {
+				t.Error("Expected error creating invalid tag")
+			}
+		})
+	}
+}
+
+func TestConversationTagAssociation(t *testing.T) {
+	db := setupTestDB(t)
+
+	conv, err := db.CreateConversation("test-session", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	tag, err := db.CreateTag("bugfix", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	added, err := db.AddTagToConversation(conv.ID, tag.ID)
+	if err != nil {
+		t.Fatalf("Failed to add tag: %v", err)
+	}
+	if !added {
+		t.Error("Expected first add to create an association")
+	}
+
+	// Adding again is idempotent
+	added, err = db.AddTagToConversation(conv.ID, tag.ID)
+	if err != nil {
+		t.Fatalf("Failed to re-add tag: %v", err)
+	}
+	if added {
+		t.Error("Expected second add to be a no-op")
+	}
+
+	withMessages, err := db.GetConversationWithMessages(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	if len(withMessages.Tags) != 1 || withMessages.Tags[0].Name != "bugfix" {
+		t.Errorf("Expected conversation to carry the bugfix tag, got %v", withMessages.Tags)
+	}
+
+	conversations, err := db.ListConversations(nil, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list conversations: %v", err)
+	}
+	if len(conversations) != 1 || len(conversations[0].Tags) != 1 {
+		t.Errorf("Expected listed conversation to carry one tag, got %v", conversations)
+	}
+
+	// Missing conversation or tag
+	if _, err := db.AddTagToConversation(999, tag.ID); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound, got %v", err)
+	}
+	if _, err := db.AddTagToConversation(conv.ID, 999); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound, got %v", err)
+	}
+
+	// Remove
+	if err := db.RemoveTagFromConversation(conv.ID, tag.ID); err != nil {
+		t.Fatalf("Failed to remove tag: %v", err)
+	}
+	if err := db.RemoveTagFromConversation(conv.ID, tag.ID); !errors.Is(err, ErrConversationTagNotFound) {
+		t.Errorf("Expected ErrConversationTagNotFound, got %v", err)
+	}
+
+	tags, err := db.GetConversationTags(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to get conversation tags: %v", err)
+	}
+	if len(tags) != 0 {
+		t.Errorf("Expected no tags after removal, got %d", len(tags))
+	}
+}
+
+func TestListConversationsTagFilter(t *testing.T) {
+	db := setupTestDB(t)
+
+	bugfix, err := db.CreateTag("bugfix", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	urgent, err := db.CreateTag("urgent", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	var ids []int
+	for i := 0; i < 3; i++ {
+		conv, err := db.CreateConversation("tag-session", nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		ids = append(ids, conv.ID)
+	}
+
+	// ids[0]: bugfix, ids[1]: bugfix+urgent, ids[2]: untagged
+	for _, pair := range [][2]int{{ids[0], bugfix.ID}, {ids[1], bugfix.ID}, {ids[1], urgent.ID}} {
+		if _, err := db.AddTagToConversation(pair[0], pair[1]); err != nil {
+			t.Fatalf("Failed to add tag: %v", err)
+		}
+	}
+
+	docs, err := db.CreateTag("docs", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		tagIDs   []int
+		mode     TagMatchMode
+		expected int
+	}{
+		{"single tag", []int{bugfix.ID}, "", 2},
+		{"all tags required", []int{bugfix.ID, urgent.ID}, "", 1},
+		{"explicit all mode", []int{bugfix.ID, urgent.ID}, TagMatchAll, 1},
+		{"duplicate tag ignored", []int{urgent.ID, urgent.ID}, "", 1},
+		{"any tag matches", []int{urgent.ID, docs.ID}, TagMatchAny, 1},
+		{"any mode counts each conversation once", []int{bugfix.ID, urgent.ID}, TagMatchAny, 2},
+		{"any mode with unused tag", []int{docs.ID}, TagMatchAny, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := &ConversationFilter{TagIDs: tt.tagIDs, TagMode: tt.mode}
+
+			conversations, err := db.ListConversations(filter, 10, 0)
+			if err != nil {
+				t.Fatalf("Failed to list conversations: %v", err)
+			}
+			if len(conversations) != tt.expected {
+				t.Errorf("Expected %d conversations, got %d", tt.expected, len(conversations))
+			}
+
+			count, err := db.GetConversationCount(filter)
+			if err != nil {
+				t.Fatalf("Failed to count conversations: %v", err)
+			}
+			if count != tt.expected {
+				t.Errorf("Expected count %d, got %d", tt.expected, count)
+			}
+		})
+	}
+
+	// Pagination applies to the filtered set
+	page, err := db.ListConversationsByTag(bugfix.ID, 1, 1)
+	if err != nil {
+		t.Fatalf("Failed to list conversations: %v", err)
+	}
+	if len(page) != 1 {
+		t.Errorf("Expected 1 conversation on second page, got %d", len(page))
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	db := setupTestDB(t)
+
+	source, err := db.CreateTag("bug-fix", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	target, err := db.CreateTag("bugfix", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	both, err := db.CreateConversation("session-both", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	sourceOnly, err := db.CreateConversation("session-source", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	for _, pair := range [][2]int{{both.ID, source.ID}, {both.ID, target.ID}, {sourceOnly.ID, source.ID}} {
+		if _, err := db.AddTagToConversation(pair[0], pair[1]); err != nil {
+			t.Fatalf("Failed to add tag: %v", err)
+		}
+	}
+
+	if err := db.MergeTags(source.ID, source.ID); err == nil {
+		t.Error("Expected error merging a tag into itself")
+	}
+	if err := db.MergeTags(999, target.ID); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound, got %v", err)
+	}
+
+	if err := db.MergeTags(source.ID, target.ID); err != nil {
+		t.Fatalf("Failed to merge tags: %v", err)
+	}
+
+	if _, err := db.GetTag(source.ID); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected source tag to be deleted, got %v", err)
+	}
+
+	for _, convID := range []int{both.ID, sourceOnly.ID} {
+		tags, err := db.GetConversationTags(convID)
+		if err != nil {
+			t.Fatalf("Failed to get conversation tags: %v", err)
+		}
+		if len(tags) != 1 || tags[0].ID != target.ID {
+			t.Errorf("Expected conversation %d to carry only the target tag, got %v", convID, tags)
+		}
+	}
+
+	count, err := db.GetConversationCountByTag(target.ID)
+	if err != nil {
+		t.Fatalf("Failed to count conversations: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 conversations on target tag, got %d", count)
+	}
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/models"
+)
+
+// csvMessageHeader lists the columns written by CSVMessages
+var csvMessageHeader = []string{"id", "type", "timestamp", "character_count", "execution_time", "content"}
+
+// CSVMessages renders messages as CSV with one row per message. Content is
+// quoted as needed so embedded commas, quotes and newlines survive a round trip.
+// Messages without an execution time leave that column empty.
+func CSVMessages(messages []models.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write(csvMessageHeader); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, msg := range messages {
+		executionTime := ""
+		if msg.ExecutionTime != nil {
+			executionTime = strconv.Itoa(*msg.ExecutionTime)
+		}
+
+		record := []string{
+			strconv.Itoa(msg.ID),
+			string(msg.MessageType),
+			msg.Timestamp.UTC().Format(time.RFC3339),
+			strconv.Itoa(msg.CharacterCount),
+			executionTime,
+			msg.Content,
+		}
+
+		if err := w.Write(record); err != nil {
+			return nil, fmt.Errorf("failed to write CSV row for message %d: %w", msg.ID, err)
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to flush CSV: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/claude-code-template/prompt-manager/internal/models"
+)
+
+func TestCSVMessagesRoundTrip(t *testing.T) {
+	executionTime := 1500
+	content := "Line one, with a comma\nLine two with \"quotes\""
+
+	messages := []models.Message{
+		{
+			ID:             1,
+			MessageType:    models.MessageTypePrompt,
+			Content:        "Plain prompt",
+			CharacterCount: 12,
+			Timestamp:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			ID:             2,
+			MessageType:    models.MessageTypeResponse,
+			Content:        content,
+			CharacterCount: len(content),
+			Timestamp:      time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC),
+			ExecutionTime:  &executionTime,
+		},
+	}
+
+	output, err := CSVMessages(messages)
+	if err != nil {
+		t.Fatalf("Failed to render CSV: %v", err)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %d records", len(records))
+	}
+
+	if records[0][0] != "id" || records[0][5] != "content" {
+		t.Errorf("Unexpected header: %v", records[0])
+	}
+
+	if records[1][4] != "" {
+		t.Errorf("Expected empty execution time, got %q", records[1][4])
+	}
+
+	expected := []string{"2", "response", "2024-01-02T03:04:06Z", strconv.Itoa(len(content)), "1500", content}
+	for i, want := range expected {
+		if records[2][i] != want {
+			t.Errorf("Column %d: expected %q, got %q", i, want, records[2][i])
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxBarWidth is the width in characters of the longest distribution bar
+const maxBarWidth = 40
+
+// RatingReport holds the data rendered into a rating evaluation report
+type RatingReport struct {
+	GeneratedAt          time.Time
+	AverageRating        float64
+	TotalRatings         int
+	Distribution         map[int]int
+	UnratedConversations int
+	LowestRated          []LowRatedConversation
+}
+
+// LowRatedConversation describes a poorly rated conversation and its feedback
+type LowRatedConversation struct {
+	ConversationID int
+	Title          *string
+	AverageRating  float64
+	RatingCount    int
+	Comments       []string
+}
+
+// RatingReportMarkdown renders a rating report as human-readable Markdown
+func RatingReportMarkdown(report RatingReport) []byte {
+	var b strings.Builder
+
+	b.WriteString("# Rating Evaluation Report\n\n")
+	fmt.Fprintf(&b, "Generated: %s\n\n", report.GeneratedAt.UTC().Format(time.RFC3339))
+
+	b.WriteString("## Summary\n\n")
+	fmt.Fprintf(&b, "- Average rating: %.2f / 5\n", report.AverageRating)
+	fmt.Fprintf(&b, "- Total ratings: %d\n", report.TotalRatings)
+	fmt.Fprintf(&b, "- Unrated conversations: %d\n\n", report.UnratedConversations)
+
+	b.WriteString("## Distribution\n\n")
+	b.WriteString("```\n")
+	b.WriteString(distributionBars(report.Distribution))
+	b.WriteString("```\n\n")
+
+	b.WriteString("## Lowest-Rated Conversations\n\n")
+	if len(report.LowestRated) == 0 {
+		b.WriteString("No rated conversations yet.\n")
+		return []byte(b.String())
+	}
+
+	for _, conv := range report.LowestRated {
+		title := "Untitled"
+		if conv.Title != nil && *conv.Title != "" {
+			title = *conv.Title
+		}
+		fmt.Fprintf(&b, "### #%d %s (%.2f from %d ratings)\n\n", conv.ConversationID, title, conv.AverageRating, conv.RatingCount)
+
+		if len(conv.Comments) == 0 {
+			b.WriteString("_No comments._\n\n")
+			continue
+		}
+		for _, comment := range conv.Comments {
+			fmt.Fprintf(&b, "> %s\n", strings.ReplaceAll(comment, "\n", " "))
+		}
+		b.WriteString("\n")
+	}
+
+	return []byte(b.String())
+}
+
+// distributionBars draws one ASCII bar per rating value, scaled to the largest count
+func distributionBars(distribution map[int]int) string {
+	maxCount := 0
+	for _, count := range distribution {
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+
+	var b strings.Builder
+	for rating := 5; rating >= 1; rating-- {
+		count := distribution[rating]
+		width := 0
+		if maxCount > 0 {
+			width = count * maxBarWidth / maxCount
+		}
+		if count > 0 && width == 0 {
+			width = 1
+		}
+		fmt.Fprintf(&b, "%d | %s %d\n", rating, strings.Repeat("#", width), count)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRatingReportMarkdown(t *testing.T) {
+	title := "Refactor parser"
+	report := RatingReport{
+		GeneratedAt:          time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		AverageRating:        3.5,
+		TotalRatings:         4,
+		Distribution:         map[int]int{5: 2, 2: 1, 1: 1},
+		UnratedConversations: 3,
+		LowestRated: []LowRatedConversation{
+			{ConversationID: 7, Title: &title, AverageRating: 1.5, RatingCount: 2, Comments: []string{"Missed the point"}},
+		},
+	}
+
+	output := string(RatingReportMarkdown(report))
+
+	expected := []string{
+		"Average rating: 3.50 / 5",
+		"Unrated conversations: 3",
+		"5 | " + strings.Repeat("#", maxBarWidth) + " 2",
+		"2 | " + strings.Repeat("#", maxBarWidth/2) + " 1",
+		"3 |  0",
+		"#7 Refactor parser (1.50 from 2 ratings)",
+		"> Missed the point",
+	}
+
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected report to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestRatingReportMarkdownEmpty(t *testing.T) {
+	output := string(RatingReportMarkdown(RatingReport{Distribution: map[int]int{}}))
+
+	if !strings.Contains(output, "No rated conversations yet.") {
+		t.Errorf("Expected empty report notice, got:\n%s", output)
+	}
+
+	if !strings.Contains(output, "1 |  0") {
+		t.Errorf("Expected zero bars for every rating, got:\n%s", output)
+	}
+}
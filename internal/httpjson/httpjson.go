@@ -0,0 +1,34 @@
+// Package httpjson writes JSON responses for the API server and the hook
+// handlers, so both encode, fall back and log failures the same way.
+package httpjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+)
+
+// EncodeFailureBody is sent when a response payload cannot be encoded
+const EncodeFailureBody = `{"success":false,"error":"Failed to encode response"}` + "\n"
+
+// Write encodes payload and writes it with the given status. The body is
+// encoded before anything is written, so a payload that fails to encode
+// becomes a 500 rather than a truncated body. Encode and write errors are
+// logged to logger, since the client can no longer be told about them.
+func Write(logger logging.Logger, w http.ResponseWriter, status int, payload interface{}) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		logger.Errorf("Failed to encode JSON response: %v", err)
+		status = http.StatusInternalServerError
+		body.Reset()
+		body.WriteString(EncodeFailureBody)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(body.Bytes()); err != nil {
+		logger.Errorf("Failed to write JSON response: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package httpjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/claude-code-template/prompt-manager/internal/logging"
+)
+
+func TestWrite(t *testing.T) {
+	t.Run("encodes payload", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Write(logging.Nop{}, w, http.StatusCreated, map[string]int{"id": 1})
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d", http.StatusCreated, w.Code)
+		}
+		if contentType := w.Result().Header.Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("Expected Content-Type application/json, got %s", contentType)
+		}
+		var body map[string]int
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil || body["id"] != 1 {
+			t.Errorf("Expected the payload to be encoded, got %v (%v)", body, err)
+		}
+	})
+
+	t.Run("unencodable payload", func(t *testing.T) {
+		var logs bytes.Buffer
+		w := httptest.NewRecorder()
+		Write(logging.Standard{Logger: log.New(&logs, "", 0)}, w, http.StatusCreated, map[string]interface{}{"data": make(chan int)})
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+		}
+		if w.Body.String() != EncodeFailureBody {
+			t.Errorf("Expected the fallback body, got %q", w.Body.String())
+		}
+		if !strings.Contains(logs.String(), "ERROR: Failed to encode JSON response") {
+			t.Errorf("Expected the encode failure to be logged through the given logger, got %q", logs.String())
+		}
+	})
+}
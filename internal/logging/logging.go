@@ -0,0 +1,51 @@
+// Package logging defines the small logger interface the API server and
+// database report through, so embedders can route messages into their own
+// logger and tests can capture or silence them.
+package logging
+
+import "log"
+
+// Logger receives log messages. Printf is for operational notes and
+// warnings, Errorf for failures that could not be reported to a caller.
+// Implementations must be safe for concurrent use.
+type Logger interface {
+	Printf(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+}
+
+// Standard writes through a *log.Logger, prefixing errors with "ERROR: ".
+// A nil Logger uses the standard library's default logger.
+type Standard struct {
+	Logger *log.Logger
+}
+
+// Default returns a Logger backed by the standard library's default logger
+func Default() Logger {
+	return Standard{}
+}
+
+// Printf logs a message
+func (s Standard) Printf(format string, v ...interface{}) {
+	s.logger().Printf(format, v...)
+}
+
+// Errorf logs a failure
+func (s Standard) Errorf(format string, v ...interface{}) {
+	s.logger().Printf("ERROR: "+format, v...)
+}
+
+func (s Standard) logger() *log.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return log.Default()
+}
+
+// Nop is a Logger that discards every message
+type Nop struct{}
+
+// Printf does nothing
+func (Nop) Printf(format string, v ...interface{}) {}
+
+// Errorf does nothing
+func (Nop) Errorf(format string, v ...interface{}) {}
@@ -0,0 +1,20 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestStandard(t *testing.T) {
+	var out bytes.Buffer
+	logger := Standard{Logger: log.New(&out, "", 0)}
+
+	logger.Printf("applied %d migrations", 2)
+	logger.Errorf("failed to release lock: %v", "busy")
+
+	expected := "applied 2 migrations\nERROR: failed to release lock: busy\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,82 @@
+// Package metrics records request and database timings and renders them,
+// along with gauges sampled at scrape time, in the Prometheus text format.
+package metrics
+
+import (
+	"time"
+)
+
+// Recorder receives request and database observations. Implementations must
+// be safe for concurrent use.
+type Recorder interface {
+	// ObserveRequest records one HTTP request served by the named handler
+	ObserveRequest(handler string, status int, duration time.Duration)
+
+	// ObserveDBOperation records how long one database operation took
+	ObserveDBOperation(operation string, duration time.Duration)
+}
+
+// Nop is a Recorder that discards every observation, used when metrics are
+// disabled and in tests
+type Nop struct{}
+
+// ObserveRequest does nothing
+func (Nop) ObserveRequest(handler string, status int, duration time.Duration) {}
+
+// ObserveDBOperation does nothing
+func (Nop) ObserveDBOperation(operation string, duration time.Duration) {}
+
+// Gauge is a point-in-time value sampled when metrics are scraped
+type Gauge struct {
+	Name  string
+	Help  string
+	Value float64
+}
+
+// GaugeFunc samples a set of gauges. It is called on every scrape.
+type GaugeFunc func() ([]Gauge, error)
+
+// DBStatsGauges adapts a DB.Stats-style function into gauges for the record
+// counts and database file size it reports
+func DBStatsGauges(stats func() (map[string]interface{}, error)) GaugeFunc {
+	gauges := []Gauge{
+		{Name: "prompt_manager_conversations", Help: "Number of stored conversations."},
+		{Name: "prompt_manager_messages", Help: "Number of stored messages."},
+		{Name: "prompt_manager_ratings", Help: "Number of stored ratings."},
+		{Name: "prompt_manager_database_size_bytes", Help: "Size of the database file in bytes."},
+	}
+	keys := []string{"conversations", "messages", "ratings", "database_size_bytes"}
+
+	return func() ([]Gauge, error) {
+		values, err := stats()
+		if err != nil {
+			return nil, err
+		}
+
+		var sampled []Gauge
+		for i, key := range keys {
+			value, ok := toFloat(values[key])
+			if !ok {
+				continue
+			}
+			gauge := gauges[i]
+			gauge.Value = value
+			sampled = append(sampled, gauge)
+		}
+		return sampled, nil
+	}
+}
+
+// toFloat converts the integer types DB.Stats reports into a gauge value
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
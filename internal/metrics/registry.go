@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the histogram upper bounds in seconds, matching the
+// Prometheus client defaults
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metric names exported by Registry
+const (
+	requestsTotalName       = "prompt_manager_http_requests_total"
+	requestDurationName     = "prompt_manager_http_request_duration_seconds"
+	dbOperationDurationName = "prompt_manager_db_operation_duration_seconds"
+)
+
+// requestKey identifies one request counter series
+type requestKey struct {
+	handler string
+	status  int
+}
+
+// histogram accumulates observations into cumulative buckets
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(buckets []float64, seconds float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(buckets))
+	}
+	for i, bound := range buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// Registry is an in-process Recorder that serves what it has recorded in the
+// Prometheus text exposition format
+type Registry struct {
+	mu               sync.Mutex
+	buckets          []float64
+	requests         map[requestKey]uint64
+	requestDurations map[string]*histogram
+	dbDurations      map[string]*histogram
+	gauges           []GaugeFunc
+}
+
+// NewRegistry creates an empty registry using DefaultBuckets
+func NewRegistry() *Registry {
+	return &Registry{
+		buckets:          DefaultBuckets,
+		requests:         make(map[requestKey]uint64),
+		requestDurations: make(map[string]*histogram),
+		dbDurations:      make(map[string]*histogram),
+	}
+}
+
+// RegisterGauges adds a gauge source sampled on every scrape
+func (r *Registry) RegisterGauges(fn GaugeFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges = append(r.gauges, fn)
+}
+
+// ObserveRequest counts the request and records its latency
+func (r *Registry) ObserveRequest(handler string, status int, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests[requestKey{handler: handler, status: status}]++
+	observe(r.requestDurations, handler, r.buckets, duration)
+}
+
+// ObserveDBOperation records the operation's latency
+func (r *Registry) ObserveDBOperation(operation string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	observe(r.dbDurations, operation, r.buckets, duration)
+}
+
+// observe adds a duration to the named histogram, creating it if needed
+func observe(histograms map[string]*histogram, name string, buckets []float64, duration time.Duration) {
+	h, ok := histograms[name]
+	if !ok {
+		h = &histogram{}
+		histograms[name] = h
+	}
+	h.observe(buckets, duration.Seconds())
+}
+
+// ServeHTTP writes every metric in the Prometheus text format. Gauge sources
+// that fail are logged and left out rather than failing the whole scrape.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	gaugeFuncs := append([]GaugeFunc(nil), r.gauges...)
+	r.mu.Unlock()
+
+	// Sample gauges without holding the lock, since they may query the database
+	var gauges []Gauge
+	for _, fn := range gaugeFuncs {
+		sampled, err := fn()
+		if err != nil {
+			log.Printf("Failed to sample metrics gauges: %v", err)
+			continue
+		}
+		gauges = append(gauges, sampled...)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fmt.Fprintf(out, "# HELP %s Total HTTP requests by handler and status code.\n", requestsTotalName)
+	fmt.Fprintf(out, "# TYPE %s counter\n", requestsTotalName)
+	keys := make([]requestKey, 0, len(r.requests))
+	for key := range r.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].handler != keys[j].handler {
+			return keys[i].handler < keys[j].handler
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, key := range keys {
+		fmt.Fprintf(out, "%s{handler=%s,status=\"%d\"} %d\n", requestsTotalName, quoteLabel(key.handler), key.status, r.requests[key])
+	}
+
+	writeHistograms(out, requestDurationName, "HTTP request latency in seconds by handler.", "handler", r.buckets, r.requestDurations)
+	writeHistograms(out, dbOperationDurationName, "Database operation latency in seconds.", "operation", r.buckets, r.dbDurations)
+
+	for _, gauge := range gauges {
+		fmt.Fprintf(out, "# HELP %s %s\n", gauge.Name, gauge.Help)
+		fmt.Fprintf(out, "# TYPE %s gauge\n", gauge.Name)
+		fmt.Fprintf(out, "%s %s\n", gauge.Name, formatFloat(gauge.Value))
+	}
+}
+
+// writeHistograms writes one histogram family with a series per label value
+func writeHistograms(out *bufio.Writer, name, help, label string, buckets []float64, histograms map[string]*histogram) {
+	fmt.Fprintf(out, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(out, "# TYPE %s histogram\n", name)
+
+	values := make([]string, 0, len(histograms))
+	for value := range histograms {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	for _, value := range values {
+		h := histograms[value]
+		labelPair := label + "=" + quoteLabel(value)
+		for i, bound := range buckets {
+			fmt.Fprintf(out, "%s_bucket{%s,le=\"%s\"} %d\n", name, labelPair, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(out, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labelPair, h.count)
+		fmt.Fprintf(out, "%s_sum{%s} %s\n", name, labelPair, formatFloat(h.sum))
+		fmt.Fprintf(out, "%s_count{%s} %d\n", name, labelPair, h.count)
+	}
+}
+
+// quoteLabel quotes a label value, escaping the characters the text format
+// reserves
+func quoteLabel(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return `"` + escaped + `"`
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
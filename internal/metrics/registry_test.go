@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistryServeHTTP(t *testing.T) {
+	registry := NewRegistry()
+	registry.ObserveRequest("/conversations/{id}", 200, 20*time.Millisecond)
+	registry.ObserveRequest("/conversations/{id}", 200, 2*time.Second)
+	registry.ObserveRequest("/conversations/{id}", 404, time.Millisecond)
+	registry.ObserveDBOperation("create_message", 3*time.Millisecond)
+	registry.RegisterGauges(func() ([]Gauge, error) {
+		return []Gauge{{Name: "prompt_manager_messages", Help: "Number of stored messages.", Value: 42}}, nil
+	})
+	registry.RegisterGauges(func() ([]Gauge, error) {
+		return nil, errors.New("database is locked")
+	})
+
+	rr := httptest.NewRecorder()
+	registry.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	if got := rr.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("Expected the Prometheus text content type, got %q", got)
+	}
+
+	body := rr.Body.String()
+	expected := []string{
+		"# TYPE prompt_manager_http_requests_total counter",
+		`prompt_manager_http_requests_total{handler="/conversations/{id}",status="200"} 2`,
+		`prompt_manager_http_requests_total{handler="/conversations/{id}",status="404"} 1`,
+		"# TYPE prompt_manager_http_request_duration_seconds histogram",
+		`prompt_manager_http_request_duration_seconds_bucket{handler="/conversations/{id}",le="0.005"} 1`,
+		`prompt_manager_http_request_duration_seconds_bucket{handler="/conversations/{id}",le="0.025"} 2`,
+		`prompt_manager_http_request_duration_seconds_bucket{handler="/conversations/{id}",le="2.5"} 3`,
+		`prompt_manager_http_request_duration_seconds_bucket{handler="/conversations/{id}",le="+Inf"} 3`,
+		`prompt_manager_http_request_duration_seconds_count{handler="/conversations/{id}"} 3`,
+		`prompt_manager_db_operation_duration_seconds_count{operation="create_message"} 1`,
+		"# TYPE prompt_manager_messages gauge",
+		"prompt_manager_messages 42",
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestDBStatsGauges(t *testing.T) {
+	gauges, err := DBStatsGauges(func() (map[string]interface{}, error) {
+		return map[string]interface{}{
+			"conversations":       3,
+			"messages":            12,
+			"ratings":             2,
+			"database_size_bytes": int64(4096),
+			"connection_pool":     map[string]interface{}{},
+		}, nil
+	})()
+	if err != nil {
+		t.Fatalf("Failed to sample gauges: %v", err)
+	}
+
+	values := make(map[string]float64)
+	for _, gauge := range gauges {
+		values[gauge.Name] = gauge.Value
+	}
+
+	expected := map[string]float64{
+		"prompt_manager_conversations":       3,
+		"prompt_manager_messages":            12,
+		"prompt_manager_ratings":             2,
+		"prompt_manager_database_size_bytes": 4096,
+	}
+	for name, value := range expected {
+		if values[name] != value {
+			t.Errorf("Expected %s = %v, got %v", name, value, values[name])
+		}
+	}
+	if len(gauges) != len(expected) {
+		t.Errorf("Expected %d gauges, got %d", len(expected), len(gauges))
+	}
+}
+
+func TestQuoteLabel(t *testing.T) {
+	if got := quoteLabel("a\"b\\c\nd"); got != `"a\"b\\c\nd"` {
+		t.Errorf("quoteLabel escaped incorrectly: %s", got)
+	}
+}
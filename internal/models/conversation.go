@@ -17,6 +17,7 @@ type Conversation struct {
 	TotalCharacters  int                     `json:"total_characters"`
 	WorkingDirectory *string                 `json:"working_directory,omitempty"`
 	TranscriptPath   *string                 `json:"transcript_path,omitempty"`
+	DeletedAt        *time.Time              `json:"deleted_at,omitempty"`
 	Messages         []Message               `json:"messages,omitempty"`
 	Ratings          []Rating                `json:"ratings,omitempty"`
 	Tags             []Tag                   `json:"tags,omitempty"`
@@ -32,7 +33,9 @@ type Message struct {
 	CharacterCount int                    `json:"character_count"`
 	Timestamp      time.Time              `json:"timestamp"`
 	ToolCalls      []ToolCall             `json:"tool_calls,omitempty"`
+	ToolCallCount  int                    `json:"tool_call_count"`
 	ExecutionTime  *int                   `json:"execution_time,omitempty"` // milliseconds
+	Model          *string                `json:"model,omitempty"`
 	Ratings        []Rating               `json:"ratings,omitempty"`
 	Metadata       map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -60,11 +63,13 @@ type Session struct {
 	SessionID           string    `json:"session_id"`
 	StartTime           time.Time `json:"start_time"`
 	EndTime             *time.Time `json:"end_time,omitempty"`
+	DurationMs          *int64    `json:"duration_ms,omitempty"`
 	ConversationCount   int       `json:"conversation_count"`
 	TotalPromptCount    int       `json:"total_prompt_count"`
 	AvgResponseTime     int       `json:"avg_response_time"` // milliseconds
+	LastActivity        *time.Time `json:"last_activity,omitempty"`
 	WorkingDirectory    *string   `json:"working_directory,omitempty"`
-	Status              SessionStatus `json:"status"`
+	Status              SessionStatus `json:"status,omitempty"`
 	Conversations       []Conversation `json:"conversations,omitempty"`
 }
 
@@ -77,6 +82,40 @@ const (
 	SessionStatusArchived  SessionStatus = "archived"
 )
 
+// ParseSessionStatus parses a session status, which must be active,
+// completed or archived
+func ParseSessionStatus(value string) (SessionStatus, error) {
+	switch status := SessionStatus(value); status {
+	case SessionStatusActive, SessionStatusCompleted, SessionStatusArchived:
+		return status, nil
+	default:
+		return "", fmt.Errorf("invalid session status %q: must be active, completed or archived", value)
+	}
+}
+
+// CanTransitionTo reports whether a session may move from s to next. Active
+// and completed sessions can move freely between each other, since a
+// completed session may be resumed, and either can be archived. Archiving is
+// final. Staying in the same status is always allowed; unknown statuses
+// never are.
+func (s SessionStatus) CanTransitionTo(next SessionStatus) bool {
+	if _, err := ParseSessionStatus(string(next)); err != nil {
+		return false
+	}
+	if s == next {
+		return true
+	}
+
+	switch s {
+	case SessionStatusActive:
+		return next == SessionStatusCompleted || next == SessionStatusArchived
+	case SessionStatusCompleted:
+		return next == SessionStatusActive || next == SessionStatusArchived
+	default:
+		return false
+	}
+}
+
 // Rating represents a user rating for a conversation or message
 type Rating struct {
 	ID             int        `json:"id"`
@@ -95,7 +134,7 @@ type Tag struct {
 	Description *string   `json:"description,omitempty"`
 	Color       *string   `json:"color,omitempty"` // hex color code
 	CreatedAt   time.Time `json:"created_at"`
-	UsageCount  int       `json:"usage_count,omitempty"` // computed field
+	UsageCount  int       `json:"usage_count"` // computed field
 }
 
 // ConversationTag represents the many-to-many relationship between conversations and tags
@@ -107,17 +146,19 @@ type ConversationTag struct {
 
 // ConversationSummary provides aggregated information about a conversation
 type ConversationSummary struct {
-	ID              int       `json:"id"`
-	SessionID       string    `json:"session_id"`
-	Title           *string   `json:"title,omitempty"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
-	PromptCount     int       `json:"prompt_count"`
-	ResponseCount   int       `json:"response_count"`
-	TotalCharacters int       `json:"total_characters"`
-	AvgRating       *float64  `json:"avg_rating,omitempty"`
-	TagCount        int       `json:"tag_count"`
-	Tags            []Tag     `json:"tags,omitempty"`
+	ID                int        `json:"id"`
+	SessionID         string     `json:"session_id"`
+	Title             *string    `json:"title,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	PromptCount       int        `json:"prompt_count"`
+	ResponseCount     int        `json:"response_count"`
+	TotalCharacters   int        `json:"total_characters"`
+	AvgRating         *float64   `json:"avg_rating,omitempty"`
+	AvgResponseTimeMs *float64   `json:"avg_response_time_ms"` // null when no response is timed
+	TagCount          int        `json:"tag_count"`
+	Tags              []Tag      `json:"tags,omitempty"`
+	DeletedAt         *time.Time `json:"deleted_at,omitempty"`
 }
 
 // Validation methods
@@ -240,21 +281,18 @@ func (c *Conversation) AddTag(tag Tag) {
 
 // GetAverageRating calculates the average rating for the conversation
 func (c *Conversation) GetAverageRating() *float64 {
-	if len(c.Ratings) == 0 {
-		return nil
-	}
-	
-	var total int
-	for _, rating := range c.Ratings {
-		total += rating.Rating
-	}
-	
-	avg := float64(total) / float64(len(c.Ratings))
-	return &avg
+	return MeanRating(c.Ratings)
 }
 
-// ToSummary converts a conversation to a summary
+// ToSummary converts a conversation to a summary, scoring its ratings by mean.
+// Callers honouring a configured aggregation use ToSummaryWithAggregation.
 func (c *Conversation) ToSummary() ConversationSummary {
+	return c.ToSummaryWithAggregation(RatingAggregationMean)
+}
+
+// ToSummaryWithAggregation converts a conversation to a summary, scoring its
+// ratings with the given aggregation method
+func (c *Conversation) ToSummaryWithAggregation(method RatingAggregation) ConversationSummary {
 	responseCount := 0
 	for _, msg := range c.Messages {
 		if msg.MessageType == MessageTypeResponse {
@@ -271,9 +309,10 @@ func (c *Conversation) ToSummary() ConversationSummary {
 		PromptCount:     c.PromptCount,
 		ResponseCount:   responseCount,
 		TotalCharacters: c.TotalCharacters,
-		AvgRating:       c.GetAverageRating(),
+		AvgRating:       method.Aggregate(c.Ratings),
 		TagCount:        len(c.Tags),
 		Tags:            c.Tags,
+		DeletedAt:       c.DeletedAt,
 	}
 }
 
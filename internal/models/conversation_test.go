@@ -445,4 +445,36 @@ func intPtr(i int) *int {
 
 func stringPtr(s string) *string {
 	return &s
-}
\ No newline at end of file
+}
+func TestSessionStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from    SessionStatus
+		to      SessionStatus
+		allowed bool
+	}{
+		{SessionStatusActive, SessionStatusActive, true},
+		{SessionStatusActive, SessionStatusCompleted, true},
+		{SessionStatusActive, SessionStatusArchived, true},
+		{SessionStatusCompleted, SessionStatusActive, true},
+		{SessionStatusCompleted, SessionStatusArchived, true},
+		{SessionStatusArchived, SessionStatusArchived, true},
+		{SessionStatusArchived, SessionStatusActive, false},
+		{SessionStatusArchived, SessionStatusCompleted, false},
+		{SessionStatusActive, SessionStatus("paused"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"_to_"+string(tt.to), func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.allowed {
+				t.Errorf("CanTransitionTo() = %v, want %v", got, tt.allowed)
+			}
+		})
+	}
+
+	if _, err := ParseSessionStatus("archived"); err != nil {
+		t.Errorf("ParseSessionStatus() rejected a known status: %v", err)
+	}
+	if _, err := ParseSessionStatus("Archived"); err == nil {
+		t.Error("ParseSessionStatus() accepted an unknown status")
+	}
+}
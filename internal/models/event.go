@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Event types pushed to live update subscribers
+const (
+	EventConversationCreated = "conversation.created"
+	EventMessageCreated      = "message.created"
+	// EventBatch carries an array of the events above when the event
+	// stream batches them
+	EventBatch = "batch"
+)
+
+// Event is a small notification that a conversation or message was created.
+// It carries identifiers only; subscribers fetch the full record if needed.
+type Event struct {
+	Type           string    `json:"type"`
+	ConversationID int       `json:"conversation_id"`
+	MessageID      *int      `json:"message_id,omitempty"`
+	MessageType    string    `json:"message_type,omitempty"`
+	SessionID      string    `json:"session_id"`
+	Timestamp      time.Time `json:"timestamp"`
+}
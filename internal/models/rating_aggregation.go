@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RatingAggregation selects how a conversation's ratings are combined into a
+// single quality score
+type RatingAggregation string
+
+const (
+	RatingAggregationMean   RatingAggregation = "mean"
+	RatingAggregationMedian RatingAggregation = "median"
+	RatingAggregationLatest RatingAggregation = "latest"
+)
+
+// ParseRatingAggregation parses an aggregation name, which must be mean,
+// median or latest
+func ParseRatingAggregation(value string) (RatingAggregation, error) {
+	switch method := RatingAggregation(value); method {
+	case RatingAggregationMean, RatingAggregationMedian, RatingAggregationLatest:
+		return method, nil
+	default:
+		return "", fmt.Errorf("invalid rating aggregation %q: must be mean, median or latest", value)
+	}
+}
+
+// Aggregate combines the ratings using the method. Unknown methods, including
+// the zero value, fall back to the mean. It returns nil when there are no ratings.
+func (a RatingAggregation) Aggregate(ratings []Rating) *float64 {
+	switch a {
+	case RatingAggregationMedian:
+		return MedianRating(ratings)
+	case RatingAggregationLatest:
+		return LatestRating(ratings)
+	default:
+		return MeanRating(ratings)
+	}
+}
+
+// MeanRating returns the arithmetic mean of the ratings
+func MeanRating(ratings []Rating) *float64 {
+	if len(ratings) == 0 {
+		return nil
+	}
+
+	var total int
+	for _, rating := range ratings {
+		total += rating.Rating
+	}
+
+	mean := float64(total) / float64(len(ratings))
+	return &mean
+}
+
+// MedianRating returns the median of the ratings, averaging the two middle
+// values when there is an even number of ratings
+func MedianRating(ratings []Rating) *float64 {
+	if len(ratings) == 0 {
+		return nil
+	}
+
+	values := make([]int, len(ratings))
+	for i, rating := range ratings {
+		values[i] = rating.Rating
+	}
+	sort.Ints(values)
+
+	mid := len(values) / 2
+	median := float64(values[mid])
+	if len(values)%2 == 0 {
+		median = float64(values[mid-1]+values[mid]) / 2
+	}
+	return &median
+}
+
+// LatestRating returns the most recently created rating. Ratings created at
+// the same time are ordered by ID.
+func LatestRating(ratings []Rating) *float64 {
+	if len(ratings) == 0 {
+		return nil
+	}
+
+	latest := ratings[0]
+	for _, rating := range ratings[1:] {
+		if rating.CreatedAt.After(latest.CreatedAt) ||
+			(rating.CreatedAt.Equal(latest.CreatedAt) && rating.ID > latest.ID) {
+			latest = rating
+		}
+	}
+
+	value := float64(latest.Rating)
+	return &value
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRatingAggregation(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Ratings are deliberately out of chronological order
+	ratings := []Rating{
+		{ID: 1, Rating: 5, CreatedAt: base.Add(time.Hour)},
+		{ID: 2, Rating: 1, CreatedAt: base},
+		{ID: 3, Rating: 5, CreatedAt: base.Add(2 * time.Hour)},
+	}
+
+	tests := []struct {
+		name     string
+		method   RatingAggregation
+		ratings  []Rating
+		expected *float64
+	}{
+		{"mean", RatingAggregationMean, ratings, floatPtr(11.0 / 3)},
+		{"median", RatingAggregationMedian, ratings, floatPtr(5)},
+		{"latest", RatingAggregationLatest, ratings, floatPtr(5)},
+		{"zero value falls back to mean", "", ratings, floatPtr(11.0 / 3)},
+		{"median of even count", RatingAggregationMedian, ratings[:2], floatPtr(3)},
+		{"latest breaks ties by ID", RatingAggregationLatest, []Rating{
+			{ID: 2, Rating: 4, CreatedAt: base},
+			{ID: 1, Rating: 2, CreatedAt: base},
+		}, floatPtr(4)},
+		{"no ratings", RatingAggregationMedian, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.method.Aggregate(tt.ratings)
+			if tt.expected == nil {
+				if got != nil {
+					t.Errorf("Expected nil, got %v", *got)
+				}
+				return
+			}
+			if got == nil || math.Abs(*got-*tt.expected) > 0.005 {
+				t.Errorf("Expected %.2f, got %v", *tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseRatingAggregation(t *testing.T) {
+	for _, valid := range []string{"mean", "median", "latest"} {
+		if _, err := ParseRatingAggregation(valid); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", valid, err)
+		}
+	}
+
+	if _, err := ParseRatingAggregation("mode"); err == nil {
+		t.Error("Expected error for unknown aggregation")
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
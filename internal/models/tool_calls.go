@@ -0,0 +1,39 @@
+package models
+
+import "fmt"
+
+// DefaultMaxToolCallDepth is the default limit on how deeply tool call
+// payloads may nest. Real tool arguments rarely exceed a handful of levels.
+const DefaultMaxToolCallDepth = 64
+
+// JSONDepth returns the nesting depth of a decoded JSON value. Scalars have
+// depth 0 and each enclosing object or array adds one level.
+func JSONDepth(value interface{}) int {
+	depth := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			if d := JSONDepth(child); d > depth {
+				depth = d
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if d := JSONDepth(child); d > depth {
+				depth = d
+			}
+		}
+	default:
+		return 0
+	}
+	return depth + 1
+}
+
+// ValidateToolCallDepth rejects decoded tool calls, including their
+// arguments, that nest deeper than maxDepth
+func ValidateToolCallDepth(toolCalls interface{}, maxDepth int) error {
+	if depth := JSONDepth(toolCalls); depth > maxDepth {
+		return fmt.Errorf("tool_calls nesting depth %d exceeds maximum of %d", depth, maxDepth)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import "testing"
+
+// nestedArguments builds a tool call list whose arguments nest depth objects deep
+func nestedArguments(depth int) []interface{} {
+	var arguments interface{} = "leaf"
+	for i := 0; i < depth; i++ {
+		arguments = map[string]interface{}{"nested": arguments}
+	}
+	return []interface{}{map[string]interface{}{"name": "tool", "arguments": arguments}}
+}
+
+func TestJSONDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected int
+	}{
+		{"scalar", "text", 0},
+		{"empty object", map[string]interface{}{}, 1},
+		{"flat array", []interface{}{1.0, "two"}, 1},
+		{"deepest branch wins", map[string]interface{}{
+			"shallow": 1.0,
+			"deep":    []interface{}{map[string]interface{}{"x": 1.0}},
+		}, 3},
+		{"tool call arguments", nestedArguments(3), 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := JSONDepth(tt.value); got != tt.expected {
+				t.Errorf("Expected depth %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateToolCallDepth(t *testing.T) {
+	if err := ValidateToolCallDepth(nestedArguments(8), 10); err != nil {
+		t.Errorf("Expected depth 10 to be accepted, got %v", err)
+	}
+
+	if err := ValidateToolCallDepth(nestedArguments(9), 10); err == nil {
+		t.Error("Expected depth 11 to be rejected")
+	}
+}
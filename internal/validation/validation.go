@@ -5,6 +5,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -16,6 +18,9 @@ const (
 	MaxPathLength        = 1000
 	MaxSessionIDLength   = 100
 	MaxToolCallLength    = 50000 // 50KB for tool calls JSON
+	MaxTagNameLength     = 50
+	MaxTagDescriptionLength = 500
+	MaxToolNameLength    = 100
 	MinRating           = 1
 	MaxRating           = 5
 	MaxPageSize         = 100
@@ -23,10 +28,17 @@ const (
 	MaxPageNumber       = 10000
 )
 
+// HardMaxPageSize is the ceiling for a configured maximum page size, keeping
+// a single page from loading an unbounded number of rows
+const HardMaxPageSize = 1000
+
+// MaxSinceWindow is the longest relative window accepted for since, about a
+// year
+const MaxSinceWindow = 366 * 24 * time.Hour
+
 // Regular expressions for validation
 var (
-	sessionIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
-	pathRegex      = regexp.MustCompile(`^[a-zA-Z0-9._/\\:-]+$`)
+	hexColorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
 )
 
 // ValidationError represents input validation errors
@@ -61,8 +73,25 @@ func SanitizeString(input string, maxLength int) string {
 	return cleaned
 }
 
+// CollapseWhitespace replaces each run of whitespace, including newlines,
+// with a single space and trims the ends
+func CollapseWhitespace(input string) string {
+	return strings.Join(strings.Fields(input), " ")
+}
+
+// SessionIDSeparators are the punctuation characters ValidateSessionID
+// accepts in addition to letters, numbers, underscores and hyphens, for
+// clients whose session IDs contain dotted or colon-separated parts
+const SessionIDSeparators = ".:"
+
 // ValidateSessionID validates a session ID
 func ValidateSessionID(sessionID string) error {
+	return ValidateSessionIDWithSeparators(sessionID, SessionIDSeparators)
+}
+
+// ValidateSessionIDWithSeparators validates a session ID made of ASCII
+// letters, numbers, underscores, hyphens and the characters in separators
+func ValidateSessionIDWithSeparators(sessionID string, separators string) error {
 	if sessionID == "" {
 		return &ValidationError{Field: "session_id", Message: "cannot be empty"}
 	}
@@ -75,16 +104,28 @@ func ValidateSessionID(sessionID string) error {
 		}
 	}
 	
-	if !sessionIDRegex.MatchString(sessionID) {
+	for _, r := range sessionID {
+		if isSessionIDChar(r) || strings.ContainsRune(separators, r) {
+			continue
+		}
+		message := "can only contain letters, numbers, underscores, and hyphens"
+		if separators != "" {
+			message = fmt.Sprintf("can only contain letters, numbers, underscores, hyphens, and %q", separators)
+		}
 		return &ValidationError{
 			Field:   "session_id",
-			Message: "can only contain letters, numbers, underscores, and hyphens",
+			Message: message,
 		}
 	}
 	
 	return nil
 }
 
+// isSessionIDChar reports whether r is always allowed in a session ID
+func isSessionIDChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-'
+}
+
 // ValidateTitle validates a conversation title
 func ValidateTitle(title *string) error {
 	if title == nil {
@@ -156,23 +197,60 @@ func ValidateComment(comment *string) error {
 	return nil
 }
 
+// ValidateToolCalls validates the serialized tool calls of a response
+func ValidateToolCalls(raw string) error {
+	if len(raw) > MaxToolCallLength {
+		return &ValidationError{
+			Field:   "tool_calls",
+			Message: fmt.Sprintf("cannot exceed %d bytes", MaxToolCallLength),
+		}
+	}
+	
+	if !utf8.ValidString(raw) {
+		return &ValidationError{
+			Field:   "tool_calls",
+			Message: "must be valid UTF-8",
+		}
+	}
+	
+	return nil
+}
+
 // ValidatePath validates file paths
 func ValidatePath(path *string) error {
+	return ValidatePathField(path, "path", MaxPathLength)
+}
+
+// ValidatePathField validates a file path against a field-specific length
+// limit. Any valid UTF-8 is accepted, including spaces and accented names,
+// except control characters such as null bytes and newlines.
+func ValidatePathField(path *string, field string, maxLength int) error {
 	if path == nil {
 		return nil // Path is optional
 	}
 	
-	if len(*path) > MaxPathLength {
+	if len(*path) > maxLength {
 		return &ValidationError{
-			Field:   "path",
+			Field:   field,
 			Value:   *path,
-			Message: fmt.Sprintf("cannot exceed %d characters", MaxPathLength),
+			Message: fmt.Sprintf("cannot exceed %d characters", maxLength),
+		}
+	}
+	
+	if *path == "" {
+		return &ValidationError{Field: field, Message: "cannot be empty"}
+	}
+	
+	if !utf8.ValidString(*path) {
+		return &ValidationError{
+			Field:   field,
+			Message: "must be valid UTF-8",
 		}
 	}
 	
-	if !pathRegex.MatchString(*path) {
+	if strings.IndexFunc(*path, unicode.IsControl) >= 0 {
 		return &ValidationError{
-			Field:   "path",
+			Field:   field,
 			Message: "contains invalid characters",
 		}
 	}
@@ -180,6 +258,64 @@ func ValidatePath(path *string) error {
 	return nil
 }
 
+// ValidateTagName validates a tag name
+func ValidateTagName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return &ValidationError{Field: "name", Message: "cannot be empty"}
+	}
+	
+	if len(name) > MaxTagNameLength {
+		return &ValidationError{
+			Field:   "name",
+			Value:   name,
+			Message: fmt.Sprintf("cannot exceed %d characters", MaxTagNameLength),
+		}
+	}
+	
+	if !utf8.ValidString(name) {
+		return &ValidationError{
+			Field:   "name",
+			Message: "must be valid UTF-8",
+		}
+	}
+	
+	return nil
+}
+
+// ValidateToolName validates a tool name used to filter conversations
+func ValidateToolName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return &ValidationError{Field: "tool", Message: "cannot be empty"}
+	}
+	
+	if len(name) > MaxToolNameLength {
+		return &ValidationError{
+			Field:   "tool",
+			Value:   name,
+			Message: fmt.Sprintf("cannot exceed %d characters", MaxToolNameLength),
+		}
+	}
+	
+	return nil
+}
+
+// ValidateColor validates an optional hex color code such as #FF0000
+func ValidateColor(color *string) error {
+	if color == nil || *color == "" {
+		return nil // Color is optional
+	}
+	
+	if !hexColorRegex.MatchString(*color) {
+		return &ValidationError{
+			Field:   "color",
+			Value:   *color,
+			Message: "must be a valid hex color code (e.g., #FF0000)",
+		}
+	}
+	
+	return nil
+}
+
 // ValidateRating validates rating values
 func ValidateRating(rating int) error {
 	if rating < MinRating || rating > MaxRating {
@@ -208,6 +344,12 @@ func ValidateID(id int, fieldName string) error {
 
 // ValidatePagination validates pagination parameters
 func ValidatePagination(page, perPage int) error {
+	return ValidatePaginationWithMax(page, perPage, MaxPageSize)
+}
+
+// ValidatePaginationWithMax validates pagination parameters against a
+// configured maximum page size
+func ValidatePaginationWithMax(page, perPage, maxPageSize int) error {
 	if page < 1 {
 		return &ValidationError{
 			Field:   "page",
@@ -232,11 +374,11 @@ func ValidatePagination(page, perPage int) error {
 		}
 	}
 	
-	if perPage > MaxPageSize {
+	if perPage > maxPageSize {
 		return &ValidationError{
 			Field:   "per_page",
 			Value:   perPage,
-			Message: fmt.Sprintf("cannot exceed %d", MaxPageSize),
+			Message: fmt.Sprintf("cannot exceed %d", maxPageSize),
 		}
 	}
 	
@@ -270,6 +412,12 @@ func ParseAndValidateID(idStr, fieldName string) (int, error) {
 
 // ParseAndValidatePage safely parses pagination parameters
 func ParseAndValidatePage(pageStr, perPageStr string) (int, int, error) {
+	return ParseAndValidatePageWithMax(pageStr, perPageStr, MaxPageSize)
+}
+
+// ParseAndValidatePageWithMax safely parses pagination parameters, allowing
+// pages of up to maxPageSize
+func ParseAndValidatePageWithMax(pageStr, perPageStr string, maxPageSize int) (int, int, error) {
 	page := 1
 	perPage := 20 // Default page size
 	
@@ -297,15 +445,87 @@ func ParseAndValidatePage(pageStr, perPageStr string) (int, int, error) {
 		perPage = pp
 	}
 	
-	if err := ValidatePagination(page, perPage); err != nil {
+	if err := ValidatePaginationWithMax(page, perPage, maxPageSize); err != nil {
 		return 0, 0, err
 	}
 	
 	return page, perPage, nil
 }
 
-// IsValidationError checks if an error is a ValidationError
+// ParseAndValidateTimeRange parses optional RFC3339 from/to bounds.
+// Empty strings yield nil bounds; from must not be after to.
+func ParseAndValidateTimeRange(fromStr, toStr string) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
+	
+	if fromStr != "" {
+		t, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			return nil, nil, &ValidationError{
+				Field:   "from",
+				Value:   fromStr,
+				Message: "must be an RFC3339 timestamp",
+			}
+		}
+		from = &t
+	}
+	
+	if toStr != "" {
+		t, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			return nil, nil, &ValidationError{
+				Field:   "to",
+				Value:   toStr,
+				Message: "must be an RFC3339 timestamp",
+			}
+		}
+		to = &t
+	}
+	
+	if from != nil && to != nil && from.After(*to) {
+		return nil, nil, &ValidationError{
+			Field:   "from",
+			Value:   fromStr,
+			Message: "must not be after to",
+		}
+	}
+	
+	return from, to, nil
+}
+
+// ParseAndValidateSince parses an optional relative window such as "24h" and
+// returns the cutoff that far before now. An empty string yields nil; the
+// window must be positive and no longer than MaxSinceWindow.
+func ParseAndValidateSince(sinceStr string, now time.Time) (*time.Time, error) {
+	if sinceStr == "" {
+		return nil, nil
+	}
+
+	window, err := time.ParseDuration(sinceStr)
+	if err != nil {
+		return nil, &ValidationError{
+			Field:   "since",
+			Value:   sinceStr,
+			Message: "must be a duration such as 30m or 24h",
+		}
+	}
+
+	if window <= 0 || window > MaxSinceWindow {
+		return nil, &ValidationError{
+			Field:   "since",
+			Value:   sinceStr,
+			Message: fmt.Sprintf("must be positive and at most %s", MaxSinceWindow),
+		}
+	}
+
+	cutoff := now.Add(-window)
+	return &cutoff, nil
+}
+
+// IsValidationError checks if an error is a ValidationError or ValidationErrors
 func IsValidationError(err error) bool {
-	_, ok := err.(*ValidationError)
-	return ok
+	switch err.(type) {
+	case *ValidationError, ValidationErrors:
+		return true
+	}
+	return false
 }
\ No newline at end of file
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidateSessionID(t *testing.T) {
@@ -18,6 +19,13 @@ func TestValidateSessionID(t *testing.T) {
 		{"invalid characters", "session@123", true},
 		{"valid with underscores", "session_123", false},
 		{"valid with hyphens", "session-123", false},
+		{"UUID", "3f2b8c1e-9a4d-4e7b-b6a2-1c0d9e8f7a65", false},
+		{"dotted", "claude.session.3f2b8c1e", false},
+		{"colon separated", "project:3f2b8c1e-9a4d:1", false},
+		{"whitespace", "session 123", true},
+		{"tab", "session\t123", true},
+		{"control character", "session\x00123", true},
+		{"non-ASCII letter", "séssion", true},
 	}
 	
 	for _, tt := range tests {
@@ -30,6 +38,30 @@ func TestValidateSessionID(t *testing.T) {
 	}
 }
 
+func TestValidateSessionIDWithSeparators(t *testing.T) {
+	tests := []struct {
+		name       string
+		sessionID  string
+		separators string
+		expectErr  bool
+	}{
+		{"no separators keeps the original rules", "session-123_abc", "", false},
+		{"no separators rejects dots", "claude.session", "", true},
+		{"no separators rejects colons", "project:1", "", true},
+		{"custom separator", "team/session-1", "/", false},
+		{"separator not listed", "team.session", "/", true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSessionIDWithSeparators(tt.sessionID, tt.separators)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("ValidateSessionIDWithSeparators() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
 func TestValidateTitle(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -75,6 +107,28 @@ func TestValidateContent(t *testing.T) {
 	}
 }
 
+func TestValidateToolCalls(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		expectErr bool
+	}{
+		{"valid tool calls", `[{"name":"Read","arguments":{"file_path":"main.go"}}]`, false},
+		{"at the limit", strings.Repeat("a", MaxToolCallLength), false},
+		{"just over the limit", strings.Repeat("a", MaxToolCallLength+1), true},
+		{"invalid UTF-8", "[\"\xff\"]", true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateToolCalls(tt.raw)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("ValidateToolCalls() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
 func TestValidateRating(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -99,6 +153,77 @@ func TestValidateRating(t *testing.T) {
 	}
 }
 
+func TestValidateTagName(t *testing.T) {
+	tests := []struct {
+		name      string
+		tagName   string
+		expectErr bool
+	}{
+		{"valid name", "bugfix", false},
+		{"empty name", "", true},
+		{"whitespace name", "   ", true},
+		{"max length name", strings.Repeat("a", MaxTagNameLength), false},
+		{"too long name", strings.Repeat("a", MaxTagNameLength+1), true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTagName(tt.tagName)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("ValidateTagName() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestValidateToolName(t *testing.T) {
+	tests := []struct {
+		name      string
+		toolName  string
+		expectErr bool
+	}{
+		{"valid tool name", "Bash", false},
+		{"max length", strings.Repeat("a", MaxToolNameLength), false},
+		{"empty", "", true},
+		{"whitespace only", "   ", true},
+		{"too long", strings.Repeat("a", MaxToolNameLength+1), true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateToolName(tt.toolName)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("ValidateToolName() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestValidateColor(t *testing.T) {
+	tests := []struct {
+		name      string
+		color     *string
+		expectErr bool
+	}{
+		{"nil color", nil, false},
+		{"empty color", stringPtr(""), false},
+		{"uppercase hex", stringPtr("#FF0000"), false},
+		{"lowercase hex", stringPtr("#00ff7f"), false},
+		{"missing hash", stringPtr("FF0000"), true},
+		{"short hex", stringPtr("#FFF"), true},
+		{"invalid hex digit", stringPtr("#GG0000"), true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateColor(tt.color)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("ValidateColor() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
 func TestValidateID(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -121,6 +246,46 @@ func TestValidateID(t *testing.T) {
 	}
 }
 
+func TestValidatePathField(t *testing.T) {
+	path := func(n int) *string {
+		p := "/" + strings.Repeat("a", n-1)
+		return &p
+	}
+
+	tests := []struct {
+		name      string
+		path      *string
+		maxLength int
+		expectErr bool
+	}{
+		{"nil path", nil, 10, false},
+		{"at limit", path(10), 10, false},
+		{"over limit", path(11), 10, true},
+		{"empty path", stringPtr(""), 10, true},
+		{"spaces", stringPtr("/Users/me/My Project/"), 100, false},
+		{"accented characters", stringPtr("/home/zoë/Café Notes"), 100, false},
+		{"non-Latin characters", stringPtr("/home/用户/项目"), 100, false},
+		{"Windows drive letter", stringPtr(`C:\Users\me\My Project`), 100, false},
+		{"null byte", stringPtr("/tmp/a\x00b"), 100, true},
+		{"newline", stringPtr("/tmp/a\nb"), 100, true},
+		{"escape character", stringPtr("/tmp/\x1b[31m"), 100, true},
+		{"invalid UTF-8", stringPtr("/tmp/\xff"), 100, true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePathField(tt.path, "transcript_path", tt.maxLength)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("ValidatePathField() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			var validationErr *ValidationError
+			if err != nil && (!errors.As(err, &validationErr) || validationErr.Field != "transcript_path") {
+				t.Errorf("Expected a transcript_path validation error, got %v", err)
+			}
+		})
+	}
+}
+
 func TestValidatePagination(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -146,6 +311,31 @@ func TestValidatePagination(t *testing.T) {
 	}
 }
 
+func TestValidatePaginationWithMax(t *testing.T) {
+	tests := []struct {
+		name        string
+		perPage     int
+		maxPageSize int
+		expectErr   bool
+	}{
+		{"within default max", MaxPageSize, MaxPageSize, false},
+		{"above default max", MaxPageSize + 1, MaxPageSize, true},
+		{"within raised max", 500, 500, false},
+		{"above raised max", 501, 500, true},
+		{"within lowered max", 10, 10, false},
+		{"above lowered max", 11, 10, true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePaginationWithMax(1, tt.perPage, tt.maxPageSize)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("ValidatePaginationWithMax() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
 func TestParseAndValidateID(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -209,6 +399,77 @@ func TestParseAndValidatePage(t *testing.T) {
 	}
 }
 
+func TestParseAndValidateTimeRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		fromStr    string
+		toStr      string
+		expectFrom bool
+		expectTo   bool
+		expectErr  bool
+	}{
+		{"no bounds", "", "", false, false, false},
+		{"from only", "2024-01-01T00:00:00Z", "", true, false, false},
+		{"to only", "", "2024-01-01T00:00:00Z", false, true, false},
+		{"valid range", "2024-01-01T00:00:00Z", "2024-02-01T00:00:00+02:00", true, true, false},
+		{"equal bounds", "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z", true, true, false},
+		{"from after to", "2024-02-01T00:00:00Z", "2024-01-01T00:00:00Z", false, false, true},
+		{"invalid from", "yesterday", "", false, false, true},
+		{"invalid to", "", "2024-01-01", false, false, true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := ParseAndValidateTimeRange(tt.fromStr, tt.toStr)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("ParseAndValidateTimeRange() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if (from != nil) != tt.expectFrom {
+				t.Errorf("ParseAndValidateTimeRange() from = %v, expectFrom %v", from, tt.expectFrom)
+			}
+			if (to != nil) != tt.expectTo {
+				t.Errorf("ParseAndValidateTimeRange() to = %v, expectTo %v", to, tt.expectTo)
+			}
+		})
+	}
+}
+
+func TestParseAndValidateSince(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		sinceStr     string
+		expectCutoff *time.Time
+		expectErr    bool
+	}{
+		{"empty", "", nil, false},
+		{"hours", "24h", timePtr(now.Add(-24 * time.Hour)), false},
+		{"minutes", "90m", timePtr(now.Add(-90 * time.Minute)), false},
+		{"maximum", MaxSinceWindow.String(), timePtr(now.Add(-MaxSinceWindow)), false},
+		{"too large", "10000h", nil, true},
+		{"negative", "-1h", nil, true},
+		{"zero", "0s", nil, true},
+		{"days unsupported", "1d", nil, true},
+		{"not a duration", "yesterday", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cutoff, err := ParseAndValidateSince(tt.sinceStr, now)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("ParseAndValidateSince() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if (cutoff == nil) != (tt.expectCutoff == nil) {
+				t.Fatalf("ParseAndValidateSince() cutoff = %v, expected %v", cutoff, tt.expectCutoff)
+			}
+			if cutoff != nil && !cutoff.Equal(*tt.expectCutoff) {
+				t.Errorf("ParseAndValidateSince() cutoff = %v, expected %v", cutoff, tt.expectCutoff)
+			}
+		})
+	}
+}
+
 func TestSanitizeString(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -233,6 +494,28 @@ func TestSanitizeString(t *testing.T) {
 	}
 }
 
+func TestCollapseWhitespace(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"single spaces unchanged", "too slow", "too slow"},
+		{"multiple spaces", "too    slow", "too slow"},
+		{"tabs and newlines", "too\t\tslow\n\nand wrong", "too slow and wrong"},
+		{"leading and trailing", "  too slow  ", "too slow"},
+		{"only whitespace", " \t ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := CollapseWhitespace(tt.input); result != tt.expected {
+				t.Errorf("CollapseWhitespace() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestIsValidationError(t *testing.T) {
 	validationErr := &ValidationError{Field: "test", Message: "test error"}
 	regularErr := errors.New("regular error")
@@ -249,4 +532,8 @@ func TestIsValidationError(t *testing.T) {
 // Helper function
 func stringPtr(s string) *string {
 	return &s
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
 }
\ No newline at end of file
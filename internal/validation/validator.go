@@ -0,0 +1,50 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+)
+
+// ValidationErrors reports every field that failed validation in a request
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validator accumulates validation failures so a request's invalid fields
+// can all be reported at once instead of one per round trip
+type Validator struct {
+	errs ValidationErrors
+}
+
+// Check records err if it is non-nil. Errors that are not ValidationErrors
+// are recorded with their message and no field.
+func (v *Validator) Check(err error) {
+	if err == nil {
+		return
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		validationErr = &ValidationError{Message: err.Error()}
+	}
+	v.errs = append(v.errs, validationErr)
+}
+
+// Err returns nil when every check passed, the single failure when there was
+// one, and ValidationErrors otherwise
+func (v *Validator) Err() error {
+	switch len(v.errs) {
+	case 0:
+		return nil
+	case 1:
+		return v.errs[0]
+	default:
+		return v.errs
+	}
+}
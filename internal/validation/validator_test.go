@@ -0,0 +1,65 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	t.Run("no failures", func(t *testing.T) {
+		v := &Validator{}
+		v.Check(ValidateSessionID("good-session"))
+		v.Check(nil)
+
+		if err := v.Err(); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("single failure", func(t *testing.T) {
+		v := &Validator{}
+		v.Check(ValidateSessionID(""))
+		v.Check(ValidateTitle(nil))
+
+		err := v.Err()
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Field != "session_id" {
+			t.Fatalf("Expected a single session_id error, got %v", err)
+		}
+		if !IsValidationError(err) {
+			t.Error("Expected IsValidationError to be true")
+		}
+	})
+
+	t.Run("multiple failures", func(t *testing.T) {
+		long := strings.Repeat("a", MaxTitleLength+1)
+		badPath := "/tmp/bad\x00path"
+
+		v := &Validator{}
+		v.Check(ValidateSessionID("good-session"))
+		v.Check(ValidateTitle(&long))
+		v.Check(ValidatePathField(&badPath, "working_directory", MaxPathLength))
+		v.Check(errors.New("plain failure"))
+
+		err := v.Err()
+		var validationErrs ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			t.Fatalf("Expected ValidationErrors, got %T", err)
+		}
+		if !IsValidationError(err) {
+			t.Error("Expected IsValidationError to be true")
+		}
+
+		fields := make([]string, len(validationErrs))
+		for i, fieldErr := range validationErrs {
+			fields[i] = fieldErr.Field
+		}
+		if got := strings.Join(fields, ","); got != "title,working_directory," {
+			t.Errorf("Expected title, working_directory and an unnamed field, got %q", got)
+		}
+		if !strings.Contains(err.Error(), "; ") {
+			t.Errorf("Expected every message in the error string, got %q", err.Error())
+		}
+	})
+}
@@ -20,7 +20,7 @@ func main() {
 		log.Fatal(err)
 	}
 
-	convs, err := db.ListConversations(10, 0)
+	convs, err := db.ListConversations(nil, 10, 0)
 	if err != nil {
 		log.Fatal(err)
 	}